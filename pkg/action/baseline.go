@@ -0,0 +1,128 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/render"
+)
+
+// baselineFile is what a baseline report recorded about one file.
+type baselineFile struct {
+	checksum string
+	ids      map[string]bool
+}
+
+// CompareToBaseline returns a report holding only the findings of current that are absent from the JSON
+// report saved at baselinePath, such as one written by a scan of the main branch.
+//
+// Files are matched by report path, made relative to c.RelativeTo when it is set, so both scans should report
+// paths the same way. Files whose checksum matches the baseline are left out entirely; other files keep only
+// the behaviors whose IDs the baseline did not report for them, and their risk is that of the riskiest one.
+// Files without new behaviors are left out.
+func CompareToBaseline(ctx context.Context, c malcontent.Config, current *malcontent.Report, baselinePath string) (*malcontent.Report, error) {
+	if current == nil {
+		return nil, fmt.Errorf("no report to compare")
+	}
+	if current.Diff != nil {
+		return nil, fmt.Errorf("diff reports cannot be compared to a baseline")
+	}
+
+	f, err := os.Open(baselinePath)
+	if err != nil {
+		return nil, fmt.Errorf("open baseline: %w", err)
+	}
+	defer f.Close()
+
+	baseline, err := render.MergeReports(f)
+	if err != nil {
+		return nil, fmt.Errorf("load baseline %s: %w", baselinePath, err)
+	}
+
+	known := map[string]*baselineFile{}
+	baseline.Files.Range(func(key, value any) bool {
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok || fr == nil {
+			return true
+		}
+		bf := &baselineFile{checksum: fr.Checksum(), ids: map[string]bool{}}
+		for _, b := range fr.Behaviors {
+			bf.ids[b.ID] = true
+		}
+		// Reports grouped by namespace hold their behaviors in BehaviorGroups instead
+		for _, group := range fr.BehaviorGroups {
+			for _, b := range group {
+				bf.ids[b.ID] = true
+			}
+		}
+		known[baselineKey(key, fr, c)] = bf
+		return true
+	})
+
+	result := &malcontent.Report{Filter: current.Filter, Interrupted: current.Interrupted}
+	current.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok || fr == nil || fr.Skipped != "" || len(fr.Behaviors) == 0 {
+			return true
+		}
+
+		bf := known[baselineKey(key, fr, c)]
+		if bf != nil && bf.checksum != "" && bf.checksum == fr.Checksum() {
+			return true
+		}
+
+		added := make([]*malcontent.Behavior, 0, len(fr.Behaviors))
+		risk := 0
+		for _, b := range fr.Behaviors {
+			if bf != nil && bf.ids[b.ID] {
+				continue
+			}
+			added = append(added, b)
+			risk = max(risk, b.RiskScore)
+		}
+		if len(added) == 0 {
+			return true
+		}
+
+		nfr := cloneFileReport(fr)
+		nfr.Behaviors = added
+		nfr.RiskScore = risk
+		nfr.RiskLevel = malcontent.RiskLevel(risk)
+		result.Files.Store(key, nfr)
+		return true
+	})
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	return result, nil
+}
+
+// baselineKey returns the path fr is matched by between a baseline and the current report.
+func baselineKey(key any, fr *malcontent.FileReport, c malcontent.Config) string {
+	path := fr.Path
+	if path == "" {
+		path, _ = key.(string)
+	}
+	if c.RelativeTo == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	root, err := filepath.Abs(c.RelativeTo)
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/programkind"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"github.com/chainguard-dev/malcontent/pkg/version"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// rulesHashes memoizes the hash of each compiled ruleset, as serializing rules is expensive.
+var rulesHashes sync.Map
+
+// cacheSettings holds everything besides file content and rules that shapes a cached report.
+type cacheSettings struct {
+	Version                string
+	Rules                  string
+	CombinationRules       []malcontent.CombinationRule
+	CorroborationThreshold int
+	DeepPE                 bool
+	DefaultConfidence      int
+	ExcludeRuleIDs         []string
+	ExtractSyscalls        bool
+	FilterByTag            []string
+	HashAlgo               string
+	IgnoreSelf             bool
+	IgnoreTags             []string
+	IncludeRuleIDs         []string
+	KeepDuplicateBehaviors bool
+	MaxMatchStringLen      int
+	MaxMatchStrings        int
+	MinConfidence          int
+	MinFileRisk            int
+	MinRisk                int
+	NormalizeEncoding      bool
+	OmitMatchStrings       bool
+	Overrides              string
+	QuantityIncreasesRisk  bool
+	RedactMatches          string
+	Scan                   bool
+}
+
+// scanCache stores file reports on disk, keyed by file content, the compiled rules and report settings.
+// Changing the rules or settings changes every key, so stale reports are never served.
+type scanCache struct {
+	dir    string
+	salt   string
+	logger *clog.Logger
+}
+
+// rulesHash returns the SHA256 of the serialized ruleset.
+func rulesHash(yrs *yarax.Rules) (string, error) {
+	if h, ok := rulesHashes.Load(yrs); ok {
+		if s, ok := h.(string); ok {
+			return s, nil
+		}
+	}
+
+	h := sha256.New()
+	if _, err := yrs.WriteTo(h); err != nil {
+		return "", fmt.Errorf("serialize rules: %w", err)
+	}
+	s := hex.EncodeToString(h.Sum(nil))
+	rulesHashes.Store(yrs, s)
+	return s, nil
+}
+
+// newScanCache returns the cache for c, or nil when caching is disabled or unavailable.
+func newScanCache(ctx context.Context, c malcontent.Config, yrs *yarax.Rules, logger *clog.Logger) *scanCache {
+	// Profiling rules, finding unused ones and OnMatch require every file to be run through the scanner,
+	// and reports scored by a custom function cannot be keyed by their settings
+	if c.CacheDir == "" || c.NoCache || c.OnMatch != nil || c.ProfileRules || c.ReportUnusedRules || c.ScoreFunc != nil || yrs == nil {
+		return nil
+	}
+
+	rh, err := rulesHash(yrs)
+	if err != nil {
+		logger.Debugf("scan cache disabled: %v", err)
+		return nil
+	}
+
+	overrides, err := report.OverridesFor(ctx, c)
+	if err != nil {
+		logger.Debugf("scan cache disabled: %v", err)
+		return nil
+	}
+
+	settings, err := json.Marshal(cacheSettings{
+		Version:                version.ID,
+		Rules:                  rh,
+		CombinationRules:       c.CombinationRules,
+		CorroborationThreshold: c.CorroborationThreshold,
+		DeepPE:                 c.DeepPE,
+		DefaultConfidence:      c.DefaultConfidence,
+		ExcludeRuleIDs:         c.ExcludeRuleIDs,
+		ExtractSyscalls:        c.ExtractSyscalls,
+		FilterByTag:            c.FilterByTag,
+		HashAlgo:               c.HashAlgo,
+		IgnoreSelf:             c.IgnoreSelf,
+		IgnoreTags:             c.IgnoreTags,
+		IncludeRuleIDs:         c.IncludeRuleIDs,
+		KeepDuplicateBehaviors: c.KeepDuplicateBehaviors,
+		MaxMatchStringLen:      c.MaxMatchStringLen,
+		MaxMatchStrings:        c.MaxMatchStrings,
+		MinConfidence:          c.MinConfidence,
+		MinFileRisk:            int(c.MinFileRisk),
+		MinRisk:                int(c.MinRisk),
+		NormalizeEncoding:      c.NormalizeEncoding,
+		OmitMatchStrings:       c.OmitMatchStrings,
+		Overrides:              overrides.Digest(),
+		QuantityIncreasesRisk:  c.QuantityIncreasesRisk,
+		RedactMatches:          c.RedactMatches,
+		Scan:                   c.Scan,
+	})
+	if err != nil {
+		logger.Debugf("scan cache disabled: %v", err)
+		return nil
+	}
+	sum := sha256.Sum256(settings)
+
+	return &scanCache{dir: c.CacheDir, salt: hex.EncodeToString(sum[:]), logger: logger}
+}
+
+// entry returns the cache file for the given file content, or "" if sc is nil.
+// The detected kind is part of the key because rules may be restricted to specific file types.
+func (sc *scanCache) entry(fc []byte, kind *programkind.FileType) string {
+	if sc == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	h.Write([]byte(sc.salt))
+	h.Write(fc)
+	if kind != nil {
+		fmt.Fprintf(h, "\x00%s\x00%s", kind.Ext, kind.MIME)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	return filepath.Join(sc.dir, key[:2], key+".json")
+}
+
+// load returns the report cached in entry, if any.
+func (sc *scanCache) load(entry string) (*malcontent.FileReport, bool) {
+	if sc == nil || entry == "" {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(entry)
+	if err != nil {
+		return nil, false
+	}
+
+	var fr malcontent.FileReport
+	if err := json.Unmarshal(data, &fr); err != nil {
+		sc.logger.Debugf("ignoring corrupt cache entry %s: %v", entry, err)
+		return nil, false
+	}
+	return &fr, true
+}
+
+// store caches fr in entry; failures are logged, as the cache is best-effort.
+func (sc *scanCache) store(entry string, fr *malcontent.FileReport) {
+	if sc == nil || entry == "" || fr == nil {
+		return
+	}
+
+	if err := sc.write(entry, fr); err != nil {
+		sc.logger.Debugf("unable to cache %s: %v", fr.Path, err)
+	}
+}
+
+func (sc *scanCache) write(p string, fr *malcontent.FileReport) error {
+	data, err := json.Marshal(fr)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent scans never read a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
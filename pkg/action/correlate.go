@@ -0,0 +1,59 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+)
+
+// correlate returns the match strings of r found in more than one file, along with the files and behaviors
+// they were found in. Strings found in the most files come first. Masked matches are left out, as their
+// placeholder says nothing about the content.
+func correlate(r *malcontent.Report) []malcontent.Correlation {
+	type sightings struct {
+		paths   map[string]bool
+		ruleIDs map[string]bool
+	}
+	seen := map[string]*sightings{}
+
+	r.EachBehavior(func(_ string, fr *malcontent.FileReport, b *malcontent.Behavior) bool {
+		for _, ms := range b.MatchStrings {
+			if ms == "" || ms == report.RedactedMask {
+				continue
+			}
+			s, ok := seen[ms]
+			if !ok {
+				s = &sightings{paths: map[string]bool{}, ruleIDs: map[string]bool{}}
+				seen[ms] = s
+			}
+			s.paths[fr.Path] = true
+			s.ruleIDs[b.ID] = true
+		}
+		return true
+	})
+
+	cs := make([]malcontent.Correlation, 0, len(seen))
+	for ms, s := range seen {
+		if len(s.paths) < 2 {
+			continue
+		}
+		cs = append(cs, malcontent.Correlation{
+			MatchString: ms,
+			Paths:       slices.Sorted(maps.Keys(s.paths)),
+			RuleIDs:     slices.Sorted(maps.Keys(s.ruleIDs)),
+		})
+	}
+	slices.SortFunc(cs, func(a, b malcontent.Correlation) int {
+		if n := cmp.Compare(len(b.Paths), len(a.Paths)); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.MatchString, b.MatchString)
+	})
+	return cs
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/programkind"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+)
+
+type contentDedupKey struct{}
+
+// contentDedup shares the report of the first file scanned with some content
+// with every later file that has identical content.
+type contentDedup struct {
+	algo string
+	mu   sync.Mutex
+	seen map[string]*dedupEntry
+}
+
+// dedupEntry is the result of scanning the first file with some content.
+type dedupEntry struct {
+	done chan struct{}
+	fr   *malcontent.FileReport
+	// path is the display path of the file that was scanned
+	path string
+}
+
+// withContentDedup returns a context that deduplicates scans of identical files when c.DedupByHash is set.
+func withContentDedup(ctx context.Context, c malcontent.Config) context.Context {
+	if !c.DedupByHash {
+		return ctx
+	}
+	return context.WithValue(ctx, contentDedupKey{}, &contentDedup{algo: c.HashAlgo, seen: map[string]*dedupEntry{}})
+}
+
+func contentDedupFrom(ctx context.Context) *contentDedup {
+	d, _ := ctx.Value(contentDedupKey{}).(*contentDedup)
+	return d
+}
+
+// claim returns the entry for fc, and whether the caller is the first to see
+// that content and must publish its report.
+func (d *contentDedup) claim(ctx context.Context, fc []byte, kind *programkind.FileType) (*dedupEntry, bool) {
+	// As with the scan cache, the detected kind is part of the key because rules may be restricted to specific file types
+	key := report.ChecksumFor(ctx, fc, d.algo)
+	if kind != nil {
+		key = fmt.Sprintf("%s\x00%s\x00%s", key, kind.Ext, kind.MIME)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e, ok := d.seen[key]; ok {
+		return e, false
+	}
+	e := &dedupEntry{done: make(chan struct{})}
+	d.seen[key] = e
+	return e, true
+}
+
+// publish records the report for the entry, or that scanning failed if fr is nil.
+func (e *dedupEntry) publish(fr *malcontent.FileReport) {
+	if fr != nil {
+		e.fr = cloneFileReport(fr)
+		e.path = fr.Path
+	}
+	close(e.done)
+}
+
+// wait returns a copy of the report published for the entry, or false if scanning failed.
+func (e *dedupEntry) wait(ctx context.Context) (*malcontent.FileReport, bool) {
+	select {
+	case <-ctx.Done():
+		return nil, false
+	case <-e.done:
+	}
+	if e.fr == nil {
+		return nil, false
+	}
+	fr := cloneFileReport(e.fr)
+	fr.DuplicateOf = e.path
+	return fr, true
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// deterministicFiles returns the file reports in r sorted by path, for c.Deterministic.
+// Behaviors and match strings are sorted, and each set of duplicate files refers to the first of them by path,
+// rather than to whichever was scanned first.
+func deterministicFiles(r *malcontent.Report) []*malcontent.FileReport {
+	type keyed struct {
+		key string
+		fr  *malcontent.FileReport
+	}
+
+	var files []keyed
+	r.Files.Range(func(key, value any) bool {
+		k, ok := key.(string)
+		if !ok {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok && fr != nil {
+			files = append(files, keyed{key: k, fr: fr})
+		}
+		return true
+	})
+	slices.SortFunc(files, func(a, b keyed) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	frs := make([]*malcontent.FileReport, 0, len(files))
+	for _, f := range files {
+		sortBehaviors(f.fr)
+		frs = append(frs, f.fr)
+	}
+	canonicalDuplicates(frs)
+	return frs
+}
+
+// renderDeterministic passes the file reports of r to c.Renderer in order of path, as they
+// are not rendered while scanning when c.Deterministic is set.
+func renderDeterministic(ctx context.Context, c malcontent.Config, r *malcontent.Report) error {
+	for _, fr := range deterministicFiles(r) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if r.Diff == nil && shouldRender(c, fr) {
+			if err := c.Renderer.File(ctx, fr); err != nil {
+				return fmt.Errorf("render: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// sortBehaviors sorts the behaviors of fr by ID, then rule name, and their match strings.
+func sortBehaviors(fr *malcontent.FileReport) {
+	slices.SortStableFunc(fr.Behaviors, func(a, b *malcontent.Behavior) int {
+		return cmp.Or(strings.Compare(a.ID, b.ID), strings.Compare(a.RuleName, b.RuleName))
+	})
+	for _, b := range fr.Behaviors {
+		slices.Sort(b.MatchStrings)
+	}
+}
+
+// canonicalDuplicates makes each set of identical files in frs, sorted by path, refer to the first of them with DuplicateOf.
+func canonicalDuplicates(frs []*malcontent.FileReport) {
+	byPath := make(map[string]*malcontent.FileReport, len(frs))
+	sets := map[string][]*malcontent.FileReport{}
+	for _, fr := range frs {
+		byPath[fr.Path] = fr
+		if fr.DuplicateOf != "" {
+			sets[fr.DuplicateOf] = append(sets[fr.DuplicateOf], fr)
+		}
+	}
+
+	for original, dups := range sets {
+		if fr, ok := byPath[original]; ok {
+			dups = append(dups, fr)
+		}
+		first := slices.MinFunc(dups, func(a, b *malcontent.FileReport) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		for _, fr := range dups {
+			fr.DuplicateOf = first.Path
+		}
+		first.DuplicateOf = ""
+	}
+}
@@ -7,10 +7,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"math"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/agext/levenshtein"
@@ -127,6 +128,8 @@ func relFileReport(ctx context.Context, c malcontent.Config, fromPath string, is
 
 	fromConfig := c
 	fromConfig.Renderer = nil
+	// Diffs pair files by their paths relative to each side, so need the scanned paths
+	fromConfig.RelativeTo = ""
 	fromConfig.ScanPaths = []string{fromPath}
 	fromReport, err := recursiveScan(ctx, fromConfig)
 	if err != nil {
@@ -199,6 +202,7 @@ func Diff(ctx context.Context, c malcontent.Config, _ *clog.Logger) (*malcontent
 	if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
+	ctx = withLogger(ctx, c)
 
 	if len(c.ScanPaths) != 2 {
 		return nil, fmt.Errorf("diff mode requires 2 paths, you passed in %d path(s)", len(c.ScanPaths))
@@ -320,6 +324,10 @@ func Diff(ctx context.Context, c malcontent.Config, _ *clog.Logger) (*malcontent
 	if d.Added != nil && d.Removed != nil {
 		inferMoves(ctx, c, d, srcResult, destResult, isImage)
 	}
+
+	if c.DiffAddedOnly {
+		filterAddedOnly(d)
+	}
 	return &malcontent.Report{Diff: d}, nil
 }
 
@@ -389,7 +397,7 @@ func handleFile(ctx context.Context, c malcontent.Config, fr, tr *malcontent.Fil
 	}
 
 	// We've now established that file exists in both source & destination
-	if fr.RiskScore < c.MinFileRisk && tr.RiskScore < c.MinFileRisk {
+	if !fr.Risk().AtLeast(c.MinFileRisk) && !tr.Risk().AtLeast(c.MinFileRisk) {
 		clog.FromContext(ctx).Info("diff does not meet min trigger level", slog.Any("path", tr.Path))
 		return
 	}
@@ -481,18 +489,97 @@ func inferMoves(ctx context.Context, c malcontent.Config, d *malcontent.DiffRepo
 		return
 	}
 
+	// Identical content is a stronger signal than path similarity, so handle those moves first
+	if !c.DiffNoHashMoves {
+		inferHashMoves(ctx, c, d, dest, isImage)
+	}
+
 	for _, cr := range combineReports(d.Removed, d.Added) {
 		fileMove(ctx, c, cr.RemovedFR, cr.AddedFR, cr.Removed, cr.Added, d, cr.Score, src, dest, isImage)
 	}
 }
 
+// inferHashMoves pairs removed and added files with identical checksums and records them as moves.
+// Files without checksums are left for path pairing.
+func inferHashMoves(ctx context.Context, c malcontent.Config, d *malcontent.DiffReport, dest ScanResult, isImage bool) {
+	if ctx.Err() != nil {
+		return
+	}
+
+	removed := make(map[string]string, d.Removed.Len())
+	for r := d.Removed.Oldest(); r != nil; r = r.Next() {
+		sum := r.Value.Checksum()
+		if sum == "" {
+			continue
+		}
+		if _, exists := removed[sum]; !exists {
+			removed[sum] = r.Key
+		}
+	}
+
+	if len(removed) == 0 {
+		return
+	}
+
+	var moves []malcontent.CombinedReport
+	for a := d.Added.Oldest(); a != nil; a = a.Next() {
+		rpath, exists := removed[a.Value.Checksum()]
+		if !exists {
+			continue
+		}
+		fr, _ := d.Removed.Get(rpath)
+		moves = append(moves, malcontent.CombinedReport{
+			Added:     a.Key,
+			AddedFR:   a.Value,
+			Removed:   rpath,
+			RemovedFR: fr,
+			Score:     1,
+		})
+		// Each removed file can only account for a single move
+		delete(removed, a.Value.Checksum())
+	}
+
+	for _, m := range moves {
+		d.Removed.Delete(m.Removed)
+		d.Added.Delete(m.Added)
+
+		// The content is unchanged, so the only thing left to filter is the move itself
+		if filterDiff(ctx, c, m.RemovedFR, m.AddedFR) {
+			continue
+		}
+
+		abs := &malcontent.FileReport{
+			Path:                 m.AddedFR.Path,
+			SHA256:               m.AddedFR.SHA256,
+			Hash:                 m.AddedFR.Hash,
+			HashAlgo:             m.AddedFR.HashAlgo,
+			PreviousPath:         m.RemovedFR.Path,
+			PreviousRelPath:      m.Removed,
+			PreviousRelPathScore: m.Score,
+
+			Behaviors:         m.AddedFR.Behaviors,
+			PreviousRiskScore: m.RemovedFR.RiskScore,
+			PreviousRiskLevel: m.RemovedFR.RiskLevel,
+
+			RiskScore: m.AddedFR.RiskScore,
+			RiskLevel: m.AddedFR.RiskLevel,
+		}
+
+		if isImage {
+			abs.Path = strings.TrimPrefix(abs.Path, "/private")
+			abs.Path = fmt.Sprintf("%s ∴ %s", dest.imageURI, strings.TrimPrefix(abs.Path, dest.tmpRoot))
+		}
+		d.Modified.Set(m.Added, abs)
+	}
+}
+
 func fileMove(ctx context.Context, c malcontent.Config, fr, tr *malcontent.FileReport, rpath, apath string, d *malcontent.DiffReport, score float64, _, dest ScanResult, isImage bool) {
 	if ctx.Err() != nil {
 		return
 	}
 
-	minRisk := int(math.Min(float64(c.MinRisk), float64(c.MinFileRisk)))
-	if fr.RiskScore < minRisk && tr.RiskScore < minRisk {
+	minRisk := min(c.MinRisk, c.MinFileRisk)
+	if !fr.Risk().AtLeast(minRisk) && !tr.Risk().AtLeast(minRisk) {
 		clog.FromContext(ctx).Info("diff does not meet min trigger level", slog.Any("path", tr.Path))
 		return
 	}
@@ -566,3 +653,140 @@ func filterDiff(ctx context.Context, c malcontent.Config, fr, tr *malcontent.Fil
 
 	return false
 }
+
+// filterAddedOnly reduces d to what the destination introduced: removed files are dropped, modified files
+// keep only their DiffAdded behaviors and are dropped if none remain, and added files are kept whole.
+func filterAddedOnly(d *malcontent.DiffReport) {
+	d.Removed = orderedmap.New[string, *malcontent.FileReport]()
+
+	var unchanged []string
+	for pair := d.Modified.Oldest(); pair != nil; pair = pair.Next() {
+		fr := pair.Value
+		fr.Behaviors = slices.DeleteFunc(fr.Behaviors, func(b *malcontent.Behavior) bool { return !b.DiffAdded })
+		if len(fr.Behaviors) == 0 {
+			unchanged = append(unchanged, pair.Key)
+		}
+	}
+	for _, k := range unchanged {
+		d.Modified.Delete(k)
+	}
+}
+
+// DiffReports compares two completed scan reports, such as those of a package and its predecessor,
+// without rescanning either. Files are matched by their path relative to the root of each report;
+// unmatched files are recorded as added or removed, moves are inferred as in Diff, and behaviors
+// of modified files are marked with DiffAdded or DiffRemoved. The input reports are not modified.
+// Wrap the result in a malcontent.Report to render it with any renderer that supports diffs.
+func DiffReports(ctx context.Context, c malcontent.Config, src, dest *malcontent.Report) (*malcontent.DiffReport, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if src == nil || dest == nil {
+		return nil, fmt.Errorf("diff requires two reports")
+	}
+	if src.Diff != nil || dest.Diff != nil {
+		return nil, fmt.Errorf("cannot diff a diff report")
+	}
+
+	srcFiles := reportRelPaths(src)
+	destFiles := reportRelPaths(dest)
+
+	d := &malcontent.DiffReport{
+		Added:    orderedmap.New[string, *malcontent.FileReport](),
+		Removed:  orderedmap.New[string, *malcontent.FileReport](),
+		Modified: orderedmap.New[string, *malcontent.FileReport](),
+	}
+
+	for _, rel := range slices.Sorted(maps.Keys(srcFiles)) {
+		fr := srcFiles[rel]
+		tr, exists := destFiles[rel]
+		if !exists {
+			d.Removed.Set(rel, fr)
+			continue
+		}
+		handleFile(ctx, c, fr, tr, rel, d, ScanResult{}, ScanResult{}, false)
+	}
+
+	for _, rel := range slices.Sorted(maps.Keys(destFiles)) {
+		if _, exists := srcFiles[rel]; !exists {
+			d.Added.Set(rel, destFiles[rel])
+		}
+	}
+
+	inferMoves(ctx, c, d, ScanResult{}, ScanResult{}, false)
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if c.DiffAddedOnly {
+		filterAddedOnly(d)
+	}
+	return d, nil
+}
+
+// reportRelPaths returns copies of the scanned files in r, keyed by their path relative to the report root:
+// the path within the archive or image for extracted files, and the path below the common parent directory otherwise.
+func reportRelPaths(r *malcontent.Report) map[string]*malcontent.FileReport {
+	files := map[string]*malcontent.FileReport{}
+	var plain []string
+
+	r.Files.Range(func(key, value any) bool {
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok || fr.Skipped != "" {
+			return true
+		}
+		p, ok := key.(string)
+		if !ok || p == "" {
+			p = fr.Path
+		}
+
+		if i := strings.LastIndex(p, "∴"); i >= 0 {
+			files[strings.TrimPrefix(strings.TrimSpace(p[i+len("∴"):]), "/")] = cloneFileReport(fr)
+			return true
+		}
+		plain = append(plain, p)
+		files[p] = cloneFileReport(fr)
+		return true
+	})
+
+	root := commonDir(plain)
+	for _, p := range plain {
+		rel := filepath.Base(p)
+		if len(plain) > 1 {
+			if r, err := filepath.Rel(root, p); err == nil {
+				rel = r
+			}
+		}
+		fr := files[p]
+		delete(files, p)
+		files[filepath.ToSlash(rel)] = fr
+	}
+	return files
+}
+
+// commonDir returns the deepest directory containing every path in paths.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	root := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for root != filepath.Dir(root) && !strings.HasPrefix(p, root+string(filepath.Separator)) {
+			root = filepath.Dir(root)
+		}
+	}
+	return root
+}
+
+// cloneFileReport returns a copy of fr whose behaviors and metadata can be changed without modifying fr.
+func cloneFileReport(fr *malcontent.FileReport) *malcontent.FileReport {
+	c := *fr
+	c.Meta = maps.Clone(fr.Meta)
+	c.Behaviors = make([]*malcontent.Behavior, 0, len(fr.Behaviors))
+	for _, b := range fr.Behaviors {
+		bc := *b
+		c.Behaviors = append(c.Behaviors, &bc)
+	}
+	return &c
+}
@@ -0,0 +1,351 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/render"
+	"github.com/google/go-cmp/cmp"
+	orderedmap "github.com/wk8/go-ordered-map/v2"
+)
+
+func TestInferHashMoves(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	behaviors := []*malcontent.Behavior{
+		{ID: "net/download", RiskScore: 2, RiskLevel: malcontent.RiskMedium},
+	}
+	moved := &malcontent.FileReport{
+		Path:      "old/scripts/fetch.sh",
+		SHA256:    "4355a46b19d348dc2f57c046f8ef63d4538ebb936000f3c9ee954a27460dd865",
+		Behaviors: behaviors,
+		RiskScore: 2,
+		RiskLevel: malcontent.RiskMedium,
+	}
+	renamed := &malcontent.FileReport{
+		Path:      "new/bin/download",
+		SHA256:    moved.SHA256,
+		Behaviors: behaviors,
+		RiskScore: 2,
+		RiskLevel: malcontent.RiskMedium,
+	}
+	removed := &malcontent.FileReport{
+		Path:      "old/gone.sh",
+		SHA256:    "53c234e5e8472b6ac51c1ae1cab3fe06fad053beb8ebfd8977b010655bfdd3c3",
+		RiskScore: 1,
+		RiskLevel: malcontent.RiskLow,
+	}
+	added := &malcontent.FileReport{
+		Path:      "new/fresh.sh",
+		SHA256:    "1121cfccd5913f0a63fec40a6ffd44ea64f9dc135c66634ba001d10bcf4302a2",
+		RiskScore: 1,
+		RiskLevel: malcontent.RiskLow,
+	}
+
+	d := &malcontent.DiffReport{
+		Added:    orderedmap.New[string, *malcontent.FileReport](),
+		Removed:  orderedmap.New[string, *malcontent.FileReport](),
+		Modified: orderedmap.New[string, *malcontent.FileReport](),
+	}
+	d.Removed.Set(moved.Path, moved)
+	d.Removed.Set(removed.Path, removed)
+	d.Added.Set(renamed.Path, renamed)
+	d.Added.Set(added.Path, added)
+
+	inferHashMoves(ctx, malcontent.Config{}, d, ScanResult{}, false)
+
+	if _, ok := d.Removed.Get(moved.Path); ok {
+		t.Errorf("renamed file %s is still reported as removed", moved.Path)
+	}
+	if _, ok := d.Added.Get(renamed.Path); ok {
+		t.Errorf("renamed file %s is still reported as added", renamed.Path)
+	}
+	if _, ok := d.Removed.Get(removed.Path); !ok {
+		t.Errorf("removed file %s is missing from removals", removed.Path)
+	}
+	if _, ok := d.Added.Get(added.Path); !ok {
+		t.Errorf("added file %s is missing from additions", added.Path)
+	}
+
+	got, ok := d.Modified.Get(renamed.Path)
+	if !ok {
+		t.Fatalf("renamed file %s was not reported as a move", renamed.Path)
+	}
+	if got.PreviousPath != moved.Path {
+		t.Errorf("PreviousPath = %q, want %q", got.PreviousPath, moved.Path)
+	}
+	if got.PreviousRelPathScore != 1 {
+		t.Errorf("PreviousRelPathScore = %f, want 1", got.PreviousRelPathScore)
+	}
+	for _, b := range got.Behaviors {
+		if b.DiffAdded || b.DiffRemoved {
+			t.Errorf("behavior %s marked as changed for an unchanged file", b.ID)
+		}
+	}
+}
+
+func TestDiffNoHashMoves(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	report := func(files ...*malcontent.FileReport) *malcontent.Report {
+		r := &malcontent.Report{}
+		for _, fr := range files {
+			r.Files.Store(fr.Path, fr)
+		}
+		return r
+	}
+	behaviors := []*malcontent.Behavior{{ID: "exec/shell", RiskScore: 1, RiskLevel: malcontent.RiskLow}}
+
+	// helper.sh moves with unchanged content; tool-1.2.sh has no checksum, so only its path pairs it
+	src := report(
+		&malcontent.FileReport{Path: "/pkgs/v1/lib/helper.sh", SHA256: "3333", RiskScore: 1, Behaviors: behaviors},
+		&malcontent.FileReport{Path: "/pkgs/v1/bin/tool-1.2.sh", RiskScore: 1, Behaviors: behaviors},
+	)
+	dest := report(
+		&malcontent.FileReport{Path: "/pkgs/v2/libexec/helper-tool", SHA256: "3333", RiskScore: 1, Behaviors: behaviors},
+		&malcontent.FileReport{Path: "/pkgs/v2/bin/tool-1.3.sh", RiskScore: 1, Behaviors: behaviors},
+	)
+
+	for _, noHash := range []bool{false, true} {
+		d, err := DiffReports(ctx, malcontent.Config{DiffNoHashMoves: noHash}, src, dest)
+		if err != nil {
+			t.Fatalf("DiffReports(DiffNoHashMoves=%v): %v", noHash, err)
+		}
+
+		if m, ok := d.Modified.Get("bin/tool-1.3.sh"); !ok || m.PreviousRelPath != "bin/tool-1.2.sh" {
+			t.Errorf("DiffNoHashMoves=%v: move of bin/tool-1.2.sh without a checksum not detected: %+v", noHash, m)
+		}
+
+		_, moved := d.Modified.Get("libexec/helper-tool")
+		_, removed := d.Removed.Get("lib/helper.sh")
+		_, added := d.Added.Get("libexec/helper-tool")
+		if moved == noHash || removed != noHash || added != noHash {
+			t.Errorf("DiffNoHashMoves=%v: helper moved=%v, removed=%v, added=%v", noHash, moved, removed, added)
+		}
+	}
+}
+
+func TestDiffReports(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	report := func(files ...*malcontent.FileReport) *malcontent.Report {
+		r := &malcontent.Report{}
+		for _, fr := range files {
+			r.Files.Store(fr.Path, fr)
+		}
+		return r
+	}
+
+	src := report(
+		&malcontent.FileReport{
+			Path:      "/pkgs/v1/bin/run.sh",
+			SHA256:    "1111",
+			RiskScore: 2,
+			RiskLevel: malcontent.RiskMedium,
+			Behaviors: []*malcontent.Behavior{
+				{ID: "net/download", RiskScore: 2},
+				{ID: "fs/permission/modify", RiskScore: 1},
+			},
+		},
+		&malcontent.FileReport{Path: "/pkgs/v1/lib/legacy.sh", SHA256: "2222", RiskScore: 1, Behaviors: []*malcontent.Behavior{{ID: "fs/file/delete", RiskScore: 1}}},
+		&malcontent.FileReport{Path: "/pkgs/v1/lib/helper.sh", SHA256: "3333", RiskScore: 1, Behaviors: []*malcontent.Behavior{{ID: "exec/shell", RiskScore: 1}}},
+	)
+	dest := report(
+		&malcontent.FileReport{
+			Path:      "/pkgs/v2/bin/run.sh",
+			SHA256:    "4444",
+			RiskScore: 3,
+			RiskLevel: malcontent.RiskHigh,
+			Behaviors: []*malcontent.Behavior{
+				{ID: "net/download", RiskScore: 2},
+				{ID: "evasion/logging/hide", RiskScore: 3},
+			},
+		},
+		// Identical content under a new name is a move rather than an add and remove
+		&malcontent.FileReport{Path: "/pkgs/v2/libexec/helper-tool", SHA256: "3333", RiskScore: 1, Behaviors: []*malcontent.Behavior{{ID: "exec/shell", RiskScore: 1}}},
+		&malcontent.FileReport{Path: "/pkgs/v2/lib/new.sh", SHA256: "5555", RiskScore: 1, Behaviors: []*malcontent.Behavior{{ID: "net/socket", RiskScore: 1}}},
+		&malcontent.FileReport{Path: "/pkgs/v2/README", Skipped: "data file or empty"},
+	)
+
+	d, err := DiffReports(ctx, malcontent.Config{}, src, dest)
+	if err != nil {
+		t.Fatalf("DiffReports: %v", err)
+	}
+
+	if _, ok := d.Removed.Get("lib/legacy.sh"); !ok || d.Removed.Len() != 1 {
+		t.Errorf("removed = %v, want only lib/legacy.sh", d.Removed.Len())
+	}
+	if _, ok := d.Added.Get("lib/new.sh"); !ok || d.Added.Len() != 1 {
+		t.Errorf("added = %v, want only lib/new.sh", d.Added.Len())
+	}
+
+	moved, ok := d.Modified.Get("libexec/helper-tool")
+	if !ok || moved.PreviousRelPath != "lib/helper.sh" {
+		t.Errorf("move of lib/helper.sh not detected: %+v", moved)
+	}
+
+	modified, ok := d.Modified.Get("bin/run.sh")
+	if !ok {
+		t.Fatalf("bin/run.sh not reported as modified")
+	}
+	got := map[string]string{}
+	for _, b := range modified.Behaviors {
+		switch {
+		case b.DiffAdded:
+			got[b.ID] = "added"
+		case b.DiffRemoved:
+			got[b.ID] = "removed"
+		default:
+			got[b.ID] = "unchanged"
+		}
+	}
+	want := map[string]string{"net/download": "unchanged", "evasion/logging/hide": "added", "fs/permission/modify": "removed"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("behavior changes mismatch (-want +got):\n%s", diff)
+	}
+	if modified.PreviousRiskScore != 2 || modified.RiskScore != 3 {
+		t.Errorf("risk = %d -> %d, want 2 -> 3", modified.PreviousRiskScore, modified.RiskScore)
+	}
+
+	// The inputs are left untouched for reuse
+	dest.Files.Range(func(_, value any) bool {
+		for _, b := range value.(*malcontent.FileReport).Behaviors {
+			if b.DiffAdded || b.DiffRemoved {
+				t.Errorf("input behavior %s was marked", b.ID)
+			}
+		}
+		return true
+	})
+
+	// Only the added file and the new behavior of bin/run.sh remain when limited to additions
+	ad, err := DiffReports(ctx, malcontent.Config{DiffAddedOnly: true}, src, dest)
+	if err != nil {
+		t.Fatalf("DiffReports added only: %v", err)
+	}
+	if ad.Removed.Len() != 0 {
+		t.Errorf("removed = %d files with DiffAddedOnly, want 0", ad.Removed.Len())
+	}
+	if _, ok := ad.Added.Get("lib/new.sh"); !ok || ad.Added.Len() != 1 {
+		t.Errorf("added = %d files with DiffAddedOnly, want only lib/new.sh", ad.Added.Len())
+	}
+	if _, ok := ad.Modified.Get("libexec/helper-tool"); ok {
+		t.Error("move without added behaviors reported with DiffAddedOnly")
+	}
+	modified, ok = ad.Modified.Get("bin/run.sh")
+	if !ok || len(modified.Behaviors) != 1 || modified.Behaviors[0].ID != "evasion/logging/hide" {
+		t.Errorf("bin/run.sh = %+v with DiffAddedOnly, want only evasion/logging/hide", modified)
+	}
+
+	var out bytes.Buffer
+	r, err := render.New("json", &out)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if err := r.Full(ctx, nil, &malcontent.Report{Diff: d}); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	var rendered struct {
+		Diff struct {
+			Added, Removed, Modified map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &rendered); err != nil {
+		t.Fatalf("unmarshal %s: %v", out.String(), err)
+	}
+	if len(rendered.Diff.Added) != 1 || len(rendered.Diff.Removed) != 1 || len(rendered.Diff.Modified) != 2 {
+		t.Errorf("rendered diff = %s", out.String())
+	}
+
+	if _, err := DiffReports(ctx, malcontent.Config{}, src, &malcontent.Report{Diff: d}); err == nil {
+		t.Error("DiffReports accepted a diff report")
+	}
+}
+
+func TestCompareToBaseline(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	root := t.TempDir()
+
+	download := &malcontent.Behavior{ID: "net/download", RiskScore: 2, RiskLevel: malcontent.RiskMedium}
+	exec := &malcontent.Behavior{ID: "exec/shell", RiskScore: 3, RiskLevel: malcontent.RiskHigh}
+	persist := &malcontent.Behavior{ID: "persist/cron", RiskScore: 4, RiskLevel: malcontent.RiskCritical}
+
+	// The baseline scan of main, saved with absolute paths
+	base := &malcontent.Report{}
+	base.Files.Store(filepath.Join(root, "fetch.sh"), &malcontent.FileReport{
+		Path: filepath.Join(root, "fetch.sh"), SHA256: "aaaa", Behaviors: []*malcontent.Behavior{download},
+	})
+	base.Files.Store(filepath.Join(root, "same.sh"), &malcontent.FileReport{
+		Path: filepath.Join(root, "same.sh"), SHA256: "bbbb", Behaviors: []*malcontent.Behavior{download},
+	})
+	var buf bytes.Buffer
+	if err := render.NewJSON(&buf).Full(ctx, nil, base); err != nil {
+		t.Fatalf("render baseline: %v", err)
+	}
+	baselinePath := filepath.Join(root, "baseline.json")
+	if err := os.WriteFile(baselinePath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current scan, reported relative to the same root
+	current := &malcontent.Report{}
+	for _, fr := range []*malcontent.FileReport{
+		// Changed, with a new behavior besides the known one
+		{Path: "fetch.sh", SHA256: "cccc", RiskScore: 3, RiskLevel: malcontent.RiskHigh, Behaviors: []*malcontent.Behavior{download, exec}},
+		// Unchanged, although rules now find more in it
+		{Path: "same.sh", SHA256: "bbbb", RiskScore: 4, RiskLevel: malcontent.RiskCritical, Behaviors: []*malcontent.Behavior{download, persist}},
+		// A new file
+		{Path: "new.sh", SHA256: "dddd", RiskScore: 4, RiskLevel: malcontent.RiskCritical, Behaviors: []*malcontent.Behavior{persist}},
+		// A new file without findings
+		{Path: "clean.sh", SHA256: "eeee"},
+	} {
+		current.Files.Store(fr.Path, fr)
+	}
+
+	got, err := CompareToBaseline(ctx, malcontent.Config{RelativeTo: root}, current, baselinePath)
+	if err != nil {
+		t.Fatalf("CompareToBaseline: %v", err)
+	}
+
+	ids := map[string][]string{}
+	got.Files.Range(func(key, value any) bool {
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok {
+			t.Fatalf("unexpected value for %v: %T", key, value)
+		}
+		for _, b := range fr.Behaviors {
+			ids[fr.Path] = append(ids[fr.Path], b.ID)
+		}
+		if fr.Path == "fetch.sh" && fr.RiskLevel != malcontent.RiskHigh {
+			t.Errorf("fetch.sh RiskLevel = %s, want HIGH", fr.RiskLevel)
+		}
+		return true
+	})
+	want := map[string][]string{
+		"fetch.sh": {"exec/shell"},
+		"new.sh":   {"persist/cron"},
+	}
+	if diff := cmp.Diff(want, ids); diff != "" {
+		t.Errorf("new findings mismatch (-want +got):\n%s", diff)
+	}
+
+	// The current report is left alone
+	if v, _ := current.Files.Load("fetch.sh"); len(v.(*malcontent.FileReport).Behaviors) != 2 {
+		t.Errorf("CompareToBaseline modified the current report")
+	}
+
+	if _, err := CompareToBaseline(ctx, malcontent.Config{}, current, filepath.Join(root, "missing.json")); err == nil {
+		t.Error("CompareToBaseline accepted a missing baseline")
+	}
+}
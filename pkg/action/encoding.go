@@ -0,0 +1,62 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bytes"
+	"context"
+	"unicode/utf8"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"golang.org/x/net/html/charset"
+)
+
+// encodingSniffLen is how much of a file is examined to detect its encoding.
+const encodingSniffLen = 8192
+
+// encodingMeta is the Meta key recording the encoding a file was transcoded from.
+const encodingMeta = "encoding"
+
+// utf8BOM is the byte order mark some editors write at the start of UTF-8 text.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// normalizeEncoding returns fc transcoded to UTF-8 and the name of the encoding it was detected as,
+// or fc and "" if it is UTF-8 already, binary, or cannot be transcoded.
+//
+// Text is detected by its byte order mark, such as for the UTF-16 scripts Windows tools write,
+// or by being invalid UTF-8 without NUL bytes, in which case it is assumed to be windows-1252.
+func normalizeEncoding(fc []byte) ([]byte, string) {
+	if utf8.Valid(fc) {
+		return fc, ""
+	}
+
+	sniff := fc[:min(len(fc), encodingSniffLen)]
+	enc, name, certain := charset.DetermineEncoding(sniff, "text/plain")
+	if !certain && bytes.IndexByte(sniff, 0) >= 0 {
+		return fc, ""
+	}
+	if name == "utf-8" {
+		return fc, ""
+	}
+
+	out, err := enc.NewDecoder().Bytes(fc)
+	if err != nil {
+		return fc, ""
+	}
+	return bytes.TrimPrefix(out, utf8BOM), name
+}
+
+// withOriginalEncoding records encoding in fr, a report generated for fc transcoded to UTF-8,
+// and restores the size and checksum of fc so the report identifies the original file.
+func withOriginalEncoding(ctx context.Context, fr *malcontent.FileReport, c malcontent.Config, fc []byte, encoding string) {
+	fr.Size = int64(len(fc))
+	checksum := report.ChecksumFor(ctx, fc, c.HashAlgo)
+	if fr.Hash != "" {
+		fr.Hash = checksum
+	} else {
+		fr.SHA256 = checksum
+	}
+	fr.Meta[encodingMeta] = encoding
+}
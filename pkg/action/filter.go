@@ -0,0 +1,75 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/programkind"
+)
+
+// normalizeExt returns ext in the lowercase, dot-prefixed form used for comparisons.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// hasExtension reports whether path ends in one of exts, including multi-part extensions such as ".tar.gz".
+func hasExtension(path string, exts []string) bool {
+	name := strings.ToLower(filepath.Base(path))
+	return slices.ContainsFunc(exts, func(ext string) bool {
+		ext = normalizeExt(ext)
+		return ext != "" && strings.HasSuffix(name, ext)
+	})
+}
+
+// extensionAllowed reports whether path passes the IncludeExtensions and ExcludeExtensions filters.
+// Exclusions take precedence over inclusions.
+func extensionAllowed(c malcontent.Config, path string) bool {
+	if hasExtension(path, c.ExcludeExtensions) {
+		return false
+	}
+	return len(c.IncludeExtensions) == 0 || hasExtension(path, c.IncludeExtensions)
+}
+
+// fileFilteredOut reports whether the file at path should not be scanned or reported
+// because of the extension or executable filters.
+func fileFilteredOut(c malcontent.Config, path string) (bool, error) {
+	if !extensionAllowed(c, path) {
+		return true, nil
+	}
+	if !c.OnlyExecutables {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	hdr := make([]byte, programkind.ExecutableMagicSize)
+	n, err := io.ReadFull(f, hdr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	return !programkind.IsExecutable(hdr[:n]), nil
+}
+
+// contentFilteredOut is fileFilteredOut for in-memory content named name.
+func contentFilteredOut(c malcontent.Config, name string, fc []byte) bool {
+	if !extensionAllowed(c, name) {
+		return true
+	}
+	return c.OnlyExecutables && !programkind.IsExecutable(fc)
+}
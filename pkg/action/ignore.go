@@ -0,0 +1,191 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// ignoreFileName is the per-directory file listing paths to skip when walking scan paths.
+const ignoreFileName = ".malcontentignore"
+
+// ignoreRule is a single pattern from an ignore file, using gitignore semantics.
+type ignoreRule struct {
+	// base is the slash-separated directory of the ignore file relative to the walk root
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	// anchored patterns contain a slash and match relative to base rather than at any depth
+	anchored bool
+}
+
+// ignoreMatcher accumulates the rules of ignore files found while walking a scan path.
+type ignoreMatcher struct {
+	// file, if set, replaces the ignore file at the walk root
+	file  string
+	rules []ignoreRule
+}
+
+// newIgnoreMatcher returns a matcher for walking the configured scan paths, or nil if ignore files are disabled.
+func newIgnoreMatcher(c malcontent.Config) *ignoreMatcher {
+	if c.NoIgnore || c.OCI {
+		return nil
+	}
+	return &ignoreMatcher{file: c.IgnoreFile}
+}
+
+// parseIgnore parses gitignore-style patterns; base is the directory they are relative to.
+func parseIgnore(data []byte, base string) []ignoreRule {
+	var rules []ignoreRule
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSuffix(s.Text(), "\r")
+		// Trailing spaces are ignored unless escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{base: base}
+		switch {
+		case strings.HasPrefix(line, "!"):
+			r.negate = true
+			line = line[1:]
+		case strings.HasPrefix(line, `\!`), strings.HasPrefix(line, `\#`):
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			r.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		r.segments = strings.Split(line, "/")
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+// load reads the ignore file for dir, which is rel (slash-separated) below the walk root.
+func (m *ignoreMatcher) load(dir string, rel string) error {
+	p := filepath.Join(dir, ignoreFileName)
+	if rel == "" && m.file != "" {
+		p = m.file
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		// Only a missing custom ignore file is an error
+		if errors.Is(err, os.ErrNotExist) && p != m.file {
+			return nil
+		}
+		return fmt.Errorf("read ignore file: %w", err)
+	}
+
+	m.rules = append(m.rules, parseIgnore(data, rel)...)
+	return nil
+}
+
+// visit loads the ignore file of directories as they are entered and reports whether path should be skipped.
+func (m *ignoreMatcher) visit(root string, p string, info os.DirEntry) (bool, error) {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false, nil
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		if !info.IsDir() {
+			return false, nil
+		}
+		rel = ""
+	}
+
+	if rel != "" && m.ignored(rel, info.IsDir()) {
+		return true, nil
+	}
+	if info.IsDir() {
+		return false, m.load(p, rel)
+	}
+	return false, nil
+}
+
+// ignored reports whether rel (slash-separated, relative to the walk root) should be skipped.
+// As with git, the last matching rule wins, so rules from deeper ignore files take precedence.
+func (m *ignoreMatcher) ignored(rel string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+
+	ignored := false
+	for _, r := range m.rules {
+		if r.matches(rel, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+
+	if r.base != "" {
+		if !strings.HasPrefix(rel, r.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, r.base+"/")
+	}
+
+	if !r.anchored {
+		ok, err := path.Match(r.segments[0], path.Base(rel))
+		return err == nil && ok
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where "**" matches zero or more segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(rest, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		ok, err := path.Match(pattern[0], parts[0])
+		if err != nil || !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
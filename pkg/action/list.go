@@ -0,0 +1,33 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// ListFiles returns the files of r, the report of a scan with Config.ListOnly set, that would have been scanned.
+// Files skipped by the scan, such as empty or data files, are left out.
+func ListFiles(r *malcontent.Report) malcontent.FileList {
+	var fl malcontent.FileList
+	if r == nil {
+		return fl
+	}
+	r.Files.Range(func(_, value any) bool {
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok || fr == nil || fr.Skipped != "" || fr.Path == "" {
+			return true
+		}
+		fl.Files = append(fl.Files, malcontent.ListedFile{Path: fr.Path, Size: fr.Size})
+		fl.TotalBytes += fr.Size
+		return true
+	})
+	slices.SortFunc(fl.Files, func(a, b malcontent.ListedFile) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return fl
+}
@@ -0,0 +1,22 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// withLogger returns a context logging to c.Logger, or discarding log messages if it is unset,
+// so that the diagnostics of a scan reach only the logger its caller configured.
+func withLogger(ctx context.Context, c malcontent.Config) context.Context {
+	l := c.Logger
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	return clog.WithLogger(ctx, clog.NewLogger(l))
+}
@@ -0,0 +1,65 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"golang.org/x/sync/semaphore"
+)
+
+type inFlightKey struct{}
+
+// inFlightLimit bounds the bytes of file content that scan workers hold in memory at once.
+type inFlightLimit struct {
+	sem   *semaphore.Weighted
+	limit int64
+
+	// held is the number of bytes acquired, and peak the most held at once
+	mu   sync.Mutex
+	held int64
+	peak int64
+}
+
+// withInFlightLimit returns a context that makes workers wait for memory before reading files
+// when c.MaxInFlightBytes is set.
+func withInFlightLimit(ctx context.Context, c malcontent.Config) context.Context {
+	if c.MaxInFlightBytes <= 0 {
+		return ctx
+	}
+	l := &inFlightLimit{sem: semaphore.NewWeighted(c.MaxInFlightBytes), limit: c.MaxInFlightBytes}
+	return context.WithValue(ctx, inFlightKey{}, l)
+}
+
+func inFlightLimitFrom(ctx context.Context) *inFlightLimit {
+	l, _ := ctx.Value(inFlightKey{}).(*inFlightLimit)
+	return l
+}
+
+// acquire blocks until size bytes can be held without exceeding the limit, returning a function that
+// gives them back. A file larger than the limit waits for the whole limit, so it is read on its own.
+// acquire is a no-op if l is nil.
+func (l *inFlightLimit) acquire(ctx context.Context, size int64) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	n := min(size, l.limit)
+	if err := l.sem.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	l.held += n
+	l.peak = max(l.peak, l.held)
+	l.mu.Unlock()
+
+	return func() {
+		l.mu.Lock()
+		l.held -= n
+		l.mu.Unlock()
+		l.sem.Release(n)
+	}, nil
+}
@@ -0,0 +1,20 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package action
+
+import (
+	"errors"
+	"os"
+)
+
+// mmapFile is unsupported on this platform; callers fall back to reading the file.
+func mmapFile(_ *os.File, _ int64) ([]byte, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func munmap(_ []byte) error {
+	return nil
+}
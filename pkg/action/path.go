@@ -1,24 +1,41 @@
 package action
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/report"
 )
 
 // findFilesRecursively returns a list of files found recursively within a path.
-func findFilesRecursively(ctx context.Context, rootPath string) ([]string, error) {
+// If ignore is non-nil, paths matched by ignore files are skipped.
+func findFilesRecursively(ctx context.Context, rootPath string, ignore *ignoreMatcher) ([]string, error) {
+	var files []string
+	err := walkFiles(ctx, rootPath, ignore, false, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+	return files, err
+}
+
+// walkFiles calls fn for each file found recursively within a path, as it is found.
+// If ignore is non-nil, paths matched by ignore files are skipped. Errors returned by fn stop the walk.
+// If follow is set, symlinked directories are walked, and broken symlinks are passed to fn.
+func walkFiles(ctx context.Context, rootPath string, ignore *ignoreMatcher, follow bool, fn func(path string) error) error {
 	if ctx.Err() != nil {
-		return nil, ctx.Err()
+		return ctx.Err()
 	}
 
 	logger := clog.FromContext(ctx)
-	var files []string
 
 	// Follow symlink if provided at the root
 	root, err := filepath.EvalSymlinks(rootPath)
@@ -27,50 +44,162 @@ func findFilesRecursively(ctx context.Context, rootPath string) ([]string, error
 		// This is useful when scanning -compat packages
 		if os.IsNotExist(err) {
 			logger.Debugf("symlink target does not exist: %s", err.Error())
-			return nil, nil
+			return nil
 		}
 		// Allow /proc/XXX/exe to be scanned even if symlink is not resolveable
 		if strings.HasPrefix(rootPath, "/proc/") {
 			root = rootPath
 		} else {
-			return nil, fmt.Errorf("eval %q: %w", rootPath, err)
+			return fmt.Errorf("eval %q: %w", rootPath, err)
 		}
 	}
 
-	err = filepath.WalkDir(root,
-		func(path string, info os.DirEntry, err error) error {
-			if err != nil {
-				logger.Debugf("error: %s: %s", path, err)
-				return nil
-			}
-			if info.IsDir() || strings.Contains(path, "/.git/") {
-				return nil
-			}
-
-			// Ignore symlinked directories like regular directories
-			if info.Type()&fs.ModeSymlink == fs.ModeSymlink {
-				logger.Debugf("attempting to resolve symlink: %s", path)
-				eval, err := filepath.EvalSymlinks(path)
-				if err != nil {
-					logger.Debugf("eval: %s: %s", path, err)
-					return nil
+	// visited holds the resolved directories walked so far through symlinks, so that link cycles terminate
+	visited := map[string]bool{root: true}
+
+	var walk func(dir string, display string) error
+	walk = func(dir string, display string) error {
+		return filepath.WalkDir(dir,
+			func(path string, info os.DirEntry, err error) error {
+				// Files within a followed symlinked directory are reported below the link
+				if display != dir {
+					if rel, relErr := filepath.Rel(dir, path); relErr == nil {
+						path = filepath.Join(display, rel)
+					}
 				}
-				fi, err := os.Stat(eval)
 				if err != nil {
-					logger.Debugf("stat: %s: %s", path, err)
+					logger.Debugf("error: %s: %s", path, err)
 					return nil
 				}
-				if fi.IsDir() {
-					logger.Debugf("ignoring symlinked directory: %s", path)
+				if ignore != nil {
+					skip, err := ignore.visit(root, path, info)
+					if err != nil {
+						return err
+					}
+					if skip {
+						if info.IsDir() {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+				}
+				if info.IsDir() || strings.Contains(path, "/.git/") {
 					return nil
 				}
-				path = eval
-			}
 
-			files = append(files, path)
+				// Ignore symlinked directories like regular directories, unless following symlinks
+				if info.Type()&fs.ModeSymlink == fs.ModeSymlink {
+					logger.Debugf("attempting to resolve symlink: %s", path)
+					eval, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						logger.Debugf("eval: %s: %s", path, err)
+						if follow {
+							// Reported as a broken symlink by the scan
+							return fn(path)
+						}
+						return nil
+					}
+					fi, err := os.Stat(eval)
+					if err != nil {
+						logger.Debugf("stat: %s: %s", path, err)
+						return nil
+					}
+					if fi.IsDir() {
+						if !follow {
+							logger.Debugf("ignoring symlinked directory: %s", path)
+							return nil
+						}
+						if visited[eval] {
+							logger.Debugf("not following symlink to already visited directory: %s -> %s", path, eval)
+							return nil
+						}
+						visited[eval] = true
+						return walk(eval, path)
+					}
+					if !follow {
+						path = eval
+					}
+				}
+
+				return fn(path)
+			})
+	}
+	return walk(root, root)
+}
+
+// modifiedSince wraps fn, a walkFiles callback, to skip files last modified before c.ModifiedSince when it is set.
+// Archives are judged by their own modification time, so their members are scanned only if the archive changed.
+// Files that cannot be stat'd, such as broken symlinks, are passed to fn.
+func modifiedSince(ctx context.Context, c malcontent.Config, fn func(path string) error) func(path string) error {
+	if c.ModifiedSince.IsZero() || c.OCI {
+		return fn
+	}
+	return func(path string) error {
+		if fi, err := os.Stat(path); err == nil && fi.ModTime().Before(c.ModifiedSince) {
+			clog.FromContext(ctx).Debug("skipping file", slog.String("path", path), slog.String("reason", "not modified since"), slog.Time("since", c.ModifiedSince))
 			return nil
-		})
-	return files, err
+		}
+		return fn(path)
+	}
+}
+
+// walkFilesLargestFirst calls fn for each file found recursively within a path, like walkFiles,
+// but only once the walk is complete, in order of decreasing size and then by path.
+func walkFilesLargestFirst(ctx context.Context, rootPath string, ignore *ignoreMatcher, follow bool, fn func(path string) error) error {
+	return walkFilesSortedBy(ctx, rootPath, ignore, follow, func(files []string) {
+		slices.Sort(files)
+		largestFirst(files)
+	}, fn)
+}
+
+// walkFilesSorted calls fn for each file found recursively within a path, like walkFiles,
+// but only once the walk is complete, in order of path.
+func walkFilesSorted(ctx context.Context, rootPath string, ignore *ignoreMatcher, follow bool, fn func(path string) error) error {
+	return walkFilesSortedBy(ctx, rootPath, ignore, follow, slices.Sort[[]string], fn)
+}
+
+// walkFilesSortedBy collects the files found recursively within a path, orders them with order, then calls fn for each.
+func walkFilesSortedBy(ctx context.Context, rootPath string, ignore *ignoreMatcher, follow bool, order func(files []string), fn func(path string) error) error {
+	var files []string
+	walkErr := walkFiles(ctx, rootPath, ignore, follow, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+
+	order(files)
+	for _, path := range files {
+		if err := fn(path); err != nil {
+			return err
+		}
+	}
+	return walkErr
+}
+
+// largestFirst sorts paths by decreasing file size, keeping the order of equally sized files.
+// Files that can't be stat'd sort as if empty.
+func largestFirst(paths []string) {
+	sizes := make(map[string]int64, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			sizes[path] = info.Size()
+		}
+	}
+	slices.SortStableFunc(paths, func(a, b string) int {
+		return cmp.Compare(sizes[b], sizes[a])
+	})
+}
+
+// symlinkTarget returns the resolved path of a file reached through a symlink, or "" if path involves none.
+func symlinkTarget(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	eval, err := filepath.EvalSymlinks(abs)
+	if err != nil || eval == abs {
+		return ""
+	}
+	return eval
 }
 
 // cleanPath removes the temporary directory prefix from the path.
@@ -85,3 +214,44 @@ func formatPath(path string) string {
 	}
 	return path
 }
+
+// addFingerprints sets the fingerprint of each behavior of fr, found below scanPath, if c.Fingerprint is set.
+func addFingerprints(c malcontent.Config, fr *malcontent.FileReport, scanPath string) {
+	if !c.Fingerprint || fr == nil {
+		return
+	}
+	path := fingerprintPath(c, fr.Path, scanPath)
+	for _, b := range fr.Behaviors {
+		b.Fingerprint = report.Fingerprint(path, b)
+	}
+}
+
+// fingerprintPath returns the report path of a file found below scanPath as used by fingerprints: relative to
+// scanPath, or its base name if it is scanPath. Report paths that are already relative to c.RelativeTo or to an
+// image are used as they are, as are those of archive members, whose member part is relative to the archive.
+func fingerprintPath(c malcontent.Config, path string, scanPath string) string {
+	if c.RelativeTo != "" || c.OCI || scanPath == "" {
+		return path
+	}
+	outer, member, isMember := strings.Cut(path, " ∴ ")
+	absRoot, err := filepath.Abs(scanPath)
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(outer)
+	if err != nil {
+		return path
+	}
+	rel := filepath.Base(abs)
+	if abs != absRoot {
+		rel, err = filepath.Rel(absRoot, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return path
+		}
+	}
+	rel = filepath.ToSlash(rel)
+	if isMember {
+		return fmt.Sprintf("%s ∴ %s", rel, member)
+	}
+	return rel
+}
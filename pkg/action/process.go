@@ -3,6 +3,7 @@ package action
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"runtime"
 	"sort"
@@ -37,7 +38,7 @@ func ActiveProcesses(ctx context.Context) ([]*ProcessInfo, error) {
 	for _, p := range procs {
 		pi, err := processInfo(ctx, p)
 		if err != nil {
-			clog.Warnf("skipping pid %d: %v", p.Pid, err)
+			clog.FromContext(ctx).Warn("skipping process", slog.Int("pid", int(p.Pid)), slog.Any("error", err))
 			continue
 		}
 		if pi == nil {
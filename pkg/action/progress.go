@@ -0,0 +1,84 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+type progressKey struct{}
+
+// scanProgress counts completed files for Config.Progress.
+type scanProgress struct {
+	mu    sync.Mutex
+	done  int
+	total int
+	fn    func(done, total int, currentPath string)
+}
+
+// withProgress returns a context that reports completed files to c.Progress, if set.
+// The total starts as the number of files found by walking the local scan paths.
+func withProgress(ctx context.Context, c malcontent.Config) context.Context {
+	if c.Progress == nil {
+		return ctx
+	}
+
+	p := &scanProgress{fn: c.Progress}
+	for _, scanPath := range c.ScanPaths {
+		if scanPath == stdinPath {
+			p.total++
+			continue
+		}
+		// Images are counted once they have been extracted, and ScanFS paths once they have been walked
+		if _, ok := imageRef(scanPath); ok || c.OCI || c.ScanFS != nil {
+			continue
+		}
+		p.total += countFiles(ctx, scanPath, c)
+	}
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+func progressFrom(ctx context.Context) *scanProgress {
+	p, _ := ctx.Value(progressKey{}).(*scanProgress)
+	return p
+}
+
+// countFiles returns the number of files below root that a scan would walk.
+func countFiles(ctx context.Context, root string, c malcontent.Config) int {
+	n := 0
+	// A failed walk is reported by the scan itself; the count just stays low
+	_ = walkFiles(ctx, root, newIgnoreMatcher(c), c.FollowSymlinks && !c.OCI, modifiedSince(ctx, c, func(string) error {
+		n++
+		return nil
+	}))
+	return n
+}
+
+// expand adds n files, such as the members of an extracted archive, to the total.
+func (p *scanProgress) expand(n int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.total += n
+}
+
+// complete counts path as done and reports it. Reports are serialized, so done only increases.
+func (p *scanProgress) complete(path string) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	// Files that appeared after the walk would otherwise exceed the total
+	p.total = max(p.total, p.done)
+	p.fn(p.done, p.total, path)
+}
@@ -0,0 +1,147 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/compile"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/version"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// ruleCacheMagic starts the header line of rule cache files.
+const ruleCacheMagic = "malcontent-rules"
+
+// yaraXModule is the module whose version determines the serialization format of compiled rules.
+const yaraXModule = "github.com/VirusTotal/yara-x/go"
+
+// ruleCacheKey returns the header line identifying rules compiled from fss by this build of malcontent.
+func ruleCacheKey(fss []fs.FS) (string, error) {
+	sh, err := compile.SourceHash(fss)
+	if err != nil {
+		return "", err
+	}
+	yxVersion := "unknown"
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range bi.Deps {
+			if dep.Path == yaraXModule {
+				yxVersion = dep.Version
+			}
+		}
+	}
+	return fmt.Sprintf("%s %s %s %s\n", ruleCacheMagic, version.ID, yxVersion, sh), nil
+}
+
+// loadOrCompileRules returns the rules compiled from fss, read from cacheFile if it holds rules compiled
+// from the same sources by the same versions of malcontent and YARA-X. Otherwise the rules are compiled
+// and, if cacheFile is set, written to it for the next run. Cache files that cannot be read or written
+// are logged and ignored.
+func loadOrCompileRules(ctx context.Context, fss []fs.FS, cacheFile string) (*yarax.Rules, []malcontent.RuleCompileError, error) {
+	if cacheFile == "" {
+		return compile.RecursiveWithErrors(ctx, fss)
+	}
+
+	logger := clog.FromContext(ctx).With("rule_cache", cacheFile)
+	key, err := ruleCacheKey(fss)
+	if err != nil {
+		logger.Warnf("rule cache disabled: %v", err)
+		return compile.RecursiveWithErrors(ctx, fss)
+	}
+
+	yrs, ruleErrors, err := readRuleCache(cacheFile, key)
+	if err == nil {
+		logger.Debug("loaded compiled rules from cache")
+		return yrs, ruleErrors, nil
+	}
+	if !os.IsNotExist(err) {
+		logger.Infof("recompiling rules: %v", err)
+	}
+
+	yrs, ruleErrors, err = compile.RecursiveWithErrors(ctx, fss)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := writeRuleCache(cacheFile, key, yrs, ruleErrors); err != nil {
+		logger.Warnf("unable to write rule cache: %v", err)
+	}
+	return yrs, ruleErrors, nil
+}
+
+// readRuleCache returns the rules and user rule errors stored in path, or an error if it was not written for key.
+func readRuleCache(path string, key string) (*yarax.Rules, []malcontent.RuleCompileError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+	header, err := br.ReadString('\n')
+	if err != nil || header != key {
+		return nil, nil, fmt.Errorf("%s was written for other rules or versions", path)
+	}
+
+	line, err := br.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, fmt.Errorf("read rule errors: %w", err)
+	}
+	var ruleErrors []malcontent.RuleCompileError
+	if err := json.Unmarshal(line, &ruleErrors); err != nil {
+		return nil, nil, fmt.Errorf("decode rule errors: %w", err)
+	}
+
+	yrs, err := yarax.ReadFrom(br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("deserialize rules: %w", err)
+	}
+	return yrs, ruleErrors, nil
+}
+
+// writeRuleCache stores yrs and ruleErrors in path under key, replacing it atomically.
+func writeRuleCache(path string, key string, yrs *yarax.Rules, ruleErrors []malcontent.RuleCompileError) error {
+	errs, err := json.Marshal(ruleErrors)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(key)
+	buf.Write(errs)
+	buf.WriteByte('\n')
+	if _, err := yrs.WriteTo(&buf); err != nil {
+		return fmt.Errorf("serialize rules: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent runs never read a partial cache
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,123 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+type rulesProfileKey struct{}
+
+// rulesProfile aggregates per-rule evaluation costs across the scanner pool.
+type rulesProfile struct {
+	mu    sync.Mutex
+	rules map[string]*malcontent.RuleProfile
+	// untimed is set once the YARA-X library is found to lack rules profiling support
+	untimed atomic.Bool
+	warn    sync.Once
+}
+
+// withRulesProfile returns a context that records rule costs when c.ProfileRules is set.
+func withRulesProfile(ctx context.Context, c malcontent.Config) (context.Context, *rulesProfile) {
+	if !c.ProfileRules {
+		return ctx, nil
+	}
+	p := &rulesProfile{rules: map[string]*malcontent.RuleProfile{}}
+	return context.WithValue(ctx, rulesProfileKey{}, p), p
+}
+
+func rulesProfileFrom(ctx context.Context) *rulesProfile {
+	p, _ := ctx.Value(rulesProfileKey{}).(*rulesProfile)
+	return p
+}
+
+// rule returns the entry for a rule, creating it if needed. p.mu must be held.
+func (p *rulesProfile) rule(namespace string, rule string) *malcontent.RuleProfile {
+	key := namespace + ":" + rule
+	rp, ok := p.rules[key]
+	if !ok {
+		rp = &malcontent.RuleProfile{ID: report.RuleID(namespace, rule), Rule: rule}
+		p.rules[key] = rp
+	}
+	return rp
+}
+
+// record adds the costs of the last scan by scanner, which uses yrs, then clears its profiling data.
+func (p *rulesProfile) record(ctx context.Context, yrs *yarax.Rules, scanner *yarax.Scanner, mrs *yarax.ScanResults) {
+	if p == nil {
+		return
+	}
+
+	timings := p.timings(ctx, yrs, scanner)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range timings {
+		rp := p.rule(t.Namespace, t.Rule)
+		rp.Invocations++
+		rp.Time += t.PatternMatchingTime + t.ConditionExecTime
+	}
+	if mrs == nil {
+		return
+	}
+	for _, m := range mrs.MatchingRules() {
+		p.rule(m.Namespace(), m.Identifier()).FilesMatched++
+	}
+}
+
+// timings returns the per-rule costs collected by scanner since it was last cleared.
+// Timings are only available when the YARA-X library is built with the rules-profiling
+// feature; without it the bindings panic, and only match counts are recorded.
+func (p *rulesProfile) timings(ctx context.Context, yrs *yarax.Rules, scanner *yarax.Scanner) (timings []yarax.ProfilingInfo) {
+	if p.untimed.Load() {
+		return nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			p.untimed.Store(true)
+			p.warn.Do(func() {
+				clog.FromContext(ctx).Warnf("rule timings are unavailable, only match counts will be profiled: %v", r)
+			})
+			timings = nil
+		}
+	}()
+
+	timings = scanner.SlowestRules(yrs.Count())
+	scanner.ClearProfilingData()
+	return timings
+}
+
+// results returns the recorded rule costs, slowest first.
+func (p *rulesProfile) results() []malcontent.RuleProfile {
+	if p == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rps := make([]malcontent.RuleProfile, 0, len(p.rules))
+	for _, rp := range p.rules {
+		rps = append(rps, *rp)
+	}
+	slices.SortFunc(rps, func(a, b malcontent.RuleProfile) int {
+		return cmp.Or(
+			cmp.Compare(b.Time, a.Time),
+			cmp.Compare(b.FilesMatched, a.FilesMatched),
+			cmp.Compare(a.ID, b.ID),
+			cmp.Compare(a.Rule, b.Rule),
+		)
+	})
+	return rps
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// RulesetInfo summarizes the compiled rules yrs. Its Hash is the key the scan cache stores reports under,
+// and is empty if the rules cannot be serialized.
+func RulesetInfo(yrs *yarax.Rules) malcontent.RulesetSummary {
+	if yrs == nil {
+		return malcontent.RulesetSummary{}
+	}
+
+	rules := yrs.Slice()
+	namespaces := make([]string, 0, len(rules))
+	for _, r := range rules {
+		namespaces = append(namespaces, r.Namespace())
+	}
+	slices.Sort(namespaces)
+
+	// A failure leaves the hash empty, as it does the scan cache disabled
+	h, _ := rulesHash(yrs)
+	return malcontent.RulesetSummary{
+		Hash:       h,
+		Namespaces: slices.Compact(namespaces),
+		Rules:      len(rules),
+	}
+}
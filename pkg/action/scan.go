@@ -5,18 +5,24 @@ package action
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/chainguard-dev/clog"
 	"github.com/chainguard-dev/malcontent/pkg/archive"
@@ -35,18 +41,40 @@ func interactive(c malcontent.Config) bool {
 	return c.Renderer != nil && c.Renderer.Name() == "Interactive"
 }
 
+// reportsCleanFiles reports whether c.Renderer lists every scanned file, including those below c.MinFileRisk,
+// as JUnit does with passing testcases.
+func reportsCleanFiles(c malcontent.Config) bool {
+	return c.Renderer != nil && c.Renderer.Name() == "JUnit"
+}
+
 var (
 	// compiledRuleCache are a cache of previously compiled rules.
 	compiledRuleCache atomic.Pointer[yarax.Rules]
 	// compileOnce ensures that we compile rules only once even across threads.
-	compileOnce         sync.Once
+	compileOnce sync.Once
+	// compiledRuleErrors are the user rule files left out of compiledRuleCache, set before it is stored.
+	compiledRuleErrors []malcontent.RuleCompileError
+	// compiledRuleSets holds a *compiledRuleSet for each ruleSetKey that scans compiled rules for.
+	compiledRuleSets    sync.Map
 	ErrMatchedCondition = errors.New("matched exit criteria")
-	// initializeOnce ensures that the file and scanner pools are only initialized once.
+	// initializeOnce ensures that the file pool is only initialized once.
 	initializeOnce sync.Once
 	filePool       *pool.BufferPool
-	scannerPool    *pool.ScannerPool
+	// scannerPools holds a *pool.ScannerPool for each *yarax.Rules scanned with.
+	scannerPools sync.Map
+	// timeoutScannerPools holds a *pool.ScannerPool for each timeoutPoolKey, as yara-x
+	// scanners keep their timeout and cannot have it removed.
+	timeoutScannerPools sync.Map
 )
 
+// scanTimeout is the Skipped reason of files whose scan exceeded Config.PerFileTimeout.
+const scanTimeout = "scan timeout"
+
+type timeoutPoolKey struct {
+	rules   *yarax.Rules
+	timeout time.Duration
+}
+
 // scanSinglePath YARA scans a single path and converts it to a fileReport.
 //
 //nolint:cyclop // ignore complexity of 38
@@ -61,9 +89,25 @@ func scanSinglePath(ctx context.Context, c malcontent.Config, path string, ruleF
 	isArchive := archiveRoot != ""
 
 	fi, err := os.Stat(path)
+	if err != nil {
+		if lfi, lerr := os.Lstat(path); c.FollowSymlinks && lerr == nil && lfi.Mode()&fs.ModeSymlink != 0 {
+			return &malcontent.FileReport{Skipped: "broken symlink", Path: path}, nil
+		}
+		return nil, NewFileReportError(err, path, TypeReadError)
+	}
+
+	// Files excluded by type filters are dropped without a report
+	filtered, err := fileFilteredOut(c, path)
 	if err != nil {
 		return nil, err
 	}
+	if filtered {
+		logger.Debug("skipping file", slog.String("reason", "excluded by file type filters"))
+		if isArchive {
+			defer os.RemoveAll(path)
+		}
+		return nil, nil
+	}
 
 	size := fi.Size()
 	if size == 0 {
@@ -76,15 +120,18 @@ func scanSinglePath(ctx context.Context, c malcontent.Config, path string, ruleF
 
 	mime := "<unknown>"
 	kind, err := programkind.File(path)
-	if err != nil && !interactive(c) {
-		logger.Errorf("file type failure: %s: %s", path, err)
+	if err != nil {
+		scanErrorsFrom(ctx).add(errorPath(c, path, absPath, archiveRoot), malcontent.ScanPhaseRead, fmt.Errorf("file type: %w", err))
+		if !interactive(c) {
+			logger.Errorf("file type failure: %s: %s", path, err)
+		}
 	}
 	if kind != nil {
 		mime = kind.MIME
 	}
 
 	if !c.IncludeDataFiles && kind == nil {
-		logger.Debugf("skipping %s [%s]: data file or empty", path, mime)
+		logger.Debug("skipping file", slog.String("reason", "data file or empty"), slog.String("mime", mime))
 		fr := &malcontent.FileReport{Skipped: "data file or empty", Path: path}
 		// Immediately remove skipped files within archives
 		if isArchive {
@@ -94,74 +141,92 @@ func scanSinglePath(ctx context.Context, c malcontent.Config, path string, ruleF
 	}
 	logger = logger.With("mime", mime)
 
-	var yrs *yarax.Rules
-	if c.Rules == nil {
-		yrs, err = CachedRules(ctx, ruleFS)
-		if err != nil {
-			return nil, fmt.Errorf("rules: %w", err)
+	if c.ListOnly {
+		if isArchive {
+			defer os.RemoveAll(path)
 		}
-	} else {
-		yrs = c.Rules
+		return &malcontent.FileReport{Path: report.DisplayPath(path, archiveRoot, c), Size: size}, nil
 	}
 
-	initializeOnce.Do(func() {
-		filePool = pool.NewBufferPool(c.Concurrency + 1)
-		scannerPool = pool.NewScannerPool(yrs, c.Concurrency+1)
-	})
+	yrs, err := scanRules(ctx, c, ruleFS)
+	if err != nil {
+		return nil, err
+	}
+	initializeFilePool(c)
 
-	scanner := scannerPool.Get()
-	if scanner == nil {
-		scanner = yarax.NewScanner(yrs)
+	// Wait for memory before reading, and hold it until the report is built
+	done, err := inFlightLimitFrom(ctx).acquire(ctx, size)
+	if err != nil {
+		return nil, err
 	}
-	defer scannerPool.Put(scanner)
+	defer done()
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, NewFileReportError(err, path, TypeReadError)
 	}
 	defer f.Close()
 
-	fc := filePool.Get(size)
-	defer filePool.Put(fc)
+	fc, sum, release, err := readContent(c, f, size, logger)
+	if err != nil {
+		return nil, NewFileReportError(err, path, TypeReadError)
+	}
+	defer release()
+	ctx = report.WithChecksum(ctx, fc, c.HashAlgo, sum)
 
-	var bytesRead int
-	var totalRead int64
-	for totalRead < size {
-		bytesRead, err = f.Read(fc[totalRead:])
-		if errors.Is(err, io.EOF) {
-			break
-		}
-		if err != nil {
-			return nil, err
-		}
-		totalRead += int64(bytesRead)
+	return scanFileContent(ctx, c, yrs, path, absPath, archiveRoot, fc, kind, logger)
+}
+
+// scanStreamedFile scans sf, a file of the archive at absPath read into memory rather than extracted,
+// reporting it as though it had been extracted.
+func scanStreamedFile(ctx context.Context, c malcontent.Config, ruleFS []fs.FS, absPath string, sf archive.StreamedFile) (*malcontent.FileReport, error) {
+	if ctx.Err() != nil {
+		return &malcontent.FileReport{}, ctx.Err()
 	}
 
-	if totalRead < size && err != nil {
-		return nil, fmt.Errorf("incomplete read: got %d bytes, expected %d: %w", totalRead, size, err)
+	logger := clog.FromContext(ctx).With("path", sf.Path)
+
+	if contentFilteredOut(c, sf.Path, sf.Content) {
+		logger.Debug("skipping file", slog.String("reason", "excluded by file type filters"))
+		return nil, nil
+	}
+	if len(sf.Content) == 0 {
+		return &malcontent.FileReport{Skipped: "zero-sized file", Path: sf.Path}, nil
 	}
 
-	mrs, err := scanner.Scan(fc)
+	kind := programkind.Detect(sf.Path, sf.Content)
+	if !c.IncludeDataFiles && kind == nil {
+		logger.Debug("skipping file", slog.String("reason", "data file or empty"))
+		return &malcontent.FileReport{Skipped: "data file or empty", Path: sf.Path}, nil
+	}
+
+	yrs, err := scanRules(ctx, c, ruleFS)
 	if err != nil {
-		logger.Debug("skipping", slog.Any("error", err))
 		return nil, err
 	}
+	initializeFilePool(c)
 
-	// If running a scan, only generate reports for mrs that satisfy the risk threshold of 3
-	// This is a short-circuit that avoids any report generation logic
-	risk := report.HighestMatchRisk(mrs)
-	threshold := max(3, c.MinFileRisk, c.MinRisk)
-	if c.Scan && risk < threshold {
-		fr := &malcontent.FileReport{Skipped: "overall risk too low for scan", Path: path}
+	return scanFileContent(ctx, c, yrs, sf.Path, absPath, sf.Root, sf.Content, kind, logger)
+}
+
+// scanFileContent generates the report for fc, the content of path, which is below archiveRoot
+// if it was extracted from the archive at absPath.
+func scanFileContent(ctx context.Context, c malcontent.Config, yrs *yarax.Rules, path string, absPath string, archiveRoot string, fc []byte, kind *programkind.FileType, logger *clog.Logger) (*malcontent.FileReport, error) {
+	isArchive := archiveRoot != ""
+
+	fr, err := reportFor(ctx, c, yrs, scannersFor(c, yrs), path, archiveRoot, fc, kind, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if fr.Skipped != "" {
 		if isArchive {
 			os.RemoveAll(path)
 		}
 		return fr, nil
 	}
-
-	fr, err := report.Generate(ctx, path, mrs, c, archiveRoot, logger, fc, kind)
-	if err != nil {
-		return nil, NewFileReportError(err, path, TypeGenerateError)
+	if c.ExtractSyscalls {
+		fr = withFileCapabilities(fr, path, logger)
 	}
 
 	// Clean up the path if scanning an archive
@@ -187,7 +252,7 @@ func scanSinglePath(ctx context.Context, c malcontent.Config, path string, ruleF
 			if len(c.TrimPrefixes) > 0 {
 				absPath = report.TrimPrefixes(absPath, c.TrimPrefixes)
 			}
-			fr.Path = fmt.Sprintf("%s ∴ %s", absPath, clean)
+			fr.Path = fmt.Sprintf("%s ∴ %s", report.RelativePath(absPath, c), clean)
 		}
 	}
 
@@ -200,11 +265,327 @@ func scanSinglePath(ctx context.Context, c malcontent.Config, path string, ruleF
 			}
 		}
 		if isArchive {
-			return &malcontent.FileReport{Path: fmt.Sprintf("%s ∴ %s", absPath, clean)}, nil
+			return &malcontent.FileReport{Path: fmt.Sprintf("%s ∴ %s", report.RelativePath(absPath, c), clean)}, nil
+		}
+		return &malcontent.FileReport{Path: report.RelativePath(path, c)}, nil
+	}
+
+	return fr, nil
+}
+
+// readChunkSize is how much of a file readContent reads at a time, small enough for each chunk to still be
+// in the CPU cache when it is hashed.
+const readChunkSize = 1 << 20
+
+// readContent returns the size bytes of f and a function releasing them once they are no longer used.
+// When c.Mmap is set the file is memory-mapped, falling back to reading it into a pooled buffer.
+// Content that is read is hashed a chunk at a time as it arrives, rather than in a second pass over it;
+// its SHA256 is returned if c.HashAlgo selects it, otherwise "".
+func readContent(c malcontent.Config, f *os.File, size int64, logger *clog.Logger) ([]byte, string, func(), error) {
+	if c.Mmap {
+		fc, err := mmapFile(f, size)
+		if err == nil {
+			return fc, "", func() {
+				if err := munmap(fc); err != nil {
+					logger.Errorf("munmap %s: %v", f.Name(), err)
+				}
+			}, nil
+		}
+		logger.Debugf("unable to mmap %s, reading instead: %v", f.Name(), err)
+	}
+
+	fc := filePool.Get(size)
+	release := func() { filePool.Put(fc) }
+
+	var h hash.Hash
+	if c.HashAlgo == "" || c.HashAlgo == report.HashSHA256 {
+		h = sha256.New()
+	}
+
+	var bytesRead int
+	var totalRead int64
+	var err error
+	for totalRead < size {
+		bytesRead, err = f.Read(fc[totalRead:min(size, totalRead+readChunkSize)])
+		if h != nil {
+			h.Write(fc[totalRead : totalRead+int64(bytesRead)])
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			release()
+			return nil, "", nil, err
+		}
+		totalRead += int64(bytesRead)
+	}
+
+	if totalRead < size && err != nil {
+		release()
+		return nil, "", nil, fmt.Errorf("incomplete read: got %d bytes, expected %d: %w", totalRead, size, err)
+	}
+	if h == nil {
+		return fc, "", release, nil
+	}
+	return fc, hex.EncodeToString(h.Sum(nil)), release, nil
+}
+
+// ruleSources returns the rule sources for c: c.RuleFS when c.Rules is set,
+// and otherwise ruleFS and c.ExtraRulePaths, limited to c.RuleFilter.
+func ruleSources(c malcontent.Config, ruleFS []fs.FS) ([]fs.FS, error) {
+	if c.Rules != nil {
+		return c.RuleFS, nil
+	}
+
+	extra, err := compile.Dirs(c.ExtraRulePaths, c.StrictRules)
+	if err != nil {
+		return nil, err
+	}
+	rfs, err := compile.Filter(append(slices.Clone(ruleFS), extra...), c.RuleFilter)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+	return rfs, nil
+}
+
+// ruleSetKey identifies the rules selected by the rule settings of a Config.
+type ruleSetKey struct {
+	// sources is the printed form of the rule filesystems, which is the identity of embedded ones
+	sources    string
+	extraPaths string
+	filter     string
+	strict     bool
+}
+
+func ruleSetKeyFor(c malcontent.Config, ruleFS []fs.FS) ruleSetKey {
+	return ruleSetKey{
+		sources:    fmt.Sprint(ruleFS),
+		extraPaths: strings.Join(c.ExtraRulePaths, "\x00"),
+		filter:     strings.Join(c.RuleFilter, "\x00"),
+		strict:     c.StrictRules,
+	}
+}
+
+// compiledRuleSet holds the rules compiled for a ruleSetKey.
+type compiledRuleSet struct {
+	mu     sync.Mutex
+	rules  atomic.Pointer[yarax.Rules]
+	errors []malcontent.RuleCompileError
+}
+
+// scanRules returns the configured rules, compiling ruleFS and c.ExtraRulePaths if none were provided.
+// Rules are compiled once for each selection of rules, so scans with other rule settings get their own.
+func scanRules(ctx context.Context, c malcontent.Config, ruleFS []fs.FS) (*yarax.Rules, error) {
+	if c.Rules != nil {
+		return c.Rules, nil
+	}
+
+	v, _ := compiledRuleSets.LoadOrStore(ruleSetKeyFor(c, ruleFS), &compiledRuleSet{})
+	rs, ok := v.(*compiledRuleSet)
+	if !ok {
+		return nil, fmt.Errorf("unexpected rule set type: %T", v)
+	}
+	if yrs := rs.rules.Load(); yrs != nil {
+		return yrs, nil
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if yrs := rs.rules.Load(); yrs != nil {
+		return yrs, nil
+	}
+
+	rfs, err := ruleSources(c, ruleFS)
+	if err != nil {
+		return nil, err
+	}
+	yrs, errs, err := loadOrCompileRules(ctx, rfs, c.RuleCacheFile)
+	if err != nil {
+		return nil, fmt.Errorf("rules: compile: %w", err)
+	}
+	rs.errors = errs
+	rs.rules.Store(yrs)
+	return yrs, nil
+}
+
+// initializeFilePool creates the shared file buffer pool on first use.
+func initializeFilePool(c malcontent.Config) {
+	initializeOnce.Do(func() {
+		filePool = pool.NewBufferPool(c.Concurrency + 1)
+	})
+}
+
+// scannersFor returns the shared pool of scanners for yrs, creating it on first use.
+func scannersFor(c malcontent.Config, yrs *yarax.Rules) *pool.ScannerPool {
+	if sp, ok := scannerPools.Load(yrs); ok {
+		if sp, ok := sp.(*pool.ScannerPool); ok {
+			return sp
+		}
+	}
+	sp, _ := scannerPools.LoadOrStore(yrs, pool.NewScannerPool(yrs, c.Concurrency+1))
+	if sp, ok := sp.(*pool.ScannerPool); ok {
+		return sp
+	}
+	return pool.NewScannerPool(yrs, c.Concurrency+1)
+}
+
+// reportFor returns the report for file content, reusing the report of an identical
+// file when deduplicating, or from the scan cache if possible.
+// Allowlisted content is reported as skipped without being scanned.
+// scanners must hold scanners for yrs.
+func reportFor(ctx context.Context, c malcontent.Config, yrs *yarax.Rules, scanners *pool.ScannerPool, path string, archiveRoot string, fc []byte, kind *programkind.FileType, logger *clog.Logger) (*malcontent.FileReport, error) {
+	allow, err := report.AllowHashesFor(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if ok, sum := allow.Allows(fc); ok {
+		return &malcontent.FileReport{Skipped: "allowlisted", Path: path, SHA256: sum}, nil
+	}
+
+	d := contentDedupFrom(ctx)
+	if d == nil {
+		return cachedReportFor(ctx, c, yrs, scanners, path, archiveRoot, fc, kind, logger)
+	}
+
+	e, first := d.claim(ctx, fc, kind)
+	if !first {
+		if fr, ok := e.wait(ctx); ok {
+			fr.Path = reportPath(fr, path, archiveRoot, c)
+			return fr, nil
+		}
+		// Scanning the first copy failed, so scan this one instead
+		return cachedReportFor(ctx, c, yrs, scanners, path, archiveRoot, fc, kind, logger)
+	}
+
+	fr, err := cachedReportFor(ctx, c, yrs, scanners, path, archiveRoot, fc, kind, logger)
+	if err != nil {
+		e.publish(nil)
+		return nil, err
+	}
+	e.publish(fr)
+	return fr, nil
+}
+
+// reportPath returns the path to use for fr, a report generated for other content, when it describes path.
+func reportPath(fr *malcontent.FileReport, path string, archiveRoot string, c malcontent.Config) string {
+	if fr.Skipped != "" {
+		return path
+	}
+	return report.DisplayPath(path, archiveRoot, c)
+}
+
+// withFileCapabilities returns fr with the file capabilities set on path added to fr.Capabilities.
+// fr may be shared with the reports of identical files, so it is cloned rather than modified.
+func withFileCapabilities(fr *malcontent.FileReport, path string, logger *clog.Logger) *malcontent.FileReport {
+	caps, err := report.FileCapabilities(path)
+	if err != nil {
+		logger.Debug("unable to read file capabilities", slog.Any("error", err))
+		return fr
+	}
+	if len(caps) == 0 {
+		return fr
+	}
+
+	fr = cloneFileReport(fr)
+	fr.Capabilities = slices.Concat(fr.Capabilities, caps)
+	slices.Sort(fr.Capabilities)
+	fr.Capabilities = slices.Compact(fr.Capabilities)
+	return fr
+}
+
+// cachedReportFor returns the report for file content, from the scan cache if possible.
+func cachedReportFor(ctx context.Context, c malcontent.Config, yrs *yarax.Rules, scanners *pool.ScannerPool, path string, archiveRoot string, fc []byte, kind *programkind.FileType, logger *clog.Logger) (*malcontent.FileReport, error) {
+	cache := newScanCache(ctx, c, yrs, logger)
+	entry := cache.entry(fc, kind)
+	if fr, ok := cache.load(entry); ok {
+		fr.Path = reportPath(fr, path, archiveRoot, c)
+		return fr, nil
+	}
+
+	scanners = timeoutScanners(c, yrs, scanners)
+	scanner := scanners.Get()
+	if scanner == nil {
+		scanner = yarax.NewScanner(yrs)
+	}
+	defer scanners.Put(scanner)
+
+	fr, err := scanContent(ctx, c, yrs, scanner, path, archiveRoot, fc, kind, logger)
+	if err != nil {
+		return nil, err
+	}
+	// A later scan with a longer timeout may succeed
+	if fr.Skipped != scanTimeout {
+		cache.store(entry, fr)
+	}
+	return fr, nil
+}
+
+// timeoutScanners returns a pool of scanners for yrs that give up after c.PerFileTimeout,
+// or scanners if no timeout is set.
+func timeoutScanners(c malcontent.Config, yrs *yarax.Rules, scanners *pool.ScannerPool) *pool.ScannerPool {
+	if c.PerFileTimeout <= 0 {
+		return scanners
+	}
+	key := timeoutPoolKey{rules: yrs, timeout: c.PerFileTimeout}
+	if sp, ok := timeoutScannerPools.Load(key); ok {
+		if sp, ok := sp.(*pool.ScannerPool); ok {
+			return sp
 		}
-		return &malcontent.FileReport{Path: path}, nil
+	}
+	sp, _ := timeoutScannerPools.LoadOrStore(key, pool.NewScannerPoolWithTimeout(yrs, 0, c.PerFileTimeout))
+	if sp, ok := sp.(*pool.ScannerPool); ok {
+		return sp
+	}
+	return scanners
+}
+
+// scanContent matches file content against the rules and generates its report.
+// With c.PerFileTimeout set, content taking longer is reported as skipped, discarding any partial results.
+func scanContent(ctx context.Context, c malcontent.Config, yrs *yarax.Rules, scanner *yarax.Scanner, path string, archiveRoot string, fc []byte, kind *programkind.FileType, logger *clog.Logger) (*malcontent.FileReport, error) {
+	if c.PerFileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.PerFileTimeout)
+		defer cancel()
+	}
+
+	// Text in other encodings is matched as UTF-8, while the report describes the original file
+	scanned, encoding := fc, ""
+	if c.NormalizeEncoding {
+		scanned, encoding = normalizeEncoding(fc)
 	}
 
+	mrs, err := scanner.Scan(scanned)
+	rulesProfileFrom(ctx).record(ctx, yrs, scanner, mrs)
+	ruleUsageFrom(ctx).record(mrs)
+	if errors.Is(err, yarax.ErrTimeout) {
+		logger.Warn("skipping file", slog.String("reason", scanTimeout), slog.Duration("timeout", c.PerFileTimeout))
+		return &malcontent.FileReport{Skipped: scanTimeout, Path: path}, nil
+	}
+	if err != nil {
+		logger.Debug("skipping", slog.Any("error", err))
+		return nil, NewFileReportError(err, path, TypeScanError)
+	}
+
+	// If running a scan, only generate reports for mrs that satisfy the risk threshold of 3
+	// This is a short-circuit that avoids any report generation logic, unless combination rules or a custom
+	// score function may escalate the file
+	risk := report.HighestMatchRisk(mrs)
+	threshold := max(malcontent.RiskHigh, c.MinFileRisk, c.MinRisk)
+	if c.Scan && !malcontent.RiskLevel(risk).AtLeast(threshold) && len(c.CombinationRules) == 0 && c.ScoreFunc == nil {
+		return &malcontent.FileReport{Skipped: "overall risk too low for scan", Path: path}, nil
+	}
+
+	fr, err := report.Generate(ctx, path, mrs, c, archiveRoot, logger, scanned, kind)
+	if errors.Is(err, context.DeadlineExceeded) && c.PerFileTimeout > 0 {
+		logger.Warn("skipping file", slog.String("reason", scanTimeout), slog.Duration("timeout", c.PerFileTimeout))
+		return &malcontent.FileReport{Skipped: scanTimeout, Path: path}, nil
+	}
+	if err != nil {
+		return nil, NewFileReportError(err, path, TypeGenerateError)
+	}
+	if encoding != "" {
+		withOriginalEncoding(ctx, fr, c, fc, encoding)
+	}
 	return fr, nil
 }
 
@@ -268,18 +649,38 @@ func exitIfHitOrMiss(frs *sync.Map, scanPath string, errIfHit bool, errIfMiss bo
 }
 
 func CachedRules(ctx context.Context, fss []fs.FS) (*yarax.Rules, error) {
+	yrs, _, err := CachedRulesWithErrors(ctx, fss)
+	return yrs, err
+}
+
+// CachedNamedRules is CachedRules for rulesets labeled with their source, such as "upstream" and "internal",
+// which reports give as the RuleSource of each behavior.
+func CachedNamedRules(ctx context.Context, fss []compile.NamedFS) (*yarax.Rules, error) {
+	return CachedRules(ctx, compile.Named(fss))
+}
+
+// CachedRulesWithErrors is CachedRules, also returning the user rule files that were skipped because they failed to compile.
+func CachedRulesWithErrors(ctx context.Context, fss []fs.FS) (*yarax.Rules, []malcontent.RuleCompileError, error) {
+	return CachedRulesWithFile(ctx, fss, "")
+}
+
+// CachedRulesWithFile is CachedRulesWithErrors, also keeping the compiled rules in cacheFile, if set, so that
+// later processes can load them rather than compile them again. The file is only used while it holds rules
+// compiled from the same sources by the same versions of malcontent and YARA-X; otherwise, or if it is
+// corrupt, the rules are compiled and the file replaced.
+func CachedRulesWithFile(ctx context.Context, fss []fs.FS, cacheFile string) (*yarax.Rules, []malcontent.RuleCompileError, error) {
 	if ctx.Err() != nil {
-		return nil, ctx.Err()
+		return nil, nil, ctx.Err()
 	}
 
 	if rules := compiledRuleCache.Load(); rules != nil {
-		return rules, nil
+		return rules, compiledRuleErrors, nil
 	}
 
 	var err error
 	compileOnce.Do(func() {
 		var yrs *yarax.Rules
-		yrs, err = compile.Recursive(ctx, fss)
+		yrs, compiledRuleErrors, err = loadOrCompileRules(ctx, fss, cacheFile)
 		if err != nil {
 			err = fmt.Errorf("compile: %w", err)
 			return
@@ -288,10 +689,30 @@ func CachedRules(ctx context.Context, fss []fs.FS) (*yarax.Rules, error) {
 	})
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return compiledRuleCache.Load(), nil
+	return compiledRuleCache.Load(), compiledRuleErrors, nil
+}
+
+// ruleErrors returns the user rule files left out of the rules used by c, as long as they were compiled
+// by the scan or by CachedRulesWithFile. Callers providing rules compiled otherwise have their errors already.
+func ruleErrors(c malcontent.Config) []malcontent.RuleCompileError {
+	if c.Rules == nil {
+		v, ok := compiledRuleSets.Load(ruleSetKeyFor(c, c.RuleFS))
+		if !ok {
+			return nil
+		}
+		rs, ok := v.(*compiledRuleSet)
+		if !ok || rs.rules.Load() == nil {
+			return nil
+		}
+		return rs.errors
+	}
+	if c.Rules != compiledRuleCache.Load() {
+		return nil
+	}
+	return compiledRuleErrors
 }
 
 // matchResult represents the outcome of a match operation.
@@ -306,6 +727,45 @@ type scanPathInfo struct {
 	effectivePath  string
 	ociExtractPath string
 	imageURI       string
+	// layers maps layer directories beneath ociExtractPath to their digests when an
+	// image is extracted layer by layer
+	layers map[string]string
+}
+
+// imageSchemes are the scan path prefixes that reference a container image.
+var imageSchemes = []string{"oci://", "docker://"}
+
+// imageRef returns the image reference of a scan path such as oci://cgr.dev/chainguard/static.
+func imageRef(scanPath string) (string, bool) {
+	for _, scheme := range imageSchemes {
+		if ref, ok := strings.CutPrefix(scanPath, scheme); ok {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+// layer returns the directory and digest of the image layer path was extracted from, if any.
+func (si scanPathInfo) layer(path string) (string, string) {
+	rel, err := filepath.Rel(si.ociExtractPath, path)
+	if err != nil {
+		return "", ""
+	}
+	dir, _, _ := strings.Cut(filepath.ToSlash(rel), "/")
+	digest, ok := si.layers[dir]
+	if !ok {
+		return "", ""
+	}
+	return filepath.Join(si.ociExtractPath, dir), digest
+}
+
+// shortDigest abbreviates a layer digest for display.
+func shortDigest(digest string) string {
+	algo, sum, ok := strings.Cut(digest, ":")
+	if !ok || len(sum) <= 12 {
+		return digest
+	}
+	return algo + ":" + sum[:12]
 }
 
 // recursiveScan recursively YARA scans the configured paths - handling archives and OCI images.
@@ -342,10 +802,23 @@ func handleScanPath(ctx context.Context, scanPath string, c malcontent.Config, r
 		return ctx.Err()
 	}
 
-	if c.Renderer != nil {
+	if c.Renderer != nil && !c.Quiet && !c.ListOnly {
 		c.Renderer.Scanning(ctx, scanPath)
 	}
 
+	if c.ScanFS != nil {
+		return handleScanFS(ctx, scanPath, c, r, matchChan, matchOnce, logger)
+	}
+
+	if scanPath == stdinPath && !c.OCI {
+		return handleStdin(ctx, c, r, matchChan, matchOnce)
+	}
+
+	// Image references are scanned like --image, but layer by layer
+	if _, ok := imageRef(scanPath); ok {
+		c.OCI = true
+	}
+
 	scanInfo, err := prepareScanPath(ctx, scanPath, c.OCI, logger)
 	if err != nil {
 		return fmt.Errorf("failed to prepare scan path: %w", err)
@@ -355,16 +828,26 @@ func handleScanPath(ctx context.Context, scanPath string, c malcontent.Config, r
 		defer cleanupOCIPath(scanInfo.ociExtractPath, logger)
 	}
 
-	paths, err := findFilesRecursively(ctx, scanInfo.effectivePath)
-	if err != nil {
-		if len(c.ScanPaths) == 1 {
-			return fmt.Errorf("find: %w", err)
-		}
-		logger.Errorf("find failed: %v", err)
+	err = processPaths(ctx, scanInfo, c, r, matchChan, matchOnce, logger)
+	var fe findError
+	if errors.As(err, &fe) && len(c.ScanPaths) > 1 {
+		logger.Errorf("find failed: %v", fe.err)
 		return nil
 	}
+	return err
+}
+
+// findError is returned by processPaths when walking a scan path fails.
+type findError struct {
+	err error
+}
 
-	return processPaths(ctx, paths, scanInfo, c, r, matchChan, matchOnce, logger)
+func (e findError) Error() string {
+	return fmt.Sprintf("find: %v", e.err)
+}
+
+func (e findError) Unwrap() error {
+	return e.err
 }
 
 func prepareScanPath(ctx context.Context, scanPath string, isOCI bool, logger *clog.Logger) (scanPathInfo, error) {
@@ -381,6 +864,20 @@ func prepareScanPath(ctx context.Context, scanPath string, isOCI bool, logger *c
 		return info, nil
 	}
 
+	if ref, ok := imageRef(scanPath); ok {
+		info.imageURI = ref
+		root, layers, err := archive.OCILayers(ctx, ref)
+		if err != nil {
+			return info, fmt.Errorf("failed to prepare OCI image layers for scanning: %w", err)
+		}
+
+		info.ociExtractPath = root
+		info.effectivePath = root
+		info.layers = layers
+		logger.Debug("oci image layers", slog.Any("scanPath", scanPath), slog.Any("ociExtractPath", root), slog.Int("layers", len(layers)))
+		return info, nil
+	}
+
 	info.imageURI = scanPath
 	ociPath, err := archive.OCI(ctx, info.imageURI)
 	if err != nil {
@@ -394,7 +891,9 @@ func prepareScanPath(ctx context.Context, scanPath string, isOCI bool, logger *c
 	return info, nil
 }
 
-func processPaths(ctx context.Context, paths []string, scanInfo scanPathInfo, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
+// processPaths walks scanInfo.effectivePath, handing each file to a pool of c.Concurrency workers as
+// it is found so that walking the tree overlaps with scanning.
+func processPaths(ctx context.Context, scanInfo scanPathInfo, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -402,37 +901,65 @@ func processPaths(ctx context.Context, paths []string, scanInfo scanPathInfo, c
 	maxConcurrency := getMaxConcurrency(c.Concurrency)
 
 	scanCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	// Wait for the watcher to exit so that nothing is logged once the scan has returned
+	stopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-stopped
+	}()
 
 	go func() {
-		<-ctx.Done()
-		logger.Debug("parent context canceled, stopping scan")
-		cancel()
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			logger.Debug("parent context canceled, stopping scan")
+			cancel()
+		case <-scanCtx.Done():
+		}
 	}()
 
 	g, gCtx := errgroup.WithContext(scanCtx)
-	g.SetLimit(maxConcurrency)
 
 	setupMatchHandler(gCtx, matchChan, c, cancel, logger)
 
-	pc := make(chan string, len(paths))
-	go func() {
+	if c.OCI {
+		progressFrom(ctx).expand(countFiles(ctx, scanInfo.effectivePath, c))
+	}
+
+	// Files already found are still scanned if the walk fails part way through
+	var walkErr error
+	pc := make(chan string, maxConcurrency)
+	walk := walkFiles
+	switch {
+	case c.LargestFirst:
+		walk = walkFilesLargestFirst
+	case c.Deterministic:
+		walk = walkFilesSorted
+	}
+	g.Go(func() error {
 		defer close(pc)
-		for _, path := range paths {
+		walkErr = walk(gCtx, scanInfo.effectivePath, newIgnoreMatcher(c), c.FollowSymlinks && !c.OCI, modifiedSince(gCtx, c, func(path string) error {
 			select {
 			case <-gCtx.Done():
-				return
+				return gCtx.Err()
 			case pc <- path:
+				return nil
 			}
-		}
-	}()
+		}))
+		return nil
+	})
 
-	for path := range pc {
+	for range maxConcurrency {
 		g.Go(func() error {
-			if gCtx.Err() != nil {
-				return scanCtx.Err()
+			for path := range pc {
+				if gCtx.Err() != nil {
+					return scanCtx.Err()
+				}
+				if err := processPath(gCtx, path, scanInfo, c, r, matchChan, matchOnce, logger); err != nil {
+					return err
+				}
 			}
-			return processPath(gCtx, path, scanInfo, c, r, matchChan, matchOnce, logger)
+			return nil
 		})
 	}
 
@@ -447,6 +974,10 @@ func processPaths(ctx context.Context, paths []string, scanInfo scanPathInfo, c
 		return handleScanError(matchChan, r, c, err)
 	}
 
+	if walkErr != nil {
+		return findError{err: walkErr}
+	}
+
 	if c.OCI && ctx.Err() == nil {
 		return handleOCIResults(ctx, scanInfo.imageURI, &r.Files, c, logger)
 	}
@@ -469,7 +1000,7 @@ func setupMatchHandler(ctx context.Context, matchChan chan matchResult, c malcon
 	go func() {
 		select {
 		case match := <-matchChan:
-			if match.fr != nil && c.Renderer != nil && match.fr.RiskScore >= c.MinFileRisk {
+			if match.fr != nil && shouldRender(c, match.fr) {
 				if err := c.Renderer.File(ctx, match.fr); err != nil {
 					logger.Errorf("render error: %v", err)
 				}
@@ -489,17 +1020,18 @@ func processPath(ctx context.Context, path string, scanInfo scanPathInfo, c malc
 	case <-ctx.Done():
 		return ctx.Err()
 	default:
-		if programkind.IsSupportedArchive(path) {
-			return handleArchiveFile(ctx, path, c, r, matchChan, matchOnce, logger)
+		if programkind.IsSupportedArchive(path) && !c.ListOnly {
+			return handleArchiveFile(ctx, path, scanInfo, c, r, matchChan, matchOnce, logger)
 		}
 		return handleSingleFile(ctx, path, scanInfo, c, r, matchChan, matchOnce, logger)
 	}
 }
 
-func handleArchiveFile(ctx context.Context, path string, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
+func handleArchiveFile(ctx context.Context, path string, scanInfo scanPathInfo, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
+	defer progressFrom(ctx).complete(path)
 
 	frs, err := processArchive(ctx, c, c.RuleFS, path, logger)
 	if err != nil {
@@ -531,8 +1063,9 @@ func handleArchiveFile(ctx context.Context, path string, c malcontent.Config, r
 					if len(c.TrimPrefixes) > 0 {
 						k = report.TrimPrefixes(k, c.TrimPrefixes)
 					}
+					addFingerprints(c, fr, scanInfo.originalPath)
 					r.Files.Store(k, fr)
-					if c.Renderer != nil && r.Diff == nil && fr.RiskScore >= c.MinFileRisk {
+					if r.Diff == nil && !c.Deterministic && shouldRender(c, fr) {
 						if err := c.Renderer.File(ctx, fr); err != nil {
 							logger.Errorf("render error: %v", err)
 						}
@@ -547,23 +1080,52 @@ func handleArchiveFile(ctx context.Context, path string, c malcontent.Config, r
 
 func handleSingleFile(ctx context.Context, path string, scanInfo scanPathInfo, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
 	trimPath := ""
+	layer := ""
 	if c.OCI {
 		scanInfo.effectivePath = scanInfo.imageURI
 		trimPath = scanInfo.ociExtractPath
+		// Report paths relative to the originating layer, which is named in the display path
+		if dir, digest := scanInfo.layer(path); digest != "" {
+			scanInfo.effectivePath = fmt.Sprintf("%s ∴ %s", scanInfo.imageURI, shortDigest(digest))
+			trimPath = dir
+			layer = digest
+		}
 	}
 
 	fr, err := processFile(ctx, c, c.RuleFS, path, scanInfo.effectivePath, trimPath, logger)
+	progressPath := path
+	if fr != nil && fr.Path != "" {
+		progressPath = fr.Path
+	}
+	defer progressFrom(ctx).complete(progressPath)
 	if err != nil && !interactive(c) {
 		if len(c.TrimPrefixes) > 0 {
 			path = report.TrimPrefixes(path, c.TrimPrefixes)
 		}
-		r.Files.Store(path, &malcontent.FileReport{})
+		r.Files.Store(report.RelativePath(path, c), &malcontent.FileReport{})
 		return fmt.Errorf("process: %w", err)
 	}
 	if fr == nil {
 		return nil
 	}
 
+	if c.FollowSymlinks && !c.OCI {
+		fr.SymlinkTarget = symlinkTarget(path)
+	}
+
+	if layer != "" && fr.Skipped == "" && len(fr.Behaviors) > 0 {
+		if fr.Meta == nil {
+			fr.Meta = map[string]string{}
+		}
+		fr.Meta["oci_layer"] = layer
+	}
+	addFingerprints(c, fr, scanInfo.originalPath)
+
+	return storeFileReport(ctx, path, fr, c, r, matchChan, matchOnce)
+}
+
+// storeFileReport adds a single file's report to r, honoring the exit-first-hit/miss options.
+func storeFileReport(ctx context.Context, path string, fr *malcontent.FileReport, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once) error {
 	if !c.OCI && (c.ExitFirstHit || c.ExitFirstMiss) {
 		var frMap sync.Map
 		frMap.Store(path, fr)
@@ -579,8 +1141,8 @@ func handleSingleFile(ctx context.Context, path string, scanInfo scanPathInfo, c
 	if len(c.TrimPrefixes) > 0 {
 		path = report.TrimPrefixes(path, c.TrimPrefixes)
 	}
-	r.Files.Store(path, fr)
-	if c.Renderer != nil && r.Diff == nil && fr.RiskScore >= c.MinFileRisk {
+	r.Files.Store(report.RelativePath(path, c), fr)
+	if r.Diff == nil && !c.Deterministic && shouldRender(c, fr) {
 		if err := c.Renderer.File(ctx, fr); err != nil {
 			return fmt.Errorf("render: %w", err)
 		}
@@ -588,6 +1150,16 @@ func handleSingleFile(ctx context.Context, path string, scanInfo scanPathInfo, c
 	return nil
 }
 
+// shouldRender reports whether fr is passed to c.Renderer as it is scanned. Files below
+// c.MinFileRisk are never rendered, and in quiet mode neither are files without behaviors.
+// Nothing is rendered while listing files.
+func shouldRender(c malcontent.Config, fr *malcontent.FileReport) bool {
+	if c.Renderer == nil || c.ListOnly || !fr.Risk().AtLeast(c.MinFileRisk) {
+		return false
+	}
+	return !c.Quiet || len(fr.Behaviors) > 0
+}
+
 func handleScanError(matchChan chan matchResult, r *malcontent.Report, c malcontent.Config, err error) error {
 	select {
 	case match := <-matchChan:
@@ -613,7 +1185,7 @@ func cleanupOCIPath(path string, logger *clog.Logger) {
 
 func handleOCIResults(ctx context.Context, imageURI string, files *sync.Map, c malcontent.Config, logger *clog.Logger) error {
 	match, err := exitIfHitOrMiss(files, imageURI, c.ExitFirstHit, c.ExitFirstMiss)
-	if err != nil && match != nil && c.Renderer != nil && match.RiskScore >= c.MinFileRisk {
+	if err != nil && match != nil && shouldRender(c, match) {
 		if renderErr := c.Renderer.File(ctx, match); renderErr != nil {
 			logger.Errorf("render error: %v", renderErr)
 		}
@@ -628,11 +1200,87 @@ func processArchive(ctx context.Context, c malcontent.Config, rfs []fs.FS, archi
 
 	var frs sync.Map
 
-	tmpRoot, err := archive.ExtractArchiveToTempDir(ctx, archivePath)
+	ctx = archive.WithLimits(ctx, archive.Limits{
+		MaxDepth:          c.MaxArchiveDepth,
+		MaxExtractedBytes: c.MaxExtractedBytes,
+		MaxExtractedFiles: c.MaxExtractedFiles,
+	})
+
+	maxConcurrency := getMaxConcurrency(c.Concurrency)
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g, gCtx := errgroup.WithContext(scanCtx)
+	g.SetLimit(maxConcurrency)
+
+	progress := progressFrom(ctx)
+	// store adds the report for the file extracted to path below root
+	store := func(path string, root string, fr *malcontent.FileReport, archiveMeta map[string]string) {
+		clean := strings.TrimPrefix(path, root)
+		progress.complete(fmt.Sprintf("%s ∴ %s", archivePath, clean))
+		if fr == nil {
+			return
+		}
+		if len(archiveMeta) > 0 && fr.Skipped == "" {
+			if fr.Meta == nil {
+				fr.Meta = make(map[string]string, len(archiveMeta))
+			}
+			maps.Copy(fr.Meta, archiveMeta)
+		}
+		frs.Store(clean, fr)
+	}
+
+	var tmpRoot string
+	var err error
+	if c.StreamTarMemory > 0 && archive.IsStreamable(archivePath) {
+		tmpRoot, err = archive.StreamTarToTempDir(ctx, archivePath, c.StreamTarMemory, func(sf archive.StreamedFile) error {
+			progress.expand(1)
+			g.Go(func() error {
+				fr, err := scanStreamedFile(gCtx, c, rfs, archivePath, sf)
+				if err != nil {
+					scanErrorsFrom(ctx).addFileReportError(errorPath(c, sf.Path, archivePath, sf.Root), err)
+				}
+				if err != nil && !interactive(c) {
+					fr, err = handleFileReportError(err, sf.Path, logger)
+				}
+				if err != nil {
+					return err
+				}
+				store(sf.Path, sf.Root, fr, nil)
+				return nil
+			})
+			return gCtx.Err()
+		})
+		// A failed scan of a streamed file stops the stream, so its error takes precedence
+		if err != nil {
+			if werr := g.Wait(); werr != nil {
+				return nil, werr
+			}
+		}
+	} else {
+		tmpRoot, err = archive.ExtractArchiveToTempDir(ctx, archivePath)
+	}
 	if err != nil {
+		// Archives that exceed the extraction limits (e.g., zip bombs) are reported as skipped
+		if errors.Is(err, archive.ErrLimitsExceeded) {
+			logger.Warn("skipping file", slog.String("reason", "archive limits exceeded"), slog.Any("error", err))
+			frs.Store(archivePath, &malcontent.FileReport{Path: archivePath, Skipped: "archive limits exceeded"})
+			return &frs, nil
+		}
 		// Avoid failing an entire scan when encountering problematic archives
 		// e.g., joblib_0.8.4_compressed_pickle_py27_np17.gz: not a valid gzip archive
 		if !c.ExitExtraction {
+			scanErrorsFrom(ctx).add(report.DisplayPath(archivePath, "", c), malcontent.ScanPhaseExtract, err)
+			if errors.Is(err, archive.ErrCorruptStream) {
+				logger.Warn("skipping file", slog.String("reason", "corrupt compressed stream"), slog.Any("error", err))
+				frs.Store(archivePath, &malcontent.FileReport{Path: archivePath, Skipped: "corrupt compressed stream"})
+				return &frs, nil
+			}
+			if errors.Is(err, archive.ErrCorruptPackage) {
+				logger.Warn("skipping file", slog.String("reason", "corrupt package"), slog.Any("error", err))
+				frs.Store(archivePath, &malcontent.FileReport{Path: archivePath, Skipped: "corrupt package"})
+				return &frs, nil
+			}
 			return nil, nil
 		}
 		return nil, fmt.Errorf("extract to temp: %w", err)
@@ -650,10 +1298,26 @@ func processArchive(ctx context.Context, c malcontent.Config, rfs []fs.FS, archi
 		tmpRoot = fmt.Sprintf("/private%s", tmpRoot)
 	}
 
-	extractedPaths, err := findFilesRecursively(ctx, tmpRoot)
+	extractedPaths, err := findFilesRecursively(ctx, tmpRoot, nil)
 	if err != nil {
 		return nil, fmt.Errorf("find: %w", err)
 	}
+	if c.Deterministic {
+		slices.Sort(extractedPaths)
+	}
+	if c.LargestFirst {
+		largestFirst(extractedPaths)
+	}
+	progress.expand(len(extractedPaths))
+
+	// Surface filesystem metadata for images that carry it
+	var archiveMeta map[string]string
+	if ext := programkind.GetExt(archivePath); ext == ".sqfs" || ext == ".squashfs" {
+		archiveMeta, err = archive.SquashfsMeta(archivePath)
+		if err != nil {
+			logger.Debugf("unable to read squashfs metadata: %v", err)
+		}
+	}
 
 	ep := make(chan string, len(extractedPaths))
 	go func() {
@@ -667,23 +1331,13 @@ func processArchive(ctx context.Context, c malcontent.Config, rfs []fs.FS, archi
 		}
 	}()
 
-	maxConcurrency := getMaxConcurrency(c.Concurrency)
-	scanCtx, cancel := context.WithCancel(ctx)
-	defer cancel()
-
-	g, gCtx := errgroup.WithContext(scanCtx)
-	g.SetLimit(maxConcurrency)
-
 	for path := range ep {
 		g.Go(func() error {
 			fr, err := processFile(gCtx, c, rfs, path, archivePath, tmpRoot, logger)
 			if err != nil {
 				return err
 			}
-			if fr != nil {
-				clean := strings.TrimPrefix(path, tmpRoot)
-				frs.Store(clean, fr)
-			}
+			store(path, tmpRoot, fr, archiveMeta)
 			return nil
 		})
 	}
@@ -706,23 +1360,47 @@ func handleFileReportError(err error, path string, logger *clog.Logger) (*malcon
 	case TypeUnknown:
 		return nil, fmt.Errorf("unknown error occurred while scanning path %s: %w", path, err)
 	case TypeScanError:
-		logger.Errorf("scan path: %v", err)
-		return nil, fmt.Errorf("scan failed for path %s: %w", path, err)
+		logger.Warn("skipping file", slog.String("reason", errMsgScanFailed), slog.Any("error", err))
+		return &malcontent.FileReport{
+			Path:    path,
+			Skipped: errMsgScanFailed,
+		}, nil
 	case TypeGenerateError:
 		return &malcontent.FileReport{
 			Path:    path,
 			Skipped: errMsgGenerateFailed,
 		}, nil
+	case TypeReadError:
+		logger.Warn("skipping file", slog.String("reason", errMsgReadFailed), slog.Any("error", err))
+		return &malcontent.FileReport{
+			Path:    path,
+			Skipped: errMsgReadFailed,
+		}, nil
 	default:
 		return nil, fmt.Errorf("unhandled error type scanning path %s: %w", path, err)
 	}
 }
 
+// errorPath returns the path Report.Errors names the file at path by: its display path, or for a file
+// extracted below archiveRoot from the archive or image at scanPath, its path within it.
+func errorPath(c malcontent.Config, path string, scanPath string, archiveRoot string) string {
+	if archiveRoot == "" || scanPath == "" || scanPath == path {
+		return report.DisplayPath(path, "", c)
+	}
+	if len(c.TrimPrefixes) > 0 {
+		scanPath = report.TrimPrefixes(scanPath, c.TrimPrefixes)
+	}
+	return fmt.Sprintf("%s ∴ %s", report.RelativePath(scanPath, c), formatPath(cleanPath(path, archiveRoot)))
+}
+
 // processFile scans a single output file, rendering live output if available.
 func processFile(ctx context.Context, c malcontent.Config, ruleFS []fs.FS, path string, scanPath string, archiveRoot string, logger *clog.Logger) (*malcontent.FileReport, error) {
 	logger = logger.With("path", path)
 
 	fr, err := scanSinglePath(ctx, c, path, ruleFS, scanPath, archiveRoot)
+	if err != nil {
+		scanErrorsFrom(ctx).addFileReportError(errorPath(c, path, scanPath, archiveRoot), err)
+	}
 	if err != nil && !interactive(c) {
 		return handleFileReportError(err, path, logger)
 	}
@@ -735,33 +1413,104 @@ func processFile(ctx context.Context, c malcontent.Config, ruleFS []fs.FS, path
 }
 
 // Scan YARA scans a data source, applying output filters if necessary.
+// If ctx is canceled during the scan, the reports of the files completed so far are
+// returned with Interrupted set, along with the error.
 func Scan(ctx context.Context, c malcontent.Config) (*malcontent.Report, error) {
+	ctx = withLogger(ctx, c)
+
+	// Read the overrides file and allowlist once rather than for every scanned file
+	overrides, err := report.LoadOverrides(c.OverridesFile)
+	if err != nil {
+		return nil, err
+	}
+	ctx = report.WithOverrides(ctx, overrides)
+	allow, err := report.LoadAllowHashes(c.AllowHashesFile)
+	if err != nil {
+		return nil, err
+	}
+	ctx = report.WithAllowHashes(ctx, allow)
+	if err := report.ValidateHashAlgo(c.HashAlgo); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRedactMatches(c.RedactMatches); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateCombinationRules(c.CombinationRules); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRuleIDPatterns(c); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	ctx, profile := withRulesProfile(ctx, c)
+	ctx, usage := withRuleUsage(ctx, c)
+	ctx = withContentDedup(ctx, c)
+	ctx = withProgress(ctx, c)
+	ctx = withInFlightLimit(ctx, c)
+	ctx, scanErrs := withScanErrors(ctx)
 	scanCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	r, err := recursiveScan(scanCtx, c)
-	if errors.Is(err, context.Canceled) {
+	switch {
+	case r != nil && ctx.Err() != nil:
+		// Keep the reports of the files completed before the scan was interrupted
+		r.Interrupted = true
+	case errors.Is(err, context.Canceled):
 		return r, fmt.Errorf("scan operation cancelled: %w", err)
-	}
-	if err != nil && !interactive(c) {
+	case err != nil && !interactive(c):
 		return r, err
 	}
+	r.Errors = scanErrs.list()
 
 	r.Files.Range(func(key, value any) bool {
-		if scanCtx.Err() != nil {
-			return false
-		}
 		if key == nil || value == nil {
 			return true
 		}
 		if fr, ok := value.(*malcontent.FileReport); ok {
-			if fr.RiskScore < c.MinFileRisk {
+			// Files whose scan was cut short by an interruption have empty reports, and listed files have no risk
+			keepClean := c.ListOnly || reportsCleanFiles(c)
+			if (!keepClean && !fr.Risk().AtLeast(c.MinFileRisk)) || (r.Interrupted && fr.Path == "") {
 				r.Files.Delete(key)
 			}
 		}
 		return true
 	})
-	if scanCtx.Err() == nil && c.Stats && c.Renderer.Name() != "JSON" && c.Renderer.Name() != "YAML" {
+	if c.Correlate {
+		r.Correlations = correlate(r)
+	}
+	if c.Deterministic {
+		if err := renderDeterministic(context.WithoutCancel(scanCtx), c, r); err != nil {
+			return r, err
+		}
+	}
+	r.Stats = render.ScanStatistics(&c, &r.Files)
+	r.Stats.Duration = time.Since(start)
+	r.Stats.Errors = len(r.Errors)
+	r.Stats.RuleErrors = ruleErrors(c)
+	r.Stats.RulesProfile = profile.results()
+	if c.Stats {
+		yrs, err := scanRules(ctx, c, c.RuleFS)
+		if err != nil {
+			return r, err
+		}
+		ri := RulesetInfo(yrs)
+		r.Stats.Ruleset = &ri
+	}
+	if usage != nil {
+		yrs, err := scanRules(ctx, c, c.RuleFS)
+		if err != nil {
+			return r, err
+		}
+		r.Stats.UnusedRules = usage.unused(yrs)
+	}
+
+	if r.Interrupted {
+		return r, fmt.Errorf("scan operation cancelled: %w", ctx.Err())
+	}
+
+	if scanCtx.Err() == nil && c.Stats && !c.ListOnly && !slices.Contains([]string{"JSON", "JSONGzip", "YAML"}, c.Renderer.Name()) {
 		err = render.Statistics(&c, r)
 		if err != nil {
 			return r, fmt.Errorf("stats: %w", err)
@@ -769,3 +1518,43 @@ func Scan(ctx context.Context, c malcontent.Config) (*malcontent.Report, error)
 	}
 	return r, nil
 }
+
+// ExitCode returns the process exit code for a completed scan based on the highest file risk.
+// When c.ExitCodeOnRisk is set, the code for the highest configured level at or below the
+// worst finding is used; otherwise any file reaching c.MinFileRisk results in 1.
+func ExitCode(c malcontent.Config, r *malcontent.Report) int {
+	if r == nil {
+		return 0
+	}
+
+	highest := -1
+	r.Files.Range(func(key, value any) bool {
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" && len(fr.Behaviors) > 0 {
+				highest = max(highest, fr.RiskScore)
+			}
+		}
+		return true
+	})
+
+	if highest < 0 {
+		return 0
+	}
+
+	if c.ExitCodeOnRisk == nil {
+		if malcontent.RiskLevel(highest).AtLeast(c.MinFileRisk) {
+			return 1
+		}
+		return 0
+	}
+
+	for risk := highest; risk >= 0; risk-- {
+		if code, ok := c.ExitCodeOnRisk[malcontent.RiskLevel(risk)]; ok {
+			return code
+		}
+	}
+	return 0
+}
@@ -1,6 +1,15 @@
 package action
 
-import "fmt"
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
 
 // Error message constants for NewFileReportError reasons.
 // If the compiled rules are invalid or the scanner malfunctions, yrs.Scan will fail.
@@ -9,6 +18,7 @@ const (
 	errMsgUnknown        = "unknown error"
 	errMsgScanFailed     = "scan failed"
 	errMsgGenerateFailed = "failed to generate file report"
+	errMsgReadFailed     = "failed to read file"
 )
 
 type ErrorType int
@@ -21,6 +31,8 @@ const (
 	TypeScanError
 	// TypeGenerateError is to be used when a file's report cannot be created.
 	TypeGenerateError
+	// TypeReadError is to be used when a file cannot be opened or read.
+	TypeReadError
 )
 
 // FileReportError is a custom error type to hold the error, path, and vanity reason.
@@ -47,6 +59,8 @@ func (e *FileReportError) errMsg() string {
 		return errMsgScanFailed
 	case TypeGenerateError:
 		return errMsgGenerateFailed
+	case TypeReadError:
+		return errMsgReadFailed
 	default:
 		return fmt.Sprintf("unknown error type(%d)", e.reason)
 	}
@@ -76,3 +90,65 @@ func (e *FileReportError) Type() ErrorType {
 func (e *FileReportError) Unwrap() error {
 	return e.err
 }
+
+type scanErrorsKey struct{}
+
+// scanErrors collects the files a scan carried on without, for Report.Errors.
+type scanErrors struct {
+	mu   sync.Mutex
+	errs []malcontent.ScanError
+}
+
+// withScanErrors returns a context that collects the files that could not be read, extracted or scanned.
+func withScanErrors(ctx context.Context) (context.Context, *scanErrors) {
+	e := &scanErrors{}
+	return context.WithValue(ctx, scanErrorsKey{}, e), e
+}
+
+func scanErrorsFrom(ctx context.Context) *scanErrors {
+	e, _ := ctx.Value(scanErrorsKey{}).(*scanErrors)
+	return e
+}
+
+// add records that the file at path failed in phase, unless an error was already recorded for both.
+func (e *scanErrors) add(path string, phase string, err error) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if slices.ContainsFunc(e.errs, func(se malcontent.ScanError) bool { return se.Path == path && se.Phase == phase }) {
+		return
+	}
+	e.errs = append(e.errs, malcontent.ScanError{Error: err.Error(), Path: path, Phase: phase})
+}
+
+// addFileReportError records err, returned for the file reported at path, if it is a FileReportError that
+// the scan carries on without. Other errors fail the scan, so need no record.
+func (e *scanErrors) addFileReportError(path string, err error) {
+	var fileErr *FileReportError
+	if !errors.As(err, &fileErr) {
+		return
+	}
+	switch fileErr.Type() {
+	case TypeReadError:
+		e.add(path, malcontent.ScanPhaseRead, fileErr.Unwrap())
+	case TypeGenerateError, TypeScanError:
+		e.add(path, malcontent.ScanPhaseScan, fileErr.Unwrap())
+	case TypeUnknown:
+	}
+}
+
+// list returns the recorded errors sorted by path, then phase.
+func (e *scanErrors) list() []malcontent.ScanError {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	errs := slices.Clone(e.errs)
+	slices.SortFunc(errs, func(a, b malcontent.ScanError) int {
+		return cmp.Or(cmp.Compare(a.Path, b.Path), cmp.Compare(a.Phase, b.Phase))
+	})
+	return errs
+}
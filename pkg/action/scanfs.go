@@ -0,0 +1,136 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"golang.org/x/sync/errgroup"
+)
+
+// scanFSRoot returns the fs.FS path that scanPath names within c.ScanFS: scanPath without any leading or
+// trailing slashes, or "." for the root.
+func scanFSRoot(scanPath string) (string, error) {
+	root := path.Clean("/" + scanPath)[1:]
+	if root == "" {
+		root = "."
+	}
+	if !fs.ValidPath(root) {
+		return "", fmt.Errorf("invalid path within ScanFS: %q", scanPath)
+	}
+	return root, nil
+}
+
+// walkScanFS returns the regular files found recursively below root within fsys, in lexical order.
+func walkScanFS(ctx context.Context, fsys fs.FS, root string) ([]string, error) {
+	logger := clog.FromContext(ctx)
+
+	var paths []string
+	err := fs.WalkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if p == root {
+				return err
+			}
+			logger.Debugf("error: %s: %s", p, err)
+			return nil
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+// handleScanFS scans the files below scanPath within c.ScanFS, reading them into memory rather than from disk.
+// Archives are scanned as they are, without being extracted.
+func handleScanFS(ctx context.Context, scanPath string, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once, logger *clog.Logger) error {
+	root, err := scanFSRoot(scanPath)
+	if err != nil {
+		return err
+	}
+	paths, err := walkScanFS(ctx, c.ScanFS, root)
+	if err != nil {
+		if len(c.ScanPaths) > 1 && ctx.Err() == nil {
+			logger.Errorf("find failed: %v", err)
+			return nil
+		}
+		return findError{err: err}
+	}
+
+	progress := progressFrom(ctx)
+	progress.expand(len(paths))
+
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g, gCtx := errgroup.WithContext(scanCtx)
+	g.SetLimit(getMaxConcurrency(c.Concurrency))
+	setupMatchHandler(gCtx, matchChan, c, cancel, logger)
+
+	for _, p := range paths {
+		if gCtx.Err() != nil {
+			break
+		}
+		g.Go(func() error {
+			defer progress.complete(p)
+			fr, err := scanFSFile(gCtx, c, p, logger)
+			if err != nil {
+				return err
+			}
+			if fr == nil {
+				return nil
+			}
+			addFingerprints(c, fr, root)
+			return storeFileReport(gCtx, p, fr, c, r, matchChan, matchOnce)
+		})
+	}
+
+	err = g.Wait()
+	if scanCtx.Err() != nil && errors.Is(scanCtx.Err(), context.Canceled) {
+		logger.Debug("scan operation was canceled")
+		return scanCtx.Err()
+	}
+	if err != nil {
+		return handleScanError(matchChan, r, c, err)
+	}
+	return nil
+}
+
+// scanFSFile reads and scans the file at p within c.ScanFS, reporting it as p.
+func scanFSFile(ctx context.Context, c malcontent.Config, p string, logger *clog.Logger) (*malcontent.FileReport, error) {
+	logger = logger.With("path", p)
+
+	fc, err := fs.ReadFile(c.ScanFS, p)
+	if err != nil {
+		err = NewFileReportError(err, p, TypeReadError)
+	}
+	var fr *malcontent.FileReport
+	if err == nil {
+		fr, err = scanBytes(ctx, c, p, fc)
+	}
+	if err != nil {
+		scanErrorsFrom(ctx).addFileReportError(p, err)
+	}
+	if err != nil && !interactive(c) {
+		return handleFileReportError(err, p, logger)
+	}
+	return fr, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/pool"
+	"github.com/chainguard-dev/malcontent/pkg/programkind"
+)
+
+const (
+	// stdinPath is the scan path that reads the content to scan from standard input.
+	stdinPath = "-"
+	// stdinName is the path reported for content read from standard input.
+	stdinName = "<stdin>"
+)
+
+// scanBytes scans in-memory content as though it were a file named name.
+func scanBytes(ctx context.Context, c malcontent.Config, name string, fc []byte) (*malcontent.FileReport, error) {
+	yrs, err := scanRules(ctx, c, c.RuleFS)
+	if err != nil {
+		return nil, err
+	}
+	c.Rules = yrs
+	return ScanBytes(ctx, c, scannersFor(c, yrs), name, fc)
+}
+
+// ScanBytes scans in-memory content as though it were a file named name, using c.Rules
+// and scanners drawn from scanners, which must have been created for c.Rules.
+// A nil report is returned for content excluded by the file type filters.
+func ScanBytes(ctx context.Context, c malcontent.Config, scanners *pool.ScannerPool, name string, fc []byte) (*malcontent.FileReport, error) {
+	if ctx.Err() != nil {
+		return &malcontent.FileReport{}, ctx.Err()
+	}
+	if c.Rules == nil {
+		return nil, fmt.Errorf("no rules provided")
+	}
+	ctx = withLogger(ctx, c)
+
+	logger := clog.FromContext(ctx).With("path", name)
+
+	if contentFilteredOut(c, name, fc) {
+		return nil, nil
+	}
+
+	if len(fc) == 0 {
+		return &malcontent.FileReport{Skipped: "zero-sized file", Path: name}, nil
+	}
+
+	kind := programkind.Detect(name, fc)
+	if !c.IncludeDataFiles && kind == nil {
+		logger.Debug("skipping file", slog.String("reason", "data file or empty"))
+		return &malcontent.FileReport{Skipped: "data file or empty", Path: name}, nil
+	}
+	if c.ListOnly {
+		return &malcontent.FileReport{Path: name, Size: int64(len(fc))}, nil
+	}
+
+	fr, err := reportFor(ctx, c, c.Rules, scanners, name, "", fc, kind, logger)
+	if err != nil {
+		return nil, err
+	}
+	if fr.Skipped == "" && len(fr.Behaviors) == 0 {
+		return &malcontent.FileReport{Path: name}, nil
+	}
+	return fr, nil
+}
+
+// handleStdin reads all of standard input (or c.Stdin) and scans it as a single file.
+func handleStdin(ctx context.Context, c malcontent.Config, r *malcontent.Report, matchChan chan matchResult, matchOnce *sync.Once) error {
+	var in io.Reader = os.Stdin
+	if c.Stdin != nil {
+		in = c.Stdin
+	}
+
+	fc, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("read stdin: %w", err)
+	}
+
+	fr, err := scanBytes(ctx, c, stdinName, fc)
+	progressFrom(ctx).complete(stdinName)
+	if err != nil {
+		scanErrorsFrom(ctx).addFileReportError(stdinName, err)
+		if !interactive(c) {
+			return fmt.Errorf("process: %w", err)
+		}
+		return nil
+	}
+	if fr == nil {
+		return nil
+	}
+	addFingerprints(c, fr, "")
+
+	return storeFileReport(ctx, stdinName, fr, c, r, matchChan, matchOnce)
+}
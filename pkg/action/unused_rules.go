@@ -0,0 +1,71 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package action
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+type ruleUsageKey struct{}
+
+// ruleUsage accumulates the rules matched by any file across the scanner pool.
+type ruleUsage struct {
+	mu      sync.Mutex
+	matched map[string]bool
+}
+
+// withRuleUsage returns a context that records matched rules when c.ReportUnusedRules is set.
+func withRuleUsage(ctx context.Context, c malcontent.Config) (context.Context, *ruleUsage) {
+	if !c.ReportUnusedRules {
+		return ctx, nil
+	}
+	u := &ruleUsage{matched: map[string]bool{}}
+	return context.WithValue(ctx, ruleUsageKey{}, u), u
+}
+
+func ruleUsageFrom(ctx context.Context) *ruleUsage {
+	u, _ := ctx.Value(ruleUsageKey{}).(*ruleUsage)
+	return u
+}
+
+// record marks the rules in mrs as matched.
+func (u *ruleUsage) record(mrs *yarax.ScanResults) {
+	if u == nil || mrs == nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, m := range mrs.MatchingRules() {
+		u.matched[m.Namespace()+":"+m.Identifier()] = true
+	}
+}
+
+// unused returns the rules of yrs that were never matched, sorted by ID and then rule name.
+func (u *ruleUsage) unused(yrs *yarax.Rules) []malcontent.UnusedRule {
+	if u == nil || yrs == nil {
+		return nil
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var unused []malcontent.UnusedRule
+	for _, r := range yrs.Slice() {
+		if !u.matched[r.Namespace()+":"+r.Identifier()] {
+			unused = append(unused, malcontent.UnusedRule{ID: report.RuleID(r.Namespace(), r.Identifier()), Rule: r.Identifier()})
+		}
+	}
+	slices.SortFunc(unused, func(a, b malcontent.UnusedRule) int {
+		return cmp.Or(cmp.Compare(a.ID, b.ID), cmp.Compare(a.Rule, b.Rule))
+	})
+	return unused
+}
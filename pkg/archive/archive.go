@@ -3,6 +3,7 @@ package archive
 import (
 	"archive/tar"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -28,6 +29,25 @@ var (
 	initializeOnce                sync.Once
 )
 
+// ErrCorruptStream is returned when a compressed stream cannot be decoded.
+var ErrCorruptStream = errors.New("corrupt compressed stream")
+
+// ErrCorruptPackage is returned when a .deb or .rpm package cannot be parsed.
+var ErrCorruptPackage = errors.New("corrupt package")
+
+// streamReader marks errors from decompressing r as ErrCorruptStream.
+type streamReader struct {
+	r io.Reader
+}
+
+func (sr streamReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		err = fmt.Errorf("%w: %w", ErrCorruptStream, err)
+	}
+	return n, err
+}
+
 // isValidPath checks if the target file is within the given directory.
 func IsValidPath(target, dir string) bool {
 	return strings.HasPrefix(filepath.Clean(target), filepath.Clean(dir))
@@ -98,6 +118,10 @@ func extractNestedArchive(ctx context.Context, d string, f string, extracted *sy
 		archivePath = fmt.Sprintf("%s%d", archivePath, time.Now().UnixNano())
 	}
 
+	if err := budgetFrom(ctx).enter(filepath.Dir(fullPath), archivePath); err != nil {
+		return err
+	}
+
 	if err := os.MkdirAll(archivePath, 0o755); err != nil {
 		return fmt.Errorf("failed to create extraction directory: %w", err)
 	}
@@ -113,6 +137,24 @@ func extractNestedArchive(ctx context.Context, d string, f string, extracted *sy
 		return fmt.Errorf("failed to remove archive file: %w", err)
 	}
 
+	// Extract archives contained within this one, which count against the depth limit
+	err = filepath.WalkDir(archivePath, func(path string, de os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() || !programkind.ArchiveMap[programkind.GetExt(path)] {
+			return nil
+		}
+		rel, err := filepath.Rel(d, path)
+		if err != nil {
+			return fmt.Errorf("filepath.Rel: %w", err)
+		}
+		return extractNestedArchive(ctx, d, rel, extracted, logger)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract nested archives: %w", err)
+	}
+
 	files, err := os.ReadDir(d)
 	if err != nil {
 		return fmt.Errorf("failed to read directory after extraction: %w", err)
@@ -145,6 +187,7 @@ func ExtractArchiveToTempDir(ctx context.Context, path string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp dir: %w", err)
 	}
+	ctx = newBudget(ctx, tmpDir)
 
 	go func() {
 		<-ctx.Done()
@@ -177,12 +220,23 @@ func ExtractArchiveToTempDir(ctx context.Context, path string) (string, error) {
 	}
 	err = extract(ctx, tmpDir, path)
 	if err != nil {
+		os.RemoveAll(tmpDir)
 		return "", fmt.Errorf("failed to extract %s: %w", path, err)
 	}
 
+	if err := extractNestedArchives(ctx, tmpDir, logger); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+
+	return tmpDir, nil
+}
+
+// extractNestedArchives extracts the archives found below tmpDir in place.
+func extractNestedArchives(ctx context.Context, tmpDir string, logger *clog.Logger) error {
 	var extractedFiles sync.Map
 
-	err = filepath.WalkDir(tmpDir, func(path string, d os.DirEntry, err error) error {
+	err := filepath.WalkDir(tmpDir, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
@@ -210,10 +264,9 @@ func ExtractArchiveToTempDir(ctx context.Context, path string) (string, error) {
 		return nil
 	})
 	if err != nil {
-		return "", fmt.Errorf("failed to walk directory: %w", err)
+		return fmt.Errorf("failed to walk directory: %w", err)
 	}
-
-	return tmpDir, nil
+	return nil
 }
 
 func ExtractionMethod(ext string) func(context.Context, string, string) error {
@@ -221,7 +274,7 @@ func ExtractionMethod(ext string) func(context.Context, string, string) error {
 	// that are substrings of other extensions (e.g., `.gz` and `.tar.gz` or `.tgz`)
 	switch ext {
 	// New cases should go below this line so that the lengthier tar extensions are evaluated first
-	case ".apk", ".gem", ".tar", ".tar.bz2", ".tar.gz", ".tgz", ".tar.xz", ".tbz", ".xz":
+	case ".apk", ".gem", ".tar", ".tar.bz2", ".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.zst", ".tar.zstd", ".tzst", ".tbz", ".xz":
 		return ExtractTar
 	case ".gz", ".gzip":
 		return ExtractGzip
@@ -235,6 +288,8 @@ func ExtractionMethod(ext string) func(context.Context, string, string) error {
 		return ExtractRPM
 	case ".deb":
 		return ExtractDeb
+	case ".sqfs", ".squashfs":
+		return ExtractSquashfs
 	default:
 		return nil
 	}
@@ -249,7 +304,7 @@ func handleDirectory(target string) error {
 }
 
 // handleFile extracts valid files within .deb or .tar archives.
-func handleFile(target string, tr *tar.Reader) error {
+func handleFile(ctx context.Context, target string, tr *tar.Reader) error {
 	buf := tarPool.Get(extractBuffer)
 	defer tarPool.Put(buf)
 
@@ -257,7 +312,7 @@ func handleFile(target string, tr *tar.Reader) error {
 		return fmt.Errorf("failed to create parent directory: %w", err)
 	}
 
-	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	out, err := createExtracted(ctx, target)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
 	}
@@ -265,6 +320,9 @@ func handleFile(target string, tr *tar.Reader) error {
 
 	written, err := io.CopyBuffer(out, io.LimitReader(tr, maxBytes), buf)
 	if err != nil {
+		if errors.Is(err, ErrCorruptStream) {
+			return fmt.Errorf("failed to copy file: %w", err)
+		}
 		if (strings.Contains(err.Error(), "unexpected EOF") && written == 0) ||
 			!strings.Contains(err.Error(), "unexpected EOF") {
 			return fmt.Errorf("failed to copy file: %w", err)
@@ -8,9 +8,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/pool"
 	"github.com/egibs/go-debian/deb"
 )
 
@@ -23,6 +25,11 @@ func ExtractDeb(ctx context.Context, d, f string) error {
 	logger := clog.FromContext(ctx).With("dir", d, "file", f)
 	logger.Debug("extracting deb")
 
+	// The data member is a tar archive, extracted through the tar buffer pool
+	initTarPool.Do(func() {
+		tarPool = pool.NewBufferPool(runtime.GOMAXPROCS(0))
+	})
+
 	fd, err := os.Open(f)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
@@ -30,7 +37,8 @@ func ExtractDeb(ctx context.Context, d, f string) error {
 
 	df, err := deb.Load(fd, f)
 	if err != nil {
-		return fmt.Errorf("failed to load file: %w", err)
+		fd.Close()
+		return fmt.Errorf("failed to load file: %w: %w", ErrCorruptPackage, err)
 	}
 
 	defer func() {
@@ -44,7 +52,7 @@ func ExtractDeb(ctx context.Context, d, f string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("failed to read tar header: %w", err)
+			return fmt.Errorf("failed to read tar header: %w: %w", ErrCorruptPackage, err)
 		}
 
 		clean := filepath.Clean(header.Name)
@@ -63,7 +71,7 @@ func ExtractDeb(ctx context.Context, d, f string) error {
 				return fmt.Errorf("failed to extract directory: %w", err)
 			}
 		case tar.TypeReg:
-			if err := handleFile(target, df.Data); err != nil {
+			if err := handleFile(ctx, target, df.Data); err != nil {
 				return fmt.Errorf("failed to extract file: %w", err)
 			}
 		case tar.TypeSymlink:
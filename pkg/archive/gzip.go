@@ -71,7 +71,7 @@ func ExtractGzip(ctx context.Context, d string, f string) error {
 		return fmt.Errorf("failed to create gzip reader: %w", err)
 	}
 
-	out, err := os.Create(target)
+	out, err := createExtracted(ctx, target)
 	if err != nil {
 		return fmt.Errorf("failed to create extracted file: %w", err)
 	}
@@ -0,0 +1,151 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// DefaultMaxDepth is the default number of nested archive levels that are extracted.
+	DefaultMaxDepth = 16
+	// DefaultMaxExtractedBytes is the default total size of the files extracted from an archive.
+	DefaultMaxExtractedBytes int64 = 1 << 33 // 8GB
+	// DefaultMaxExtractedFiles is the default number of files extracted from an archive.
+	DefaultMaxExtractedFiles = 1 << 20
+)
+
+// ErrLimitsExceeded is returned when extracting an archive would exceed its Limits.
+var ErrLimitsExceeded = errors.New("archive limits exceeded")
+
+// Limits bound the extraction of a single archive, including any archives nested within it.
+// Zero values select the defaults.
+type Limits struct {
+	MaxDepth          int
+	MaxExtractedBytes int64
+	MaxExtractedFiles int
+}
+
+// withDefaults returns l with unset limits replaced by their defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MaxDepth <= 0 {
+		l.MaxDepth = DefaultMaxDepth
+	}
+	if l.MaxExtractedBytes <= 0 {
+		l.MaxExtractedBytes = DefaultMaxExtractedBytes
+	}
+	if l.MaxExtractedFiles <= 0 {
+		l.MaxExtractedFiles = DefaultMaxExtractedFiles
+	}
+	return l
+}
+
+type limitsKey struct{}
+
+type budgetKey struct{}
+
+// WithLimits returns a context whose archive extractions are bounded by l.
+func WithLimits(ctx context.Context, l Limits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, l)
+}
+
+// budget tracks what has been extracted from an archive so far.
+type budget struct {
+	limits Limits
+	bytes  atomic.Int64
+	files  atomic.Int64
+	// depths records the nesting depth of each extraction directory
+	depths sync.Map
+}
+
+// newBudget returns a context carrying a fresh budget for extracting an archive into root.
+func newBudget(ctx context.Context, root string) context.Context {
+	l, _ := ctx.Value(limitsKey{}).(Limits)
+	b := &budget{limits: l.withDefaults()}
+	b.depths.Store(filepath.Clean(root), 1)
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+func budgetFrom(ctx context.Context) *budget {
+	b, _ := ctx.Value(budgetKey{}).(*budget)
+	return b
+}
+
+// enter records that an archive found in dir is being extracted into target,
+// failing if that exceeds the maximum nesting depth.
+func (b *budget) enter(dir string, target string) error {
+	if b == nil {
+		return nil
+	}
+
+	depth := 1
+	for d := filepath.Clean(dir); ; d = filepath.Dir(d) {
+		if v, ok := b.depths.Load(d); ok {
+			depth, _ = v.(int)
+			break
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+
+	if depth+1 > b.limits.MaxDepth {
+		return fmt.Errorf("%w: more than %d nested archives", ErrLimitsExceeded, b.limits.MaxDepth)
+	}
+	b.depths.Store(filepath.Clean(target), depth+1)
+	return nil
+}
+
+// admit counts a file of size bytes that is read into memory rather than extracted,
+// failing if that exceeds the number of files or bytes an archive may produce.
+func (b *budget) admit(size int64) error {
+	if b == nil {
+		return nil
+	}
+	if b.files.Add(1) > int64(b.limits.MaxExtractedFiles) {
+		return fmt.Errorf("%w: more than %d files", ErrLimitsExceeded, b.limits.MaxExtractedFiles)
+	}
+	if b.bytes.Add(size) > b.limits.MaxExtractedBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrLimitsExceeded, b.limits.MaxExtractedBytes)
+	}
+	return nil
+}
+
+// extractedFile is a file being extracted whose writes count against the archive budget.
+type extractedFile struct {
+	f *os.File
+	b *budget
+}
+
+// createExtracted creates target for writing extracted content, failing once the
+// archive being extracted has produced too many files.
+func createExtracted(ctx context.Context, target string) (*extractedFile, error) {
+	b := budgetFrom(ctx)
+	if b != nil && b.files.Add(1) > int64(b.limits.MaxExtractedFiles) {
+		return nil, fmt.Errorf("%w: more than %d files", ErrLimitsExceeded, b.limits.MaxExtractedFiles)
+	}
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &extractedFile{f: f, b: b}, nil
+}
+
+func (ef *extractedFile) Write(p []byte) (int, error) {
+	if ef.b != nil && ef.b.bytes.Add(int64(len(p))) > ef.b.limits.MaxExtractedBytes {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrLimitsExceeded, ef.b.limits.MaxExtractedBytes)
+	}
+	return ef.f.Write(p)
+}
+
+func (ef *extractedFile) Close() error {
+	return ef.f.Close()
+}
@@ -6,8 +6,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
@@ -57,3 +59,82 @@ func OCI(ctx context.Context, path string) (string, error) {
 
 	return tmpDir, nil
 }
+
+// LayerDir returns the directory name OCILayers extracts the layer with the given digest into.
+func LayerDir(digest string) string {
+	return strings.ReplaceAll(digest, ":", "-")
+}
+
+// OCILayers pulls an image, authenticating with the default Docker keychain, and extracts each
+// layer into its own directory (see LayerDir) beneath the returned directory. Files that are
+// deleted or replaced by later layers are kept, so that every layer can be inspected.
+func OCILayers(ctx context.Context, ref string) (string, map[string]string, error) {
+	if ctx.Err() != nil {
+		return "", nil, ctx.Err()
+	}
+
+	logger := clog.FromContext(ctx).With("image", ref)
+	logger.Debug("preparing image layers")
+
+	image, err := crane.Pull(ref, crane.WithContext(ctx), crane.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to pull image: %w", err)
+	}
+
+	layers, err := image.Layers()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list layers: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", filepath.Base(ref))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	digests := make(map[string]string, len(layers))
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			os.RemoveAll(tmpDir)
+			return "", nil, fmt.Errorf("failed to get layer digest: %w", err)
+		}
+
+		dir := LayerDir(digest.String())
+		if err := extractLayer(ctx, filepath.Join(tmpDir, dir), layer); err != nil {
+			os.RemoveAll(tmpDir)
+			return "", nil, fmt.Errorf("layer %s: %w", digest, err)
+		}
+		digests[dir] = digest.String()
+	}
+
+	return tmpDir, digests, nil
+}
+
+// extractLayer writes the uncompressed layer to a temporary tarball and extracts it into d.
+func extractLayer(ctx context.Context, d string, layer v1.Layer) error {
+	if err := os.MkdirAll(d, 0o700); err != nil {
+		return fmt.Errorf("failed to create layer dir: %w", err)
+	}
+
+	rc, err := layer.Uncompressed()
+	if err != nil {
+		return fmt.Errorf("failed to read layer: %w", err)
+	}
+	defer rc.Close()
+
+	tf, err := os.CreateTemp("", "layer-*.tar")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tf.Name())
+
+	if _, err := io.Copy(tf, rc); err != nil {
+		tf.Close()
+		return fmt.Errorf("failed to write layer: %w", err)
+	}
+	if err := tf.Close(); err != nil {
+		return fmt.Errorf("failed to write layer: %w", err)
+	}
+
+	return ExtractTar(ctx, d, tf.Name())
+}
@@ -45,7 +45,7 @@ func ExtractRPM(ctx context.Context, d, f string) error {
 
 	pkg, err := rpm.Read(rpmFile)
 	if err != nil {
-		return fmt.Errorf("failed to read RPM package headers: %w", err)
+		return fmt.Errorf("failed to read RPM package headers: %w: %w", ErrCorruptPackage, err)
 	}
 
 	if format := pkg.PayloadFormat(); format != "cpio" {
@@ -66,20 +66,20 @@ func ExtractRPM(ctx context.Context, d, f string) error {
 	case "gzip":
 		gzStream, err := gzip.NewReader(rpmFile)
 		if err != nil {
-			return fmt.Errorf("failed to create gzip reader: %w", err)
+			return fmt.Errorf("failed to create gzip reader: %w: %w", ErrCorruptPackage, err)
 		}
 		defer gzStream.Close()
 		cr = cpio.NewReader(gzStream)
 	case "xz":
 		xzStream, err := xz.NewReader(rpmFile)
 		if err != nil {
-			return fmt.Errorf("failed to create xz reader: %w", err)
+			return fmt.Errorf("failed to create xz reader: %w: %w", ErrCorruptPackage, err)
 		}
 		cr = cpio.NewReader(xzStream)
 	case "zstd":
 		zstdStream, err := zstd.NewReader(rpmFile)
 		if err != nil {
-			return fmt.Errorf("failed to create zstd reader: %w", err)
+			return fmt.Errorf("failed to create zstd reader: %w: %w", ErrCorruptPackage, err)
 		}
 		cr = cpio.NewReader(zstdStream)
 	default:
@@ -92,7 +92,7 @@ func ExtractRPM(ctx context.Context, d, f string) error {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("failed to read cpio header: %w", err)
+			return fmt.Errorf("failed to read cpio header: %w: %w", ErrCorruptPackage, err)
 		}
 
 		clean := filepath.Clean(header.Name)
@@ -116,7 +116,7 @@ func ExtractRPM(ctx context.Context, d, f string) error {
 			return fmt.Errorf("failed to create parent directory: %w", err)
 		}
 
-		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		out, err := createExtracted(ctx, target)
 		if err != nil {
 			return fmt.Errorf("failed to create file: %w", err)
 		}
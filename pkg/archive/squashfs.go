@@ -0,0 +1,645 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+const (
+	squashfsMagic        = 0x73717368 // "hsqs"
+	squashfsMetaSize     = 8192
+	squashfsMaxBlockSize = 1 << 20 // 1MB
+	squashfsMaxDepth     = 256
+	squashfsNoFragment   = 0xFFFFFFFF
+	squashfsNoTable      = 0xFFFFFFFFFFFFFFFF
+
+	squashfsMetaUncompressed  = 1 << 15
+	squashfsBlockUncompressed = 1 << 24
+)
+
+// squashfs inode types.
+const (
+	squashfsDir     = 1
+	squashfsFile    = 2
+	squashfsExtDir  = 8
+	squashfsExtFile = 9
+)
+
+var squashfsCompression = map[uint16]string{
+	1: "gzip",
+	2: "lzma",
+	3: "lzo",
+	4: "xz",
+	5: "lz4",
+	6: "zstd",
+}
+
+func squashfsCompressionName(id uint16) string {
+	if name, ok := squashfsCompression[id]; ok {
+		return name
+	}
+	return strconv.Itoa(int(id))
+}
+
+// squashfsSuperblock is the on-disk layout of a squashfs 4.0 superblock.
+type squashfsSuperblock struct {
+	Magic              uint32
+	InodeCount         uint32
+	ModTime            uint32
+	BlockSize          uint32
+	FragmentCount      uint32
+	Compression        uint16
+	BlockLog           uint16
+	Flags              uint16
+	IDCount            uint16
+	VersionMajor       uint16
+	VersionMinor       uint16
+	RootInode          uint64
+	BytesUsed          uint64
+	IDTableStart       uint64
+	XattrTableStart    uint64
+	InodeTableStart    uint64
+	DirTableStart      uint64
+	FragmentTableStart uint64
+	ExportTableStart   uint64
+}
+
+type squashfsInode struct {
+	Type uint16
+
+	// directories
+	DirBlock  uint32
+	DirOffset uint16
+	DirSize   uint32
+
+	// regular files
+	BlocksStart uint64
+	FileSize    uint64
+	Fragment    uint32
+	FragOffset  uint32
+	BlockSizes  []uint32
+}
+
+type squashfsFragment struct {
+	Start uint64
+	Size  uint32
+}
+
+type squashfsMetaBlock struct {
+	data []byte
+	next uint64
+}
+
+type squashfsReader struct {
+	r         io.ReaderAt
+	sb        squashfsSuperblock
+	zr        *zstd.Decoder
+	meta      map[uint64]squashfsMetaBlock
+	fragments []squashfsFragment
+	// dirs records the directory inodes already extracted, so that a
+	// directory entry pointing back at an ancestor cannot loop forever
+	dirs map[uint64]bool
+}
+
+func readSquashfsSuperblock(r io.ReaderAt) (squashfsSuperblock, error) {
+	var sb squashfsSuperblock
+	if err := binary.Read(io.NewSectionReader(r, 0, int64(binary.Size(sb))), binary.LittleEndian, &sb); err != nil {
+		return sb, fmt.Errorf("failed to read squashfs superblock: %w", err)
+	}
+
+	switch {
+	case sb.Magic != squashfsMagic:
+		return sb, fmt.Errorf("not a valid squashfs image")
+	case sb.VersionMajor != 4:
+		return sb, fmt.Errorf("unsupported squashfs version: %d.%d", sb.VersionMajor, sb.VersionMinor)
+	case sb.BlockSize < 4096 || sb.BlockSize > squashfsMaxBlockSize || sb.BlockSize&(sb.BlockSize-1) != 0:
+		return sb, fmt.Errorf("invalid squashfs block size: %d", sb.BlockSize)
+	}
+
+	return sb, nil
+}
+
+// SquashfsMeta returns filesystem-level metadata for a squashfs image.
+func SquashfsMeta(f string) (map[string]string, error) {
+	file, err := os.Open(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open squashfs image: %w", err)
+	}
+	defer file.Close()
+
+	sb, err := readSquashfsSuperblock(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		"squashfs_block_size":  strconv.FormatUint(uint64(sb.BlockSize), 10),
+		"squashfs_bytes_used":  strconv.FormatUint(sb.BytesUsed, 10),
+		"squashfs_compression": squashfsCompressionName(sb.Compression),
+		"squashfs_inodes":      strconv.FormatUint(uint64(sb.InodeCount), 10),
+		"squashfs_modified":    time.Unix(int64(sb.ModTime), 0).UTC().Format(time.RFC3339),
+		"squashfs_version":     fmt.Sprintf("%d.%d", sb.VersionMajor, sb.VersionMinor),
+	}, nil
+}
+
+// ExtractSquashfs extracts the regular files within a squashfs image.
+// The image is read in userspace and never mounted.
+func ExtractSquashfs(ctx context.Context, d string, f string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	logger := clog.FromContext(ctx).With("dir", d, "file", f)
+	logger.Debug("extracting squashfs")
+
+	fi, err := os.Stat(f)
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", f, err)
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+
+	file, err := os.Open(f)
+	if err != nil {
+		return fmt.Errorf("failed to open squashfs image: %w", err)
+	}
+	defer file.Close()
+
+	sb, err := readSquashfsSuperblock(file)
+	if err != nil {
+		return err
+	}
+	if sb.BytesUsed > uint64(fi.Size()) {
+		return fmt.Errorf("squashfs image is truncated: %d bytes used, %d bytes available", sb.BytesUsed, fi.Size())
+	}
+
+	s := &squashfsReader{
+		r:    file,
+		sb:   sb,
+		meta: map[uint64]squashfsMetaBlock{},
+		dirs: map[uint64]bool{sb.RootInode: true},
+	}
+
+	switch sb.Compression {
+	case 1, 4:
+	case 6:
+		s.zr, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+		defer s.zr.Close()
+	default:
+		return fmt.Errorf("unsupported squashfs compression: %s", squashfsCompressionName(sb.Compression))
+	}
+
+	if err := s.readFragmentTable(); err != nil {
+		return err
+	}
+
+	root, err := s.readInode(sb.RootInode)
+	if err != nil {
+		return fmt.Errorf("failed to read root inode: %w", err)
+	}
+
+	if err := os.MkdirAll(d, 0o700); err != nil {
+		return fmt.Errorf("failed to create extraction directory: %w", err)
+	}
+
+	return s.extractDir(ctx, root, d, "", 0, logger)
+}
+
+// decompress inflates a single squashfs block, refusing to produce more than limit bytes.
+func (s *squashfsReader) decompress(src []byte, limit int) ([]byte, error) {
+	var r io.Reader
+	switch s.sb.Compression {
+	case 1:
+		zr, err := zlib.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zlib reader: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	case 4:
+		xr, err := xz.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create xz reader: %w", err)
+		}
+		r = xr
+	case 6:
+		if err := s.zr.Reset(bytes.NewReader(src)); err != nil {
+			return nil, fmt.Errorf("failed to reset zstd reader: %w", err)
+		}
+		r = s.zr
+	}
+
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress block: %w", err)
+	}
+	if len(out) > limit {
+		return nil, fmt.Errorf("decompressed block exceeds %d bytes", limit)
+	}
+	return out, nil
+}
+
+// readBlock reads size bytes at pos, decompressing them unless uncompressed is set.
+func (s *squashfsReader) readBlock(pos uint64, size uint32, uncompressed bool, limit int) ([]byte, error) {
+	if pos+uint64(size) > s.sb.BytesUsed {
+		return nil, fmt.Errorf("block at %d extends past the end of the image", pos)
+	}
+	if size > uint32(limit) {
+		return nil, fmt.Errorf("block at %d exceeds %d bytes", pos, limit)
+	}
+
+	buf := make([]byte, size)
+	if _, err := s.r.ReadAt(buf, int64(pos)); err != nil {
+		return nil, fmt.Errorf("failed to read block at %d: %w", pos, err)
+	}
+	if uncompressed {
+		return buf, nil
+	}
+	return s.decompress(buf, limit)
+}
+
+// readMetaBlock returns the contents of the metadata block at pos and the position of the next one.
+func (s *squashfsReader) readMetaBlock(pos uint64) (squashfsMetaBlock, error) {
+	if mb, ok := s.meta[pos]; ok {
+		return mb, nil
+	}
+
+	var hdr [2]byte
+	if _, err := s.r.ReadAt(hdr[:], int64(pos)); err != nil {
+		return squashfsMetaBlock{}, fmt.Errorf("failed to read metadata header at %d: %w", pos, err)
+	}
+	h := binary.LittleEndian.Uint16(hdr[:])
+	size := uint32(h &^ squashfsMetaUncompressed)
+
+	data, err := s.readBlock(pos+2, size, h&squashfsMetaUncompressed != 0, squashfsMetaSize)
+	if err != nil {
+		return squashfsMetaBlock{}, err
+	}
+
+	mb := squashfsMetaBlock{data: data, next: pos + 2 + uint64(size)}
+	s.meta[pos] = mb
+	return mb, nil
+}
+
+// squashfsMetaReader reads a metadata stream that may span consecutive metadata blocks.
+type squashfsMetaReader struct {
+	s    *squashfsReader
+	buf  []byte
+	next uint64
+}
+
+func (s *squashfsReader) newMetaReader(pos uint64, offset uint16) (*squashfsMetaReader, error) {
+	mb, err := s.readMetaBlock(pos)
+	if err != nil {
+		return nil, err
+	}
+	if int(offset) > len(mb.data) {
+		return nil, fmt.Errorf("metadata offset %d exceeds block size %d", offset, len(mb.data))
+	}
+	return &squashfsMetaReader{s: s, buf: mb.data[offset:], next: mb.next}, nil
+}
+
+func (m *squashfsMetaReader) Read(p []byte) (int, error) {
+	for len(m.buf) == 0 {
+		if m.next >= m.s.sb.BytesUsed {
+			return 0, io.ErrUnexpectedEOF
+		}
+		mb, err := m.s.readMetaBlock(m.next)
+		if err != nil {
+			return 0, err
+		}
+		m.buf, m.next = mb.data, mb.next
+	}
+	n := copy(p, m.buf)
+	m.buf = m.buf[n:]
+	return n, nil
+}
+
+func (m *squashfsMetaReader) read(data ...any) error {
+	for _, d := range data {
+		if err := binary.Read(m, binary.LittleEndian, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *squashfsReader) readFragmentTable() error {
+	if s.sb.FragmentCount == 0 || s.sb.FragmentTableStart == squashfsNoTable {
+		return nil
+	}
+	if s.sb.FragmentCount > s.sb.InodeCount {
+		return fmt.Errorf("invalid squashfs fragment count: %d", s.sb.FragmentCount)
+	}
+
+	perBlock := uint32(squashfsMetaSize / 16)
+	blocks := (s.sb.FragmentCount + perBlock - 1) / perBlock
+	ptrs := make([]uint64, blocks)
+	if err := binary.Read(io.NewSectionReader(s.r, int64(s.sb.FragmentTableStart), int64(blocks)*8), binary.LittleEndian, ptrs); err != nil {
+		return fmt.Errorf("failed to read fragment table: %w", err)
+	}
+
+	s.fragments = make([]squashfsFragment, 0, s.sb.FragmentCount)
+	for _, ptr := range ptrs {
+		m, err := s.newMetaReader(ptr, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read fragment table: %w", err)
+		}
+		for i := uint32(0); i < perBlock && len(s.fragments) < int(s.sb.FragmentCount); i++ {
+			var frag squashfsFragment
+			var unused uint32
+			if err := m.read(&frag.Start, &frag.Size, &unused); err != nil {
+				return fmt.Errorf("failed to read fragment entry: %w", err)
+			}
+			s.fragments = append(s.fragments, frag)
+		}
+	}
+	return nil
+}
+
+// readInode parses the inode referenced by ref, where the upper bits locate the
+// metadata block relative to the inode table and the lower 16 bits the offset into it.
+func (s *squashfsReader) readInode(ref uint64) (*squashfsInode, error) {
+	m, err := s.newMetaReader(s.sb.InodeTableStart+(ref>>16), uint16(ref&0xFFFF))
+	if err != nil {
+		return nil, err
+	}
+
+	var hdr struct {
+		Type, Mode, UID, GID uint16
+		MTime, Number        uint32
+	}
+	if err := m.read(&hdr); err != nil {
+		return nil, fmt.Errorf("failed to read inode header: %w", err)
+	}
+
+	in := &squashfsInode{Type: hdr.Type}
+	switch hdr.Type {
+	case squashfsDir:
+		var d struct {
+			Block     uint32
+			Links     uint32
+			Size      uint16
+			Offset    uint16
+			ParentIno uint32
+		}
+		if err := m.read(&d); err != nil {
+			return nil, fmt.Errorf("failed to read directory inode: %w", err)
+		}
+		in.DirBlock, in.DirOffset, in.DirSize = d.Block, d.Offset, uint32(d.Size)
+	case squashfsExtDir:
+		var d struct {
+			Links      uint32
+			Size       uint32
+			Block      uint32
+			ParentIno  uint32
+			IndexCount uint16
+			Offset     uint16
+			Xattr      uint32
+		}
+		if err := m.read(&d); err != nil {
+			return nil, fmt.Errorf("failed to read directory inode: %w", err)
+		}
+		in.DirBlock, in.DirOffset, in.DirSize = d.Block, d.Offset, d.Size
+	case squashfsFile:
+		var f struct {
+			Start    uint32
+			Fragment uint32
+			Offset   uint32
+			Size     uint32
+		}
+		if err := m.read(&f); err != nil {
+			return nil, fmt.Errorf("failed to read file inode: %w", err)
+		}
+		in.BlocksStart, in.Fragment, in.FragOffset, in.FileSize = uint64(f.Start), f.Fragment, f.Offset, uint64(f.Size)
+	case squashfsExtFile:
+		var f struct {
+			Start    uint64
+			Size     uint64
+			Sparse   uint64
+			Links    uint32
+			Fragment uint32
+			Offset   uint32
+			Xattr    uint32
+		}
+		if err := m.read(&f); err != nil {
+			return nil, fmt.Errorf("failed to read file inode: %w", err)
+		}
+		in.BlocksStart, in.Fragment, in.FragOffset, in.FileSize = f.Start, f.Fragment, f.Offset, f.Size
+	default:
+		// Symlinks, devices, FIFOs and sockets carry no content worth scanning
+		return in, nil
+	}
+
+	if in.Type == squashfsFile || in.Type == squashfsExtFile {
+		if in.FileSize > maxBytes {
+			return nil, fmt.Errorf("file exceeds maximum allowed size (%d bytes)", maxBytes)
+		}
+		count := in.FileSize / uint64(s.sb.BlockSize)
+		if in.Fragment == squashfsNoFragment && in.FileSize%uint64(s.sb.BlockSize) != 0 {
+			count++
+		}
+		in.BlockSizes = make([]uint32, count)
+		if err := m.read(in.BlockSizes); err != nil {
+			return nil, fmt.Errorf("failed to read block list: %w", err)
+		}
+	}
+
+	return in, nil
+}
+
+type squashfsDirEntry struct {
+	name  string
+	inode uint64
+}
+
+func (s *squashfsReader) readDir(in *squashfsInode) ([]squashfsDirEntry, error) {
+	// The stored size includes three bytes for the implicit "." and ".." entries
+	if in.DirSize <= 3 {
+		return nil, nil
+	}
+	remaining := int64(in.DirSize) - 3
+
+	m, err := s.newMetaReader(s.sb.DirTableStart+uint64(in.DirBlock), in.DirOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []squashfsDirEntry
+	for remaining > 0 {
+		var hdr struct {
+			Count, Start, Number uint32
+		}
+		if err := m.read(&hdr); err != nil {
+			return nil, fmt.Errorf("failed to read directory header: %w", err)
+		}
+		remaining -= 12
+		if hdr.Count >= 256 {
+			return nil, fmt.Errorf("invalid directory header entry count: %d", hdr.Count+1)
+		}
+
+		for range hdr.Count + 1 {
+			var e struct {
+				Offset   uint16
+				InodeOff int16
+				Type     uint16
+				NameSize uint16
+			}
+			if err := m.read(&e); err != nil {
+				return nil, fmt.Errorf("failed to read directory entry: %w", err)
+			}
+			name := make([]byte, int(e.NameSize)+1)
+			if _, err := io.ReadFull(m, name); err != nil {
+				return nil, fmt.Errorf("failed to read directory entry name: %w", err)
+			}
+			remaining -= 8 + int64(len(name))
+			entries = append(entries, squashfsDirEntry{
+				name:  string(name),
+				inode: uint64(hdr.Start)<<16 | uint64(e.Offset),
+			})
+		}
+	}
+	return entries, nil
+}
+
+func (s *squashfsReader) extractDir(ctx context.Context, in *squashfsInode, d, rel string, depth int, logger *clog.Logger) error {
+	if depth > squashfsMaxDepth {
+		return fmt.Errorf("squashfs directory nesting exceeds %d levels: %s", squashfsMaxDepth, rel)
+	}
+
+	entries, err := s.readDir(in)
+	if err != nil {
+		return fmt.Errorf("failed to read directory %q: %w", rel, err)
+	}
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if e.name == "." || e.name == ".." || strings.ContainsAny(e.name, "/\x00") {
+			logger.Warnf("skipping potentially unsafe squashfs entry: %q", e.name)
+			continue
+		}
+
+		name := filepath.Join(rel, e.name)
+		target := filepath.Join(d, name)
+		if !IsValidPath(target, d) {
+			logger.Warnf("skipping file path outside extraction directory: %s", target)
+			continue
+		}
+
+		child, err := s.readInode(e.inode)
+		if err != nil {
+			return fmt.Errorf("failed to read inode for %s: %w", name, err)
+		}
+
+		switch child.Type {
+		case squashfsDir, squashfsExtDir:
+			if s.dirs[e.inode] {
+				return fmt.Errorf("squashfs directory loop at %s", name)
+			}
+			s.dirs[e.inode] = true
+			if err := handleDirectory(target); err != nil {
+				return fmt.Errorf("failed to extract directory: %w", err)
+			}
+			if err := s.extractDir(ctx, child, d, name, depth+1, logger); err != nil {
+				return err
+			}
+		case squashfsFile, squashfsExtFile:
+			if err := s.extractFile(ctx, child, target); err != nil {
+				return fmt.Errorf("failed to extract file %s: %w", name, err)
+			}
+		default:
+			logger.Debugf("skipping squashfs entry %s of type %d", name, child.Type)
+		}
+	}
+	return nil
+}
+
+func (s *squashfsReader) extractFile(ctx context.Context, in *squashfsInode, target string) error {
+	out, err := createExtracted(ctx, target)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer out.Close()
+
+	bs := uint64(s.sb.BlockSize)
+	remaining := in.FileSize
+	pos := in.BlocksStart
+
+	for _, size := range in.BlockSizes {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		want := min(bs, remaining)
+		onDisk := size &^ squashfsBlockUncompressed
+
+		var data []byte
+		if onDisk == 0 {
+			// sparse block
+			data = make([]byte, want)
+		} else {
+			data, err = s.readBlock(pos, onDisk, size&squashfsBlockUncompressed != 0, int(bs))
+			if err != nil {
+				return err
+			}
+			pos += uint64(onDisk)
+		}
+
+		if uint64(len(data)) < want {
+			return fmt.Errorf("short data block: got %d bytes, expected %d", len(data), want)
+		}
+		if _, err := out.Write(data[:want]); err != nil {
+			return fmt.Errorf("failed to write file contents: %w", err)
+		}
+		remaining -= want
+	}
+
+	if remaining == 0 {
+		return nil
+	}
+
+	if in.Fragment == squashfsNoFragment || int(in.Fragment) >= len(s.fragments) {
+		return fmt.Errorf("missing fragment %d for %d trailing bytes", in.Fragment, remaining)
+	}
+
+	frag := s.fragments[in.Fragment]
+	onDisk := frag.Size &^ squashfsBlockUncompressed
+	data, err := s.readBlock(frag.Start, onDisk, frag.Size&squashfsBlockUncompressed != 0, int(bs))
+	if err != nil {
+		return fmt.Errorf("failed to read fragment: %w", err)
+	}
+
+	end := uint64(in.FragOffset) + remaining
+	if end > uint64(len(data)) {
+		return errors.New("fragment data is shorter than the file tail")
+	}
+	if _, err := out.Write(data[in.FragOffset:end]); err != nil {
+		return fmt.Errorf("failed to write file contents: %w", err)
+	}
+
+	return nil
+}
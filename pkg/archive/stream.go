@@ -0,0 +1,179 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/pool"
+	"github.com/chainguard-dev/malcontent/pkg/programkind"
+	"github.com/klauspost/compress/zstd"
+	gzip "github.com/klauspost/pgzip"
+	"github.com/ulikunitz/xz"
+)
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// StreamedFile is a regular file read from an archive into memory rather than extracted to disk.
+type StreamedFile struct {
+	// Root is the directory the archive is extracted into
+	Root string
+	// Path is where the file would have been extracted to, below Root
+	Path    string
+	Content []byte
+}
+
+// IsStreamable reports whether the archive at path is a tar archive that StreamTarToTempDir can read.
+func IsStreamable(path string) bool {
+	switch programkind.GetExt(path) {
+	case ".apk", ".gem", ".tar", ".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.zst", ".tar.zstd", ".tzst":
+		return true
+	default:
+		return false
+	}
+}
+
+// StreamTarToTempDir reads the tar archive at path one entry at a time, passing regular files of at most
+// maxInMemory bytes to fn without writing them to disk; fn owns the content it is given. Larger files and
+// nested archives are extracted to a temporary directory, which is returned for the caller to scan and remove,
+// as with ExtractArchiveToTempDir. Both count against the limits set with WithLimits.
+func StreamTarToTempDir(ctx context.Context, path string, maxInMemory int64, fn func(StreamedFile) error) (string, error) {
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+
+	logger := clog.FromContext(ctx).With("path", path)
+	logger.Debug("streaming tar")
+
+	initTarPool.Do(func() {
+		tarPool = pool.NewBufferPool(runtime.GOMAXPROCS(0))
+	})
+
+	tmpDir, err := os.MkdirTemp("", filepath.Base(path))
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	ctx = newBudget(ctx, tmpDir)
+
+	if err := streamTar(ctx, tmpDir, path, maxInMemory, fn); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", fmt.Errorf("failed to stream %s: %w", path, err)
+	}
+	if err := extractNestedArchives(ctx, tmpDir, logger); err != nil {
+		os.RemoveAll(tmpDir)
+		return "", err
+	}
+	return tmpDir, nil
+}
+
+// streamTar passes the small regular files of the tar archive f to fn, extracting the rest into d.
+func streamTar(ctx context.Context, d string, f string, maxInMemory int64, fn func(StreamedFile) error) error {
+	tf, err := os.Open(f)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer tf.Close()
+
+	tr, closeStream, err := tarReader(f, tf)
+	if err != nil {
+		return err
+	}
+	defer closeStream()
+
+	b := budgetFrom(ctx)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		header, err := tr.Next()
+		if errors.Is(err, ErrCorruptStream) {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		clean := filepath.Clean(header.Name)
+		if filepath.IsAbs(clean) || strings.Contains(clean, "../") {
+			return fmt.Errorf("path is absolute or contains a relative path traversal: %s", clean)
+		}
+		target := filepath.Join(d, clean)
+		if !IsValidPath(target, d) {
+			return fmt.Errorf("invalid file path: %s", target)
+		}
+
+		// Nested archives are extracted from disk, like files too large to hold in memory
+		if header.Size > maxInMemory || programkind.ArchiveMap[programkind.GetExt(clean)] {
+			if err := handleFile(ctx, target, tr); err != nil {
+				return fmt.Errorf("failed to extract file: %w", err)
+			}
+			continue
+		}
+
+		if err := b.admit(header.Size); err != nil {
+			return err
+		}
+		content := make([]byte, header.Size)
+		n, err := io.ReadFull(tr, content)
+		// Like extraction, keep what could be read of a truncated archive's last file
+		if err != nil && (n == 0 || !errors.Is(err, io.ErrUnexpectedEOF)) {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if err := fn(StreamedFile{Root: d, Path: target, Content: content[:n]}); err != nil {
+			return err
+		}
+	}
+}
+
+// tarReader returns a reader for the tar archive f, read from r, decompressing it according to its extension,
+// and a function releasing the decompressor.
+func tarReader(f string, r io.Reader) (*tar.Reader, func(), error) {
+	switch ext := programkind.GetExt(f); ext {
+	case ".apk", ".tar.gz", ".tgz":
+		br := bufio.NewReader(r)
+		// Like ExtractTar, read .tar.gz files that are not actually compressed as plain tar
+		magic, _ := br.Peek(len(gzipMagic))
+		if ext != ".apk" && !bytes.Equal(magic, gzipMagic) {
+			return tar.NewReader(br), func() {}, nil
+		}
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+		return tar.NewReader(gz), func() { gz.Close() }, nil
+	case ".tar.xz", ".txz":
+		xzr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create xz reader: %w: %w", ErrCorruptStream, err)
+		}
+		return tar.NewReader(streamReader{r: xzr}), func() {}, nil
+	case ".tar.zst", ".tar.zstd", ".tzst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create zstd reader: %w: %w", ErrCorruptStream, err)
+		}
+		return tar.NewReader(streamReader{r: zr}), zr.Close, nil
+	default:
+		return tar.NewReader(r), func() {}, nil
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/chainguard-dev/malcontent/pkg/pool"
 	"github.com/chainguard-dev/malcontent/pkg/programkind"
 	bzip2 "github.com/cosnicolaou/pbzip2"
+	"github.com/klauspost/compress/zstd"
 	gzip "github.com/klauspost/pgzip"
 	"github.com/ulikunitz/xz"
 )
@@ -84,24 +85,32 @@ func ExtractTar(ctx context.Context, d string, f string) error {
 		}
 		defer gzStream.Close()
 		tr = tar.NewReader(gzStream)
-	case strings.Contains(filename, ".tar.xz"):
+	case strings.Contains(filename, ".tar.xz") || strings.Contains(filename, ".txz"):
 		xzStream, err := xz.NewReader(tf)
 		if err != nil {
-			return fmt.Errorf("failed to create xz reader: %w", err)
+			return fmt.Errorf("failed to create xz reader: %w: %w", ErrCorruptStream, err)
 		}
-		tr = tar.NewReader(xzStream)
+		tr = tar.NewReader(streamReader{r: xzStream})
+	case strings.Contains(filename, ".tar.zst") || strings.Contains(filename, ".tzst"):
+		zr, err := zstd.NewReader(tf)
+		if err != nil {
+			return fmt.Errorf("failed to create zstd reader: %w: %w", ErrCorruptStream, err)
+		}
+		defer zr.Close()
+		tr = tar.NewReader(streamReader{r: zr})
 	case strings.Contains(filename, ".xz"):
-		xzStream, err := xz.NewReader(tf)
+		xzr, err := xz.NewReader(tf)
 		if err != nil {
-			return fmt.Errorf("failed to create xz reader: %w", err)
+			return fmt.Errorf("failed to create xz reader: %w: %w", ErrCorruptStream, err)
 		}
+		xzStream := streamReader{r: xzr}
 		uncompressed := strings.Trim(filepath.Base(f), ".xz")
 		target := filepath.Join(d, filepath.Base(filepath.Dir(f)), uncompressed)
 		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
 			return fmt.Errorf("failed to create directory for file: %w", err)
 		}
 
-		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		out, err := createExtracted(ctx, target)
 		if err != nil {
 			return fmt.Errorf("failed to create file: %w", err)
 		}
@@ -140,7 +149,7 @@ func ExtractTar(ctx context.Context, d string, f string) error {
 		if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
 			return fmt.Errorf("failed to create directory for file: %w", err)
 		}
-		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		out, err := createExtracted(ctx, target)
 		if err != nil {
 			return fmt.Errorf("failed to create file: %w", err)
 		}
@@ -178,6 +187,10 @@ func ExtractTar(ctx context.Context, d string, f string) error {
 	for {
 		header, err := tr.Next()
 
+		if errors.Is(err, ErrCorruptStream) {
+			return fmt.Errorf("failed to read tar header: %w", err)
+		}
+
 		if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
 			break
 		}
@@ -202,7 +215,7 @@ func ExtractTar(ctx context.Context, d string, f string) error {
 				return fmt.Errorf("failed to extract directory: %w", err)
 			}
 		case tar.TypeReg:
-			if err := handleFile(target, tr); err != nil {
+			if err := handleFile(ctx, target, tr); err != nil {
 				return fmt.Errorf("failed to extract file: %w", err)
 			}
 		case tar.TypeSymlink:
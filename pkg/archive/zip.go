@@ -146,7 +146,7 @@ func extractFile(ctx context.Context, file *zip.File, destDir string, logger *cl
 		return fmt.Errorf("failed to open archived file: %w", err)
 	}
 
-	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	dst, err := createExtracted(ctx, target)
 	if err != nil {
 		return fmt.Errorf("failed to create destination file: %w", err)
 	}
@@ -46,7 +46,7 @@ func ExtractZlib(ctx context.Context, d string, f string) error {
 		return fmt.Errorf("failed to create zlib reader: %w", err)
 	}
 
-	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	out, err := createExtracted(ctx, target)
 	if err != nil {
 		return fmt.Errorf("failed to create extracted file: %w", err)
 	}
@@ -44,7 +44,7 @@ func ExtractZstd(ctx context.Context, d string, f string) error {
 		return fmt.Errorf("failed to create directory for decomrpessed zstd file: %w", err)
 	}
 
-	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	out, err := createExtracted(ctx, target)
 	if err != nil {
 		return fmt.Errorf("failed to create decompressed zstd file: %w", err)
 	}
@@ -56,7 +56,7 @@ func ExtractZstd(ctx context.Context, d string, f string) error {
 
 	zr, err := zstd.NewReader(zstdFile)
 	if err != nil {
-		return fmt.Errorf("failed to open zstd file %s: %w", f, err)
+		return fmt.Errorf("failed to open zstd file %s: %w: %w", f, ErrCorruptStream, err)
 	}
 
 	defer func() {
@@ -72,7 +72,7 @@ func ExtractZstd(ctx context.Context, d string, f string) error {
 			return ctx.Err()
 		}
 
-		n, err := zr.Read(buf)
+		n, err := streamReader{r: zr}.Read(buf)
 		if n > 0 {
 			written += int64(n)
 			if written > maxBytes {
@@ -7,11 +7,13 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"log/slog"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
 	"github.com/chainguard-dev/malcontent/rules"
 
 	yarax "github.com/VirusTotal/yara-x/go"
@@ -160,18 +162,34 @@ func removeRules(data []byte, rulesToRemove []string) []byte {
 }
 
 func Recursive(ctx context.Context, fss []fs.FS) (*yarax.Rules, error) {
+	yrs, _, err := RecursiveWithErrors(ctx, fss)
+	return yrs, err
+}
+
+// RecursiveWithErrors compiles the rules in fss like Recursive, also returning the errors of
+// user rule files (see Dirs) that were left out of the rules because they failed to compile.
+func RecursiveWithErrors(ctx context.Context, fss []fs.FS) (*yarax.Rules, []malcontent.RuleCompileError, error) {
 	if ctx.Err() != nil {
-		return nil, ctx.Err()
+		return nil, nil, ctx.Err()
 	}
 
 	yxc, err := yarax.NewCompiler(yarax.ConditionOptimization(true), yarax.EnableIncludes(true))
 	if err != nil {
-		return nil, fmt.Errorf("yarax compiler: %w", err)
+		return nil, nil, fmt.Errorf("yarax compiler: %w", err)
 	}
 
 	rulesToRemove := getRulesToRemove()
+	// seen counts the compile errors already reported for skipped user rule files
+	seen := 0
+	var skipped []malcontent.RuleCompileError
 
 	for _, root := range fss {
+		user, isUser := userRules(root)
+		var name string
+		name, err = rulesetName(root)
+		if err != nil {
+			break
+		}
 		err = fs.WalkDir(root, ".", func(path string, d fs.DirEntry, err error) error {
 			if err != nil {
 				return err
@@ -189,9 +207,27 @@ func Recursive(ctx context.Context, fss []fs.FS) (*yarax.Rules, error) {
 
 				bs = removeRules(bs, rulesToRemove)
 
-				yxc.NewNamespace(path)
-				if err := yxc.AddSource(string(bs), yarax.WithOrigin(path)); err != nil {
-					return fmt.Errorf("failed to parse %s: %v", path, err)
+				origin := path
+				if isUser {
+					origin = user.origin(path)
+				}
+
+				yxc.NewNamespace(namespace(name, path))
+				if err := yxc.AddSource(string(bs), yarax.WithOrigin(origin)); err != nil {
+					if !isUser {
+						return fmt.Errorf("failed to parse %s: %v", path, err)
+					}
+					errs := yxc.Errors()
+					res := ruleErrors(origin, errs[min(seen, len(errs)):], err)
+					seen = len(errs)
+					if user.strict {
+						return fmt.Errorf("failed to parse %s", describeErrors(res))
+					}
+					for _, re := range res {
+						clog.WarnContext(ctx, "skipping user rules that failed to compile",
+							slog.String("rule", re.Path), slog.Int("line", re.Line), slog.Int("column", re.Column), slog.String("error", re.Message))
+					}
+					skipped = append(skipped, res...)
 				}
 			}
 
@@ -203,20 +239,21 @@ func Recursive(ctx context.Context, fss []fs.FS) (*yarax.Rules, error) {
 	}
 
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	errors := []string{}
-	for _, yce := range yxc.Errors() {
+	yces := yxc.Errors()
+	for _, yce := range yces[min(seen, len(yces)):] {
 		clog.ErrorContext(ctx, "error", yce.Error())
 		errors = append(errors, yce.Text)
 	}
 
 	if len(errors) > 0 {
-		return nil, fmt.Errorf("compile errors encountered: %v", errors)
+		return nil, nil, fmt.Errorf("compile errors encountered: %v", errors)
 	}
 
 	yrs := yxc.Build()
 
-	return yrs, nil
+	return yrs, skipped, nil
 }
@@ -0,0 +1,82 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// filterFS hides rule files whose paths do not match any of the given glob patterns.
+type filterFS struct {
+	fs.FS
+	patterns []string
+}
+
+// Filter limits the rule files visible in fss to those matching one of patterns.
+// Patterns are path.Match globs over rule paths such as "exfil/*"; a pattern that
+// matches a directory selects every rule below it. No patterns selects all rules.
+func Filter(fss []fs.FS, patterns []string) ([]fs.FS, error) {
+	if len(patterns) == 0 {
+		return fss, nil
+	}
+
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("rule filter %q: %w", p, err)
+		}
+	}
+
+	filtered := make([]fs.FS, 0, len(fss))
+	for _, f := range fss {
+		filtered = append(filtered, filterFS{FS: f, patterns: patterns})
+	}
+	return filtered, nil
+}
+
+// isRule reports whether name is a YARA rule file.
+func isRule(name string) bool {
+	return filepath.Ext(name) == ".yara" || filepath.Ext(name) == ".yar"
+}
+
+// selected reports whether the rule file at name, or one of its parent directories, matches a pattern.
+func (f filterFS) selected(name string) bool {
+	for i := 0; i <= len(name); i++ {
+		if i < len(name) && name[i] != '/' {
+			continue
+		}
+		for _, p := range f.patterns {
+			if ok, _ := path.Match(p, name[:i]); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f filterFS) Open(name string) (fs.File, error) {
+	if isRule(name) && !f.selected(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f.FS.Open(name)
+}
+
+func (f filterFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	des, err := fs.ReadDir(f.FS, name)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]fs.DirEntry, 0, len(des))
+	for _, de := range des {
+		p := path.Join(name, de.Name())
+		if !de.IsDir() && isRule(p) && !f.selected(p) {
+			continue
+		}
+		kept = append(kept, de)
+	}
+	return kept, nil
+}
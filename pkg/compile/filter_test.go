@@ -0,0 +1,64 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFilter(t *testing.T) {
+	t.Parallel()
+	rfs := fstest.MapFS{
+		"exfil/stealer/browser.yara": {},
+		"exfil/upload.yara":          {},
+		"crypto/aes.yar":             {},
+		"exec/shell.yara":            {},
+		"README.md":                  {},
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+	}{
+		{"no filter", nil, []string{"README.md", "crypto/aes.yar", "exec/shell.yara", "exfil/stealer/browser.yara", "exfil/upload.yara"}},
+		{"family glob", []string{"exfil/*"}, []string{"README.md", "exfil/stealer/browser.yara", "exfil/upload.yara"}},
+		{"directory", []string{"exfil/stealer", "crypto"}, []string{"README.md", "crypto/aes.yar", "exfil/stealer/browser.yara"}},
+		{"file", []string{"exec/shell.yara"}, []string{"README.md", "exec/shell.yara"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fss, err := Filter([]fs.FS{rfs}, tt.patterns)
+			if err != nil {
+				t.Fatalf("Filter: %v", err)
+			}
+
+			var got []string
+			err = fs.WalkDir(fss[0], ".", func(path string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					got = append(got, path)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("walk: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Filter([]fs.FS{rfs}, []string{"exfil/["}); err == nil {
+		t.Error("Filter with a malformed pattern succeeded, want error")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+)
+
+// SourceHash returns the SHA256 of the rule files in fss, as compiled by Recursive: their namespaces and
+// contents in compilation order, and the names of the rules Recursive removes from them.
+func SourceHash(fss []fs.FS) (string, error) {
+	h := sha256.New()
+
+	removed := getRulesToRemove()
+	slices.Sort(removed)
+	for _, name := range removed {
+		fmt.Fprintf(h, "remove %s\n", name)
+	}
+
+	for i, root := range fss {
+		name, err := rulesetName(root)
+		if err != nil {
+			return "", err
+		}
+		err = fs.WalkDir(root, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || (filepath.Ext(path) != ".yara" && filepath.Ext(path) != ".yar") {
+				return nil
+			}
+			bs, err := fs.ReadFile(root, path)
+			if err != nil {
+				return fmt.Errorf("readfile: %w", err)
+			}
+			fmt.Fprintf(h, "%d %s %d\n", i, namespace(name, path), len(bs))
+			h.Write(bs)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
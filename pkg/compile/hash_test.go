@@ -0,0 +1,60 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSourceHash(t *testing.T) {
+	t.Parallel()
+	base := fstest.MapFS{
+		"exec/shell.yara": {Data: []byte("rule shell { condition: true }")},
+		"README.md":       {Data: []byte("docs")},
+	}
+
+	hash := func(t *testing.T, fss ...fs.FS) string {
+		t.Helper()
+		h, err := SourceHash(fss)
+		if err != nil {
+			t.Fatalf("SourceHash: %v", err)
+		}
+		return h
+	}
+
+	want := hash(t, base)
+	if got := hash(t, base); got != want {
+		t.Errorf("SourceHash() = %s, want stable %s", got, want)
+	}
+
+	tests := []struct {
+		name string
+		fss  []fs.FS
+	}{
+		{"changed rule", []fs.FS{fstest.MapFS{"exec/shell.yara": {Data: []byte("rule shell { condition: false }")}}}},
+		{"renamed rule", []fs.FS{fstest.MapFS{"exec/sh.yara": base["exec/shell.yara"]}}},
+		{"extra filesystem", []fs.FS{base, fstest.MapFS{"extra.yar": {Data: []byte("rule extra { condition: true }")}}}},
+		{"named ruleset", []fs.FS{NamedFS{FS: base, Name: "internal"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := hash(t, tt.fss...); got == want {
+				t.Errorf("SourceHash() = %s, want a different hash", got)
+			}
+		})
+	}
+
+	// An empty name leaves the ruleset unlabeled
+	if got := hash(t, NamedFS{FS: base}); got != want {
+		t.Errorf("SourceHash() of an unnamed NamedFS = %s, want %s", got, want)
+	}
+
+	// Files that are not rules do not affect the hash
+	if got := hash(t, fstest.MapFS{"exec/shell.yara": base["exec/shell.yara"]}); got != want {
+		t.Errorf("SourceHash() without README = %s, want %s", got, want)
+	}
+}
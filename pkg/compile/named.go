@@ -0,0 +1,61 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// sourceSep separates the name of a ruleset from the path of a rule file in the namespaces of its rules.
+const sourceSep = "::"
+
+// NamedFS is a ruleset labeled with its source, such as "upstream" or "internal". Its rules are compiled
+// into namespaces that carry the name, which reports give as the RuleSource of the behaviors they match.
+type NamedFS struct {
+	fs.FS
+	Name string
+}
+
+// Named returns fss as filesystems, so that rulesets from several sources can be compiled together.
+func Named(fss []NamedFS) []fs.FS {
+	res := make([]fs.FS, 0, len(fss))
+	for _, f := range fss {
+		res = append(res, f)
+	}
+	return res
+}
+
+// SplitNamespace splits the namespace of a compiled rule into the name of the NamedFS it was compiled
+// from, or "" if its ruleset was not named, and the path of its rule file.
+func SplitNamespace(namespace string) (string, string) {
+	if source, path, ok := strings.Cut(namespace, sourceSep); ok {
+		return source, path
+	}
+	return "", namespace
+}
+
+// rulesetName returns the name of the ruleset backing root, looking through rule filters.
+func rulesetName(root fs.FS) (string, error) {
+	if f, ok := root.(filterFS); ok {
+		root = f.FS
+	}
+	n, ok := root.(NamedFS)
+	if !ok {
+		return "", nil
+	}
+	if strings.Contains(n.Name, sourceSep) {
+		return "", fmt.Errorf("ruleset name %q: must not contain %q", n.Name, sourceSep)
+	}
+	return n.Name, nil
+}
+
+// namespace returns the namespace of the rule file at path in the ruleset named name.
+func namespace(name string, path string) string {
+	if name == "" {
+		return path
+	}
+	return name + sourceSep + path
+}
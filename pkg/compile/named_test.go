@@ -0,0 +1,74 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"context"
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitNamespace(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		namespace string
+		source    string
+		path      string
+	}{
+		{"net/download.yara", "", "net/download.yara"},
+		{"internal::net/download.yara", "internal", "net/download.yara"},
+		{"upstream::yara/YARAForge/yaraforge.yar", "upstream", "yara/YARAForge/yaraforge.yar"},
+	}
+	for _, tt := range tests {
+		source, path := SplitNamespace(tt.namespace)
+		if source != tt.source || path != tt.path {
+			t.Errorf("SplitNamespace(%q) = %q, %q, want %q, %q", tt.namespace, source, path, tt.source, tt.path)
+		}
+		if got := namespace(source, path); got != tt.namespace {
+			t.Errorf("namespace(%q, %q) = %q, want %q", source, path, got, tt.namespace)
+		}
+	}
+}
+
+func TestRecursiveNamed(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	fss := Named([]NamedFS{
+		{FS: fstest.MapFS{"net/download.yara": {Data: []byte("rule download { strings: $a = \"curl\" condition: $a }")}}, Name: "upstream"},
+		{FS: fstest.MapFS{"persist/cron.yara": {Data: []byte("rule cron { strings: $a = \"crontab\" condition: $a }")}}, Name: "internal"},
+	})
+	// Ruleset names survive rule filters
+	fss, err := Filter(fss, []string{"net/*", "persist/*"})
+	if err != nil {
+		t.Fatalf("filter: %v", err)
+	}
+
+	yrs, err := Recursive(ctx, fss)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	rules := yrs.Slice()
+	got := make([]string, 0, len(rules))
+	for _, r := range rules {
+		got = append(got, r.Namespace())
+	}
+	slices.Sort(got)
+	if want := []string{"internal::persist/cron.yara", "upstream::net/download.yara"}; !slices.Equal(got, want) {
+		t.Errorf("namespaces = %q, want %q", got, want)
+	}
+}
+
+func TestRecursiveNamedInvalid(t *testing.T) {
+	t.Parallel()
+	fss := []fs.FS{NamedFS{FS: fstest.MapFS{"a.yara": {Data: []byte("rule a { condition: true }")}}, Name: "bad::name"}}
+	if _, err := Recursive(context.Background(), fss); err == nil {
+		t.Error("Recursive() with an invalid ruleset name succeeded, want an error")
+	}
+	if _, err := SourceHash(fss); err == nil {
+		t.Error("SourceHash() with an invalid ruleset name succeeded, want an error")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// userFS holds rules supplied by the user rather than embedded in malcontent.
+type userFS struct {
+	fs.FS
+	dir    string
+	strict bool
+}
+
+// Dirs returns a filesystem for each directory of user rules, to be compiled alongside the embedded rules.
+// Unless strict is set, user rule files that fail to compile are skipped with a warning rather than failing compilation.
+func Dirs(paths []string, strict bool) ([]fs.FS, error) {
+	fss := make([]fs.FS, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		if !fi.IsDir() {
+			return nil, fmt.Errorf("rules: %s is not a directory", p)
+		}
+		fss = append(fss, userFS{FS: os.DirFS(p), dir: p, strict: strict})
+	}
+	return fss, nil
+}
+
+// userRules returns the user rules backing root, looking through rule filters and ruleset names.
+func userRules(root fs.FS) (userFS, bool) {
+	if f, ok := root.(filterFS); ok {
+		root = f.FS
+	}
+	if n, ok := root.(NamedFS); ok {
+		root = n.FS
+	}
+	u, ok := root.(userFS)
+	return u, ok
+}
+
+// ruleErrors converts the compile errors of the rule file at origin, falling back to err if there are none.
+func ruleErrors(origin string, errs []yarax.CompileError, err error) []malcontent.RuleCompileError {
+	if len(errs) == 0 {
+		return []malcontent.RuleCompileError{{Path: origin, Message: err.Error()}}
+	}
+	res := make([]malcontent.RuleCompileError, 0, len(errs))
+	for _, ce := range errs {
+		res = append(res, malcontent.RuleCompileError{Path: origin, Line: ce.Line, Column: ce.Column, Message: ce.Title})
+	}
+	return res
+}
+
+// describeErrors formats rule errors as "file:line:column: message".
+func describeErrors(res []malcontent.RuleCompileError) string {
+	msgs := make([]string, 0, len(res))
+	for _, re := range res {
+		msgs = append(msgs, fmt.Sprintf("%s:%d:%d: %s", re.Path, re.Line, re.Column, re.Message))
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// origin returns the path reported in compile errors for a rule file.
+func (u userFS) origin(path string) string {
+	return filepath.Join(u.dir, filepath.FromSlash(path))
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package compile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	good := "rule good : high {\n\tstrings:\n\t\t$a = \"curl\"\n\tcondition:\n\t\t$a\n}\n"
+	bad := "rule bad : high {\n\tstrings:\n\t\t$a = \"wget\"\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "good.yara"), []byte(good), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.yara"), []byte(bad), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Dirs([]string{dir}, false)
+	if err != nil {
+		t.Fatalf("Dirs: %v", err)
+	}
+	yrs, ruleErrors, err := RecursiveWithErrors(ctx, fss)
+	if err != nil {
+		t.Fatalf("RecursiveWithErrors with a bad user rule: %v", err)
+	}
+	if yrs.Count() != 1 {
+		t.Errorf("compiled %d rules, want only the valid user rule", yrs.Count())
+	}
+	if len(ruleErrors) != 1 || ruleErrors[0].Path != filepath.Join(dir, "bad.yara") || ruleErrors[0].Line == 0 || ruleErrors[0].Message == "" {
+		t.Errorf("rule errors = %+v, want one for bad.yara with a line and message", ruleErrors)
+	}
+
+	fss, err = Dirs([]string{dir}, true)
+	if err != nil {
+		t.Fatalf("Dirs: %v", err)
+	}
+	fss, err = Filter(fss, []string{"*.yara"})
+	if err != nil {
+		t.Fatalf("Filter: %v", err)
+	}
+	if _, err := Recursive(ctx, fss); err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "bad.yara")) {
+		t.Errorf("strict Recursive error = %v, want one naming %s", err, filepath.Join(dir, "bad.yara"))
+	}
+
+	if _, err := Dirs([]string{filepath.Join(dir, "good.yara")}, false); err == nil {
+		t.Error("Dirs with a file succeeded, want error")
+	}
+	if _, err := Dirs([]string{filepath.Join(dir, "missing")}, false); err == nil {
+		t.Error("Dirs with a missing directory succeeded, want error")
+	}
+	if fss, err := Dirs(nil, false); err != nil || len(fss) != 0 {
+		t.Errorf("Dirs(nil) = %v, %v, want no filesystems", fss, err)
+	}
+}
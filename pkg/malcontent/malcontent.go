@@ -4,10 +4,16 @@
 package malcontent
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"io"
 	"io/fs"
+	"log/slog"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	yarax "github.com/VirusTotal/yara-x/go"
 	orderedmap "github.com/wk8/go-ordered-map/v2"
@@ -22,28 +28,208 @@ type Renderer interface {
 }
 
 type Config struct {
-	Concurrency           int
-	ExitExtraction        bool
-	ExitFirstHit          bool
-	ExitFirstMiss         bool
-	FileRiskChange        bool
-	FileRiskIncrease      bool
-	IgnoreSelf            bool
-	IgnoreTags            []string
-	IncludeDataFiles      bool
-	MinFileRisk           int
-	MinRisk               int
-	OCI                   bool
-	Output                io.Writer
-	Processes             bool
+	// AllowHashesFile lists the SHA256 hashes of known-good files, which are reported as skipped without being scanned
+	AllowHashesFile string
+	// CacheDir, if set, stores file reports keyed by content and ruleset so unchanged files are not rescanned.
+	// The cache is not used when OnMatch, ProfileRules, ReportUnusedRules or ScoreFunc are set.
+	CacheDir string
+	// CombinationRules escalate the risk of files in which all of a rule's behaviors are present
+	CombinationRules []CombinationRule
+	Concurrency      int
+	// Correlate lists the match strings found in more than one file, such as C2 domains reused by a campaign,
+	// in Report.Correlations once the scan completes
+	Correlate bool
+	// CorroborationThreshold, if greater than one, caps a file's risk at MEDIUM unless
+	// at least this many distinct behaviors matched the file.
+	CorroborationThreshold int
+	// DedupByHash scans only the first of several files with identical content, reusing its report for the others
+	DedupByHash bool
+	// DeepPE attributes the behaviors of PE (Windows) binaries to the sections, resources and overlay
+	// their matches were found in, reported in Behavior.Regions
+	DeepPE bool
+	// Deterministic walks files in order of path and renders their reports in that order once the scan is complete,
+	// with behaviors and match strings sorted, so that identical inputs give identical output at any Concurrency
+	Deterministic bool
+	// DefaultConfidence is the confidence assumed for rules without confidence metadata
+	DefaultConfidence int
+	// DiffAddedOnly limits diffs to added files and the DiffAdded behaviors of modified files
+	DiffAddedOnly bool
+	// DiffNoHashMoves stops diffs from pairing removed and added files with identical checksums as moves,
+	// leaving moves to be inferred from path similarity alone, as they always are for files without checksums
+	DiffNoHashMoves bool
+	// ExcludeExtensions skips files with these extensions (e.g. ".png") without reporting them
+	ExcludeExtensions []string
+	// ExcludeRuleIDs drops behaviors whose ID (e.g. "net/download") or rule name matches one of these
+	// path.Match globs from reports, counting them in FileReport.FilteredBehaviors. Rules are still compiled.
+	ExcludeRuleIDs []string
+	// ExitCodeOnRisk maps a risk level (e.g. RiskHigh) to the exit code reported by
+	// action.ExitCode when it is the highest level reached by a scanned file.
+	ExitCodeOnRisk map[RiskLevel]int
+	ExitExtraction bool
+	ExitFirstHit   bool
+	ExitFirstMiss  bool
+	// ExtractSyscalls infers the system calls of ELF binaries from their symbols into FileReport.Syscalls,
+	// the pledge(2) promises of OpenBSD binaries into FileReport.Pledge, and reads the file capabilities
+	// of scanned files into FileReport.Capabilities
+	ExtractSyscalls bool
+	// ExtraRulePaths are directories of user rules compiled alongside RuleFS
+	ExtraRulePaths   []string
+	FileRiskChange   bool
+	FileRiskIncrease bool
+	// FilterByTag, if set, keeps only the behaviors whose rule carries one of these tags, counting the others
+	// in FileReport.FilteredBehaviors
+	FilterByTag []string
+	// Fingerprint sets the Fingerprint of each behavior, a stable ID for tracking findings across scans
+	Fingerprint bool
+	// FollowSymlinks walks symlinked directories within scan paths and reports broken symlinks as skipped
+	FollowSymlinks bool
+	// GroupByNamespace nests behaviors under their top-level namespace (e.g. "exfil") in JSON and YAML output
+	GroupByNamespace bool
+	// HashAlgo selects the FileReport checksum: "sha256" (the default) or the faster, non-cryptographic "xxh3"
+	HashAlgo string
+	// IgnoreFile, if set, is read instead of the .malcontentignore file at the root of each scan path
+	IgnoreFile       string
+	IgnoreSelf       bool
+	IgnoreTags       []string
+	IncludeDataFiles bool
+	// IncludeExtensions, if set, only scans files with these extensions; others are not reported
+	IncludeExtensions []string
+	// IncludeRuleIDs, if set, drops behaviors whose ID or rule name matches none of these globs, like
+	// ExcludeRuleIDs, which takes precedence
+	IncludeRuleIDs []string
+	// KeepDuplicateBehaviors reports each matching rule as its own behavior. By default, the behaviors of a file
+	// that share an ID, such as several rules describing the same technique, are merged into one with the union
+	// of their match strings and the highest risk.
+	KeepDuplicateBehaviors bool
+	// LargestFirst waits for the walk of each scan path to finish, then hands its files to the Concurrency
+	// workers largest first, so that a few large files don't start last and stretch out the scan
+	LargestFirst bool
+	// ListOnly walks the scan paths with every file filter applied, reporting the path and size of each file that
+	// would be scanned without reading it or running the rules. Archives are listed rather than extracted, and
+	// nothing is rendered while walking; see action.ListFiles.
+	ListOnly bool
+	// Logger receives diagnostic messages, such as why files were skipped or user rules failed to compile,
+	// with structured attributes like "path", "reason" and "rule". It is called from scan workers concurrently,
+	// as slog handlers allow. If nil, messages are discarded.
+	Logger *slog.Logger
+	// MaxArchiveDepth limits how many levels of nested archives are extracted (0 uses the default)
+	MaxArchiveDepth int
+	// MaxBehaviorsPerFile, if positive, limits the JSON and YAML output to the riskiest behaviors of each file;
+	// the output records how many were left out. Statistics still count every behavior.
+	MaxBehaviorsPerFile int
+	// MaxExtractedBytes limits the total bytes extracted from a single archive (0 uses the default)
+	MaxExtractedBytes int64
+	// MaxExtractedFiles limits the number of files extracted from a single archive (0 uses the default)
+	MaxExtractedFiles int
+	// MaxFilesInReport, if positive, limits the JSON and YAML output to the riskiest files; the output records
+	// how many were left out. Statistics still count every file.
+	MaxFilesInReport int
+	// MaxInFlightBytes, if positive, bounds the total size of the files scan workers hold in memory at once;
+	// workers wait before reading a file that would exceed it. Larger files are scanned one at a time.
+	MaxInFlightBytes int64
+	// MaxMatchStringLen, if positive, shortens longer match strings to this many bytes followed by an ellipsis
+	MaxMatchStringLen int
+	// MaxMatchStrings, if positive, caps the distinct match strings kept per behavior; the rest are counted in TruncatedMatches
+	MaxMatchStrings int
+	// MinConfidence drops behaviors whose rule confidence metadata is below this value
+	MinConfidence int
+	// MinFileRisk leaves files less risky than this level out of the rendered results and exit code
+	MinFileRisk RiskLevel
+	// MinRisk drops behaviors less risky than this level from file reports
+	MinRisk RiskLevel
+	// Mmap memory-maps files for scanning rather than reading them into memory, where supported
+	Mmap bool
+	// ModifiedSince, if set, skips files within scan paths last modified before it, reporting nothing for them.
+	// Archives are judged by their own modification time, which their members inherit; images are not filtered.
+	ModifiedSince time.Time
+	// NoCache disables reading and writing CacheDir
+	NoCache bool
+	// NoIgnore disables .malcontentignore handling when walking scan paths
+	NoIgnore bool
+	// NormalizeEncoding transcodes text in other encodings, such as UTF-16 scripts, to UTF-8
+	// before matching. Reports still carry the size and checksum of the original file.
+	NormalizeEncoding bool
+	OCI               bool
+	// OmitMatchStrings leaves MatchStrings out of behaviors entirely, for privacy-sensitive scans where even
+	// redacted matches are unwanted; OnMatch is then called without strings
+	OmitMatchStrings bool
+	// OnMatch, if set, is called for every matching rule before behaviors are filtered or aggregated, so it also
+	// sees matches below MinRisk and those dropped by overrides.
+	// It is invoked concurrently from scan workers; implementations must be safe for
+	// concurrent use and should return quickly, as they run inline with scanning.
+	OnMatch func(ruleID, path string, strings []string)
+	// OnlyExecutables only scans files that start with ELF, Mach-O, PE or shebang magic; others are not reported
+	OnlyExecutables bool
+	Output          io.Writer
+	// OverridesFile maps rule names or behavior IDs to replacement risk levels, or "drop"
+	OverridesFile string
+	// PerFileTimeout, if positive, bounds the time spent scanning each file; files taking longer are reported
+	// with Skipped set to "scan timeout". yara-x enforces it in whole seconds. Zero, the default, means no timeout.
+	PerFileTimeout time.Duration
+	Processes      bool
+	// ProfileRules records how long each rule takes to evaluate, reported in ScanStats.RulesProfile
+	ProfileRules bool
+	// Progress, if set, is called as each file completes with the number of files done, the expected total and
+	// the file's path. total is counted by walking the scan paths beforehand, so it is approximate: it grows as
+	// archives are expanded and images extracted. Calls come from scan workers but are never concurrent.
+	Progress              func(done, total int, currentPath string)
 	QuantityIncreasesRisk bool
-	Renderer              Renderer
-	RuleFS                []fs.FS
-	Rules                 *yarax.Rules
-	Scan                  bool
-	ScanPaths             []string
-	Stats                 bool
-	TrimPrefixes          []string
+	// Quiet only renders files with behaviors, without announcing each scan path
+	Quiet bool
+	// RedactMatches replaces the match strings of rules with "sensitive = true" metadata:
+	// "mask" with a fixed mask, "hash" with a SHA256 prefix; "off" or empty leaves them as found
+	RedactMatches string
+	// RelativeTo, if set, is the directory that scanned files' report paths are made relative to; archive
+	// members stay relative to their archive, and FullPath and ArchiveRoot keep their absolute paths
+	RelativeTo string
+	Renderer   Renderer
+	// ReportUnusedRules lists the compiled rules that matched no scanned file in ScanStats.UnusedRules, to find
+	// stale rules; like ProfileRules, it runs every file through the scanner rather than using the scan cache
+	ReportUnusedRules bool
+	// RuleCacheFile, if set, is where the rules compiled from RuleFS are kept between runs, so that later
+	// processes load them instead of compiling them again; it is rewritten whenever the rules or versions change
+	RuleCacheFile string
+	RuleFS        []fs.FS
+	// RuleFilter, if set, limits the compiled rules to files whose paths match one of these globs
+	RuleFilter []string
+	Rules      *yarax.Rules
+	Scan       bool
+	// ScanFS, if set, is scanned instead of the local filesystem, with ScanPaths naming paths within it; "" and "/"
+	// name its root. Its files are read into memory and reported by those paths. Archives are scanned without
+	// being extracted, and images are unsupported.
+	ScanFS    fs.FS
+	ScanPaths []string
+	// ScoreFunc, if set, replaces the built-in aggregation of a file's behaviors into its RiskScore and
+	// RiskLevel. It returns a score from 0 (harmless) to 4 (critical), and the name of the level to report as
+	// accepted by ParseRiskLevel, or "" for the level of the score; other names fail the file with an error.
+	// It is called concurrently from scan workers, after overrides apply.
+	// The default takes the highest behavior RiskScore, caps it at MEDIUM when fewer than
+	// CorroborationThreshold distinct behavior IDs matched, raises it to the level of any satisfied
+	// CombinationRules, and with QuantityIncreasesRisk, raises HIGH to CRITICAL when the file has many
+	// HIGH matches for its size. Setting ScoreFunc disables the scan cache.
+	ScoreFunc func(behaviors []*Behavior) (int, string)
+	Stats     bool
+	// Stdin, if set, is read instead of os.Stdin when "-" is one of the ScanPaths
+	Stdin io.Reader
+	// StreamTarMemory, if positive, scans the regular files of tar archives of up to this many bytes in memory as
+	// the archive is read, rather than extracting them to disk first; larger files and nested archives are still
+	// extracted to disk
+	StreamTarMemory int64
+	// StrictRules fails rule compilation if any ExtraRulePaths rule file has errors, rather than skipping it
+	StrictRules bool
+	// TemplateFile is the path of the text/template used by the template renderer, if any
+	TemplateFile string
+	TrimPrefixes []string
+}
+
+// CombinationRule escalates a file's risk when behaviors that are more suspicious together than alone co-occur.
+type CombinationRule struct {
+	// Name identifies the rule in FileReport.Meta; it defaults to the behaviors joined with " + "
+	Name string `json:",omitempty" yaml:",omitempty"`
+	// Behaviors are the Behavior.IDs, or path.Match globs over them such as "exfil/*", that must all be present
+	Behaviors []string
+	// RiskLevel is the level the file is raised to, such as RiskHigh; files already at or above it are unchanged
+	RiskLevel RiskLevel
 }
 
 type Behavior struct {
@@ -51,9 +237,10 @@ type Behavior struct {
 	// MatchStrings are all strings found relating to this behavior
 	MatchStrings []string `json:",omitempty" yaml:",omitempty"`
 	RiskScore    int
-	RiskLevel    string `json:",omitempty" yaml:",omitempty"`
+	RiskLevel    RiskLevel
 
-	RuleURL      string `json:",omitempty" yaml:",omitempty"`
+	RuleURL string `json:",omitempty" yaml:",omitempty"`
+	// ReferenceURL holds the "reference" or "ref" metadata URLs of the rule, separated by spaces
 	ReferenceURL string `json:",omitempty" yaml:",omitempty"`
 
 	RuleAuthor    string `json:",omitempty" yaml:",omitempty"`
@@ -68,37 +255,68 @@ type Behavior struct {
 	// ID is the original map key from map[string]*Behavior
 	ID string `json:",omitempty" yaml:",omitempty"`
 
+	// Fingerprint identifies this finding across scans when Config.Fingerprint is set; see report.Fingerprint
+	Fingerprint string `json:",omitempty" yaml:",omitempty"`
+
+	// Regions names the parts of a PE file the behavior matched in, such as "section/.text",
+	// "resource/RCDATA/101" or "overlay", when Config.DeepPE is set
+	Regions []string `json:",omitempty" yaml:",omitempty"`
+
 	// Name is the value of m.Rule
 	RuleName string `json:",omitempty" yaml:",omitempty"`
 
+	// RuleSource is the name of the ruleset the rule was compiled from, such as "upstream" or "internal",
+	// for rules compiled from a compile.NamedFS
+	RuleSource string `json:",omitempty" yaml:",omitempty"`
+
 	// The name of the rule(s) this behavior overrides
 	Override []string `json:",omitempty" yaml:",omitempty"`
+
+	// Tags are the YARA tags of the matching rule, such as "high" or "persistence"
+	Tags []string `json:",omitempty" yaml:",omitempty"`
+
+	// TruncatedMatches is the number of distinct match strings omitted beyond Config.MaxMatchStrings
+	TruncatedMatches int `json:",omitempty" yaml:",omitempty"`
+}
+
+// ReferenceURLs returns each of the URLs in ReferenceURL.
+func (b *Behavior) ReferenceURLs() []string {
+	return strings.Fields(b.ReferenceURL)
 }
 
 type FileReport struct {
 	Path   string
 	SHA256 string
-	Size   int64
+	// Hash and HashAlgo hold the checksum instead of SHA256 when another Config.HashAlgo is used
+	Hash     string `json:",omitempty" yaml:",omitempty"`
+	HashAlgo string `json:",omitempty" yaml:",omitempty"`
+	Size     int64
+	// DuplicateOf is the path of the identical file whose scan this report reuses, when Config.DedupByHash is set
+	DuplicateOf string `json:",omitempty" yaml:",omitempty"`
+	// SymlinkTarget is the resolved path of a file reached through a symlink, when Config.FollowSymlinks is set
+	SymlinkTarget string `json:",omitempty" yaml:",omitempty"`
 	// compiler -> x
-	Skipped           string            `json:",omitempty" yaml:",omitempty"`
-	Meta              map[string]string `json:",omitempty" yaml:",omitempty"`
-	Syscalls          []string          `json:",omitempty" yaml:",omitempty"`
-	Pledge            []string          `json:",omitempty" yaml:",omitempty"`
-	Capabilities      []string          `json:",omitempty" yaml:",omitempty"`
-	Behaviors         []*Behavior       `json:",omitempty" yaml:",omitempty"`
-	FilteredBehaviors int               `json:",omitempty" yaml:",omitempty"`
+	Skipped      string            `json:",omitempty" yaml:",omitempty"`
+	Meta         map[string]string `json:",omitempty" yaml:",omitempty"`
+	Syscalls     []string          `json:",omitempty" yaml:",omitempty"`
+	Pledge       []string          `json:",omitempty" yaml:",omitempty"`
+	Capabilities []string          `json:",omitempty" yaml:",omitempty"`
+	Behaviors    []*Behavior       `json:",omitempty" yaml:",omitempty"`
+	// BehaviorGroups holds the behaviors by top-level namespace instead of Behaviors when Config.GroupByNamespace is set
+	BehaviorGroups    map[string][]*Behavior `json:",omitempty" yaml:",omitempty"`
+	FilteredBehaviors int                    `json:",omitempty" yaml:",omitempty"`
 
 	// The absolute path we think this moved fron
 	PreviousPath string `json:",omitempty" yaml:",omitempty"`
 	// The relative path we think this moved from.
 	PreviousRelPath string `json:",omitempty" yaml:",omitempty"`
 	// The levenshtein distance between the previous path and the current path
-	PreviousRelPathScore float64 `json:",omitempty" yaml:",omitempty"`
-	PreviousRiskScore    int     `json:",omitempty" yaml:",omitempty"`
-	PreviousRiskLevel    string  `json:",omitempty" yaml:",omitempty"`
+	PreviousRelPathScore float64   `json:",omitempty" yaml:",omitempty"`
+	PreviousRiskScore    int       `json:",omitempty" yaml:",omitempty"`
+	PreviousRiskLevel    RiskLevel `json:",omitempty" yaml:",omitempty"`
 
 	RiskScore int
-	RiskLevel string `json:",omitempty" yaml:",omitempty"`
+	RiskLevel RiskLevel
 
 	IsMalcontent bool `json:",omitempty" yaml:",omitempty"`
 
@@ -110,6 +328,20 @@ type FileReport struct {
 	FullPath    string `json:",omitempty" yaml:",omitempty"`
 }
 
+// Checksum returns the file's content hash, prefixed by its algorithm unless it is a SHA256.
+// Reports whose checksums use different algorithms never compare equal.
+func (fr *FileReport) Checksum() string {
+	if fr.SHA256 != "" || fr.Hash == "" {
+		return fr.SHA256
+	}
+	return fr.HashAlgo + ":" + fr.Hash
+}
+
+// Risk returns the file's RiskScore as a RiskLevel.
+func (fr *FileReport) Risk() RiskLevel {
+	return RiskLevel(fr.RiskScore)
+}
+
 type DiffReport struct {
 	Added    *orderedmap.OrderedMap[string, *FileReport] `json:",omitempty" yaml:",omitempty"`
 	Removed  *orderedmap.OrderedMap[string, *FileReport] `json:",omitempty" yaml:",omitempty"`
@@ -120,6 +352,200 @@ type Report struct {
 	Files  sync.Map
 	Diff   *DiffReport
 	Filter string
+	// Interrupted is set when the scan was canceled before completing; Files then holds the files completed so far
+	Interrupted bool
+	// Stats summarizes Files as returned by a scan; it is not updated by Merge
+	Stats *ScanStats
+	// Correlations lists the match strings shared by several files when Config.Correlate is set;
+	// like Stats, it is not updated by Merge
+	Correlations []Correlation
+	// Errors lists the files that could not be read, extracted or scanned, sorted by path. The scan carries on
+	// without them, reporting them as skipped, so callers can decide whether a partial scan is acceptable.
+	Errors []ScanError
+}
+
+// Scan phases in which a ScanError occurred.
+const (
+	ScanPhaseExtract = "extract"
+	ScanPhaseRead    = "read"
+	ScanPhaseScan    = "scan"
+)
+
+// ScanError describes a file that could not be read, extracted or scanned.
+type ScanError struct {
+	Error string
+	Path  string
+	// Phase is the step that failed: ScanPhaseRead, ScanPhaseExtract or ScanPhaseScan
+	Phase string
+}
+
+// Correlation is a match string found in more than one scanned file.
+type Correlation struct {
+	MatchString string
+	// Paths are the report paths of the files the string was found in, sorted
+	Paths []string
+	// RuleIDs are the IDs of the behaviors that matched the string in any of those files, sorted
+	RuleIDs []string
+}
+
+// ScanStats summarizes the files in a scan report.
+type ScanStats struct {
+	// BehaviorsByRisk counts the behaviors of scanned files by RiskLevel
+	BehaviorsByRisk map[RiskLevel]int
+	// Bytes is the total size of the scanned files
+	Bytes int64
+	// Duration is how long the scan took
+	Duration time.Duration
+	// Errors counts the files that could not be read, extracted or scanned, as listed in Report.Errors
+	Errors int
+	// FilesByRisk counts scanned files by RiskLevel
+	FilesByRisk map[RiskLevel]int
+	// FilesScanned counts every file in the report, including skipped files
+	FilesScanned int
+	FilesSkipped int
+	// RuleErrors lists the user rule files that were skipped because they failed to compile
+	RuleErrors []RuleCompileError
+	// Ruleset describes the compiled rules used by the scan, when Config.Stats is set
+	Ruleset *RulesetSummary
+	// RulesMatched is the number of distinct rules matched across all scanned files
+	RulesMatched int
+	// RulesProfile is populated when Config.ProfileRules is set, slowest rules first
+	RulesProfile   []RuleProfile
+	TotalBehaviors int
+	// UnusedRules is populated when Config.ReportUnusedRules is set, sorted by ID
+	UnusedRules []UnusedRule
+}
+
+// RuleCompileError describes a rule file that failed to compile.
+type RuleCompileError struct {
+	Column  int
+	Line    int
+	Message string
+	Path    string
+}
+
+// FileList lists the files a Config.ListOnly scan would have scanned.
+type FileList struct {
+	// Files are sorted by path
+	Files []ListedFile
+	// TotalBytes is the combined size of Files
+	TotalBytes int64
+}
+
+// ListedFile is a file that would be scanned, and its size in bytes.
+type ListedFile struct {
+	Path string
+	Size int64
+}
+
+// RulesetSummary identifies a compiled ruleset.
+type RulesetSummary struct {
+	// Hash is the SHA256 of the serialized rules, identical across runs for identical rule sources
+	Hash string
+	// Namespaces are the sorted rule files the rules were compiled from
+	Namespaces []string
+	Rules      int
+}
+
+// UnusedRule identifies a compiled rule that matched none of the scanned files.
+type UnusedRule struct {
+	// ID is the behavior ID the rule would be reported under
+	ID   string
+	Rule string
+}
+
+// RuleProfile records the cost of evaluating a rule across a scan.
+type RuleProfile struct {
+	// FilesMatched is the number of files the rule matched
+	FilesMatched int
+	// ID is the behavior ID reported for the rule
+	ID string
+	// Invocations is the number of files the rule spent measurable time evaluating
+	Invocations int
+	Rule        string
+	// Time is the cumulative time spent matching the rule's patterns and evaluating its condition
+	Time time.Duration
+}
+
+// EachBehavior calls fn for each behavior of each scanned file in r, in no particular order,
+// with the path the file is reported under. Skipped files are left out. If fn returns false, EachBehavior stops.
+func (r *Report) EachBehavior(fn func(path string, fr *FileReport, b *Behavior) bool) {
+	r.Files.Range(func(key, value any) bool {
+		path, ok := key.(string)
+		if !ok {
+			return true
+		}
+		fr, ok := value.(*FileReport)
+		if !ok || fr == nil || fr.Skipped != "" {
+			return true
+		}
+		for _, b := range fr.Behaviors {
+			if !fn(path, fr, b) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// FileCount returns the number of file reports in r, including those of skipped files.
+func (r *Report) FileCount() int {
+	n := 0
+	r.Files.Range(func(_, value any) bool {
+		if fr, ok := value.(*FileReport); ok && fr != nil {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Merge adds the file reports and errors from other into r, e.g. to combine scans of separate shards.
+// A path present in both reports must have the same checksum when both are known; if not, Merge returns
+// an error and leaves r unchanged.
+func (r *Report) Merge(other *Report) error {
+	if other == nil {
+		return nil
+	}
+	if r.Diff != nil || other.Diff != nil {
+		return fmt.Errorf("diff reports cannot be merged")
+	}
+
+	// Collect the reports to store before touching r, so that a conflict cannot leave it half-merged
+	var err error
+	merged := map[any]*FileReport{}
+	other.Files.Range(func(key, value any) bool {
+		fr, ok := value.(*FileReport)
+		if key == nil || !ok || fr == nil {
+			return true
+		}
+
+		existing, loaded := r.Files.Load(key)
+		efr, ok := existing.(*FileReport)
+		switch {
+		case !loaded || !ok || efr == nil || efr.Checksum() == "":
+			merged[key] = fr
+		case fr.Checksum() != "" && efr.Checksum() != fr.Checksum():
+			err = fmt.Errorf("conflicting reports for %v: checksum %s != %s", key, efr.Checksum(), fr.Checksum())
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for key, fr := range merged {
+		r.Files.Store(key, fr)
+	}
+	if r.Filter == "" {
+		r.Filter = other.Filter
+	}
+	r.Errors = append(r.Errors, other.Errors...)
+	slices.SortStableFunc(r.Errors, func(a, b ScanError) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return nil
 }
 
 type IntMetric struct {
@@ -0,0 +1,99 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package malcontent
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReportMerge(t *testing.T) {
+	t.Parallel()
+
+	a := &Report{}
+	a.Files.Store("a", &FileReport{Path: "a", SHA256: "aaa"})
+	a.Files.Store("shared", &FileReport{Path: "shared"})
+
+	a.Errors = []ScanError{{Path: "z", Phase: ScanPhaseRead}}
+
+	b := &Report{Filter: "high", Errors: []ScanError{{Path: "c", Phase: ScanPhaseExtract}}}
+	b.Files.Store("b", &FileReport{Path: "b", SHA256: "bbb"})
+	b.Files.Store("shared", &FileReport{Path: "shared", SHA256: "sss"})
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+
+	for path, want := range map[string]string{"a": "aaa", "b": "bbb", "shared": "sss"} {
+		v, ok := a.Files.Load(path)
+		if !ok {
+			t.Fatalf("%s missing from merged report", path)
+		}
+		if got := v.(*FileReport).SHA256; got != want {
+			t.Errorf("%s SHA256 = %q, want %q", path, got, want)
+		}
+	}
+	if a.Filter != "high" {
+		t.Errorf("Filter = %q, want %q", a.Filter, "high")
+	}
+	if want := []ScanError{{Path: "c", Phase: ScanPhaseExtract}, {Path: "z", Phase: ScanPhaseRead}}; !slices.Equal(a.Errors, want) {
+		t.Errorf("Errors = %v, want %v", a.Errors, want)
+	}
+
+	conflict := &Report{Errors: []ScanError{{Path: "d", Phase: ScanPhaseRead}}}
+	conflict.Files.Store("a", &FileReport{Path: "a", SHA256: "zzz"})
+	conflict.Files.Store("d", &FileReport{Path: "d", SHA256: "ddd"})
+	if err := a.Merge(conflict); err == nil || !strings.Contains(err.Error(), "conflicting reports") {
+		t.Errorf("Merge of conflicting report = %v, want conflict error", err)
+	}
+	// A failed merge leaves the report as it was
+	if _, ok := a.Files.Load("d"); ok || a.FileCount() != 3 || len(a.Errors) != 2 {
+		t.Errorf("Merge of conflicting report changed the report: %d files, errors %v", a.FileCount(), a.Errors)
+	}
+	if v, _ := a.Files.Load("a"); v.(*FileReport).SHA256 != "aaa" {
+		t.Errorf("a SHA256 = %q after failed merge, want %q", v.(*FileReport).SHA256, "aaa")
+	}
+
+	if err := a.Merge(&Report{Diff: &DiffReport{}}); err == nil {
+		t.Error("Merge of diff report succeeded, want error")
+	}
+}
+
+func TestReportEachBehavior(t *testing.T) {
+	t.Parallel()
+
+	r := &Report{}
+	r.Files.Store("a", &FileReport{Path: "a", Behaviors: []*Behavior{{ID: "net/a"}, {ID: "exec/a"}}})
+	r.Files.Store("b", &FileReport{Path: "b", Behaviors: []*Behavior{{ID: "net/b"}}})
+	r.Files.Store("skipped", &FileReport{Path: "skipped", Skipped: "zero-sized file", Behaviors: []*Behavior{{ID: "net/s"}}})
+	r.Files.Store("empty", &FileReport{Path: "empty"})
+
+	if got := r.FileCount(); got != 4 {
+		t.Errorf("FileCount() = %d, want 4", got)
+	}
+
+	var got []string
+	r.EachBehavior(func(path string, fr *FileReport, b *Behavior) bool {
+		if fr.Path != path {
+			t.Errorf("report for %q has path %q", path, fr.Path)
+		}
+		got = append(got, path+":"+b.ID)
+		return true
+	})
+	slices.Sort(got)
+	want := []string{"a:exec/a", "a:net/a", "b:net/b"}
+	if !slices.Equal(got, want) {
+		t.Errorf("EachBehavior visited %v, want %v", got, want)
+	}
+
+	n := 0
+	r.EachBehavior(func(string, *FileReport, *Behavior) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Errorf("EachBehavior called fn %d times after it returned false, want 1", n)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package malcontent
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RiskLevel is the risk of a behavior or file, from its RiskScore.
+// It marshals as its uppercase name, such as "HIGH", in reports and configuration.
+type RiskLevel int
+
+const (
+	// RiskIgnore is below every reported level; a minimum of RiskIgnore keeps everything.
+	RiskIgnore RiskLevel = iota - 1
+	// RiskNone is harmless: common to all executables, no system impact.
+	RiskNone
+	// RiskLow is low impact, common to good and bad executables.
+	RiskLow
+	// RiskMedium is notable: may have impact, but common.
+	RiskMedium
+	// RiskHigh is suspicious: uncommon, but could be legit.
+	RiskHigh
+	// RiskCritical is certainly malware.
+	RiskCritical
+)
+
+var riskLevelNames = map[RiskLevel]string{
+	RiskIgnore:   "IGNORE",
+	RiskNone:     "NONE",
+	RiskLow:      "LOW",
+	RiskMedium:   "MEDIUM",
+	RiskHigh:     "HIGH",
+	RiskCritical: "CRITICAL",
+}
+
+// ParseRiskLevel returns the RiskLevel named by s, ignoring case and surrounding space.
+// Besides the names returned by String, it accepts "med", "crit" and the scores "-1" to "4".
+func ParseRiskLevel(s string) (RiskLevel, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	switch name {
+	case "MED":
+		return RiskMedium, nil
+	case "CRIT":
+		return RiskCritical, nil
+	}
+	for r, n := range riskLevelNames {
+		if n == name {
+			return r, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil {
+		if r := RiskLevel(n); r.valid() {
+			return r, nil
+		}
+	}
+	return RiskIgnore, fmt.Errorf("unknown risk level: %q", s)
+}
+
+func (r RiskLevel) valid() bool {
+	return r >= RiskIgnore && r <= RiskCritical
+}
+
+// String returns the uppercase name of r, such as "HIGH".
+func (r RiskLevel) String() string {
+	if n, ok := riskLevelNames[r]; ok {
+		return n
+	}
+	return fmt.Sprintf("RiskLevel(%d)", int(r))
+}
+
+// Compare returns -1, 0 or +1 depending on whether r is less risky than, as risky as, or riskier than o.
+func (r RiskLevel) Compare(o RiskLevel) int {
+	return cmp.Compare(r, o)
+}
+
+// AtLeast reports whether r is at least as risky as minimum.
+func (r RiskLevel) AtLeast(minimum RiskLevel) bool {
+	return r.Compare(minimum) >= 0
+}
+
+// MarshalText encodes r as its name.
+func (r RiskLevel) MarshalText() ([]byte, error) {
+	if !r.valid() {
+		return nil, fmt.Errorf("invalid risk level: %d", int(r))
+	}
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText decodes any risk level accepted by ParseRiskLevel.
+func (r *RiskLevel) UnmarshalText(text []byte) error {
+	level, err := ParseRiskLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*r = level
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package malcontent
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseRiskLevel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in      string
+		want    RiskLevel
+		wantErr bool
+	}{
+		{in: "none", want: RiskNone},
+		{in: "Low", want: RiskLow},
+		{in: "MEDIUM", want: RiskMedium},
+		{in: "med", want: RiskMedium},
+		{in: " high ", want: RiskHigh},
+		{in: "Critical", want: RiskCritical},
+		{in: "CRIT", want: RiskCritical},
+		{in: "3", want: RiskHigh},
+		{in: "-1", want: RiskIgnore},
+		{in: "ignore", want: RiskIgnore},
+		{in: "suspected", want: RiskIgnore, wantErr: true},
+		{in: "5", want: RiskIgnore, wantErr: true},
+		{in: "", want: RiskIgnore, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRiskLevel(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseRiskLevel(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	for r := RiskIgnore; r <= RiskCritical; r++ {
+		if got, err := ParseRiskLevel(r.String()); err != nil || got != r {
+			t.Errorf("ParseRiskLevel(%q) = %v, %v; want %v", r.String(), got, err, r)
+		}
+	}
+}
+
+func TestRiskLevelCompare(t *testing.T) {
+	t.Parallel()
+
+	if !RiskHigh.AtLeast(RiskMedium) || !RiskHigh.AtLeast(RiskHigh) || RiskLow.AtLeast(RiskHigh) {
+		t.Error("AtLeast does not order risk levels")
+	}
+	if RiskCritical.Compare(RiskLow) != 1 || RiskLow.Compare(RiskCritical) != -1 || RiskMedium.Compare(RiskLevel(2)) != 0 {
+		t.Error("Compare does not order risk levels")
+	}
+	if !RiskNone.AtLeast(RiskIgnore) {
+		t.Error("RiskNone is below RiskIgnore")
+	}
+	if got := RiskLevel(7).String(); got != "RiskLevel(7)" {
+		t.Errorf("String() of an unknown level = %q", got)
+	}
+}
+
+func TestRiskLevelJSON(t *testing.T) {
+	t.Parallel()
+
+	type doc struct {
+		Min RiskLevel
+	}
+	b, err := json.Marshal(doc{Min: RiskCritical})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if got, want := string(b), `{"Min":"CRITICAL"}`; got != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+
+	var d doc
+	if err := json.Unmarshal([]byte(`{"Min":"high"}`), &d); err != nil || d.Min != RiskHigh {
+		t.Errorf("Unmarshal = %v, %v; want HIGH", d.Min, err)
+	}
+	if err := json.Unmarshal([]byte(`{"Min":"severe"}`), &d); err == nil {
+		t.Error("Unmarshal of an unknown level succeeded")
+	}
+	if _, err := json.Marshal(doc{Min: RiskLevel(9)}); err == nil {
+		t.Error("Marshal of an invalid level succeeded")
+	}
+}
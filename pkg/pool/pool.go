@@ -3,6 +3,7 @@ package pool
 import (
 	"math"
 	"sync"
+	"time"
 
 	yarax "github.com/VirusTotal/yara-x/go"
 )
@@ -77,16 +78,29 @@ type ScannerPool struct {
 
 // NewScannerPool creates a pool containing the specified number of yara-x scanners.
 func NewScannerPool(yrs *yarax.Rules, count int) *ScannerPool {
+	return NewScannerPoolWithTimeout(yrs, count, 0)
+}
+
+// NewScannerPoolWithTimeout creates a pool of yara-x scanners whose scans fail with yarax.ErrTimeout
+// once timeout has elapsed. yara-x rounds timeouts up to whole seconds; zero means no timeout.
+func NewScannerPoolWithTimeout(yrs *yarax.Rules, count int, timeout time.Duration) *ScannerPool {
 	sp := &ScannerPool{}
 
+	newScanner := func() *yarax.Scanner {
+		s := yarax.NewScanner(yrs)
+		if timeout > 0 {
+			s.SetTimeout(timeout)
+		}
+		return s
+	}
 	sp.pool = sync.Pool{
 		New: func() any {
-			return yarax.NewScanner(yrs)
+			return newScanner()
 		},
 	}
 
 	for range count {
-		sp.pool.Put(yarax.NewScanner(yrs))
+		sp.pool.Put(newScanner())
 	}
 	return sp
 }
@@ -22,24 +22,30 @@ import (
 
 // Supported archive extensions.
 var ArchiveMap = map[string]bool{
-	".apk":    true,
-	".bz2":    true,
-	".bzip2":  true,
-	".deb":    true,
-	".gem":    true,
-	".gz":     true,
-	".jar":    true,
-	".rpm":    true,
-	".tar":    true,
-	".tar.gz": true,
-	".tar.xz": true,
-	".tgz":    true,
-	".upx":    true,
-	".whl":    true,
-	".xz":     true,
-	".zst":    true,
-	".zstd":   true,
-	".zip":    true,
+	".apk":      true,
+	".bz2":      true,
+	".bzip2":    true,
+	".deb":      true,
+	".gem":      true,
+	".gz":       true,
+	".jar":      true,
+	".rpm":      true,
+	".sqfs":     true,
+	".squashfs": true,
+	".tar":      true,
+	".tar.gz":   true,
+	".tar.xz":   true,
+	".tar.zst":  true,
+	".tar.zstd": true,
+	".tgz":      true,
+	".txz":      true,
+	".tzst":     true,
+	".upx":      true,
+	".whl":      true,
+	".xz":       true,
+	".zst":      true,
+	".zstd":     true,
+	".zip":      true,
 }
 
 // file extension to MIME type, if it's a good scanning target.
@@ -228,8 +234,6 @@ func makeFileType(path string, ext string, mime string) *FileType {
 }
 
 // File detects what kind of program this file might be.
-//
-//nolint:cyclop // ignore complexity of 38
 func File(path string) (*FileType, error) {
 	// Follow symlinks and return cleanly if the target does not exist
 	_, err := filepath.EvalSymlinks(path)
@@ -269,34 +273,48 @@ func File(path string) (*FileType, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read: %w", err)
 	}
-	hdr := buf[:bs]
+
+	return Detect(path, buf[:bs]), nil
+}
+
+// Detect determines what kind of program the content starting with hdr might be;
+// path is only used for its name. This allows content that is not on disk to be classified.
+//
+//nolint:cyclop // ignore complexity of 38
+func Detect(path string, hdr []byte) *FileType {
+	if len(hdr) == 0 {
+		return nil
+	}
+	if len(hdr) > headerSize {
+		hdr = hdr[:headerSize]
+	}
 
 	// first strategy: mimetype
 	mimetype.SetLimit(uint32(headerSize))
 	mtype := mimetype.Detect(hdr)
 	if ft := makeFileType(path, mtype.Extension(), mtype.String()); ft != nil {
-		return ft, nil
+		return ft
 	}
 
 	// second strategy: path (extension, mostly)
 	if mtype := Path(path); mtype != nil {
-		return mtype, nil
+		return mtype
 	}
 
 	// final strategy: DIY matching where mimetype is too strict.
 	if isUPX, err := IsValidUPX(hdr, path); err == nil && isUPX {
-		return Path(".upx"), nil
+		return Path(".upx")
 	}
 
 	switch {
-	case hdr[0] == '\x7f' && (hdr[1] == 'E' && hdr[2] == 'L' && hdr[3] == 'F'):
-		return Path(".elf"), nil
+	case bytes.HasPrefix(hdr, []byte("\x7fELF")):
+		return Path(".elf")
 	case bytes.Contains(hdr, []byte("<?php")):
-		return Path(".php"), nil
+		return Path(".php")
 	case bytes.HasPrefix(hdr, []byte("import ")):
-		return Path(".py"), nil
+		return Path(".py")
 	case bytes.Contains(hdr, []byte(" = require(")):
-		return Path(".js"), nil
+		return Path(".js")
 	case bytes.HasPrefix(hdr, []byte("#!/bin/ash")) ||
 		bytes.HasPrefix(hdr, []byte("#!/bin/bash")) ||
 		bytes.HasPrefix(hdr, []byte("#!/bin/fish")) ||
@@ -309,19 +327,46 @@ func File(path string) (*FileType, error) {
 		bytes.Contains(hdr, []byte("; then")) ||
 		bytes.Contains(hdr, []byte("export ")) ||
 		strings.HasSuffix(path, "profile"):
-		return Path(".sh"), nil
+		return Path(".sh")
 	case bytes.HasPrefix(hdr, []byte("#!")):
-		return Path(".script"), nil
+		return Path(".script")
 	case bytes.Contains(hdr, []byte("#include <")):
-		return Path(".c"), nil
+		return Path(".c")
 	case bytes.Contains(hdr, []byte("BEAMAtU8")):
-		return Path(".beam"), nil
-	case hdr[0] == '\x1f' && hdr[1] == '\x8b':
-		return Path(".gzip"), nil
-	case hdr[0] == '\x78' && hdr[1] == '\x5E':
-		return Path(".Z"), nil
+		return Path(".beam")
+	case bytes.HasPrefix(hdr, []byte("\x1f\x8b")):
+		return Path(".gzip")
+	case bytes.HasPrefix(hdr, []byte("\x78\x5E")):
+		return Path(".Z")
 	}
-	return nil, nil
+	return nil
+}
+
+// executableMagic are the headers of native executables and interpreted scripts.
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	// Mach-O, 32 and 64-bit in either byte order, and universal binaries
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xce, 0xfa, 0xed, 0xfe},
+	{0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+	// PE (DOS header)
+	[]byte("MZ"),
+	[]byte("#!"),
+}
+
+// ExecutableMagicSize is the number of header bytes needed by IsExecutable.
+const ExecutableMagicSize = 4
+
+// IsExecutable reports whether content starting with hdr is an ELF, Mach-O or PE executable, or a script with a shebang.
+func IsExecutable(hdr []byte) bool {
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(hdr, magic) {
+			return true
+		}
+	}
+	return false
 }
 
 // Path returns a filetype based strictly on file path.
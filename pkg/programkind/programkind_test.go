@@ -60,3 +60,31 @@ func TestPath(t *testing.T) {
 		})
 	}
 }
+
+func TestIsExecutable(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		hdr  string
+		want bool
+	}{
+		{"elf", "\x7fELF\x02\x01", true},
+		{"mach-o 64-bit", "\xcf\xfa\xed\xfe", true},
+		{"mach-o big endian", "\xfe\xed\xfa\xce", true},
+		{"universal", "\xca\xfe\xba\xbe", true},
+		{"pe", "MZ\x90\x00", true},
+		{"shebang", "#!/usr/bin/env python3\n", true},
+		{"png", "\x89PNG\r\n\x1a\n", false},
+		{"text", "hello world", false},
+		{"short", "M", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := IsExecutable([]byte(tt.hdr)); got != tt.want {
+				t.Errorf("IsExecutable(%q) = %v, want %v", tt.hdr, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,8 +18,8 @@ import (
 type diffData struct {
 	destPath     string
 	format       string
-	minFileRisk  int
-	minRisk      int
+	minFileRisk  malcontent.RiskLevel
+	minRisk      malcontent.RiskLevel
 	outputPath   string
 	riskChange   bool
 	riskIncrease bool
@@ -180,8 +180,8 @@ func diffRefresh(ctx context.Context, rc Config) ([]TestData, error) {
 			return nil, fmt.Errorf("create renderer for %s: %w", output, err)
 		}
 
-		minFileRisk := 1
-		minRisk := 1
+		minFileRisk := malcontent.RiskLow
+		minRisk := malcontent.RiskLow
 
 		if td.minFileRisk != 0 {
 			minFileRisk = td.minFileRisk
@@ -196,10 +196,14 @@ func executeRefresh(ctx context.Context, c Config, testData []TestData, logger *
 				var err error
 				var res *malcontent.Report
 
-				if len(data.Config.ScanPaths) == 2 {
-					res, err = action.Diff(refreshCtx, *data.Config, logger)
+				cfg := *data.Config
+				if logger != nil {
+					cfg.Logger = logger.Base()
+				}
+				if len(cfg.ScanPaths) == 2 {
+					res, err = action.Diff(refreshCtx, cfg, logger)
 				} else {
-					res, err = action.Scan(refreshCtx, *data.Config)
+					res, err = action.Scan(refreshCtx, cfg)
 				}
 
 				if err != nil {
@@ -0,0 +1,129 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// By-rule renderer: lists every file that matched each behavior
+//
+// Example:
+//
+//	{
+//	    "Rules": {
+//	        "exfil/oob": {
+//	            "Description": "out-of-band exfiltration",
+//	            "RiskLevel": "HIGH",
+//	            "Files": [
+//	                {"Path": "/tmp/a", "MatchStrings": ["interact.sh"]}
+//	            ]
+//	        }
+//	    }
+//	}
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// RuleReport stores the files that matched each behavior ID.
+type RuleReport struct {
+	Rules map[string]*RuleGroup `json:",omitempty" yaml:",omitempty"`
+	// SchemaVersion is the JSONSchemaVersion of the renderer's output
+	SchemaVersion string `json:",omitempty" yaml:",omitempty"`
+}
+
+// RuleGroup describes a behavior once, along with every file that matched it.
+type RuleGroup struct {
+	Description string `json:",omitempty" yaml:",omitempty"`
+	Files       []RuleFile
+	RiskLevel   malcontent.RiskLevel
+	RiskScore   int
+	RuleName    string   `json:",omitempty" yaml:",omitempty"`
+	RuleURL     string   `json:",omitempty" yaml:",omitempty"`
+	Tags        []string `json:",omitempty" yaml:",omitempty"`
+}
+
+// RuleFile is a file that matched a rule, and the strings it matched with.
+type RuleFile struct {
+	MatchStrings []string `json:",omitempty" yaml:",omitempty"`
+	Path         string
+}
+
+type ByRule struct {
+	w io.Writer
+}
+
+func NewByRule(w io.Writer) ByRule {
+	return ByRule{w: w}
+}
+
+func (r ByRule) Name() string { return "ByRule" }
+
+func (r ByRule) Scanning(_ context.Context, _ string) {}
+
+func (r ByRule) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+func (r ByRule) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the ByRule renderer")
+	}
+
+	rr := RuleReport{
+		Rules:         groupByRule(ctx, rep),
+		SchemaVersion: JSONSchemaVersion,
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	j, err := json.MarshalIndent(rr, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", j)
+	return err
+}
+
+// groupByRule inverts the per-file behaviors of rep into groups keyed by behavior ID,
+// with each group's files sorted by path.
+func groupByRule(ctx context.Context, rep *malcontent.Report) map[string]*RuleGroup {
+	groups := map[string]*RuleGroup{}
+
+	rep.EachBehavior(func(_ string, fr *malcontent.FileReport, b *malcontent.Behavior) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		g, ok := groups[b.ID]
+		if !ok {
+			g = &RuleGroup{
+				Description: b.Description,
+				RiskLevel:   b.RiskLevel,
+				RiskScore:   b.RiskScore,
+				RuleName:    b.RuleName,
+				RuleURL:     b.RuleURL,
+				Tags:        b.Tags,
+			}
+			groups[b.ID] = g
+		}
+		g.Files = append(g.Files, RuleFile{MatchStrings: b.MatchStrings, Path: fr.Path})
+		return true
+	})
+
+	for _, g := range groups {
+		sort.Slice(g.Files, func(i, j int) bool {
+			return g.Files[i].Path < g.Files[j].Path
+		})
+	}
+	return groups
+}
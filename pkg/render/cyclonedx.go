@@ -0,0 +1,236 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// CycloneDX 1.5 renderer: scanned files become components and behaviors become
+// vulnerability entries, so findings can be merged into existing SBOM pipelines.
+//
+// See https://cyclonedx.org/docs/1.5/json/
+
+package render
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/version"
+)
+
+const cycloneDXSpecVersion = "1.5"
+
+type cdxBOM struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber,omitempty"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities"`
+}
+
+type cdxMetadata struct {
+	Timestamp string   `json:"timestamp"`
+	Tools     cdxTools `json:"tools"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type      string    `json:"type"`
+	BOMRef    string    `json:"bom-ref,omitempty"`
+	Publisher string    `json:"publisher,omitempty"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version,omitempty"`
+	Hashes    []cdxHash `json:"hashes,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxVulnerability struct {
+	BOMRef      string      `json:"bom-ref"`
+	ID          string      `json:"id"`
+	Source      *cdxSource  `json:"source,omitempty"`
+	Ratings     []cdxRating `json:"ratings"`
+	Description string      `json:"description,omitempty"`
+	Analysis    cdxAnalysis `json:"analysis"`
+	Affects     []cdxAffect `json:"affects"`
+}
+
+type cdxSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cdxRating struct {
+	Severity string `json:"severity"`
+	Method   string `json:"method"`
+}
+
+type cdxAnalysis struct {
+	State string `json:"state"`
+}
+
+type cdxAffect struct {
+	Ref string `json:"ref"`
+}
+
+type CycloneDX struct {
+	w io.Writer
+}
+
+func NewCycloneDX(w io.Writer) CycloneDX {
+	return CycloneDX{w: w}
+}
+
+func (r CycloneDX) Name() string { return "CycloneDX" }
+
+func (r CycloneDX) Scanning(_ context.Context, _ string) {}
+
+func (r CycloneDX) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+// cdxSeverity maps a malcontent risk level to a CycloneDX severity.
+func cdxSeverity(level malcontent.RiskLevel) string {
+	switch level {
+	case malcontent.RiskNone:
+		return "none"
+	case malcontent.RiskLow, malcontent.RiskMedium, malcontent.RiskHigh, malcontent.RiskCritical:
+		return strings.ToLower(level.String())
+	default:
+		return "unknown"
+	}
+}
+
+// cdxSerialNumber returns a random RFC 4122 version 4 UUID URN.
+func cdxSerialNumber() (string, error) {
+	var u [16]byte
+	if _, err := rand.Read(u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+func (r CycloneDX) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the CycloneDX renderer")
+	}
+
+	var frs []*malcontent.FileReport
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" {
+				frs = append(frs, fr)
+			}
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(frs, func(i, j int) bool {
+		return frs[i].Path < frs[j].Path
+	})
+
+	ver, err := version.Version()
+	if err != nil {
+		ver = ""
+	}
+
+	serial, err := cdxSerialNumber()
+	if err != nil {
+		return fmt.Errorf("serial number: %w", err)
+	}
+
+	components := []cdxComponent{}
+	vulns := []cdxVulnerability{}
+	// Behaviors shared between files are a single vulnerability affecting several components
+	vulnIndex := map[string]int{}
+
+	for _, fr := range frs {
+		ref := "file:" + fr.Path
+		c := cdxComponent{Type: "file", BOMRef: ref, Name: fr.Path}
+		if fr.SHA256 != "" {
+			c.Hashes = []cdxHash{{Alg: "SHA-256", Content: fr.SHA256}}
+		}
+		components = append(components, c)
+
+		for _, b := range fr.Behaviors {
+			vref := b.ID + "@" + b.RiskLevel.String()
+			if idx, ok := vulnIndex[vref]; ok {
+				vulns[idx].Affects = append(vulns[idx].Affects, cdxAffect{Ref: ref})
+				continue
+			}
+
+			v := cdxVulnerability{
+				BOMRef:      vref,
+				ID:          b.ID,
+				Ratings:     []cdxRating{{Severity: cdxSeverity(b.RiskLevel), Method: "other"}},
+				Description: b.Description,
+				// Behaviors are heuristic signals rather than confirmed vulnerabilities
+				Analysis: cdxAnalysis{State: "in_triage"},
+				Affects:  []cdxAffect{{Ref: ref}},
+			}
+			if b.RuleURL != "" {
+				v.Source = &cdxSource{Name: "malcontent", URL: b.RuleURL}
+			}
+			vulnIndex[vref] = len(vulns)
+			vulns = append(vulns, v)
+		}
+	}
+
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: serial,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: cdxTools{
+				Components: []cdxComponent{
+					{
+						Type:      "application",
+						Publisher: "Chainguard",
+						Name:      "malcontent",
+						Version:   ver,
+					},
+				},
+			},
+		},
+		Components:      components,
+		Vulnerabilities: vulns,
+	}
+
+	j, err := json.MarshalIndent(bom, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", j)
+	return err
+}
@@ -0,0 +1,64 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestCycloneDX(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// A second file with the same behavior, which is reported as one vulnerability affecting both
+	frs := append(testFiles(), &malcontent.FileReport{
+		Path:      "scripts/update.sh",
+		RiskScore: 3,
+		RiskLevel: malcontent.RiskHigh,
+		Behaviors: []*malcontent.Behavior{
+			{ID: "net/download/fetch", Description: "fetches a remote payload", RiskScore: 3, RiskLevel: malcontent.RiskHigh},
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := NewCycloneDX(&buf).Full(ctx, nil, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+
+	var bom cdxBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(bom.SerialNumber) {
+		t.Errorf("serialNumber = %q, want a version 4 UUID URN", bom.SerialNumber)
+	}
+	if _, err := time.Parse(time.RFC3339, bom.Metadata.Timestamp); err != nil {
+		t.Errorf("timestamp = %q, want RFC 3339: %v", bom.Metadata.Timestamp, err)
+	}
+
+	// Fields that vary between runs are replaced before comparing with the golden file
+	bom.SerialNumber = "urn:uuid:00000000-0000-4000-8000-000000000000"
+	bom.Metadata.Timestamp = "2024-01-01T00:00:00Z"
+	bom.Metadata.Tools.Components[0].Version = ""
+	got, err := json.MarshalIndent(bom, "", "    ")
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	checkGolden(t, "report.cdx.json", string(got)+"\n")
+}
+
+func TestCycloneDXDiff(t *testing.T) {
+	t.Parallel()
+	r := &malcontent.Report{Diff: &malcontent.DiffReport{}}
+	if err := NewCycloneDX(&bytes.Buffer{}).Full(context.Background(), nil, r); err == nil {
+		t.Error("Full() succeeded for a diff, want an error")
+	}
+}
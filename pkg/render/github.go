@@ -0,0 +1,98 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// GitHub Actions renderer: emits workflow commands that GitHub turns into inline annotations
+//
+// See https://docs.github.com/en/actions/writing-workflows/choosing-what-your-workflow-does/workflow-commands-for-github-actions
+
+package render
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+var (
+	ghaMessageEscaper  = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	ghaPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
+)
+
+type GitHubActions struct {
+	w io.Writer
+}
+
+func NewGitHubActions(w io.Writer) GitHubActions {
+	return GitHubActions{w: w}
+}
+
+func (r GitHubActions) Name() string { return "GitHubActions" }
+
+func (r GitHubActions) Scanning(_ context.Context, _ string) {}
+
+func (r GitHubActions) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+// ghaCommand returns the workflow command used to annotate a behavior of the given risk level.
+func ghaCommand(level malcontent.RiskLevel) string {
+	if level.AtLeast(malcontent.RiskHigh) {
+		return "error"
+	}
+	return "warning"
+}
+
+func (r GitHubActions) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the GitHubActions renderer")
+	}
+
+	var frs []*malcontent.FileReport
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" {
+				frs = append(frs, fr)
+			}
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(frs, func(i, j int) bool {
+		return frs[i].Path < frs[j].Path
+	})
+
+	for _, fr := range frs {
+		for _, b := range fr.Behaviors {
+			// Match offsets are not tracked, so annotations are anchored at the top of the file
+			msg := fmt.Sprintf("%s (%s)", b.Description, b.ID)
+			if _, err := fmt.Fprintf(r.w, "::%s file=%s,line=1,title=%s::%s\n",
+				ghaCommand(b.RiskLevel),
+				ghaPropertyEscaper.Replace(fr.Path),
+				ghaPropertyEscaper.Replace(b.RiskLevel.String()+" "+b.ID),
+				ghaMessageEscaper.Replace(msg)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
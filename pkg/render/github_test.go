@@ -0,0 +1,41 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestGitHubActions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Workflow command properties and messages must escape their delimiters
+	frs := append(testFiles(), &malcontent.FileReport{
+		Path:      "odd/a,b:c%.sh",
+		RiskScore: 2,
+		RiskLevel: malcontent.RiskMedium,
+		Behaviors: []*malcontent.Behavior{
+			{ID: "evasion/multiline", Description: "spans\r\nlines at 100%", RiskScore: 2, RiskLevel: malcontent.RiskMedium},
+		},
+	})
+
+	var buf bytes.Buffer
+	if err := NewGitHubActions(&buf).Full(ctx, nil, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	checkGolden(t, "report.github", buf.String())
+}
+
+func TestGitHubActionsDiff(t *testing.T) {
+	t.Parallel()
+	r := &malcontent.Report{Diff: &malcontent.DiffReport{}}
+	if err := NewGitHubActions(&bytes.Buffer{}).Full(context.Background(), nil, r); err == nil {
+		t.Error("Full() succeeded for a diff, want an error")
+	}
+}
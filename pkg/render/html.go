@@ -0,0 +1,224 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Self-contained HTML renderer: a single static page with inline CSS and JavaScript
+// that can be opened directly from disk.
+
+package render
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// htmlTemplate relies on html/template for contextual escaping: match strings may contain attacker-controlled content.
+const htmlTemplate = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>malcontent report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; }
+header { position: sticky; top: 0; background: #24292f; color: #fff; padding: 0.75em 1.5em; display: flex; gap: 1.5em; align-items: center; flex-wrap: wrap; }
+header h1 { font-size: 1.1em; margin: 0 1em 0 0; }
+.count { padding: 0.2em 0.6em; border-radius: 1em; font-weight: 600; }
+main { padding: 1em 1.5em; }
+.controls { display: flex; gap: 1em; margin-bottom: 1em; }
+.controls input { flex: 1; padding: 0.4em; }
+table { border-collapse: collapse; width: 100%; }
+th { text-align: left; cursor: pointer; user-select: none; border-bottom: 2px solid #d0d7de; padding: 0.4em; }
+td { border-bottom: 1px solid #d0d7de; padding: 0.4em; vertical-align: top; }
+tr.file { cursor: pointer; }
+tr.file:hover { background: #f6f8fa; }
+tr.details { display: none; background: #f6f8fa; }
+tr.details.open { display: table-row; }
+.path { font-family: ui-monospace, monospace; word-break: break-all; }
+.risk { font-weight: 600; }
+.NONE { background: #eaeef2; color: #57606a; }
+.LOW { background: #dafbe1; color: #1a7f37; }
+.MEDIUM { background: #fff8c5; color: #9a6700; }
+.HIGH { background: #ffebe9; color: #cf222e; }
+.CRITICAL { background: #fbefff; color: #8250df; }
+ul.matches { margin: 0.2em 0; padding-left: 1.2em; font-family: ui-monospace, monospace; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<header>
+<h1>malcontent report</h1>
+{{- range .Counts }}
+<span class="count {{ .Level }}">{{ .Level }}: {{ .Count }}</span>
+{{- end }}
+<span>Generated {{ .Generated }}</span>
+</header>
+<main>
+<div class="controls">
+<input id="filter" type="search" placeholder="Filter by path or behavior">
+<select id="risk">
+<option value="0">All risk levels</option>
+<option value="1">LOW and above</option>
+<option value="2">MEDIUM and above</option>
+<option value="3">HIGH and above</option>
+<option value="4">CRITICAL only</option>
+</select>
+</div>
+<table id="files">
+<thead>
+<tr><th data-sort="path">Path</th><th data-sort="risk">Risk</th><th data-sort="behaviors">Behaviors</th></tr>
+</thead>
+{{- range .Files }}
+<tbody data-path="{{ .Path }}" data-risk="{{ .RiskScore }}" data-behaviors="{{ len .Behaviors }}" data-search="{{ .Search }}">
+<tr class="file"><td class="path">{{ .Path }}</td><td><span class="risk count {{ .RiskLevel }}">{{ .RiskLevel }}</span></td><td>{{ len .Behaviors }}</td></tr>
+<tr class="details"><td colspan="3">
+<table>
+<tr><th>Risk</th><th>Behavior</th><th>Description</th><th>Evidence</th></tr>
+{{- range .Behaviors }}
+<tr>
+<td><span class="risk count {{ .RiskLevel }}">{{ .RiskLevel }}</span></td>
+<td>{{ if .RuleURL }}<a href="{{ .RuleURL }}">{{ .ID }}</a>{{ else }}{{ .ID }}{{ end }}</td>
+<td>{{ .Description }}{{ range .ReferenceURLs }} (<a href="{{ . }}">ref</a>){{ end }}</td>
+<td><ul class="matches">{{ range .MatchStrings }}<li>{{ . }}</li>{{ end }}</ul></td>
+</tr>
+{{- end }}
+</table>
+</td></tr>
+</tbody>
+{{- end }}
+</table>
+</main>
+<script>
+(function () {
+  var table = document.getElementById("files");
+  var filter = document.getElementById("filter");
+  var risk = document.getElementById("risk");
+  var bodies = Array.prototype.slice.call(table.tBodies);
+  var direction = {};
+
+  function apply() {
+    var q = filter.value.toLowerCase();
+    var min = parseInt(risk.value, 10);
+    bodies.forEach(function (b) {
+      var show = parseInt(b.dataset.risk, 10) >= min && b.dataset.search.indexOf(q) !== -1;
+      b.style.display = show ? "" : "none";
+    });
+  }
+
+  table.tHead.addEventListener("click", function (e) {
+    var key = e.target.dataset.sort;
+    if (!key) { return; }
+    direction[key] = !direction[key];
+    var sign = direction[key] ? 1 : -1;
+    bodies.sort(function (a, b) {
+      var x = a.dataset[key], y = b.dataset[key];
+      if (key !== "path") { return sign * (parseInt(x, 10) - parseInt(y, 10)); }
+      return sign * x.localeCompare(y);
+    });
+    bodies.forEach(function (b) { table.appendChild(b); });
+  });
+
+  bodies.forEach(function (b) {
+    b.rows[0].addEventListener("click", function () {
+      b.rows[1].classList.toggle("open");
+    });
+  });
+
+  filter.addEventListener("input", apply);
+  risk.addEventListener("change", apply);
+})();
+</script>
+</body>
+</html>
+`
+
+var htmlTmpl = template.Must(template.New("html").Parse(htmlTemplate))
+
+type htmlCount struct {
+	Level string
+	Count int
+}
+
+type htmlFile struct {
+	*malcontent.FileReport
+	// Search is the lowercased text the in-page filter matches against
+	Search string
+}
+
+type htmlReport struct {
+	Counts    []htmlCount
+	Files     []htmlFile
+	Generated string
+}
+
+type HTML struct {
+	w io.Writer
+}
+
+func NewHTML(w io.Writer) HTML {
+	return HTML{w: w}
+}
+
+func (r HTML) Name() string { return "HTML" }
+
+func (r HTML) Scanning(_ context.Context, _ string) {}
+
+func (r HTML) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+func (r HTML) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the HTML renderer")
+	}
+
+	counts := map[int]int{}
+	hr := htmlReport{Generated: time.Now().UTC().Format(time.RFC3339)}
+
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped != "" {
+				return true
+			}
+			counts[fr.RiskScore]++
+			search := fr.Path
+			for _, b := range fr.Behaviors {
+				search += " " + b.ID + " " + b.Description
+			}
+			hr.Files = append(hr.Files, htmlFile{FileReport: fr, Search: strings.ToLower(search)})
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(hr.Files, func(i, j int) bool {
+		if hr.Files[i].RiskScore != hr.Files[j].RiskScore {
+			return hr.Files[i].RiskScore > hr.Files[j].RiskScore
+		}
+		return hr.Files[i].Path < hr.Files[j].Path
+	})
+
+	for score := 4; score >= 0; score-- {
+		hr.Counts = append(hr.Counts, htmlCount{Level: riskLevels[score], Count: counts[score]})
+	}
+
+	return htmlTmpl.Execute(r.w, hr)
+}
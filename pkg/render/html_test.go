@@ -0,0 +1,72 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	frs := append(testFiles(), &malcontent.FileReport{Path: "README.md"})
+	var buf bytes.Buffer
+	if err := NewHTML(&buf).Full(ctx, nil, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	out := buf.String()
+
+	if !strings.HasPrefix(out, "<!DOCTYPE html>") || !strings.HasSuffix(out, "</html>\n") {
+		t.Errorf("output is not a complete HTML document: %q", out)
+	}
+	// Match strings and descriptions come from scanned files, so they must be escaped
+	if strings.Contains(out, "<implant>") || !strings.Contains(out, "known backdoor &lt;implant&gt;") {
+		t.Error("description was not HTML-escaped")
+	}
+	if !strings.Contains(out, `<li>curl -sSL</li>`) {
+		t.Error("match strings are missing")
+	}
+	if !strings.Contains(out, `<a href="https://github.com/chainguard-dev/malcontent/blob/main/rules/net/download/fetch.yara">net/download/fetch</a>`) {
+		t.Error("behavior is not linked to its rule")
+	}
+	if strings.Contains(out, "vendor/huge.bin") {
+		t.Error("skipped file was rendered")
+	}
+
+	counts := regexp.MustCompile(`<span class="count \w+">(\w+: \d+)</span>`).FindAllStringSubmatch(out, -1)
+	got := make([]string, 0, len(counts))
+	for _, m := range counts {
+		got = append(got, m[1])
+	}
+	if want := []string{"CRITICAL: 1", "HIGH: 1", "MEDIUM: 0", "LOW: 1", "NONE: 1"}; !slices.Equal(got, want) {
+		t.Errorf("risk counts = %v, want %v", got, want)
+	}
+
+	// Files are listed riskiest first
+	rows := regexp.MustCompile(`<tbody data-path="([^"]+)" data-risk="(\d)" data-behaviors="(\d)"`).FindAllStringSubmatch(out, -1)
+	got = got[:0]
+	for _, m := range rows {
+		got = append(got, strings.Join(m[1:], " "))
+	}
+	want := []string{"dist/pkg 1.0.tar.gz ∴ /bin/evil 4 1", "scripts/install.sh 3 2", "lib/util.py 1 1", "README.md 0 0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("file rows = %q, want %q", got, want)
+	}
+}
+
+func TestHTMLDiff(t *testing.T) {
+	t.Parallel()
+	r := &malcontent.Report{Diff: &malcontent.DiffReport{}}
+	if err := NewHTML(&bytes.Buffer{}).Full(context.Background(), nil, r); err == nil {
+		t.Error("Full() succeeded for a diff, want an error")
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// HTTP sink renderer: each file report with behaviors is POSTed as JSON to a
+// webhook as soon as it is scanned, e.g. to feed a SIEM.
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+const (
+	// DefaultHTTPSinkQueueSize is the number of reports buffered before File blocks.
+	DefaultHTTPSinkQueueSize = 64
+	// DefaultHTTPSinkRetries is the number of times a failed POST is retried.
+	DefaultHTTPSinkRetries = 3
+	// httpSinkBackoff is the delay before the first retry, doubled for each later one.
+	httpSinkBackoff = 500 * time.Millisecond
+)
+
+// HTTPSinkOption configures an HTTPSink.
+type HTTPSinkOption func(*HTTPSink)
+
+// WithAuthHeader sets the Authorization header sent with every request, e.g. "Bearer <token>".
+func WithAuthHeader(value string) HTTPSinkOption {
+	return func(s *HTTPSink) { s.auth = value }
+}
+
+// WithQueueSize sets the number of reports buffered for delivery before File blocks.
+func WithQueueSize(n int) HTTPSinkOption {
+	return func(s *HTTPSink) { s.queueSize = max(1, n) }
+}
+
+// WithRetries sets the number of times a failed POST is retried before it is reported as an error.
+func WithRetries(n int) HTTPSinkOption {
+	return func(s *HTTPSink) { s.retries = max(0, n) }
+}
+
+// HTTPSink POSTs each file report with behaviors to a URL as it is scanned.
+// Reports are delivered in the background by a single sender; Full waits for
+// every queued report to be delivered and returns any delivery errors.
+type HTTPSink struct {
+	url       string
+	client    *http.Client
+	auth      string
+	queueSize int
+	retries   int
+
+	start sync.Once
+	stop  sync.Once
+	queue chan []byte
+	done  chan struct{}
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewHTTPSink returns a renderer that POSTs JSON-encoded file reports to url using client,
+// or http.DefaultClient if client is nil.
+func NewHTTPSink(url string, client *http.Client, opts ...HTTPSinkOption) *HTTPSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	s := &HTTPSink{
+		url:       url,
+		client:    client,
+		queueSize: DefaultHTTPSinkQueueSize,
+		retries:   DefaultHTTPSinkRetries,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *HTTPSink) Name() string { return "HTTP" }
+
+func (s *HTTPSink) Scanning(_ context.Context, _ string) {}
+
+// File queues fr for delivery. Reports without behaviors are not sent; files below
+// Config.MinFileRisk are never passed to File during a scan.
+func (s *HTTPSink) File(ctx context.Context, fr *malcontent.FileReport) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if fr == nil || fr.Skipped != "" || len(fr.Behaviors) == 0 {
+		return nil
+	}
+
+	// Filter out diff-related fields without mutating the shared report
+	out := *fr
+	out.ArchiveRoot = ""
+	out.FullPath = ""
+
+	body, err := json.Marshal(&out)
+	if err != nil {
+		return err
+	}
+
+	s.start.Do(s.run)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case s.queue <- body:
+		return nil
+	}
+}
+
+// Full waits for every queued report to be delivered, returning the errors of any that could not be.
+// The sink must not be used after Full.
+func (s *HTTPSink) Full(ctx context.Context, _ *malcontent.Config, _ *malcontent.Report) error {
+	s.start.Do(s.run)
+	s.stop.Do(func() { close(s.queue) })
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.done:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.errs...)
+}
+
+// run starts the sender, which delivers queued reports until the queue is closed.
+func (s *HTTPSink) run() {
+	s.queue = make(chan []byte, s.queueSize)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		for body := range s.queue {
+			if err := s.post(context.Background(), body); err != nil {
+				s.mu.Lock()
+				s.errs = append(s.errs, err)
+				s.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// post sends body, retrying with exponential backoff on connection errors, 429s and 5xx responses.
+func (s *HTTPSink) post(ctx context.Context, body []byte) error {
+	backoff := httpSinkBackoff
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = s.send(ctx, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", s.retries+1, err)
+}
+
+// send makes a single POST of body, returning whether a failure may succeed if retried.
+func (s *HTTPSink) send(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.auth != "" {
+		req.Header.Set("Authorization", s.auth)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("post %s: %s", s.url, resp.Status)
+	default:
+		return false, fmt.Errorf("post %s: %s", s.url, resp.Status)
+	}
+}
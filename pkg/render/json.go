@@ -29,36 +29,22 @@ func (r JSON) File(_ context.Context, _ *malcontent.FileReport) error {
 }
 
 func (r JSON) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
 	jr := Report{
-		Diff:   rep.Diff,
-		Files:  make(map[string]*malcontent.FileReport),
-		Filter: "",
+		Correlations:  rep.Correlations,
+		Diff:          rep.Diff,
+		Errors:        rep.Errors,
+		Files:         make(map[string]*malcontent.FileReport),
+		Filter:        "",
+		Interrupted:   rep.Interrupted,
+		SchemaVersion: JSONSchemaVersion,
 	}
 
-	rep.Files.Range(func(key, value any) bool {
-		if ctx.Err() != nil {
-			return false
-		}
-
-		if key == nil || value == nil {
-			return true
-		}
-		if path, ok := key.(string); ok {
-			if r, ok := value.(*malcontent.FileReport); ok {
-				if r.Skipped == "" {
-					// Filter out diff-related fields
-					r.ArchiveRoot = ""
-					r.FullPath = ""
-					jr.Files[path] = r
-				}
-			}
-		}
-		return true
-	})
+	jr.addFiles(ctx, c, rep)
 
 	if c != nil && c.Stats && jr.Diff == nil {
 		jr.Stats = serializedStats(c, rep)
@@ -0,0 +1,39 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// JSONGzip renders the same report as JSON, compressed as a gzip stream for writing to a .json.gz file.
+type JSONGzip struct {
+	w io.Writer
+}
+
+func NewJSONGzip(w io.Writer) JSONGzip {
+	return JSONGzip{w: w}
+}
+
+func (r JSONGzip) Name() string { return "JSONGzip" }
+
+func (r JSONGzip) Scanning(_ context.Context, _ string) {}
+
+func (r JSONGzip) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+// Full writes the gzip stream of the JSON report, closing it so that it is complete once Full returns.
+// Nothing is written if the report cannot be rendered.
+func (r JSONGzip) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	gz := gzip.NewWriter(r.w)
+	if err := NewJSON(gz).Full(ctx, c, rep); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestJSONGzip(t *testing.T) {
+	t.Parallel()
+	rep := testReport(testFiles()...)
+	c := &malcontent.Config{}
+
+	var plain, compressed bytes.Buffer
+	if err := NewJSON(&plain).Full(context.Background(), c, rep); err != nil {
+		t.Fatalf("render json: %v", err)
+	}
+	if err := NewJSONGzip(&compressed).Full(context.Background(), c, rep); err != nil {
+		t.Fatalf("render json.gz: %v", err)
+	}
+
+	gz, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	got, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, plain.Bytes()) {
+		t.Errorf("decompressed output differs from JSON:\n%s\nwant:\n%s", got, plain.Bytes())
+	}
+
+	// Like the JSON renderer, nothing is written when the report cannot be rendered
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out bytes.Buffer
+	if err := NewJSONGzip(&out).Full(ctx, c, rep); !errors.Is(err, context.Canceled) {
+		t.Errorf("Full() with a cancelled context = %v, want %v", err, context.Canceled)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Full() with a cancelled context wrote %d bytes", out.Len())
+	}
+}
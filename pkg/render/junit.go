@@ -0,0 +1,139 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// JUnit XML renderer, for CI systems that surface scans as test results
+// (Jenkins, GitLab, etc.)
+
+package render
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	ClassName string         `xml:"classname,attr"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type JUnit struct {
+	w io.Writer
+}
+
+func NewJUnit(w io.Writer) JUnit {
+	return JUnit{w: w}
+}
+
+func (r JUnit) Name() string { return "JUnit" }
+
+func (r JUnit) Scanning(_ context.Context, _ string) {}
+
+func (r JUnit) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+// Full renders one testcase per scanned file. Files without behaviors at or above MinFileRisk, which
+// scans keep in the report for this renderer, pass. Scan timing is not recorded in reports, so the
+// optional time attributes are omitted.
+func (r JUnit) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the JUnit renderer")
+	}
+
+	minRisk := malcontent.RiskNone
+	if c != nil {
+		minRisk = c.MinFileRisk
+	}
+
+	var frs []*malcontent.FileReport
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" && fr.Path != "" {
+				frs = append(frs, fr)
+			}
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(frs, func(i, j int) bool {
+		return frs[i].Path < frs[j].Path
+	})
+
+	suite := junitTestSuite{Name: "malcontent", Cases: []junitTestCase{}}
+	for _, fr := range frs {
+		tc := junitTestCase{Name: fr.Path, ClassName: "malcontent"}
+		for _, b := range fr.Behaviors {
+			if !malcontent.RiskLevel(b.RiskScore).AtLeast(minRisk) {
+				continue
+			}
+			tc.Failures = append(tc.Failures, junitFailure{
+				Message: fmt.Sprintf("%s: %s", b.ID, b.Description),
+				Type:    b.RiskLevel.String(),
+				Text:    strings.Join(b.MatchStrings, "\n"),
+			})
+		}
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	out := junitTestSuites{
+		Name:     "malcontent",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+
+	x, err := xml.MarshalIndent(out, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s%s\n", xml.Header, x)
+	return err
+}
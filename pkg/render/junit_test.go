@@ -0,0 +1,68 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestJUnit(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	frs := append(testFiles(), &malcontent.FileReport{Path: "README.md"})
+	c := &malcontent.Config{MinFileRisk: malcontent.RiskMedium}
+
+	var buf bytes.Buffer
+	if err := NewJUnit(&buf).Full(ctx, c, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Errorf("output does not start with the XML header: %q", buf.String())
+	}
+
+	var got junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	// The skipped file is left out; the clean file and the LOW file, below MinFileRisk, pass
+	if got.Tests != 4 || got.Failures != 2 || len(got.Suites) != 1 {
+		t.Fatalf("got %d tests, %d failures and %d suites; want 4, 2 and 1", got.Tests, got.Failures, len(got.Suites))
+	}
+	suite := got.Suites[0]
+	if suite.Tests != got.Tests || suite.Failures != got.Failures {
+		t.Errorf("suite counts %d/%d differ from totals %d/%d", suite.Tests, suite.Failures, got.Tests, got.Failures)
+	}
+
+	failures := map[string][]junitFailure{}
+	names := make([]string, 0, len(suite.Cases))
+	for _, tc := range suite.Cases {
+		names = append(names, tc.Name)
+		failures[tc.Name] = tc.Failures
+	}
+	want := []string{"README.md", "dist/pkg 1.0.tar.gz ∴ /bin/evil", "lib/util.py", "scripts/install.sh"}
+	if strings.Join(names, "\n") != strings.Join(want, "\n") {
+		t.Errorf("testcases = %q, want %q", names, want)
+	}
+
+	for _, name := range []string{"README.md", "lib/util.py"} {
+		if len(failures[name]) != 0 {
+			t.Errorf("%s failed with %+v, want a passing testcase", name, failures[name])
+		}
+	}
+	install := failures["scripts/install.sh"]
+	if len(install) != 2 || install[0].Type != "HIGH" || install[0].Message != "net/download/fetch: fetches a remote payload" || install[0].Text != "curl -sSL" {
+		t.Errorf("scripts/install.sh failures = %+v, want the HIGH and MEDIUM behaviors", install)
+	}
+	if evil := failures["dist/pkg 1.0.tar.gz ∴ /bin/evil"]; len(evil) != 1 || evil[0].Message != "malware/family/backdoor: known backdoor <implant>" {
+		t.Errorf("archive member failures = %+v, want the CRITICAL behavior", evil)
+	}
+}
@@ -32,7 +32,7 @@ func NewMarkdown(w io.Writer) Markdown {
 	return Markdown{w: w}
 }
 
-func mdRisk(score int, level string) string {
+func mdRisk(score int, level malcontent.RiskLevel) string {
 	return fmt.Sprintf("%s %s", riskEmoji(score), level)
 }
 
@@ -64,6 +64,7 @@ func (r Markdown) File(ctx context.Context, fr *malcontent.FileReport) error {
 }
 
 func (r Markdown) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -213,8 +214,8 @@ func markdownTable(ctx context.Context, fr *malcontent.FileReport, w io.Writer,
 			desc = before
 		}
 
-		if k.Behavior.ReferenceURL != "" {
-			desc = fmt.Sprintf("[%s](%s)", desc, k.Behavior.ReferenceURL)
+		if refs := k.Behavior.ReferenceURLs(); len(refs) > 0 {
+			desc = fmt.Sprintf("[%s](%s)", desc, refs[0])
 		}
 
 		if k.Behavior.RuleAuthor != "" {
@@ -230,7 +231,7 @@ func markdownTable(ctx context.Context, fr *malcontent.FileReport, w io.Writer,
 			}
 		}
 
-		risk := k.Behavior.RiskLevel
+		risk := k.Behavior.RiskLevel.String()
 
 		if rc.SkipExisting && !k.Behavior.DiffAdded && !k.Behavior.DiffRemoved {
 			continue
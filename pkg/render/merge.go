@@ -0,0 +1,40 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Merging of JSON reports written by separate scan runs, e.g. one per shard of a large tree.
+
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// MergeReports decodes JSON reports, as written by the JSON renderer, and merges them into one.
+func MergeReports(readers ...io.Reader) (*malcontent.Report, error) {
+	merged := &malcontent.Report{}
+
+	for i, rd := range readers {
+		var jr Report
+		if err := json.NewDecoder(rd).Decode(&jr); err != nil {
+			return nil, fmt.Errorf("decode report %d: %w", i, err)
+		}
+		if jr.Diff != nil {
+			return nil, fmt.Errorf("report %d: diff reports cannot be merged", i)
+		}
+
+		r := &malcontent.Report{Errors: jr.Errors, Filter: jr.Filter}
+		for path, fr := range jr.Files {
+			r.Files.Store(path, fr)
+		}
+
+		if err := merged.Merge(r); err != nil {
+			return nil, fmt.Errorf("merge report %d: %w", i, err)
+		}
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Newline-delimited JSON renderer: each file report is written on its own line as soon
+// as it is scanned, followed by an optional summary line once the scan completes.
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// ndjsonSummary is the final line written by Full when statistics are enabled.
+type ndjsonSummary struct {
+	Summary bool   `json:"_summary"`
+	Stats   *Stats `json:",omitempty"`
+}
+
+type NDJSON struct {
+	w io.Writer
+	// File is called concurrently by scan workers; serialize writes so lines never interleave
+	mu *sync.Mutex
+}
+
+func NewNDJSON(w io.Writer) NDJSON {
+	return NDJSON{w: w, mu: &sync.Mutex{}}
+}
+
+func (r NDJSON) Name() string { return "NDJSON" }
+
+func (r NDJSON) Scanning(_ context.Context, _ string) {}
+
+func (r NDJSON) writeLine(v any) error {
+	j, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, err = fmt.Fprintf(r.w, "%s\n", j)
+	return err
+}
+
+func (r NDJSON) File(ctx context.Context, fr *malcontent.FileReport) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if fr == nil || fr.Skipped != "" || fr.Path == "" {
+		return nil
+	}
+
+	// Filter out diff-related fields without mutating the shared report
+	out := *fr
+	out.ArchiveRoot = ""
+	out.FullPath = ""
+
+	return r.writeLine(&out)
+}
+
+func (r NDJSON) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Diffs are only available once both sides are scanned, so they are written as a single line
+	if rep.Diff != nil {
+		return r.writeLine(Report{Diff: rep.Diff})
+	}
+
+	if c != nil && c.Stats {
+		return r.writeLine(ndjsonSummary{Summary: true, Stats: serializedStats(c, rep)})
+	}
+
+	return nil
+}
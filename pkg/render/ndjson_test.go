@@ -0,0 +1,72 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestNDJSON(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	frs := testFiles()
+	// Diff-only fields are left out of each line
+	frs[0].ArchiveRoot = "/tmp/extract"
+	frs[0].FullPath = "/tmp/extract/scripts/install.sh"
+
+	var buf bytes.Buffer
+	r := NewNDJSON(&buf)
+	for _, fr := range frs {
+		if err := r.File(ctx, fr); err != nil {
+			t.Fatalf("file: %v", err)
+		}
+	}
+	if err := r.Full(ctx, &malcontent.Config{Stats: true}, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+	checkGolden(t, "report.ndjson", buf.String())
+}
+
+func TestNDJSONConcurrent(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	r := NewNDJSON(&buf)
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fr := &malcontent.FileReport{Path: fmt.Sprintf("file%d", i)}
+			if err := r.File(ctx, fr); err != nil {
+				t.Errorf("file: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each report is written whole, on a line of its own
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var fr malcontent.FileReport
+		if err := json.Unmarshal(sc.Bytes(), &fr); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		seen[fr.Path] = true
+	}
+	if len(seen) != 64 {
+		t.Errorf("got %d distinct reports, want 64", len(seen))
+	}
+}
@@ -4,29 +4,56 @@
 package render
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 
 	"github.com/chainguard-dev/malcontent/pkg/malcontent"
 )
 
 // Report stores a JSON- or YAML-friendly representation of File Reports.
+// Its JSON form is described by JSONSchema; fields may be added without notice,
+// but changes that break existing consumers bump JSONSchemaVersion.
 type Report struct {
-	Diff   *malcontent.DiffReport            `json:",omitempty" yaml:",omitempty"`
-	Files  map[string]*malcontent.FileReport `json:",omitempty" yaml:",omitempty"`
-	Filter string                            `json:",omitempty" yaml:",omitempty"`
-	Stats  *Stats                            `json:",omitempty" yaml:",omitempty"`
+	// Correlations lists the match strings found in more than one file, when requested
+	Correlations []malcontent.Correlation `json:",omitempty" yaml:",omitempty"`
+	// Diff holds the added, removed and modified files when diffing
+	Diff *malcontent.DiffReport `json:",omitempty" yaml:",omitempty"`
+	// Errors lists the files that could not be read, extracted or scanned
+	Errors []malcontent.ScanError `json:",omitempty" yaml:",omitempty"`
+	// Files maps scanned paths to their reports
+	Files map[string]*malcontent.FileReport `json:",omitempty" yaml:",omitempty"`
+	// Filter lists the rule tags that were ignored, if any
+	Filter string `json:",omitempty" yaml:",omitempty"`
+	// Interrupted is set when the scan was canceled, so Files only holds the files completed before then
+	Interrupted bool `json:",omitempty" yaml:",omitempty"`
+	// OmittedBehaviors is the number of behaviors left out of Files by MaxBehaviorsPerFile
+	OmittedBehaviors int `json:",omitempty" yaml:",omitempty"`
+	// OmittedFiles is the number of files left out of Files by MaxFilesInReport
+	OmittedFiles int `json:",omitempty" yaml:",omitempty"`
+	// SchemaVersion is the JSONSchemaVersion of the JSON renderer's output
+	SchemaVersion string `json:",omitempty" yaml:",omitempty"`
+	// Stats summarizes the scan when statistics are requested
+	Stats *Stats `json:",omitempty" yaml:",omitempty"`
+	// Truncated is set when MaxFilesInReport or MaxBehaviorsPerFile left files or behaviors out of Files
+	Truncated bool `json:",omitempty" yaml:",omitempty"`
 }
 
 // Stats stores a JSON- or YAML-friendly Statistics report.
 type Stats struct {
-	PkgStats       []malcontent.StrMetric `json:",omitempty" yaml:",omitempty"`
-	ProcessedFiles int                    `json:",omitempty" yaml:",omitempty"`
-	RiskStats      []malcontent.IntMetric `json:",omitempty" yaml:",omitempty"`
-	SkippedFiles   int                    `json:",omitempty" yaml:",omitempty"`
-	TotalBehaviors int                    `json:",omitempty" yaml:",omitempty"`
-	TotalRisks     int                    `json:",omitempty" yaml:",omitempty"`
+	Errors         int                           `json:",omitempty" yaml:",omitempty"`
+	PkgStats       []malcontent.StrMetric        `json:",omitempty" yaml:",omitempty"`
+	ProcessedFiles int                           `json:",omitempty" yaml:",omitempty"`
+	RiskStats      []malcontent.IntMetric        `json:",omitempty" yaml:",omitempty"`
+	RuleErrors     []malcontent.RuleCompileError `json:",omitempty" yaml:",omitempty"`
+	Ruleset        *malcontent.RulesetSummary    `json:",omitempty" yaml:",omitempty"`
+	RulesProfile   []malcontent.RuleProfile      `json:",omitempty" yaml:",omitempty"`
+	SkippedFiles   int                           `json:",omitempty" yaml:",omitempty"`
+	TotalBehaviors int                           `json:",omitempty" yaml:",omitempty"`
+	TotalRisks     int                           `json:",omitempty" yaml:",omitempty"`
+	UnusedRules    []malcontent.UnusedRule       `json:",omitempty" yaml:",omitempty"`
 }
 
 // New returns a new Renderer.
@@ -36,12 +63,28 @@ func New(kind string, w io.Writer) (malcontent.Renderer, error) {
 		return NewTerminal(w), nil
 	case "terminal_brief":
 		return NewTerminalBrief(w), nil
+	case "byrule":
+		return NewByRule(w), nil
+	case "cyclonedx":
+		return NewCycloneDX(w), nil
+	case "github":
+		return NewGitHubActions(w), nil
+	case "html":
+		return NewHTML(w), nil
+	case "junit":
+		return NewJUnit(w), nil
 	case "markdown":
 		return NewMarkdown(w), nil
 	case "yaml":
 		return NewYAML(w), nil
 	case "json":
 		return NewJSON(w), nil
+	case "json.gz":
+		return NewJSONGzip(w), nil
+	case "ndjson":
+		return NewNDJSON(w), nil
+	case "sarif":
+		return NewSARIF(w), nil
 	case "simple":
 		return NewSimple(w), nil
 	case "strings":
@@ -55,6 +98,15 @@ func New(kind string, w io.Writer) (malcontent.Renderer, error) {
 	}
 }
 
+// fullContext returns the context to render rep with. The files completed before an interrupted
+// scan are still rendered, so the cancellation that interrupted it is not passed on.
+func fullContext(ctx context.Context, rep *malcontent.Report) context.Context {
+	if rep != nil && rep.Interrupted {
+		return context.WithoutCancel(ctx)
+	}
+	return ctx
+}
+
 func riskEmoji(score int) string {
 	symbol := "🔵"
 	switch score {
@@ -69,9 +121,105 @@ func riskEmoji(score int) string {
 	return symbol
 }
 
+// serializedFile returns the FileReport to serialize for fr, with its behaviors
+// grouped by top-level namespace when c.GroupByNamespace is set.
+func serializedFile(c *malcontent.Config, fr *malcontent.FileReport) *malcontent.FileReport {
+	// Filter out diff-related fields
+	fr.ArchiveRoot = ""
+	fr.FullPath = ""
+	if c == nil || !c.GroupByNamespace || len(fr.Behaviors) == 0 {
+		return fr
+	}
+
+	// Copy the report so that later consumers still see the flat list
+	grouped := *fr
+	grouped.BehaviorGroups = map[string][]*malcontent.Behavior{}
+	for _, b := range fr.Behaviors {
+		ns, _, _ := strings.Cut(b.ID, "/")
+		grouped.BehaviorGroups[ns] = append(grouped.BehaviorGroups[ns], b)
+	}
+	grouped.Behaviors = nil
+	return &grouped
+}
+
+// addFiles adds the files of rep to be rendered to sr, keeping the riskiest c.MaxFilesInReport files and the
+// riskiest c.MaxBehaviorsPerFile behaviors of each, and recording what was left out. rep is not modified,
+// so statistics still cover every file and behavior.
+func (sr *Report) addFiles(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) {
+	var frs []*malcontent.FileReport
+	var paths []string
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if path, ok := key.(string); ok {
+			if r, ok := value.(*malcontent.FileReport); ok {
+				if r.Skipped == "" && (c == nil || !c.Quiet || len(r.Behaviors) > 0) {
+					frs = append(frs, r)
+					paths = append(paths, path)
+				}
+			}
+		}
+		return true
+	})
+
+	order := make([]int, len(frs))
+	for i := range order {
+		order[i] = i
+	}
+	if c != nil && c.MaxFilesInReport > 0 && len(order) > c.MaxFilesInReport {
+		sort.SliceStable(order, func(i, j int) bool {
+			a, b := frs[order[i]], frs[order[j]]
+			if a.RiskScore != b.RiskScore {
+				return a.RiskScore > b.RiskScore
+			}
+			return paths[order[i]] < paths[order[j]]
+		})
+		sr.OmittedFiles = len(order) - c.MaxFilesInReport
+		order = order[:c.MaxFilesInReport]
+	}
+
+	for _, i := range order {
+		fr := frs[i]
+		if c != nil && c.MaxBehaviorsPerFile > 0 && len(fr.Behaviors) > c.MaxBehaviorsPerFile {
+			sr.OmittedBehaviors += len(fr.Behaviors) - c.MaxBehaviorsPerFile
+			fr = riskiestBehaviors(fr, c.MaxBehaviorsPerFile)
+		}
+		sr.Files[paths[i]] = serializedFile(c, fr)
+	}
+	sr.Truncated = sr.OmittedFiles > 0 || sr.OmittedBehaviors > 0
+}
+
+// riskiestBehaviors returns a copy of fr keeping only its n riskiest behaviors, in their original order.
+func riskiestBehaviors(fr *malcontent.FileReport, n int) *malcontent.FileReport {
+	order := make([]int, len(fr.Behaviors))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fr.Behaviors[order[i]].RiskScore > fr.Behaviors[order[j]].RiskScore
+	})
+	order = order[:n]
+	sort.Ints(order)
+
+	truncated := *fr
+	truncated.Behaviors = make([]*malcontent.Behavior, 0, n)
+	for _, i := range order {
+		truncated.Behaviors = append(truncated.Behaviors, fr.Behaviors[i])
+	}
+	return &truncated
+}
+
 func serializedStats(c *malcontent.Config, r *malcontent.Report) *Stats {
-	pkgStats, _, totalBehaviors := PkgStatistics(c, &r.Files)
-	riskStats, totalRisks, processedFiles, skippedFiles := RiskStatistics(c, &r.Files)
+	stats := r.Stats
+	if stats == nil {
+		stats = ScanStatistics(c, &r.Files)
+	}
+	pkgStats, _, _ := PkgStatistics(c, &r.Files)
+	riskStats, totalRisks, _, _ := RiskStatistics(c, &r.Files)
 
 	sort.Slice(pkgStats, func(i, j int) bool {
 		return pkgStats[i].Key < pkgStats[j].Key
@@ -82,11 +230,16 @@ func serializedStats(c *malcontent.Config, r *malcontent.Report) *Stats {
 	})
 
 	return &Stats{
+		Errors:         stats.Errors,
 		PkgStats:       pkgStats,
-		ProcessedFiles: processedFiles,
+		ProcessedFiles: stats.FilesScanned,
 		RiskStats:      riskStats,
-		SkippedFiles:   skippedFiles,
-		TotalBehaviors: totalBehaviors,
+		RuleErrors:     stats.RuleErrors,
+		Ruleset:        stats.Ruleset,
+		RulesProfile:   stats.RulesProfile,
+		SkippedFiles:   stats.FilesSkipped,
+		TotalBehaviors: stats.TotalBehaviors,
 		TotalRisks:     totalRisks,
+		UnusedRules:    stats.UnusedRules,
 	}
 }
@@ -0,0 +1,163 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/google/go-cmp/cmp"
+)
+
+// checkGolden compares got with the golden file testdata/name.
+func checkGolden(t *testing.T, name string, got string) {
+	t.Helper()
+	want, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("golden read failed: %v", err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Errorf("%s output mismatch: (-want +got):\n%s", name, diff)
+	}
+}
+
+// testReport returns a hand-built report of the given files, keyed by path as scans do.
+func testReport(frs ...*malcontent.FileReport) *malcontent.Report {
+	r := &malcontent.Report{}
+	for _, fr := range frs {
+		r.Files.Store(fr.Path, fr)
+	}
+	return r
+}
+
+// testFiles returns a HIGH risk script, a LOW risk library, a CRITICAL archive member and a skipped file.
+func testFiles() []*malcontent.FileReport {
+	return []*malcontent.FileReport{
+		{
+			Path:      "scripts/install.sh",
+			SHA256:    "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+			Size:      120,
+			RiskScore: 3,
+			RiskLevel: malcontent.RiskHigh,
+			Behaviors: []*malcontent.Behavior{
+				{
+					ID:           "net/download/fetch",
+					RuleName:     "curl_download",
+					Description:  "fetches a remote payload",
+					MatchStrings: []string{"curl -sSL"},
+					RiskScore:    3,
+					RiskLevel:    malcontent.RiskHigh,
+					RuleURL:      "https://github.com/chainguard-dev/malcontent/blob/main/rules/net/download/fetch.yara",
+				},
+				{
+					ID:          "exec/shell/exec",
+					RuleName:    "sh_exec",
+					Description: "executes a shell",
+					RiskScore:   2,
+					RiskLevel:   malcontent.RiskMedium,
+				},
+			},
+		},
+		{
+			Path:      "lib/util.py",
+			SHA256:    "60303ae22b998861bce3b28f33eec1be758a213c86c93c076dbe9f558c11c752",
+			Size:      64,
+			RiskScore: 1,
+			RiskLevel: malcontent.RiskLow,
+			Behaviors: []*malcontent.Behavior{
+				{
+					ID:          "fs/file/read",
+					RuleName:    "file_read",
+					Description: "reads files",
+					RiskScore:   1,
+					RiskLevel:   malcontent.RiskLow,
+				},
+			},
+		},
+		{
+			Path:      "dist/pkg 1.0.tar.gz ∴ /bin/evil",
+			SHA256:    "fd61a03af4f77d870fc21e05e7e80678095c92d808cfb3b5c279ee04c74aca13",
+			Size:      4096,
+			RiskScore: 4,
+			RiskLevel: malcontent.RiskCritical,
+			Behaviors: []*malcontent.Behavior{
+				{
+					ID:          "malware/family/backdoor",
+					RuleName:    "backdoor",
+					Description: "known backdoor <implant>",
+					RiskScore:   4,
+					RiskLevel:   malcontent.RiskCritical,
+				},
+			},
+		},
+		{
+			Path:    "vendor/huge.bin",
+			Skipped: "file too large",
+		},
+	}
+}
+
+func TestRenderTruncation(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	behavior := func(id string, risk int) *malcontent.Behavior {
+		return &malcontent.Behavior{ID: id, RiskScore: risk, RiskLevel: malcontent.RiskLevel(risk)}
+	}
+	rep := &malcontent.Report{}
+	for _, fr := range []*malcontent.FileReport{
+		{Path: "low.sh", RiskScore: 1, Behaviors: []*malcontent.Behavior{behavior("fs/read", 1)}},
+		{Path: "medium.sh", RiskScore: 2, Behaviors: []*malcontent.Behavior{behavior("net/http", 2)}},
+		{Path: "critical.sh", RiskScore: 4, Behaviors: []*malcontent.Behavior{
+			behavior("exec/shell", 2),
+			behavior("exfil/upload", 4),
+			behavior("fs/read", 1),
+			behavior("net/download", 3),
+		}},
+		{Path: "high.sh", RiskScore: 3, Behaviors: []*malcontent.Behavior{behavior("net/download", 3)}},
+	} {
+		rep.Files.Store(fr.Path, fr)
+	}
+
+	var out bytes.Buffer
+	c := &malcontent.Config{MaxBehaviorsPerFile: 2, MaxFilesInReport: 2, Stats: true}
+	if err := NewJSON(&out).Full(ctx, c, rep); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	var got Report
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", out.String(), err)
+	}
+
+	if !got.Truncated || got.OmittedFiles != 2 || got.OmittedBehaviors != 2 {
+		t.Errorf("truncated = %v, omitted %d files and %d behaviors, want true, 2 and 2", got.Truncated, got.OmittedFiles, got.OmittedBehaviors)
+	}
+	if paths := slices.Sorted(maps.Keys(got.Files)); !slices.Equal(paths, []string{"critical.sh", "high.sh"}) {
+		t.Errorf("files = %q, want the two riskiest", paths)
+	}
+	if fr := got.Files["critical.sh"]; fr != nil {
+		var ids []string
+		for _, b := range fr.Behaviors {
+			ids = append(ids, b.ID)
+		}
+		if want := []string{"exfil/upload", "net/download"}; !slices.Equal(ids, want) {
+			t.Errorf("critical.sh behaviors = %q, want the two riskiest %q", ids, want)
+		}
+	}
+
+	// Statistics and the report itself still cover everything
+	if got.Stats == nil || got.Stats.TotalBehaviors != 7 {
+		t.Errorf("stats = %+v, want 7 behaviors", got.Stats)
+	}
+	if v, ok := rep.Files.Load("critical.sh"); !ok || len(v.(*malcontent.FileReport).Behaviors) != 4 {
+		t.Errorf("rendering truncated the report's own behaviors")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// SARIF 2.1.0 renderer, suitable for code-scanning integrations such as GitHub Advanced Security
+//
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+package render
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/version"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+	sarifToolURI = "https://github.com/chainguard-dev/malcontent"
+	// sarifRootID is the base that relative artifact URIs are resolved against: the directory mal was run from.
+	sarifRootID = "%SRCROOT%"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Version        string      `json:"version,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name,omitempty"`
+	ShortDescription *sarifMessage `json:"shortDescription,omitempty"`
+	HelpURI          string        `json:"helpUri,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SARIF struct {
+	w io.Writer
+}
+
+func NewSARIF(w io.Writer) SARIF {
+	return SARIF{w: w}
+}
+
+func (r SARIF) Name() string { return "SARIF" }
+
+func (r SARIF) Scanning(_ context.Context, _ string) {}
+
+func (r SARIF) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+// sarifFileLocation returns the location of the file at path, relative to root when it is within it. Archive members
+// cannot be addressed by a URI, so they are located at their archive, and the member is returned separately.
+func sarifFileLocation(root string, path string) (sarifArtifactLocation, string) {
+	path, member, _ := strings.Cut(path, " ∴ ")
+
+	if root != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(root, path); err == nil && filepath.IsLocal(rel) {
+			path = rel
+		}
+	}
+	if filepath.IsAbs(path) {
+		return sarifArtifactLocation{URI: fileURI(path)}, member
+	}
+
+	u := url.URL{Path: filepath.ToSlash(filepath.Clean(path))}
+	return sarifArtifactLocation{URI: u.String(), URIBaseID: sarifRootID}, member
+}
+
+// fileURI returns the file URI of an absolute path, such as file:///C:/dir for C:\dir on Windows.
+func fileURI(path string) string {
+	p := filepath.ToSlash(path)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	u := url.URL{Scheme: "file", Path: p}
+	return u.String()
+}
+
+// sarifLevel maps a malcontent risk level to a SARIF result level.
+func sarifLevel(level malcontent.RiskLevel) string {
+	switch level {
+	case malcontent.RiskLow:
+		return "note"
+	case malcontent.RiskMedium:
+		return "warning"
+	case malcontent.RiskHigh, malcontent.RiskCritical:
+		return "error"
+	default:
+		return "none"
+	}
+}
+
+func (r SARIF) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if rep.Diff != nil {
+		return fmt.Errorf("diffs are unsupported by the SARIF renderer")
+	}
+
+	var frs []*malcontent.FileReport
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" {
+				frs = append(frs, fr)
+			}
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(frs, func(i, j int) bool {
+		return frs[i].Path < frs[j].Path
+	})
+
+	ver, err := version.Version()
+	if err != nil {
+		ver = ""
+	}
+
+	root, err := os.Getwd()
+	if err != nil {
+		root = ""
+	}
+
+	rules := []sarifRule{}
+	ruleIndex := map[string]int{}
+	results := []sarifResult{}
+
+	for _, fr := range frs {
+		loc, member := sarifFileLocation(root, fr.Path)
+		for _, b := range fr.Behaviors {
+			idx, ok := ruleIndex[b.ID]
+			if !ok {
+				idx = len(rules)
+				ruleIndex[b.ID] = idx
+				rule := sarifRule{
+					ID:      b.ID,
+					Name:    b.RuleName,
+					HelpURI: b.RuleURL,
+				}
+				if b.Description != "" {
+					rule.ShortDescription = &sarifMessage{Text: b.Description}
+				}
+				rules = append(rules, rule)
+			}
+
+			msg := fmt.Sprintf("%s [%s]", b.Description, b.RiskLevel)
+			if member != "" {
+				msg = fmt.Sprintf("%s: %s", member, msg)
+			}
+
+			results = append(results, sarifResult{
+				RuleID:    b.ID,
+				RuleIndex: idx,
+				Level:     sarifLevel(b.RiskLevel),
+				Message:   sarifMessage{Text: msg},
+				Locations: []sarifLocation{
+					{
+						PhysicalLocation: sarifPhysicalLocation{
+							ArtifactLocation: loc,
+						},
+					},
+				},
+			})
+		}
+	}
+
+	var baseIDs map[string]sarifArtifactLocation
+	if root != "" {
+		// The base URI of a directory must end with a slash
+		baseIDs = map[string]sarifArtifactLocation{sarifRootID: {URI: strings.TrimSuffix(fileURI(root), "/") + "/"}}
+	}
+
+	sl := sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "malcontent",
+						InformationURI: sarifToolURI,
+						Version:        ver,
+						Rules:          rules,
+					},
+				},
+				OriginalURIBaseIDs: baseIDs,
+				Results:            results,
+			},
+		},
+	}
+
+	j, err := json.MarshalIndent(sl, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(r.w, "%s\n", j)
+	return err
+}
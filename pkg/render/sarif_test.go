@@ -0,0 +1,161 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestSARIFFileLocation(t *testing.T) {
+	t.Parallel()
+	root := filepath.FromSlash("/src/repo")
+
+	tests := []struct {
+		path       string
+		wantURI    string
+		wantBase   string
+		wantMember string
+	}{
+		{"scripts/install.sh", "scripts/install.sh", sarifRootID, ""},
+		{"./scripts/../bin/run me.sh", "bin/run%20me.sh", sarifRootID, ""},
+		{filepath.FromSlash("/src/repo/lib/100%.py"), "lib/100%25.py", sarifRootID, ""},
+		{filepath.FromSlash("/src/repo/dist/pkg 1.0.tar.gz") + " ∴ /bin/evil", "dist/pkg%201.0.tar.gz", sarifRootID, "/bin/evil"},
+		{filepath.FromSlash("/tmp/other file"), "file:///tmp/other%20file", "", ""},
+	}
+	for _, tc := range tests {
+		loc, member := sarifFileLocation(root, tc.path)
+		if loc.URI != tc.wantURI || loc.URIBaseID != tc.wantBase || member != tc.wantMember {
+			t.Errorf("sarifFileLocation(%q) = %+v, %q; want {%s %s}, %q", tc.path, loc, member, tc.wantURI, tc.wantBase, tc.wantMember)
+		}
+	}
+}
+
+// TestSARIF checks the rendered document against the shape the SARIF 2.1.0 schema requires of the
+// properties GitHub code scanning reads.
+func TestSARIF(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	frs := testFiles()
+	frs[1].Path = filepath.Join(wd, "lib", "util.py")
+
+	var buf bytes.Buffer
+	if err := NewSARIF(&buf).Full(ctx, nil, testReport(frs...)); err != nil {
+		t.Fatalf("full: %v", err)
+	}
+
+	var doc struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			OriginalURIBaseIDs map[string]struct {
+				URI string `json:"uri"`
+			} `json:"originalUriBaseIds"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	if doc.Version != sarifVersion || doc.Schema != sarifSchema || len(doc.Runs) != 1 {
+		t.Fatalf("got version %q, schema %q and %d runs; want one 2.1.0 run", doc.Version, doc.Schema, len(doc.Runs))
+	}
+	run := doc.Runs[0]
+	if run.Tool.Driver.Name != "malcontent" {
+		t.Errorf("driver name = %q, want malcontent", run.Tool.Driver.Name)
+	}
+
+	base, err := url.Parse(run.OriginalURIBaseIDs[sarifRootID].URI)
+	if err != nil || base.Scheme != "file" || !strings.HasSuffix(base.Path, "/") {
+		t.Errorf("%s base = %q, want an absolute file URI ending in a slash", sarifRootID, run.OriginalURIBaseIDs[sarifRootID].URI)
+	}
+
+	uris := map[string]string{}
+	for _, res := range run.Results {
+		if res.RuleIndex < 0 || res.RuleIndex >= len(run.Tool.Driver.Rules) || run.Tool.Driver.Rules[res.RuleIndex].ID != res.RuleID {
+			t.Errorf("result %s has ruleIndex %d, which does not refer to its rule", res.RuleID, res.RuleIndex)
+		}
+		if !slices.Contains([]string{"none", "note", "warning", "error"}, res.Level) {
+			t.Errorf("result %s has invalid level %q", res.RuleID, res.Level)
+		}
+		if res.Message.Text == "" {
+			t.Errorf("result %s has no message", res.RuleID)
+		}
+		if len(res.Locations) != 1 {
+			t.Fatalf("result %s has %d locations, want 1", res.RuleID, len(res.Locations))
+		}
+		al := res.Locations[0].PhysicalLocation.ArtifactLocation
+		if u, err := url.Parse(al.URI); err != nil || u.IsAbs() || strings.ContainsAny(al.URI, " ∴") {
+			t.Errorf("result %s has uri %q, want a percent-encoded relative reference", res.RuleID, al.URI)
+		}
+		if al.URIBaseID != sarifRootID {
+			t.Errorf("result %s has uriBaseId %q, want %s", res.RuleID, al.URIBaseID, sarifRootID)
+		}
+		uris[res.RuleID] = al.URI
+		if res.RuleID == "malware/family/backdoor" && !strings.HasPrefix(res.Message.Text, "/bin/evil: ") {
+			t.Errorf("archive member result message = %q, want it to name the member", res.Message.Text)
+		}
+	}
+
+	want := map[string]string{
+		"net/download/fetch":      "scripts/install.sh",
+		"exec/shell/exec":         "scripts/install.sh",
+		"fs/file/read":            "lib/util.py",
+		"malware/family/backdoor": "dist/pkg%201.0.tar.gz",
+	}
+	if len(uris) != len(want) {
+		t.Errorf("got results for %v, want %v", uris, want)
+	}
+	for id, uri := range want {
+		if uris[id] != uri {
+			t.Errorf("result %s uri = %q, want %q", id, uris[id], uri)
+		}
+	}
+}
+
+func TestSARIFDiff(t *testing.T) {
+	t.Parallel()
+	r := &malcontent.Report{Diff: &malcontent.DiffReport{}}
+	if err := NewSARIF(&bytes.Buffer{}).Full(context.Background(), nil, r); err == nil {
+		t.Error("Full() succeeded for a diff, want an error")
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// JSON Schema for reports written by the JSON renderer, derived from the Report type
+// so that it cannot drift from the serialized output.
+
+package render
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchemaVersion is written as Report.SchemaVersion by the JSON renderer.
+// The major version is bumped when fields are removed, renamed or change type;
+// the minor version when fields are added.
+const JSONSchemaVersion = "1.0"
+
+// jsonSchemaID identifies the schema returned by JSONSchema.
+const jsonSchemaID = "https://github.com/chainguard-dev/malcontent/schema/report-" + JSONSchemaVersion + ".json"
+
+var (
+	durationType      = reflect.TypeFor[time.Duration]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// JSONSchema returns a JSON Schema (draft 2020-12) describing the reports written by the JSON renderer.
+func JSONSchema() ([]byte, error) {
+	g := &schemaGenerator{defs: map[string]any{}}
+	root := g.structSchema(reflect.TypeFor[Report]())
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = jsonSchemaID
+	root["title"] = "malcontent report"
+	root["$defs"] = g.defs
+	return json.MarshalIndent(root, "", "    ")
+}
+
+// schemaGenerator builds schemas for Go types, sharing the definitions of named structs in defs.
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return map[string]any{"type": "integer", "description": "nanoseconds"}
+	case t.Implements(textMarshalerType):
+		// Such as malcontent.RiskLevel, which marshals as its name
+		return map[string]any{"type": "string"}
+	case isOrderedMap(t):
+		// Ordered maps serialize as objects, looked up with Get(key) (value, ok)
+		get, _ := reflect.PointerTo(t).MethodByName("Get")
+		return map[string]any{"type": "object", "additionalProperties": g.schema(get.Type.Out(0))}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first so that recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema describes the exported fields of t as serialized by encoding/json.
+// Fields without omitempty are always present, and so are required.
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// isOrderedMap reports whether t is an orderedmap.OrderedMap, which marshals as a JSON object.
+func isOrderedMap(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && strings.HasPrefix(t.Name(), "OrderedMap[")
+}
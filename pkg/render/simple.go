@@ -35,7 +35,7 @@ func (r Simple) File(ctx context.Context, fr *malcontent.FileReport) error {
 	}
 
 	if len(fr.Behaviors) > 0 {
-		fmt.Fprintf(r.w, "# %s: %s\n", fr.Path, strings.ToLower(fr.RiskLevel))
+		fmt.Fprintf(r.w, "# %s: %s\n", fr.Path, strings.ToLower(fr.RiskLevel.String()))
 	}
 
 	var bs []*malcontent.Behavior
@@ -47,12 +47,13 @@ func (r Simple) File(ctx context.Context, fr *malcontent.FileReport) error {
 	})
 
 	for _, b := range bs {
-		fmt.Fprintf(r.w, "%s: %s\n", b.ID, strings.ToLower(b.RiskLevel))
+		fmt.Fprintf(r.w, "%s: %s\n", b.ID, strings.ToLower(b.RiskLevel.String()))
 	}
 	return nil
 }
 
 func (r Simple) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -19,6 +19,46 @@ func smLength(m *sync.Map) int {
 	return length
 }
 
+// ScanStatistics summarizes the file reports in files. Files below the scan risk
+// threshold are counted as skipped when c.Scan is set.
+func ScanStatistics(c *malcontent.Config, files *sync.Map) *malcontent.ScanStats {
+	stats := &malcontent.ScanStats{
+		BehaviorsByRisk: map[malcontent.RiskLevel]int{},
+		FilesByRisk:     map[malcontent.RiskLevel]int{},
+	}
+	rules := map[string]bool{}
+
+	files.Range(func(key, value any) bool {
+		if key == nil || value == nil {
+			return true
+		}
+		stats.FilesScanned++
+
+		fr, ok := value.(*malcontent.FileReport)
+		if !ok {
+			return true
+		}
+		if fr.Skipped == "" {
+			for _, b := range fr.Behaviors {
+				stats.TotalBehaviors++
+				stats.BehaviorsByRisk[b.RiskLevel]++
+				rules[b.RuleName] = true
+			}
+		}
+		if fr.Skipped != "" || (c != nil && c.Scan && fr.RiskScore < 3) {
+			stats.FilesSkipped++
+			return true
+		}
+
+		stats.Bytes += fr.Size
+		stats.FilesByRisk[fr.RiskLevel]++
+		return true
+	})
+
+	stats.RulesMatched = len(rules)
+	return stats
+}
+
 func RiskStatistics(c *malcontent.Config, files *sync.Map) ([]malcontent.IntMetric, int, int, int) {
 	length := smLength(files)
 
@@ -116,17 +156,30 @@ func PkgStatistics(_ *malcontent.Config, files *sync.Map) ([]malcontent.StrMetri
 	return stats, width, numBehaviors
 }
 
+// profiledRules is the number of slowest rules shown by Statistics.
+const profiledRules = 20
+
 func Statistics(c *malcontent.Config, r *malcontent.Report) error {
-	riskStats, totalRisks, processedFiles, skippedFiles := RiskStatistics(c, &r.Files)
-	pkgStats, width, totalBehaviors := PkgStatistics(c, &r.Files)
+	stats := r.Stats
+	if stats == nil {
+		stats = ScanStatistics(c, &r.Files)
+	}
+	riskStats, totalRisks, _, _ := RiskStatistics(c, &r.Files)
+	pkgStats, width, _ := PkgStatistics(c, &r.Files)
 
 	statsSymbol := "📊"
 	riskSymbol := "⚠️ "
 	pkgSymbol := "📦"
 	fmt.Printf("%s Statistics\n", statsSymbol)
 	fmt.Println("---")
-	fmt.Printf("\033[1;37m%-15s \033[1;37m%s\033[0m\n", "Files Scanned", fmt.Sprintf("%d (%d skipped)", processedFiles, skippedFiles))
+	fmt.Printf("\033[1;37m%-15s \033[1;37m%s\033[0m\n", "Files Scanned", fmt.Sprintf("%d (%d skipped)", stats.FilesScanned, stats.FilesSkipped))
 	fmt.Printf("\033[1;37m%-15s \033[1;37m%s\033[0m\n", "Total Risks", fmt.Sprintf("%d", totalRisks))
+	if stats.Errors > 0 {
+		fmt.Printf("\033[1;37m%-15s \033[33m%s\033[0m\n", "Errors", fmt.Sprintf("%d", stats.Errors))
+	}
+	if rs := stats.Ruleset; rs != nil {
+		fmt.Printf("\033[1;37m%-15s \033[1;37m%s\033[0m\n", "Ruleset", fmt.Sprintf("%d rules in %d namespaces (%s)", rs.Rules, len(rs.Namespaces), rs.Hash))
+	}
 	fmt.Println("---")
 	fmt.Printf("%s Risk Level Percentage\n", riskSymbol)
 	fmt.Println("---")
@@ -150,7 +203,7 @@ func Statistics(c *malcontent.Config, r *malcontent.Report) error {
 	}
 
 	fmt.Println("---")
-	fmt.Printf("\033[1;37m%-12s \033[1;37m%10s\033[0m\n", "Number of behaviors", fmt.Sprintf("%d", totalBehaviors))
+	fmt.Printf("\033[1;37m%-12s \033[1;37m%10s\033[0m\n", "Number of behaviors", fmt.Sprintf("%d", stats.TotalBehaviors))
 	fmt.Println("---")
 	fmt.Printf("%s Package Behaviors\n", pkgSymbol)
 	fmt.Println("---")
@@ -159,5 +212,49 @@ func Statistics(c *malcontent.Config, r *malcontent.Report) error {
 		fmt.Printf("%-*s %10.2f%s %d/%d\n", width, pkg.Key, pkg.Value, "%", pkg.Count, pkg.Total)
 	}
 
+	if len(stats.RuleErrors) > 0 {
+		fmt.Println("---")
+		fmt.Printf("%s Skipped Rule Files\n", riskSymbol)
+		fmt.Println("---")
+		for _, re := range stats.RuleErrors {
+			fmt.Printf("\033[33m%s:%d:%d\033[0m %s\n", re.Path, re.Line, re.Column, re.Message)
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Println("---")
+		fmt.Printf("%s Scan Errors\n", riskSymbol)
+		fmt.Println("---")
+		for _, e := range r.Errors {
+			fmt.Printf("\033[33m%s\033[0m (%s) %s\n", e.Path, e.Phase, e.Error)
+		}
+	}
+
+	if len(stats.RulesProfile) > 0 {
+		profileSymbol := "⏱️ "
+		rps := stats.RulesProfile[:min(len(stats.RulesProfile), profiledRules)]
+		ruleWidth := 10
+		for _, rp := range rps {
+			ruleWidth = max(ruleWidth, len(rp.ID)+len(rp.Rule)+1)
+		}
+
+		fmt.Println("---")
+		fmt.Printf("%s Slowest Rules\n", profileSymbol)
+		fmt.Println("---")
+		fmt.Printf("\033[1;37m%-*s  \033[1;37m%12s %11s %7s\033[0m\n", ruleWidth, "Rule", "Time", "Invocations", "Matched")
+		for _, rp := range rps {
+			fmt.Printf("%-*s %12s %11d %7d\n", ruleWidth, rp.ID+":"+rp.Rule, rp.Time, rp.Invocations, rp.FilesMatched)
+		}
+	}
+
+	if len(stats.UnusedRules) > 0 {
+		fmt.Println("---")
+		fmt.Printf("🪦 Unused Rules (%d)\n", len(stats.UnusedRules))
+		fmt.Println("---")
+		for _, ur := range stats.UnusedRules {
+			fmt.Printf("%s:%s\n", ur.ID, ur.Rule)
+		}
+	}
+
 	return nil
 }
@@ -376,7 +376,7 @@ func (r *Interactive) File(ctx context.Context, fr *malcontent.FileReport) error
 	case len(fr.Behaviors) > 0:
 		var builder strings.Builder
 		renderFileSummaryTea(ctx, fr, &builder, tableConfig{
-			Title: fmt.Sprintf("%s %s", fr.Path, darkBrackets(riskInColor(fr.RiskLevel))),
+			Title: fmt.Sprintf("%s %s", fr.Path, darkBrackets(riskInColor(fr.RiskLevel.String()))),
 		})
 		content = strings.TrimSpace(builder.String())
 	}
@@ -392,6 +392,7 @@ func (r *Interactive) File(ctx context.Context, fr *malcontent.FileReport) error
 }
 
 func (r *Interactive) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
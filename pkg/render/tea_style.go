@@ -168,11 +168,11 @@ func renderFileSummaryTea(ctx context.Context, fr *malcontent.FileReport, w io.W
 
 	// File header with risk level
 	pathStyle := headerStyle.
-		Foreground(riskColors[fr.RiskLevel])
+		Foreground(riskColors[fr.RiskLevel.String()])
 
 	riskBadge := riskBadgeStyle.
-		Foreground(riskColors[fr.RiskLevel]).
-		Render(fr.RiskLevel)
+		Foreground(riskColors[fr.RiskLevel.String()]).
+		Render(fr.RiskLevel.String())
 
 	header := lipgloss.JoinHorizontal(
 		lipgloss.Center,
@@ -241,7 +241,7 @@ func renderFileSummaryTea(ctx context.Context, fr *malcontent.FileReport, w io.W
 
 			// Style behavior based on risk level and diff status
 			baseStyle := behaviorStyle.
-				Foreground(riskColors[b.RiskLevel])
+				Foreground(riskColors[b.RiskLevel.String()])
 
 			bullet := "•"
 
@@ -261,8 +261,8 @@ func renderFileSummaryTea(ctx context.Context, fr *malcontent.FileReport, w io.W
 
 			// Add risk level badge to behavior
 			behaviorRisk := riskBadgeStyle.
-				Foreground(riskColors[b.RiskLevel]).
-				Render(ShortRisk(b.RiskLevel))
+				Foreground(riskColors[b.RiskLevel.String()]).
+				Render(ShortRisk(b.RiskLevel.String()))
 
 			content.WriteString(baseStyle.Render(fmt.Sprintf("%s %s %s %s",
 				bullet,
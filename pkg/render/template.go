@@ -0,0 +1,115 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+//
+// Template renderer: executes a user-supplied text/template against the full report.
+
+package render
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/template"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// templateData is the value templates are executed with.
+type templateData struct {
+	Config *malcontent.Config
+	Report *malcontent.Report
+	// Files holds the non-skipped file reports sorted by path, as templates cannot range over a sync.Map
+	Files []*malcontent.FileReport
+	// Stats is only populated when Config.Stats is set
+	Stats *Stats
+}
+
+var templateFuncs = template.FuncMap{
+	"riskEmoji": riskEmoji,
+	"shortHash": func(s string) string {
+		if len(s) > 12 {
+			return s[:12]
+		}
+		return s
+	},
+	"behaviorsByRisk": func(bs []*malcontent.Behavior) []*malcontent.Behavior {
+		sorted := make([]*malcontent.Behavior, len(bs))
+		copy(sorted, bs)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if sorted[i].RiskScore != sorted[j].RiskScore {
+				return sorted[i].RiskScore > sorted[j].RiskScore
+			}
+			return sorted[i].ID < sorted[j].ID
+		})
+		return sorted
+	},
+}
+
+type Template struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+// NewTemplate parses tmpl as a text/template and returns a renderer that executes it once per report.
+func NewTemplate(w io.Writer, tmpl string) (Template, error) {
+	t, err := template.New("report").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return Template{}, fmt.Errorf("parse template: %w", err)
+	}
+	return Template{w: w, tmpl: t}, nil
+}
+
+func (r Template) Name() string { return "Template" }
+
+func (r Template) Scanning(_ context.Context, _ string) {}
+
+func (r Template) File(_ context.Context, _ *malcontent.FileReport) error {
+	return nil
+}
+
+func (r Template) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	data := templateData{Config: c, Report: rep}
+
+	rep.Files.Range(func(key, value any) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+		if key == nil || value == nil {
+			return true
+		}
+		if fr, ok := value.(*malcontent.FileReport); ok {
+			if fr.Skipped == "" {
+				data.Files = append(data.Files, fr)
+			}
+		}
+		return true
+	})
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	sort.Slice(data.Files, func(i, j int) bool {
+		return data.Files[i].Path < data.Files[j].Path
+	})
+
+	if c != nil && c.Stats && rep.Diff == nil {
+		data.Stats = serializedStats(c, rep)
+	}
+
+	// Buffer the output so that a failing template does not leave partial results behind
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+
+	_, err := r.w.Write(buf.Bytes())
+	return err
+}
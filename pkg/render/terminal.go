@@ -89,7 +89,7 @@ func (r Terminal) File(ctx context.Context, fr *malcontent.FileReport) error {
 	if fr.Skipped == "" && len(fr.Behaviors) > 0 {
 		renderFileSummary(ctx, fr, r.w,
 			tableConfig{
-				Title: fmt.Sprintf("%s %s", fr.Path, darkBrackets(riskInColor(fr.RiskLevel))),
+				Title: fmt.Sprintf("%s %s", fr.Path, darkBrackets(riskInColor(fr.RiskLevel.String()))),
 			},
 		)
 	}
@@ -97,6 +97,7 @@ func (r Terminal) File(ctx context.Context, fr *malcontent.FileReport) error {
 }
 
 func (r Terminal) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -108,14 +109,14 @@ func (r Terminal) Full(ctx context.Context, _ *malcontent.Config, rep *malconten
 
 	for removed := rep.Diff.Removed.Oldest(); removed != nil; removed = removed.Next() {
 		renderFileSummary(ctx, removed.Value, r.w, tableConfig{
-			Title:       fmt.Sprintf("Deleted: %s %s", removed.Key, darkBrackets(riskInColor(removed.Value.RiskLevel))),
+			Title:       fmt.Sprintf("Deleted: %s %s", removed.Key, darkBrackets(riskInColor(removed.Value.RiskLevel.String()))),
 			DiffRemoved: true,
 		})
 	}
 
 	for added := rep.Diff.Added.Oldest(); added != nil; added = added.Next() {
 		renderFileSummary(ctx, added.Value, r.w, tableConfig{
-			Title:     fmt.Sprintf("Added: %s %s", added.Key, darkBrackets(riskInColor(added.Value.RiskLevel))),
+			Title:     fmt.Sprintf("Added: %s %s", added.Key, darkBrackets(riskInColor(added.Value.RiskLevel.String()))),
 			DiffAdded: true,
 		})
 	}
@@ -127,7 +128,7 @@ func (r Terminal) Full(ctx context.Context, _ *malcontent.Config, rep *malconten
 		}
 		if modified.Value.RiskScore != modified.Value.PreviousRiskScore {
 			title = fmt.Sprintf("%s %s", title,
-				darkBrackets(fmt.Sprintf("%s %s %s", riskInColor(modified.Value.PreviousRiskLevel), color.HiWhiteString("→"), riskInColor(modified.Value.RiskLevel))))
+				darkBrackets(fmt.Sprintf("%s %s %s", riskInColor(modified.Value.PreviousRiskLevel.String()), color.HiWhiteString("→"), riskInColor(modified.Value.RiskLevel.String()))))
 		}
 
 		renderFileSummary(ctx, modified.Value, r.w, tableConfig{Title: title})
@@ -277,6 +278,9 @@ func renderFileSummary(ctx context.Context, fr *malcontent.FileReport, w io.Writ
 	}
 
 	fmt.Fprintf(w, "├─ %s %s\n", riskEmoji(fr.RiskScore), rc.Title)
+	if len(fr.Pledge) > 0 {
+		fmt.Fprintf(w, "│     %s %s\n", color.HiBlackString("pledge:"), strings.Join(fr.Pledge, " "))
+	}
 
 	nss := []string{}
 	for ns := range byNamespace {
@@ -337,7 +341,7 @@ func renderFileSummary(ctx context.Context, fr *malcontent.FileReport, w io.Writ
 
 			bullet := riskEmoji(b.RiskScore)
 			diff := " "
-			content := fmt.Sprintf("%s%s%s %s", diff, indent, riskColor(b.RiskLevel, bullet+" "+rest), desc)
+			content := fmt.Sprintf("%s%s%s %s", diff, indent, riskColor(b.RiskLevel.String(), bullet+" "+rest), desc)
 			pc := color.New()
 
 			if diffMode {
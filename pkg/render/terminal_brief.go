@@ -47,7 +47,7 @@ func (r TerminalBrief) File(ctx context.Context, fr *malcontent.FileReport) erro
 	fmt.Fprintf(r.w, "├─ %s %s\n", riskEmoji(fr.RiskScore), fr.Path)
 
 	for _, b := range fr.Behaviors {
-		content := fmt.Sprintf("│     %s %s — %s", riskColor(fr.RiskLevel, "•"), riskColor(fr.RiskLevel, b.ID), b.Description)
+		content := fmt.Sprintf("│     %s %s — %s", riskColor(fr.RiskLevel.String(), "•"), riskColor(fr.RiskLevel.String(), b.ID), b.Description)
 		fmt.Fprint(r.w, content)
 
 		e := evidenceString(b.MatchStrings, b.Description)
@@ -74,6 +74,7 @@ func (r TerminalBrief) File(ctx context.Context, fr *malcontent.FileReport) erro
 }
 
 func (r TerminalBrief) Full(ctx context.Context, _ *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
@@ -29,35 +29,22 @@ func (r YAML) File(_ context.Context, _ *malcontent.FileReport) error {
 }
 
 func (r YAML) Full(ctx context.Context, c *malcontent.Config, rep *malcontent.Report) error {
+	ctx = fullContext(ctx, rep)
 	if ctx.Err() != nil {
 		return ctx.Err()
 	}
 
 	// Make the sync.Map YAML-friendly
 	yr := Report{
-		Diff:   rep.Diff,
-		Files:  make(map[string]*malcontent.FileReport),
-		Filter: "",
+		Correlations: rep.Correlations,
+		Diff:         rep.Diff,
+		Errors:       rep.Errors,
+		Files:        make(map[string]*malcontent.FileReport),
+		Filter:       "",
+		Interrupted:  rep.Interrupted,
 	}
 
-	rep.Files.Range(func(key, value any) bool {
-		if ctx.Err() != nil {
-			return false
-		}
-		if key == nil || value == nil {
-			return true
-		}
-		if path, ok := key.(string); ok {
-			if r, ok := value.(*malcontent.FileReport); ok {
-				if r.Skipped == "" {
-					r.ArchiveRoot = ""
-					r.FullPath = ""
-					yr.Files[path] = r
-				}
-			}
-		}
-		return true
-	})
+	yr.addFiles(ctx, c, rep)
 
 	if c != nil && c.Stats && yr.Diff == nil {
 		yr.Stats = serializedStats(c, rep)
@@ -0,0 +1,85 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// AllowHashes is a set of SHA256 hashes of known-good file content.
+type AllowHashes struct {
+	path   string
+	hashes map[string]struct{}
+}
+
+// LoadAllowHashes reads a file listing one SHA256 hash per line. Blank lines are ignored, as is
+// anything following a "#", so hashes may be annotated: "<sha256> # vetted libfoo 1.2".
+// An empty path returns nil, which allows nothing.
+func LoadAllowHashes(p string) (*AllowHashes, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read allowlist: %w", err)
+	}
+
+	ah := &AllowHashes{path: p, hashes: map[string]struct{}{}}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		text = strings.ToLower(strings.TrimSpace(text))
+		if text == "" {
+			continue
+		}
+		if b, err := hex.DecodeString(text); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("parse allowlist %s:%d: %q is not a SHA256 hash", p, line, text)
+		}
+		ah.hashes[text] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("parse allowlist %s: %w", p, err)
+	}
+
+	return ah, nil
+}
+
+type allowHashesKey struct{}
+
+// WithAllowHashes returns a context whose scans skip the content allowed by ah, as returned by
+// LoadAllowHashes, so that the allowlist is read once per scan rather than for every file.
+func WithAllowHashes(ctx context.Context, ah *AllowHashes) context.Context {
+	return context.WithValue(ctx, allowHashesKey{}, ah)
+}
+
+// AllowHashesFor returns the allowlist for c.AllowHashesFile, the one recorded by WithAllowHashes
+// if it was loaded from the same file, and otherwise read from it.
+func AllowHashesFor(ctx context.Context, c malcontent.Config) (*AllowHashes, error) {
+	if ah, ok := ctx.Value(allowHashesKey{}).(*AllowHashes); ok && ah != nil && ah.path == c.AllowHashesFile {
+		return ah, nil
+	}
+	return LoadAllowHashes(c.AllowHashesFile)
+}
+
+// Allows reports whether fc has one of the allowlisted hashes, returning its SHA256.
+// A nil AllowHashes allows nothing, without hashing fc.
+func (a *AllowHashes) Allows(fc []byte) (bool, string) {
+	if a == nil || len(a.hashes) == 0 {
+		return false, ""
+	}
+	sum := sha256.Sum256(fc)
+	h := hex.EncodeToString(sum[:])
+	_, ok := a.hashes[h]
+	return ok, h
+}
@@ -0,0 +1,90 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+func TestLoadAllowHashes(t *testing.T) {
+	t.Parallel()
+	vetted := []byte("#!/bin/sh\necho vetted\n")
+	sum := sha256.Sum256(vetted)
+	h := hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.txt")
+	content := "# vetted binaries\n\n" + strings.ToUpper(h) + "  # echo script\n"
+	if err := os.WriteFile(good, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ah, err := LoadAllowHashes(good)
+	if err != nil {
+		t.Fatalf("LoadAllowHashes: %v", err)
+	}
+	if ok, got := ah.Allows(vetted); !ok || got != h {
+		t.Errorf("Allows(vetted) = %v, %q, want true, %q", ok, got, h)
+	}
+	if ok, _ := ah.Allows([]byte("other")); ok {
+		t.Error("Allows(other) = true, want false")
+	}
+
+	bad := filepath.Join(dir, "bad.txt")
+	if err := os.WriteFile(bad, []byte(h+"\nnot-a-hash\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadAllowHashes(bad); err == nil || !strings.Contains(err.Error(), "bad.txt:2") {
+		t.Errorf("LoadAllowHashes(bad) error = %v, want one naming line 2", err)
+	}
+
+	if ah, err := LoadAllowHashes(""); ah != nil || err != nil {
+		t.Errorf("LoadAllowHashes(\"\") = %v, %v, want nil, nil", ah, err)
+	}
+}
+
+func TestAllowHashesFor(t *testing.T) {
+	t.Parallel()
+	vetted := []byte("#!/bin/sh\necho vetted\n")
+	sum := sha256.Sum256(vetted)
+
+	p := filepath.Join(t.TempDir(), "allow.txt")
+	if err := os.WriteFile(p, []byte(hex.EncodeToString(sum[:])+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := malcontent.Config{AllowHashesFile: p}
+
+	first, err := LoadAllowHashes(p)
+	if err != nil {
+		t.Fatalf("LoadAllowHashes: %v", err)
+	}
+	ctx := WithAllowHashes(context.Background(), first)
+
+	// Later scans see the file as it is now, while the scan that loaded it keeps its copy
+	if err := os.WriteFile(p, []byte("# nothing vetted\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := AllowHashesFor(ctx, c); err != nil || got != first {
+		t.Errorf("AllowHashesFor(scan) = %v, %v, want the allowlist recorded with WithAllowHashes", got, err)
+	}
+	later, err := AllowHashesFor(context.Background(), c)
+	if err != nil {
+		t.Fatalf("AllowHashesFor: %v", err)
+	}
+	if ok, _ := later.Allows(vetted); ok {
+		t.Error("AllowHashesFor(later scan) allows content removed from the file")
+	}
+
+	// An allowlist recorded for another file is not applied
+	if got, err := AllowHashesFor(ctx, malcontent.Config{}); got != nil || err != nil {
+		t.Errorf("AllowHashesFor(no allowlist) = %v, %v, want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// capabilityXattr is the extended attribute holding a file's capabilities.
+const capabilityXattr = "security.capability"
+
+// vfs_cap_data revisions and flags, from linux/capability.h.
+const (
+	vfsCapRevisionMask   = 0xFF000000
+	vfsCapRevision1      = 0x01000000
+	vfsCapRevision2      = 0x02000000
+	vfsCapRevision3      = 0x03000000
+	vfsCapFlagsEffective = 0x000001
+)
+
+// capabilityNames are the Linux capabilities, indexed by number.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner", "cap_fsetid",
+	"cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap", "cap_linux_immutable",
+	"cap_net_bind_service", "cap_net_broadcast", "cap_net_admin", "cap_net_raw", "cap_ipc_lock",
+	"cap_ipc_owner", "cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice", "cap_sys_resource",
+	"cap_sys_time", "cap_sys_tty_config", "cap_mknod", "cap_lease", "cap_audit_write",
+	"cap_audit_control", "cap_setfcap", "cap_mac_override", "cap_mac_admin", "cap_syslog",
+	"cap_wake_alarm", "cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// decodeCapabilities returns the capabilities in a security.capability attribute value, each in
+// setcap's form such as "cap_net_raw+ep": e if they are effective on exec, p if permitted,
+// and i if inheritable.
+func decodeCapabilities(b []byte) ([]string, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("capability attribute too short: %d bytes", len(b))
+	}
+	magic := binary.LittleEndian.Uint32(b)
+
+	words := 2
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		words = 1
+	case vfsCapRevision2, vfsCapRevision3:
+	default:
+		return nil, fmt.Errorf("unknown capability revision %#x", magic&vfsCapRevisionMask)
+	}
+	if len(b) < 4+words*8 {
+		return nil, fmt.Errorf("capability attribute too short: %d bytes", len(b))
+	}
+
+	var permitted, inheritable uint64
+	for i := range words {
+		permitted |= uint64(binary.LittleEndian.Uint32(b[4+i*8:])) << (32 * i)
+		inheritable |= uint64(binary.LittleEndian.Uint32(b[8+i*8:])) << (32 * i)
+	}
+
+	caps := make([]string, 0, bits.OnesCount64(permitted|inheritable))
+	for n := range 64 {
+		bit := uint64(1) << n
+		if (permitted|inheritable)&bit == 0 {
+			continue
+		}
+		flags := ""
+		if magic&vfsCapFlagsEffective != 0 {
+			flags += "e"
+		}
+		if inheritable&bit != 0 {
+			flags += "i"
+		}
+		if permitted&bit != 0 {
+			flags += "p"
+		}
+		name := fmt.Sprintf("cap_%d", n)
+		if n < len(capabilityNames) {
+			name = capabilityNames[n]
+		}
+		caps = append(caps, name+"+"+flags)
+	}
+	return caps, nil
+}
@@ -0,0 +1,26 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux
+
+package report
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// FileCapabilities returns the file capabilities set on path, such as "cap_net_raw+ep", or nil if it has none.
+func FileCapabilities(path string) ([]string, error) {
+	// A revision 3 attribute, the largest, is 24 bytes
+	buf := make([]byte, 64)
+	n, err := unix.Getxattr(path, capabilityXattr, buf)
+	if errors.Is(err, unix.ENODATA) || errors.Is(err, unix.ENOTSUP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeCapabilities(buf[:n])
+}
@@ -0,0 +1,11 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package report
+
+// FileCapabilities returns nil, as file capabilities are Linux-specific.
+func FileCapabilities(_ string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+// capabilityAttr returns a security.capability value of the given revision.
+func capabilityAttr(magic uint32, words ...uint32) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, magic)
+	for _, w := range words {
+		b = binary.LittleEndian.AppendUint32(b, w)
+	}
+	return b
+}
+
+func TestDecodeCapabilities(t *testing.T) {
+	t.Parallel()
+	netRaw := uint32(1) << 13
+	tests := []struct {
+		name    string
+		attr    []byte
+		want    []string
+		wantErr bool
+	}{
+		{"v2 effective", capabilityAttr(vfsCapRevision2|vfsCapFlagsEffective, netRaw, 0, 0, 0), []string{"cap_net_raw+ep"}, false},
+		{"v2 inheritable", capabilityAttr(vfsCapRevision2, 1<<7, 1<<7|1, 0, 0), []string{"cap_chown+i", "cap_setuid+ip"}, false},
+		{"v2 high word", capabilityAttr(vfsCapRevision2|vfsCapFlagsEffective, 0, 0, 1<<7, 0), []string{"cap_bpf+ep"}, false},
+		{"v3 with rootid", capabilityAttr(vfsCapRevision3|vfsCapFlagsEffective, 1<<21, 0, 0, 0, 1000), []string{"cap_sys_admin+ep"}, false},
+		{"v1", capabilityAttr(vfsCapRevision1, netRaw, 0), []string{"cap_net_raw+p"}, false},
+		{"unknown capability", capabilityAttr(vfsCapRevision2, 0, 0, 1<<30, 0), []string{"cap_62+p"}, false},
+		{"unknown revision", capabilityAttr(0x04000000, netRaw, 0, 0, 0), nil, true},
+		{"short", capabilityAttr(vfsCapRevision2, netRaw), nil, true},
+	}
+	for _, tt := range tests {
+		got, err := decodeCapabilities(tt.attr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: decodeCapabilities() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: decodeCapabilities() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// combinationMetaKey is the FileReport.Meta key naming the combination rule that escalated a file.
+const combinationMetaKey = "combination"
+
+// ValidateCombinationRules returns an error if a rule has no behaviors, an invalid glob, or a risk level outside LOW to CRITICAL.
+func ValidateCombinationRules(rules []malcontent.CombinationRule) error {
+	for i, cr := range rules {
+		if len(cr.Behaviors) == 0 {
+			return fmt.Errorf("combination rule %d: no behaviors", i)
+		}
+		for _, pattern := range cr.Behaviors {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("combination rule %d: %q: %w", i, pattern, err)
+			}
+		}
+		if _, ok := combinationRisk(cr); !ok {
+			return fmt.Errorf("combination rule %d: invalid risk level %s", i, cr.RiskLevel)
+		}
+	}
+	return nil
+}
+
+// combinationRisk returns the risk score cr escalates to, and whether it is from LOW to CRITICAL.
+func combinationRisk(cr malcontent.CombinationRule) (int, bool) {
+	return int(cr.RiskLevel), cr.RiskLevel.AtLeast(malcontent.RiskLow) && malcontent.RiskCritical.AtLeast(cr.RiskLevel)
+}
+
+// combinationName returns the name recorded for cr in FileReport.Meta.
+func combinationName(cr malcontent.CombinationRule) string {
+	if cr.Name != "" {
+		return cr.Name
+	}
+	return strings.Join(cr.Behaviors, " + ")
+}
+
+// escalatedRisk returns riskScore raised to the highest level of the rules whose behaviors are all
+// present in fr, recording the rule responsible in fr.Meta.
+func escalatedRisk(fr *malcontent.FileReport, riskScore int, rules []malcontent.CombinationRule) int {
+	for _, cr := range rules {
+		risk, ok := combinationRisk(cr)
+		if !ok || risk <= riskScore {
+			continue
+		}
+		present := func(pattern string) bool {
+			return slices.ContainsFunc(fr.Behaviors, func(b *malcontent.Behavior) bool {
+				matched, err := path.Match(pattern, b.ID)
+				return err == nil && matched
+			})
+		}
+		if !slices.ContainsFunc(cr.Behaviors, func(p string) bool { return !present(p) }) {
+			riskScore = risk
+			fr.Meta[combinationMetaKey] = combinationName(cr)
+		}
+	}
+	return riskScore
+}
@@ -0,0 +1,33 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// Fingerprint returns a stable identifier for the finding of behavior b in the file at path, so that trackers
+// can recognize the same finding across scans. path should not depend on where the scanned tree is, such as a
+// path relative to the scan root.
+//
+// The fingerprint is the hex-encoded SHA256 of path with forward slashes, b.ID, and the distinct b.MatchStrings
+// in byte order, each followed by a NUL byte. Absolute paths, line numbers and risk do not contribute, so
+// moving a tree, editing around a match or re-scoring a rule keep the fingerprint.
+func Fingerprint(path string, b *malcontent.Behavior) string {
+	ms := slices.Clone(b.MatchStrings)
+	slices.Sort(ms)
+	ms = slices.Compact(ms)
+
+	h := sha256.New()
+	for _, s := range append([]string{filepath.ToSlash(path), b.ID}, ms...) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
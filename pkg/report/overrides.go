@@ -0,0 +1,187 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"gopkg.in/yaml.v3"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// dropRisk is the override value that removes a behavior from reports.
+const dropRisk = "drop"
+
+// RiskOverrides reassigns the risk of behaviors based on a user-supplied file.
+type RiskOverrides struct {
+	path   string
+	digest string
+	// exact entries are keyed by rule name or behavior ID
+	exact map[string]riskOverride
+	// globs are ordered from most to least specific
+	globs []riskOverride
+}
+
+// riskOverride is a single entry of an overrides file.
+type riskOverride struct {
+	pattern string
+	risk    int
+	drop    bool
+}
+
+// LoadOverrides reads a YAML or JSON file mapping rule names, behavior IDs, or
+// path.Match globs over behavior IDs to a risk level (e.g. "low", "high", 3) or "drop".
+//
+// When several entries apply to a rule, an exact rule name wins over an exact
+// behavior ID, which wins over globs; among globs, the longest pattern wins.
+// An empty path returns nil, which applies no overrides.
+func LoadOverrides(p string) (*RiskOverrides, error) {
+	if p == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read overrides: %w", err)
+	}
+
+	// JSON is a subset of YAML, so one decoder handles both
+	raw := map[string]string{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse overrides %s: %w", p, err)
+	}
+
+	sum := sha256.Sum256(data)
+	ro := &RiskOverrides{
+		path:   p,
+		digest: hex.EncodeToString(sum[:]),
+		exact:  make(map[string]riskOverride, len(raw)),
+	}
+
+	for pattern, v := range raw {
+		o, err := parseOverride(pattern, v)
+		if err != nil {
+			return nil, fmt.Errorf("parse overrides %s: %w", p, err)
+		}
+		if strings.ContainsAny(pattern, `*?[\`) {
+			ro.globs = append(ro.globs, o)
+			continue
+		}
+		ro.exact[pattern] = o
+	}
+
+	slices.SortFunc(ro.globs, func(a, b riskOverride) int {
+		if len(a.pattern) != len(b.pattern) {
+			return len(b.pattern) - len(a.pattern)
+		}
+		return strings.Compare(a.pattern, b.pattern)
+	})
+
+	return ro, nil
+}
+
+type overridesKey struct{}
+
+// WithOverrides returns a context whose reports apply ro, as returned by LoadOverrides,
+// so that the overrides file is read once per scan rather than for every report.
+func WithOverrides(ctx context.Context, ro *RiskOverrides) context.Context {
+	return context.WithValue(ctx, overridesKey{}, ro)
+}
+
+// OverridesFor returns the overrides for c.OverridesFile, those recorded by WithOverrides
+// if they were loaded from the same file, and otherwise read from it.
+func OverridesFor(ctx context.Context, c malcontent.Config) (*RiskOverrides, error) {
+	if ro, ok := ctx.Value(overridesKey{}).(*RiskOverrides); ok && ro != nil && ro.path == c.OverridesFile {
+		return ro, nil
+	}
+	return LoadOverrides(c.OverridesFile)
+}
+
+// parseOverride parses the risk assigned to pattern.
+func parseOverride(pattern string, v string) (riskOverride, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return riskOverride{}, fmt.Errorf("%q: %w", pattern, err)
+	}
+
+	v = strings.ToLower(strings.TrimSpace(v))
+	if v == dropRisk {
+		return riskOverride{pattern: pattern, drop: true}, nil
+	}
+	if risk, ok := Levels[v]; ok {
+		return riskOverride{pattern: pattern, risk: risk}, nil
+	}
+	if risk, err := strconv.Atoi(v); err == nil && risk >= 0 && risk <= CRITICAL {
+		return riskOverride{pattern: pattern, risk: risk}, nil
+	}
+	return riskOverride{}, fmt.Errorf("%q: unknown risk level %q", pattern, v)
+}
+
+// Digest returns the SHA256 of the overrides file, or "" if o is nil.
+func (o *RiskOverrides) Digest() string {
+	if o == nil {
+		return ""
+	}
+	return o.digest
+}
+
+// lookup returns the override entry that applies to a rule, if any.
+func (o *RiskOverrides) lookup(rule string, id string) (riskOverride, bool) {
+	if o == nil {
+		return riskOverride{}, false
+	}
+
+	if ro, ok := o.exact[rule]; ok {
+		return ro, true
+	}
+	if ro, ok := o.exact[id]; ok {
+		return ro, true
+	}
+	for _, ro := range o.globs {
+		if ok, _ := path.Match(ro.pattern, id); ok {
+			return ro, true
+		}
+	}
+	return riskOverride{}, false
+}
+
+// behavior returns the entry recorded in FileReport.Overrides when ro is applied to rule.
+func (o *RiskOverrides) behavior(ro riskOverride, rule string) *malcontent.Behavior {
+	return &malcontent.Behavior{
+		Description: fmt.Sprintf("risk override from %s", o.path),
+		ID:          ro.pattern,
+		RiskLevel:   malcontent.RiskLevel(ro.risk),
+		RiskScore:   ro.risk,
+		Override:    []string{rule},
+	}
+}
+
+// highestMatchRisk is HighestMatchRisk with overrides applied.
+func (o *RiskOverrides) highestMatchRisk(mrs *yarax.ScanResults) int {
+	if o == nil {
+		return HighestMatchRisk(mrs)
+	}
+
+	var highestRisk int
+	for _, m := range mrs.MatchingRules() {
+		risk := behaviorRisk(m.Namespace(), m.Identifier(), m.Tags())
+		if ro, ok := o.lookup(m.Identifier(), generateKey(m.Namespace(), m.Identifier())); ok {
+			if ro.drop {
+				continue
+			}
+			risk = ro.risk
+		}
+		highestRisk = max(highestRisk, risk)
+	}
+	return highestRisk
+}
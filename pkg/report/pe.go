@@ -0,0 +1,258 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// peMagic starts every PE file, within its DOS stub.
+var peMagic = []byte("MZ")
+
+// maxResourceEntries caps the resource directory entries read from a PE file, as the entries of
+// malformed files can point back at their own directories.
+const maxResourceEntries = 16384
+
+// resourceTypes names the predefined PE resource types by ID.
+var resourceTypes = map[uint32]string{
+	1: "CURSOR", 2: "BITMAP", 3: "ICON", 4: "MENU", 5: "DIALOG", 6: "STRING", 7: "FONTDIR",
+	8: "FONT", 9: "ACCELERATOR", 10: "RCDATA", 11: "MESSAGETABLE", 12: "GROUP_CURSOR",
+	14: "GROUP_ICON", 16: "VERSION", 17: "DLGINCLUDE", 19: "PLUGPLAY", 20: "VXD",
+	21: "ANICURSOR", 22: "ANIICON", 23: "HTML", 24: "MANIFEST",
+}
+
+// peRegion is a named range of the content of a PE file, such as "section/.text",
+// "resource/RCDATA/101" or "overlay", the data appended after the last section.
+type peRegion struct {
+	name  string
+	start int
+	end   int
+}
+
+// peRegions are the regions of a PE file, most specific first: resources, which lie within a section,
+// then sections, then the overlay.
+type peRegions []peRegion
+
+// carvePE returns the regions of fc, or nil if it is not a PE file.
+func carvePE(fc []byte) peRegions {
+	if !bytes.HasPrefix(fc, peMagic) {
+		return nil
+	}
+	f, err := pe.NewFile(bytes.NewReader(fc))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	sections := make(peRegions, 0, len(f.Sections))
+	last := 0
+	for _, s := range f.Sections {
+		start := int(s.Offset)
+		end := min(start+int(s.Size), len(fc))
+		if s.Size == 0 || start <= 0 || start >= end {
+			continue
+		}
+		sections = append(sections, peRegion{name: "section/" + s.Name, start: start, end: end})
+		last = max(last, end)
+	}
+
+	regions := peResources(f, fc)
+	regions = append(regions, sections...)
+	if last > 0 && last < len(fc) {
+		regions = append(regions, peRegion{name: "overlay", start: last, end: len(fc)})
+	}
+	return regions
+}
+
+// carvedPE returns carvePE(fc) if c.DeepPE is set.
+func carvedPE(c malcontent.Config, fc []byte) peRegions {
+	if !c.DeepPE {
+		return nil
+	}
+	return carvePE(fc)
+}
+
+// locate returns the name of the most specific region holding offset, or "" if none does.
+func (rs peRegions) locate(offset int) string {
+	for _, r := range rs {
+		if offset >= r.start && offset < r.end {
+			return r.name
+		}
+	}
+	return ""
+}
+
+// process runs mp over the matches within each region of rs as a separate unit, returning the strings of
+// every match and the sorted names of the regions they were found in. Matches outside every region, such as
+// in the headers, are processed without being attributed to one.
+func (rs peRegions) process(ctx context.Context, mp *matchProcessor) ([]string, []string, error) {
+	byRegion := map[string][]yarax.Match{}
+	var order []string
+	for _, m := range mp.matches {
+		name := ""
+		if o := m.Offset(); o <= math.MaxInt {
+			name = rs.locate(int(o))
+		}
+		if _, ok := byRegion[name]; !ok {
+			order = append(order, name)
+		}
+		byRegion[name] = append(byRegion[name], m)
+	}
+
+	// Strings are limited once all regions are processed, as the same string may be found in several
+	maxStrings := mp.maxStrings
+	mp.maxStrings = 0
+
+	var strs, names []string
+	for _, name := range order {
+		mp.matches = byRegion[name]
+		s, err := mp.process(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		strs = append(strs, s...)
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	mp.maxStrings = maxStrings
+	slices.Sort(names)
+	return mp.limit(strs), names, nil
+}
+
+// peResources returns the resources of f, whose content is fc, named by their type and name or ID.
+func peResources(f *pe.File, fc []byte) peRegions {
+	var dir pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	case *pe.OptionalHeader64:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	}
+	if dir.Size == 0 {
+		return nil
+	}
+	base, ok := rvaOffset(f, dir.VirtualAddress)
+	if !ok {
+		return nil
+	}
+
+	w := &resourceWalker{f: f, fc: fc, base: base}
+	w.walk(0, nil)
+	return w.regions
+}
+
+// resourceWalker reads the resource directory tree of a PE file, which starts at base within fc.
+type resourceWalker struct {
+	f       *pe.File
+	fc      []byte
+	base    int
+	entries int
+	regions peRegions
+}
+
+// walk reads the directory at off, relative to the start of the tree, below the resource types
+// and names in path. The tree has three levels: type, name and language.
+func (w *resourceWalker) walk(off int, path []string) {
+	hdr, ok := w.read(off, 16)
+	if !ok || len(path) > 2 {
+		return
+	}
+	n := int(binary.LittleEndian.Uint16(hdr[12:])) + int(binary.LittleEndian.Uint16(hdr[14:]))
+	for i := range n {
+		w.entries++
+		e, ok := w.read(off+16+8*i, 8)
+		if !ok || w.entries > maxResourceEntries {
+			return
+		}
+		name := w.name(binary.LittleEndian.Uint32(e), len(path))
+		data := binary.LittleEndian.Uint32(e[4:])
+		if data&0x80000000 != 0 {
+			w.walk(int(data&0x7fffffff), append(slices.Clone(path), name))
+			continue
+		}
+		w.leaf(int(data), append(slices.Clone(path), name))
+	}
+}
+
+// leaf adds the resource described by the data entry at off, named by its type and name in path.
+func (w *resourceWalker) leaf(off int, path []string) {
+	e, ok := w.read(off, 16)
+	if !ok {
+		return
+	}
+	start, ok := rvaOffset(w.f, binary.LittleEndian.Uint32(e))
+	size := int(binary.LittleEndian.Uint32(e[4:]))
+	if !ok || size == 0 || start+size > len(w.fc) {
+		return
+	}
+	name := "resource/" + strings.Join(path[:min(len(path), 2)], "/")
+	w.regions = append(w.regions, peRegion{name: name, start: start, end: start + size})
+}
+
+// name returns the name of a directory entry at the given depth of the tree: its UTF-16 name if it has one,
+// otherwise the name of a predefined resource type or its numeric ID.
+func (w *resourceWalker) name(id uint32, depth int) string {
+	if id&0x80000000 == 0 {
+		if t, ok := resourceTypes[id]; ok && depth == 0 {
+			return t
+		}
+		return strconv.FormatUint(uint64(id), 10)
+	}
+
+	off := int(id & 0x7fffffff)
+	hdr, ok := w.read(off, 2)
+	if !ok {
+		return "?"
+	}
+	n := int(binary.LittleEndian.Uint16(hdr))
+	s, ok := w.read(off+2, 2*n)
+	if !ok {
+		return "?"
+	}
+	u := make([]uint16, n)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(s[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// read returns n bytes at off, relative to the start of the tree, if they are within the file.
+func (w *resourceWalker) read(off int, n int) ([]byte, bool) {
+	start := w.base + off
+	if off < 0 || start+n > len(w.fc) {
+		return nil, false
+	}
+	return w.fc[start : start+n], true
+}
+
+// rvaOffset returns the file offset of the relative virtual address rva of f, if it lies within the data
+// of a section.
+func rvaOffset(f *pe.File, rva uint32) (int, bool) {
+	for _, s := range f.Sections {
+		if rva < s.VirtualAddress {
+			continue
+		}
+		if off := rva - s.VirtualAddress; off < s.Size {
+			return int(s.Offset + off), true
+		}
+	}
+	return 0, false
+}
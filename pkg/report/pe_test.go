@@ -0,0 +1,168 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"debug/pe"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+const (
+	plantedCode    = "GetProcAddress VirtualAlloc"
+	plantedPayload = "planted-c2.example.net"
+	plantedOverlay = "overlay-config"
+)
+
+// testPE returns a 32-bit PE file with a .text section holding plantedCode, a .rsrc section holding
+// plantedPayload as RCDATA resource 101, and plantedOverlay appended after the last section.
+func testPE(t *testing.T) []byte {
+	t.Helper()
+
+	// The resource tree: one directory per level, down to a data entry describing the payload at 0x60
+	rsrc := make([]byte, 0x200)
+	le := binary.LittleEndian
+	le.PutUint16(rsrc[0x0e:], 1)
+	le.PutUint32(rsrc[0x10:], 10)
+	le.PutUint32(rsrc[0x14:], 0x80000000|0x18)
+	le.PutUint16(rsrc[0x18+0x0e:], 1)
+	le.PutUint32(rsrc[0x18+0x10:], 101)
+	le.PutUint32(rsrc[0x18+0x14:], 0x80000000|0x30)
+	le.PutUint16(rsrc[0x30+0x0e:], 1)
+	le.PutUint32(rsrc[0x30+0x10:], 1033)
+	le.PutUint32(rsrc[0x30+0x14:], 0x48)
+	le.PutUint32(rsrc[0x48:], 0x2000+0x60)
+	le.PutUint32(rsrc[0x4c:], uint32(len(plantedPayload)))
+	copy(rsrc[0x60:], plantedPayload)
+
+	text := make([]byte, 0x200)
+	copy(text[0x10:], plantedCode)
+
+	oh := pe.OptionalHeader32{
+		Magic:               0x10b,
+		AddressOfEntryPoint: 0x1000,
+		ImageBase:           0x400000,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		SizeOfImage:         0x3000,
+		SizeOfHeaders:       0x200,
+		Subsystem:           2,
+		NumberOfRvaAndSizes: 16,
+	}
+	oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE] = pe.DataDirectory{VirtualAddress: 0x2000, Size: 0x200}
+
+	sections := []pe.SectionHeader32{
+		{Name: [8]uint8{'.', 't', 'e', 'x', 't'}, VirtualSize: 0x200, VirtualAddress: 0x1000, SizeOfRawData: 0x200, PointerToRawData: 0x200},
+		{Name: [8]uint8{'.', 'r', 's', 'r', 'c'}, VirtualSize: 0x200, VirtualAddress: 0x2000, SizeOfRawData: 0x200, PointerToRawData: 0x400},
+	}
+
+	var b bytes.Buffer
+	dos := make([]byte, 0x40)
+	copy(dos, "MZ")
+	le.PutUint32(dos[0x3c:], 0x40)
+	b.Write(dos)
+	b.WriteString("PE\x00\x00")
+	for _, v := range []any{
+		pe.FileHeader{Machine: pe.IMAGE_FILE_MACHINE_I386, NumberOfSections: uint16(len(sections)), SizeOfOptionalHeader: uint16(binary.Size(oh)), Characteristics: 0x0102},
+		oh,
+		sections,
+	} {
+		if err := binary.Write(&b, le, v); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	b.Write(make([]byte, 0x200-b.Len()))
+	b.Write(text)
+	b.Write(rsrc)
+	b.WriteString(plantedOverlay)
+	return b.Bytes()
+}
+
+func TestCarvePE(t *testing.T) {
+	t.Parallel()
+	fc := testPE(t)
+
+	rs := carvePE(fc)
+	names := make([]string, 0, len(rs))
+	for _, r := range rs {
+		names = append(names, r.name)
+	}
+	if want := []string{"resource/RCDATA/101", "section/.text", "section/.rsrc", "overlay"}; !slices.Equal(names, want) {
+		t.Fatalf("carvePE() regions = %q, want %q", names, want)
+	}
+
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{bytes.Index(fc, []byte(plantedPayload)), "resource/RCDATA/101"},
+		{bytes.Index(fc, []byte(plantedCode)), "section/.text"},
+		{0x400, "section/.rsrc"},
+		{bytes.Index(fc, []byte(plantedOverlay)), "overlay"},
+		{0, ""},
+	}
+	for _, tt := range tests {
+		if got := rs.locate(tt.offset); got != tt.want {
+			t.Errorf("locate(%#x) = %q, want %q", tt.offset, got, tt.want)
+		}
+	}
+}
+
+func TestCarvePENotPE(t *testing.T) {
+	t.Parallel()
+	truncated := testPE(t)[:0x100]
+	for _, fc := range [][]byte{nil, []byte("#!/bin/sh\necho MZ\n"), []byte("MZ truncated"), truncated} {
+		if got := carvePE(fc); got != nil {
+			t.Errorf("carvePE(%.16q) = %v, want nil", fc, got)
+		}
+	}
+}
+
+func TestDeepPE(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{"test/c2.yara": `
+rule c2_domain : high {
+	strings:
+		$domain = "planted-c2.example.net"
+		$alloc = "VirtualAlloc"
+	condition:
+		all of them
+}
+`})
+
+	fc := testPE(t)
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	for _, deep := range []bool{false, true} {
+		fr, err := Generate(ctx, "dropper.exe", mrs, malcontent.Config{DeepPE: deep}, "", nil, fc, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(fr.Behaviors) != 1 {
+			t.Fatalf("DeepPE=%v: got %d behaviors, want 1", deep, len(fr.Behaviors))
+		}
+
+		b := fr.Behaviors[0]
+		if want := []string{"VirtualAlloc", plantedPayload}; !slices.Equal(slices.Sorted(slices.Values(b.MatchStrings)), want) {
+			t.Errorf("DeepPE=%v: match strings = %q, want %q", deep, b.MatchStrings, want)
+		}
+		var want []string
+		if deep {
+			want = []string{"resource/RCDATA/101", "section/.text"}
+		}
+		if !slices.Equal(b.Regions, want) {
+			t.Errorf("DeepPE=%v: regions = %q, want %q", deep, b.Regions, want)
+		}
+	}
+}
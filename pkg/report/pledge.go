@@ -0,0 +1,98 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bytes"
+	"debug/elf"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// pledgePromises are the promises accepted by OpenBSD's pledge(2).
+var pledgePromises = map[string]bool{
+	"audio": true, "bpf": true, "chown": true, "cpath": true, "disklabel": true, "dns": true,
+	"dpath": true, "drm": true, "error": true, "exec": true, "fattr": true, "flock": true,
+	"getpw": true, "id": true, "inet": true, "mcast": true, "pf": true, "proc": true,
+	"prot_exec": true, "ps": true, "recvfd": true, "route": true, "rpath": true, "sendfd": true,
+	"settime": true, "stdio": true, "tape": true, "tmppath": true, "tty": true, "unix": true,
+	"unveil": true, "video": true, "vminfo": true, "vmm": true, "wpath": true, "wroute": true,
+}
+
+// OpenBSDPledges returns the union of the promises an OpenBSD ELF binary passes to pledge(2),
+// found as string constants in its read-only data. Only strings made up entirely of promises
+// and naming "stdio" or at least two promises are counted, so that lone words such as "error"
+// elsewhere in the binary are not mistaken for promises. It returns nil for other content,
+// and for binaries that do not call pledge.
+func OpenBSDPledges(fc []byte) []string {
+	if !bytes.HasPrefix(fc, elfMagic) {
+		return nil
+	}
+	f, err := elf.NewFile(bytes.NewReader(fc))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	if f.OSABI != elf.ELFOSABI_OPENBSD && f.Section(".note.openbsd.ident") == nil {
+		return nil
+	}
+	if !callsPledge(f) {
+		return nil
+	}
+
+	var promises []string
+	for _, s := range f.Sections {
+		if s.Type != elf.SHT_PROGBITS || s.Flags&elf.SHF_ALLOC == 0 || s.Flags&(elf.SHF_WRITE|elf.SHF_EXECINSTR) != 0 {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			continue
+		}
+		for str := range bytes.SplitSeq(data, []byte{0}) {
+			promises = append(promises, pledgeString(string(str))...)
+		}
+	}
+
+	slices.Sort(promises)
+	return slices.Compact(promises)
+}
+
+// callsPledge reports whether f imports or defines the pledge function.
+func callsPledge(f *elf.File) bool {
+	isPledge := func(name string) bool {
+		name, _, _ = strings.Cut(name, "@")
+		return name == "pledge"
+	}
+	if imports, err := f.ImportedSymbols(); err == nil && slices.ContainsFunc(imports, func(s elf.ImportedSymbol) bool { return isPledge(s.Name) }) {
+		return true
+	}
+	symbols, err := f.Symbols()
+	return err == nil && slices.ContainsFunc(symbols, func(s elf.Symbol) bool { return isPledge(s.Name) })
+}
+
+// pledgeString returns the promises in s if it looks like a pledge(2) promise string, such as "stdio rpath inet".
+func pledgeString(s string) []string {
+	words := strings.Fields(s)
+	if len(words) == 0 || (len(words) == 1 && words[0] != "stdio") {
+		return nil
+	}
+	for _, w := range words {
+		if !pledgePromises[w] {
+			return nil
+		}
+	}
+	return words
+}
+
+// extractedPledges returns OpenBSDPledges(fc) if c.ExtractSyscalls is set.
+func extractedPledges(c malcontent.Config, fc []byte) []string {
+	if !c.ExtractSyscalls {
+		return nil
+	}
+	return OpenBSDPledges(fc)
+}
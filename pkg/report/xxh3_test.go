@@ -0,0 +1,99 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package report
+
+import (
+	"context"
+	"testing"
+)
+
+func TestXXH3(t *testing.T) {
+	t.Parallel()
+	// Reference values from the xxHash implementation, covering each input size class
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "2d06800538d394c2"},
+		{1, "13e608bc156defed"},
+		{2, "1c9074b93943b86c"},
+		{3, "a9088dda485b481c"},
+		{4, "6d9253b16c8b1ed3"},
+		{7, "8e8291ad89127e2e"},
+		{8, "60539db630471163"},
+		{9, "feff668361d723a8"},
+		{16, "b8c859b0f030b585"},
+		{17, "714a04408e79b80f"},
+		{32, "19ff4ee1d6ba1a55"},
+		{33, "3e44983ad21679c8"},
+		{64, "38bcde5122f74956"},
+		{65, "95a166c5957453d9"},
+		{96, "75d654bdaee123df"},
+		{97, "1296f9e2421ab74c"},
+		{128, "4634ae6a253a60e4"},
+		{129, "c095b9b1b087722d"},
+		{200, "a369f2930049476f"},
+		{240, "887af00281f75d38"},
+		{241, "82b1de299f6e411e"},
+		{255, "2e1a63db352fa1ea"},
+		{1024, "f75e768c7cdd54b2"},
+		{1025, "667a5eabe344e5df"},
+		{2048, "9e5e4a8160109a5d"},
+		{2049, "242c61fe5ed5db14"},
+		{100000, "d449c3d3e190d6d6"},
+	}
+	for _, tt := range tests {
+		b := make([]byte, tt.n)
+		for i := range b {
+			b[i] = byte((i*7 + 3) % 251)
+		}
+		if got := Checksum(b, HashXXH3); got != tt.want {
+			t.Errorf("Checksum(%d bytes, xxh3) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkChecksum(b *testing.B) {
+	fc := make([]byte, 1<<20)
+	for i := range fc {
+		fc[i] = byte(i)
+	}
+	for _, algo := range []string{HashSHA256, HashXXH3} {
+		b.Run(algo, func(b *testing.B) {
+			b.SetBytes(int64(len(fc)))
+			for b.Loop() {
+				sizeAndChecksum(context.Background(), fc, algo)
+			}
+		})
+	}
+}
+
+func TestChecksumFor(t *testing.T) {
+	t.Parallel()
+	fc := []byte("#!/bin/sh\necho hello\n")
+	// A recorded checksum is trusted, so a fake one shows when it is reused
+	ctx := WithChecksum(context.Background(), fc, "", "recorded")
+
+	tests := []struct {
+		name string
+		fc   []byte
+		algo string
+		want string
+	}{
+		{"same content", fc, HashSHA256, "recorded"},
+		{"default algorithm", fc, "", "recorded"},
+		{"other algorithm", fc, HashXXH3, Checksum(fc, HashXXH3)},
+		{"copy", append([]byte(nil), fc...), HashSHA256, Checksum(fc, HashSHA256)},
+		{"prefix", fc[:4], HashSHA256, Checksum(fc[:4], HashSHA256)},
+	}
+	for _, tt := range tests {
+		if got := ChecksumFor(ctx, tt.fc, tt.algo); got != tt.want {
+			t.Errorf("%s: ChecksumFor() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got, want := ChecksumFor(context.Background(), fc, ""), Checksum(fc, ""); got != want {
+		t.Errorf("ChecksumFor() without a recorded checksum = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package scan provides a reusable Scanner for programs that embed malcontent.
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"slices"
+
+	"github.com/chainguard-dev/malcontent/pkg/action"
+	"github.com/chainguard-dev/malcontent/pkg/compile"
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/pool"
+	"github.com/chainguard-dev/malcontent/pkg/report"
+	"github.com/chainguard-dev/malcontent/rules"
+	thirdparty "github.com/chainguard-dev/malcontent/third_party"
+
+	yarax "github.com/VirusTotal/yara-x/go"
+)
+
+// Options configure a Scanner.
+type Options struct {
+	// Config holds the report settings applied to every scan, such as MinRisk and IgnoreTags.
+	// Settings that only apply to walking scan paths or rendering are ignored.
+	Config malcontent.Config
+	// RuleFS are the rule sources to compile, defaulting to the built-in and third-party rules.
+	// Config.ExtraRulePaths are compiled with them, and Config.RuleFilter is applied to both. Ignored when Rules is set.
+	RuleFS []fs.FS
+	// Rules, if set, are used instead of compiling RuleFS. The caller keeps ownership of them.
+	Rules *yarax.Rules
+}
+
+// Scanner scans files and content against a compiled ruleset.
+// It is safe for concurrent use; call Close once it is no longer needed.
+type Scanner struct {
+	c          malcontent.Config
+	rules      *yarax.Rules
+	ruleErrors []malcontent.RuleCompileError
+	overrides  *report.RiskOverrides
+	allow      *report.AllowHashes
+	owned      bool
+	scanners   *pool.ScannerPool
+}
+
+// NewScanner compiles the rules described by opts and returns a Scanner that uses them.
+func NewScanner(ctx context.Context, opts Options) (*Scanner, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// Read the overrides file and allowlist up front rather than on every scan
+	overrides, err := report.LoadOverrides(opts.Config.OverridesFile)
+	if err != nil {
+		return nil, err
+	}
+	allow, err := report.LoadAllowHashes(opts.Config.AllowHashesFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := report.ValidateHashAlgo(opts.Config.HashAlgo); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRedactMatches(opts.Config.RedactMatches); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateCombinationRules(opts.Config.CombinationRules); err != nil {
+		return nil, err
+	}
+	if err := report.ValidateRuleIDPatterns(opts.Config); err != nil {
+		return nil, err
+	}
+
+	s := &Scanner{c: opts.Config, rules: opts.Rules, overrides: overrides, allow: allow}
+	if s.rules == nil {
+		rfs := opts.RuleFS
+		if len(rfs) == 0 {
+			rfs = []fs.FS{rules.FS, thirdparty.FS}
+		}
+		extra, err := compile.Dirs(opts.Config.ExtraRulePaths, opts.Config.StrictRules)
+		if err != nil {
+			return nil, err
+		}
+		rfs, err = compile.Filter(append(slices.Clone(rfs), extra...), opts.Config.RuleFilter)
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		yrs, ruleErrors, err := compile.RecursiveWithErrors(ctx, rfs)
+		if err != nil {
+			return nil, fmt.Errorf("compile: %w", err)
+		}
+		s.rules = yrs
+		s.ruleErrors = ruleErrors
+		s.c.RuleFS = rfs
+		s.owned = true
+	}
+
+	s.c.Rules = s.rules
+	s.c.Concurrency = max(1, s.c.Concurrency)
+	s.scanners = pool.NewScannerPool(s.rules, runtime.GOMAXPROCS(0))
+
+	return s, nil
+}
+
+// Rules returns the compiled rules used by the Scanner.
+func (s *Scanner) Rules() *yarax.Rules {
+	return s.rules
+}
+
+// RuleErrors returns the user rule files from Options.Config.ExtraRulePaths that were skipped because they failed to compile.
+func (s *Scanner) RuleErrors() []malcontent.RuleCompileError {
+	return s.ruleErrors
+}
+
+// ScanFile scans the file at path. The report is nil if the file is excluded by the file type filters in Options.Config.
+func (s *Scanner) ScanFile(ctx context.Context, path string) (*malcontent.FileReport, error) {
+	fc, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read: %w", err)
+	}
+	return s.ScanBytes(ctx, path, fc)
+}
+
+// ScanBytes scans b as though it were the content of a file named name.
+// The report is nil if the content is excluded by the file type filters in Options.Config.
+func (s *Scanner) ScanBytes(ctx context.Context, name string, b []byte) (*malcontent.FileReport, error) {
+	ctx = report.WithOverrides(ctx, s.overrides)
+	ctx = report.WithAllowHashes(ctx, s.allow)
+	return action.ScanBytes(ctx, s.c, s.scanners, name, b)
+}
+
+// Close releases the compiled rules, unless they were provided through Options.Rules.
+// The Scanner must not be used after Close.
+func (s *Scanner) Close() {
+	if s.owned && s.rules != nil {
+		s.rules.Destroy()
+	}
+	s.rules = nil
+}
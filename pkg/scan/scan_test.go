@@ -0,0 +1,146 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+)
+
+var testRules = fstest.MapFS{
+	"test/fetch.yara": &fstest.MapFile{Data: []byte(`
+rule fetch : high {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`)},
+}
+
+func TestScanner(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	s, err := NewScanner(ctx, Options{RuleFS: []fs.FS{testRules}})
+	if err != nil {
+		t.Fatalf("NewScanner: %v", err)
+	}
+	defer s.Close()
+
+	p := filepath.Join(t.TempDir(), "fetch.sh")
+	if err := os.WriteFile(p, []byte("#!/bin/sh\ncurl -O https://example.com/x\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fr, err := s.ScanFile(ctx, p)
+	if err != nil {
+		t.Fatalf("ScanFile: %v", err)
+	}
+	if fr.Path != p || len(fr.Behaviors) != 1 || fr.Behaviors[0].ID != "test/fetch" {
+		t.Errorf("ScanFile(%s) = %+v, want a single test/fetch behavior", p, fr)
+	}
+
+	// Scanners are shared by concurrent callers, which must each see their own results
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("script%d.sh", i)
+			content := "#!/bin/sh\necho hello\n"
+			if i%2 == 0 {
+				content = "#!/bin/sh\ncurl https://example.com\n"
+			}
+
+			fr, err := s.ScanBytes(ctx, name, []byte(content))
+			if err != nil {
+				t.Errorf("ScanBytes(%s): %v", name, err)
+				return
+			}
+			if got, want := len(fr.Behaviors), 1-i%2; fr.Path != name || got != want {
+				t.Errorf("ScanBytes(%s) = %s with %d behaviors, want %d", name, fr.Path, got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestScannerOptions(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	s, err := NewScanner(ctx, Options{
+		Config: malcontent.Config{MinRisk: 4},
+		RuleFS: []fs.FS{testRules},
+	})
+	if err != nil {
+		t.Fatalf("NewScanner: %v", err)
+	}
+	defer s.Close()
+
+	fr, err := s.ScanBytes(ctx, "fetch.sh", []byte("#!/bin/sh\ncurl https://example.com\n"))
+	if err != nil {
+		t.Fatalf("ScanBytes: %v", err)
+	}
+	if len(fr.Behaviors) != 0 {
+		t.Errorf("behaviors below MinRisk were reported: %+v", fr.Behaviors)
+	}
+
+	if _, err := NewScanner(ctx, Options{Config: malcontent.Config{OverridesFile: "does-not-exist.yaml"}}); err == nil {
+		t.Error("NewScanner with a missing overrides file succeeded, want error")
+	}
+
+	if _, err := s.ScanFile(ctx, "does-not-exist"); err == nil {
+		t.Error("ScanFile of a missing file succeeded, want error")
+	}
+}
+
+func TestScannerExtraRules(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wget.yara"), []byte("rule wget : high {\n\tstrings:\n\t\t$a = \"wget\"\n\tcondition:\n\t\t$a\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.yara"), []byte("rule broken {\n\tstrings:\n\t\t$a = \"x\"\n}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewScanner(ctx, Options{
+		Config: malcontent.Config{ExtraRulePaths: []string{dir}},
+		RuleFS: []fs.FS{testRules},
+	})
+	if err != nil {
+		t.Fatalf("NewScanner: %v", err)
+	}
+	defer s.Close()
+
+	if got := s.RuleErrors(); len(got) != 1 || got[0].Path != filepath.Join(dir, "broken.yara") {
+		t.Errorf("RuleErrors() = %+v, want one for broken.yara", got)
+	}
+	fr, err := s.ScanBytes(ctx, "fetch.sh", []byte("#!/bin/sh\ncurl https://example.com | wget -i -\n"))
+	if err != nil {
+		t.Fatalf("ScanBytes: %v", err)
+	}
+	if len(fr.Behaviors) != 2 {
+		t.Errorf("got %d behaviors, want matches from both the built-in and user rules: %+v", len(fr.Behaviors), fr.Behaviors)
+	}
+
+	if _, err := NewScanner(ctx, Options{
+		Config: malcontent.Config{ExtraRulePaths: []string{dir}, StrictRules: true},
+		RuleFS: []fs.FS{testRules},
+	}); err == nil {
+		t.Error("NewScanner with StrictRules and a broken user rule succeeded, want error")
+	}
+}
@@ -0,0 +1,8 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package malcontentpb holds the protocol buffer messages and gRPC stubs of the Malcontent service,
+// generated from malcontent.proto.
+package malcontentpb
+
+//go:generate go run github.com/bufbuild/buf/cmd/buf@v1.50.0 generate
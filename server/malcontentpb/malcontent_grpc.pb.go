@@ -0,0 +1,124 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: malcontent.proto
+
+package malcontentpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Malcontent_Scan_FullMethodName = "/malcontent.v1.Malcontent/Scan"
+)
+
+// MalcontentClient is the client API for Malcontent service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Malcontent scans files against a ruleset compiled once when the server starts.
+type MalcontentClient interface {
+	// Scan scans each file sent on the request stream, replying with its report in the same order.
+	Scan(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ScanRequest, FileReport], error)
+}
+
+type malcontentClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMalcontentClient(cc grpc.ClientConnInterface) MalcontentClient {
+	return &malcontentClient{cc}
+}
+
+func (c *malcontentClient) Scan(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ScanRequest, FileReport], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Malcontent_ServiceDesc.Streams[0], Malcontent_Scan_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ScanRequest, FileReport]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Malcontent_ScanClient = grpc.BidiStreamingClient[ScanRequest, FileReport]
+
+// MalcontentServer is the server API for Malcontent service.
+// All implementations must embed UnimplementedMalcontentServer
+// for forward compatibility.
+//
+// Malcontent scans files against a ruleset compiled once when the server starts.
+type MalcontentServer interface {
+	// Scan scans each file sent on the request stream, replying with its report in the same order.
+	Scan(grpc.BidiStreamingServer[ScanRequest, FileReport]) error
+	mustEmbedUnimplementedMalcontentServer()
+}
+
+// UnimplementedMalcontentServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMalcontentServer struct{}
+
+func (UnimplementedMalcontentServer) Scan(grpc.BidiStreamingServer[ScanRequest, FileReport]) error {
+	return status.Errorf(codes.Unimplemented, "method Scan not implemented")
+}
+func (UnimplementedMalcontentServer) mustEmbedUnimplementedMalcontentServer() {}
+func (UnimplementedMalcontentServer) testEmbeddedByValue()                    {}
+
+// UnsafeMalcontentServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MalcontentServer will
+// result in compilation errors.
+type UnsafeMalcontentServer interface {
+	mustEmbedUnimplementedMalcontentServer()
+}
+
+func RegisterMalcontentServer(s grpc.ServiceRegistrar, srv MalcontentServer) {
+	// If the following call pancis, it indicates UnimplementedMalcontentServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Malcontent_ServiceDesc, srv)
+}
+
+func _Malcontent_Scan_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(MalcontentServer).Scan(&grpc.GenericServerStream[ScanRequest, FileReport]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Malcontent_ScanServer = grpc.BidiStreamingServer[ScanRequest, FileReport]
+
+// Malcontent_ServiceDesc is the grpc.ServiceDesc for Malcontent service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Malcontent_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "malcontent.v1.Malcontent",
+	HandlerType: (*MalcontentServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Scan",
+			Handler:       _Malcontent_Scan_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "malcontent.proto",
+}
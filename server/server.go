@@ -0,0 +1,162 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server serves malcontent scans over gRPC, so that clients share a ruleset compiled once.
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/malcontent"
+	"github.com/chainguard-dev/malcontent/pkg/scan"
+	"github.com/chainguard-dev/malcontent/server/malcontentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultMaxMessageSize is the largest request accepted by default, bounding the size of a scanned file.
+	DefaultMaxMessageSize = 64 << 20
+	// DefaultShutdownTimeout is how long Serve waits for in-flight scans to finish by default.
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// Options configure a Server.
+type Options struct {
+	// MaxMessageSize is the largest request or response in bytes (0 uses DefaultMaxMessageSize).
+	// Larger requests are rejected with ResourceExhausted without being read into memory.
+	MaxMessageSize int
+	// ShutdownTimeout bounds how long Serve waits for in-flight scans once its context is done
+	// before closing their streams (0 uses DefaultShutdownTimeout).
+	ShutdownTimeout time.Duration
+}
+
+// Server implements the Malcontent gRPC service using a shared Scanner.
+type Server struct {
+	malcontentpb.UnimplementedMalcontentServer
+
+	opts    Options
+	scanner *scan.Scanner
+}
+
+// NewServer returns a Server that scans requests with scanner, which remains owned by the caller.
+func NewServer(scanner *scan.Scanner, opts Options) *Server {
+	if opts.MaxMessageSize <= 0 {
+		opts.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	return &Server{opts: opts, scanner: scanner}
+}
+
+// Serve accepts connections on lis until ctx is done, then stops accepting new
+// streams and waits up to Options.ShutdownTimeout for in-flight scans to finish.
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	gs := grpc.NewServer(
+		grpc.MaxRecvMsgSize(s.opts.MaxMessageSize),
+		grpc.MaxSendMsgSize(s.opts.MaxMessageSize),
+	)
+	malcontentpb.RegisterMalcontentServer(gs, s)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- gs.Serve(lis)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		gs.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(s.opts.ShutdownTimeout):
+		gs.Stop()
+	}
+	return <-errc
+}
+
+// Scan scans each file received on stream, sending its report before reading the next.
+func (s *Server) Scan(stream malcontentpb.Malcontent_ScanServer) error {
+	ctx := stream.Context()
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if req.GetName() == "" {
+			return status.Error(codes.InvalidArgument, "scan request has no name")
+		}
+
+		fr, err := s.scanner.ScanBytes(ctx, req.GetName(), req.GetData())
+		if err != nil {
+			if ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return status.Errorf(codes.Internal, "scan %s: %v", req.GetName(), err)
+		}
+		// Every request is answered, even when the file type filters exclude it
+		if fr == nil {
+			fr = &malcontent.FileReport{Path: req.GetName(), Skipped: "excluded by file type filters"}
+		}
+
+		if err := stream.Send(fileReportProto(fr)); err != nil {
+			return err
+		}
+	}
+}
+
+// fileReportProto converts fr to its protobuf representation.
+func fileReportProto(fr *malcontent.FileReport) *malcontentpb.FileReport {
+	pb := &malcontentpb.FileReport{
+		Path:              fr.Path,
+		Sha256:            fr.SHA256,
+		Hash:              fr.Hash,
+		HashAlgo:          fr.HashAlgo,
+		Size:              fr.Size,
+		DuplicateOf:       fr.DuplicateOf,
+		Skipped:           fr.Skipped,
+		Meta:              fr.Meta,
+		Syscalls:          fr.Syscalls,
+		Pledge:            fr.Pledge,
+		Capabilities:      fr.Capabilities,
+		Behaviors:         make([]*malcontentpb.Behavior, 0, len(fr.Behaviors)),
+		FilteredBehaviors: int32(fr.FilteredBehaviors), //nolint:gosec // behavior counts are far below 2^31
+		RiskScore:         int32(fr.RiskScore),         //nolint:gosec // risk scores range from -1 to 4
+		RiskLevel:         fr.RiskLevel.String(),
+		IsMalcontent:      fr.IsMalcontent,
+	}
+	for _, b := range fr.Behaviors {
+		pb.Behaviors = append(pb.Behaviors, &malcontentpb.Behavior{
+			Description:    b.Description,
+			MatchStrings:   b.MatchStrings,
+			RiskScore:      int32(b.RiskScore), //nolint:gosec // risk scores range from -1 to 4
+			RiskLevel:      b.RiskLevel.String(),
+			RuleUrl:        b.RuleURL,
+			ReferenceUrl:   b.ReferenceURL,
+			RuleAuthor:     b.RuleAuthor,
+			RuleAuthorUrl:  b.RuleAuthorURL,
+			RuleLicense:    b.RuleLicense,
+			RuleLicenseUrl: b.RuleLicenseURL,
+			Id:             b.ID,
+			RuleName:       b.RuleName,
+			Override:       b.Override,
+		})
+	}
+	return pb
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 Chainguard, Inc.
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/chainguard-dev/malcontent/pkg/scan"
+	"github.com/chainguard-dev/malcontent/server/malcontentpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+var testRules = fstest.MapFS{
+	"test/fetch.yara": &fstest.MapFile{Data: []byte(`
+rule fetch : high {
+	strings:
+		$a = "curl"
+	condition:
+		$a
+}
+`)},
+}
+
+// startServer serves a Server using testRules until the test ends, returning a client connected to it.
+func startServer(t *testing.T, opts Options) malcontentpb.MalcontentClient {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s, err := scan.NewScanner(ctx, scan.Options{RuleFS: []fs.FS{testRules}})
+	if err != nil {
+		t.Fatalf("NewScanner: %v", err)
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- NewServer(s, opts).Serve(ctx, lis)
+	}()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-served; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+		s.Close()
+	})
+	return malcontentpb.NewMalcontentClient(conn)
+}
+
+func TestScan(t *testing.T) {
+	t.Parallel()
+	client := startServer(t, Options{})
+
+	stream, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+
+	reqs := []*malcontentpb.ScanRequest{
+		{Name: "fetch.sh", Data: []byte("#!/bin/sh\ncurl -O https://example.com/x\n")},
+		{Name: "hello.sh", Data: []byte("#!/bin/sh\necho hello\n")},
+		{Name: "empty.sh"},
+	}
+	for _, req := range reqs {
+		if err := stream.Send(req); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []*malcontentpb.FileReport
+	for {
+		fr, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		got = append(got, fr)
+	}
+
+	if len(got) != len(reqs) {
+		t.Fatalf("got %d reports, want %d", len(got), len(reqs))
+	}
+	for i, req := range reqs {
+		if got[i].GetPath() != req.GetName() {
+			t.Errorf("report %d path = %q, want %q", i, got[i].GetPath(), req.GetName())
+		}
+	}
+	if bs := got[0].GetBehaviors(); len(bs) != 1 || bs[0].GetId() != "test/fetch" {
+		t.Errorf("fetch.sh behaviors = %v, want a single test/fetch behavior", bs)
+	}
+	if bs := got[1].GetBehaviors(); len(bs) != 0 {
+		t.Errorf("hello.sh behaviors = %v, want none", bs)
+	}
+	if got[2].GetSkipped() != "zero-sized file" {
+		t.Errorf("empty.sh skipped = %q, want zero-sized file", got[2].GetSkipped())
+	}
+}
+
+func TestScanMaxMessageSize(t *testing.T) {
+	t.Parallel()
+	client := startServer(t, Options{MaxMessageSize: 1024})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	stream, err := client.Scan(ctx)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	data := "#!/bin/sh\n" + strings.Repeat("curl https://example.com\n", 100)
+	if err := stream.Send(&malcontentpb.ScanRequest{Name: "big.sh", Data: []byte(data)}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Recv() error = %v, want ResourceExhausted", err)
+	}
+}
+
+func TestScanNoName(t *testing.T) {
+	t.Parallel()
+	client := startServer(t, Options{})
+
+	stream, err := client.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if err := stream.Send(&malcontentpb.ScanRequest{Data: []byte("#!/bin/sh\n")}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Recv() error = %v, want InvalidArgument", err)
+	}
+}
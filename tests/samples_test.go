@@ -314,8 +314,8 @@ func TestDiff(t *testing.T) {
 		format         string
 		src            string
 		dest           string
-		minResultScore int
-		minFileScore   int
+		minResultScore malcontent.RiskLevel
+		minFileScore   malcontent.RiskLevel
 	}{
 		{diff: "macOS/clean/ls.mdiff", format: "markdown", src: "linux/clean/ls.x86_64", dest: "macOS/clean/ls"},
 		{diff: "macOS/2023.3CX/libffmpeg.dirty.mdiff", format: "markdown", src: "macOS/2023.3CX/libffmpeg.dylib", dest: "macOS/2023.3CX/libffmpeg.dirty.dylib"},
@@ -385,8 +385,8 @@ func TestDiffFileChange(t *testing.T) {
 		format         string
 		src            string
 		dest           string
-		minResultScore int
-		minFileScore   int
+		minResultScore malcontent.RiskLevel
+		minFileScore   malcontent.RiskLevel
 	}{
 		{diff: "macOS/2023.3CX/libffmpeg.change_increase.mdiff", format: "markdown", src: "macOS/2023.3CX/libffmpeg.dylib", dest: "macOS/2023.3CX/libffmpeg.dirty.dylib"},
 		{diff: "macOS/2023.3CX/libffmpeg.change_decrease.mdiff", format: "markdown", src: "macOS/2023.3CX/libffmpeg.dirty.dylib", dest: "macOS/2023.3CX/libffmpeg.dylib"},
@@ -452,8 +452,8 @@ func TestDiffFileIncrease(t *testing.T) {
 		format         string
 		src            string
 		dest           string
-		minResultScore int
-		minFileScore   int
+		minResultScore malcontent.RiskLevel
+		minFileScore   malcontent.RiskLevel
 	}{
 		{diff: "macOS/2023.3CX/libffmpeg.increase.mdiff", format: "markdown", src: "macOS/2023.3CX/libffmpeg.dylib", dest: "macOS/2023.3CX/libffmpeg.dirty.dylib"},
 		{diff: "macOS/2023.3CX/libffmpeg.decrease.mdiff", format: "markdown", src: "macOS/2023.3CX/libffmpeg.dirty.dylib", dest: "macOS/2023.3CX/libffmpeg.dylib"},